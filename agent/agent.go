@@ -26,6 +26,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
 	"github.com/aws/amazon-ssm-agent/agent/framework/coremanager"
 	"github.com/aws/amazon-ssm-agent/agent/framework/coremodules"
 	"github.com/aws/amazon-ssm-agent/agent/health"
@@ -61,6 +62,9 @@ func start(log logger.T, instanceIDPtr *string, regionPtr *string, shouldCheckHi
 	}
 	context := context.Default(log, config)
 
+	// commands run in children of the agent cgroup, the document workers inherit the cgroup of the agent
+	executers.DelegateCommandCgroups(log)
+
 	//Reset password for default RunAs user if already exists
 	sessionUtil := &utility.SessionUtil{}
 	if err := sessionUtil.ResetPasswordIfDefaultUserExists(context); err != nil {
@@ -79,7 +83,9 @@ func start(log logger.T, instanceIDPtr *string, regionPtr *string, shouldCheckHi
 		//Starting hibernate mode
 		context.Log().Info("Entering SSM Agent hibernate - ", hibernationErr)
 		go func() {
-			hibernateState.ExecuteHibernation()
+			stopHibernationEndpoint := coremanager.StartHibernationEndpoint(context, hibernateState.Status)
+			hibernateState.ExecuteHibernation(hibernationErr)
+			stopHibernationEndpoint()
 			err = startAgent(ssmAgent, context, log, instanceIDPtr, regionPtr)
 		}()
 	} else {
@@ -21,6 +21,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/coremanager"
 	"github.com/aws/amazon-ssm-agent/agent/health"
 	"github.com/aws/amazon-ssm-agent/agent/hibernation"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 )
 
@@ -78,6 +79,8 @@ func (agent *SSMAgent) Start() {
 	}
 
 	agent.coreManager.Start()
+	// lets the updater detect crash loops of a newly installed version
+	updateutil.RecordHealthMarker(log, updateutil.HealthMarkerAgentStart, version.Version)
 }
 
 // Hibernate checks if the agent should hibernate when it can't reach the service
@@ -85,7 +88,7 @@ func (agent *SSMAgent) Hibernate() {
 	if status, err := agent.healthModule.GetAgentState(); status == health.Passive {
 		//Starting hibernate mode
 		agent.context.Log().Info("Entering SSM Agent hibernate - ", err)
-		agent.hibernateState.ExecuteHibernation()
+		agent.hibernateState.ExecuteHibernation(err)
 	}
 }
 
@@ -16,13 +16,7 @@ package appconfig
 
 import (
 	"fmt"
-	"log"
-	"os"
-	"runtime"
 	"sync"
-
-	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
-	"github.com/aws/amazon-ssm-agent/agent/version"
 )
 
 var loadedConfig *SsmagentConfig
@@ -31,30 +25,32 @@ var lock sync.RWMutex
 // Config loads the app configuration for amazon-ssm-agent.
 // If reload is true, it loads the config afresh,
 // otherwise it returns a previous loaded version, if any.
+// The configuration is layered, see LoadLayered.
 func Config(reload bool) (SsmagentConfig, error) {
 	if reload || !isLoaded() {
-		var agentConfig SsmagentConfig
-		agentConfig = DefaultConfig()
-		path, pathErr := getAppConfigPath()
-		if pathErr != nil {
-			return agentConfig, nil
+		layered, err := LoadLayered()
+		for _, layer := range layered.Layers {
+			fmt.Printf("Applying config override from %s.\n", layer)
 		}
-		agentConfig.Os.Name = runtime.GOOS
-		agentConfig.Agent.Version = version.Version
-
-		// Process config override
-		fmt.Printf("Applying config override from %s.\n", path)
-
-		if err := jsonutil.UnmarshalFile(path, &agentConfig); err != nil {
+		if err != nil {
 			fmt.Println("Failed to unmarshal config override. Fall back to default.")
-			return agentConfig, err
+			return layered.Config, err
+		}
+		if len(layered.Layers) == 0 {
+			return layered.Config, nil
 		}
-		parser(&agentConfig)
-		cache(agentConfig)
+		cache(layered.Config)
 	}
 	return getCached(), nil
 }
 
+// Load reads the app configuration for amazon-ssm-agent afresh, without caching it.
+// It returns an error when a layer of the configuration can't be parsed.
+func Load() (SsmagentConfig, error) {
+	layered, err := LoadLayered()
+	return layered.Config, err
+}
+
 func isLoaded() bool {
 	lock.RLock()
 	defer lock.RUnlock()
@@ -73,22 +69,12 @@ func getCached() SsmagentConfig {
 	return *loadedConfig
 }
 
-// looks for appconfig in working directory first and then the platform specific folder
-func getAppConfigPath() (path string, err error) {
-	// looking for appconfig in the platform specific folder
-	if _, err = os.Stat(AppConfigPath); err != nil {
-		return "", err
-	}
-
-	log.Printf("Found config file at %s.\n", AppConfigPath)
-	return AppConfigPath, err
-}
-
 // DefaultConfig returns default ssm agent configuration
 func DefaultConfig() SsmagentConfig {
 
 	var credsProfile = CredentialProfile{
-		ShareCreds: true,
+		ShareCreds:             true,
+		SessionDurationSeconds: DefaultCredentialSessionDurationSeconds,
 	}
 	var s3 S3Cfg
 	var mds = MdsCfg{
@@ -108,11 +94,13 @@ func DefaultConfig() SsmagentConfig {
 		AssociationLogsRetentionDurationHours: DefaultAssociationLogsRetentionDurationHours,
 		RunCommandLogsRetentionDurationHours:  DefaultRunCommandLogsRetentionDurationHours,
 		SessionLogsRetentionDurationHours:     DefaultSessionLogsRetentionDurationHours,
+		ExternalPluginDirectory:               DefaultExternalPluginPath,
 	}
 	var agent = AgentInfo{
 		Name:                 "amazon-ssm-agent",
 		OrchestrationRootDir: defaultOrchestrationRootDirName,
 		ContainerMode:        false,
+		DrainTimeoutSeconds:  DefaultDrainTimeoutSeconds,
 	}
 	var os = OsInfo{
 		Lang:    "en-US",
@@ -120,17 +108,39 @@ func DefaultConfig() SsmagentConfig {
 	}
 	var birdwatcher BirdwatcherCfg
 	var kms KmsConfig
+	var packageSigning PackageSigningCfg
+	var agentUpdate = AgentUpdateCfg{
+		HealthGates:             []string{"ServiceRunning"},
+		HealthGateWindowSeconds: DefaultHealthGateWindowSeconds,
+		CrashLoopRestartLimit:   DefaultCrashLoopRestartLimit,
+	}
+	var tracing = TracingCfg{
+		Endpoint: DefaultTracingEndpoint,
+	}
+
+	var output = OutputCfg{
+		SyslogFacility:              DefaultSyslogFacility,
+		WebhookBatchSize:            DefaultWebhookBatchSize,
+		WebhookBatchIntervalSeconds: DefaultWebhookBatchIntervalSeconds,
+		WebhookMaxRetries:           DefaultWebhookMaxRetries,
+		ArchiveMaxSizeMB:            DefaultArchiveMaxSizeMB,
+		ArchiveMaxFiles:             DefaultArchiveMaxFiles,
+	}
 
 	var ssmagentCfg = SsmagentConfig{
-		Profile:     credsProfile,
-		Mds:         mds,
-		Ssm:         ssm,
-		Mgs:         mgs,
-		Agent:       agent,
-		Os:          os,
-		S3:          s3,
-		Birdwatcher: birdwatcher,
-		Kms:         kms,
+		Profile:        credsProfile,
+		Mds:            mds,
+		Ssm:            ssm,
+		Mgs:            mgs,
+		Agent:          agent,
+		Os:             os,
+		S3:             s3,
+		Birdwatcher:    birdwatcher,
+		Kms:            kms,
+		PackageSigning: packageSigning,
+		AgentUpdate:    agentUpdate,
+		Tracing:        tracing,
+		Output:         output,
 	}
 
 	return ssmagentCfg
@@ -0,0 +1,222 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+// LayeredConfig is the configuration merged from all layers, along with the layer each setting comes from
+type LayeredConfig struct {
+	Config SsmagentConfig
+	// Layers are the files and environment variables applied, in order
+	Layers []string
+	// Sources maps the overridden settings, named Section.Key, to the last layer which set them
+	Sources map[string]string
+	// Ignored are the environment variables with the agent prefix which don't name a setting
+	Ignored []string
+}
+
+// Setting is the value of a setting, named Section.Key, and the layer it comes from
+type Setting struct {
+	Name   string
+	Value  interface{}
+	Source string
+}
+
+// DropInDir returns the directory whose *.json files override the config file
+func DropInDir() string {
+	return dropInDir(AppConfigPath)
+}
+
+func dropInDir(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), AppConfigDropInDirName)
+}
+
+// LoadLayered merges the default configuration, the config file, the drop-in files in lexical order
+// and the SSM_AGENT_<SECTION>_<KEY> environment variables, each layer overriding the previous ones.
+func LoadLayered() (LayeredConfig, error) {
+	return loadLayered(AppConfigPath, os.Environ())
+}
+
+func loadLayered(configPath string, environ []string) (layered LayeredConfig, err error) {
+	layered = LayeredConfig{
+		Config:  DefaultConfig(),
+		Sources: make(map[string]string),
+	}
+	layered.Config.Os.Name = runtime.GOOS
+	layered.Config.Agent.Version = version.Version
+
+	if _, statErr := os.Stat(configPath); statErr == nil {
+		log.Printf("Found config file at %s.\n", configPath)
+		if err = layered.applyFile(configPath); err != nil {
+			return
+		}
+	}
+
+	dropIns, _ := filepath.Glob(filepath.Join(dropInDir(configPath), "*.json"))
+	sort.Strings(dropIns)
+	for _, path := range dropIns {
+		if err = layered.applyFile(path); err != nil {
+			return
+		}
+	}
+
+	environ = append([]string{}, environ...)
+	sort.Strings(environ)
+	if err = layered.applyEnvironment(environ); err != nil {
+		return
+	}
+
+	parser(&layered.Config)
+	return
+}
+
+// Settings returns every setting of the configuration with the layer it comes from
+func (l LayeredConfig) Settings() []Setting {
+	settings := Settings(l.Config)
+	for i := range settings {
+		if source, ok := l.Sources[settings[i].Name]; ok {
+			settings[i].Source = source
+		} else {
+			settings[i].Source = AppConfigSourceDefault
+		}
+	}
+	return settings
+}
+
+// Settings returns every setting of the configuration, named Section.Key, in declaration order
+func Settings(config SsmagentConfig) (settings []Setting) {
+	value := reflect.ValueOf(config)
+	for i := 0; i < value.NumField(); i++ {
+		section := value.Type().Field(i)
+		if section.PkgPath != "" || section.Type.Kind() != reflect.Struct {
+			continue
+		}
+		for j := 0; j < section.Type.NumField(); j++ {
+			field := section.Type.Field(j)
+			if field.PkgPath != "" {
+				continue
+			}
+			settings = append(settings, Setting{
+				Name:  section.Name + "." + field.Name,
+				Value: value.Field(i).Field(j).Interface(),
+			})
+		}
+	}
+	return settings
+}
+
+// applyFile overrides the settings present in the json file
+func (l *LayeredConfig) applyFile(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(content, &l.Config); err != nil {
+		return fmt.Errorf("failed to parse %v, %v", path, err)
+	}
+
+	// json matches the keys case-insensitively, so does the provenance
+	var sections map[string]map[string]json.RawMessage
+	if json.Unmarshal(content, &sections) == nil {
+		config := reflect.ValueOf(&l.Config).Elem()
+		for section, keys := range sections {
+			for key := range keys {
+				if _, name, ok := lookupSetting(config, section, key); ok {
+					l.Sources[name] = path
+				}
+			}
+		}
+	}
+	l.Layers = append(l.Layers, path)
+	return nil
+}
+
+// applyEnvironment overrides the settings named by SSM_AGENT_<SECTION>_<KEY> environment variables
+func (l *LayeredConfig) applyEnvironment(environ []string) error {
+	config := reflect.ValueOf(&l.Config).Elem()
+	for _, variable := range environ {
+		pair := strings.SplitN(variable, "=", 2)
+		if len(pair) != 2 || !strings.HasPrefix(strings.ToUpper(pair[0]), AppConfigEnvPrefix) {
+			continue
+		}
+		parts := strings.SplitN(pair[0][len(AppConfigEnvPrefix):], "_", 2)
+		if len(parts) != 2 {
+			l.Ignored = append(l.Ignored, pair[0])
+			continue
+		}
+		field, name, ok := lookupSetting(config, parts[0], parts[1])
+		if !ok {
+			l.Ignored = append(l.Ignored, pair[0])
+			continue
+		}
+		if err := setFromString(field, pair[1]); err != nil {
+			return fmt.Errorf("invalid value for %v, %v", pair[0], err)
+		}
+		source := "environment variable " + pair[0]
+		l.Sources[name] = source
+		l.Layers = append(l.Layers, source)
+	}
+	return nil
+}
+
+// lookupSetting finds the field of the configuration for the section and key, ignoring case
+func lookupSetting(config reflect.Value, section string, key string) (field reflect.Value, name string, ok bool) {
+	for i := 0; i < config.NumField(); i++ {
+		sectionField := config.Type().Field(i)
+		if sectionField.PkgPath != "" || sectionField.Type.Kind() != reflect.Struct || !strings.EqualFold(sectionField.Name, section) {
+			continue
+		}
+		for j := 0; j < sectionField.Type.NumField(); j++ {
+			keyField := sectionField.Type.Field(j)
+			if keyField.PkgPath == "" && strings.EqualFold(keyField.Name, key) {
+				return config.Field(i).Field(j), sectionField.Name + "." + keyField.Name, true
+			}
+		}
+	}
+	return
+}
+
+// setFromString sets the field from the value of an environment variable.
+// Strings are taken as is, lists are json arrays or comma separated, and other values are json.
+func setFromString(field reflect.Value, value string) error {
+	switch {
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+		return nil
+	case field.Kind() == reflect.Slice && !strings.HasPrefix(strings.TrimSpace(value), "["):
+		items := reflect.MakeSlice(field.Type(), 0, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = reflect.Append(items, reflect.ValueOf(item))
+			}
+		}
+		field.Set(items)
+		return nil
+	default:
+		return json.Unmarshal([]byte(value), field.Addr().Interface())
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeLayer(t *testing.T, path string, content string) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
+
+func createTempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "appconfig")
+	assert.NoError(t, err)
+	return dir
+}
+
+func TestLoadLayeredWithoutLayers(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, AppConfigFileName)
+
+	layered, err := loadLayered(configPath, []string{"PATH=/usr/bin"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, layered.Layers)
+	assert.Empty(t, layered.Sources)
+	assert.Equal(t, DefaultCommandWorkersLimit, layered.Config.Mds.CommandWorkersLimit)
+}
+
+func TestLoadLayeredOrder(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, AppConfigFileName)
+	dropIn := dropInDir(configPath)
+	writeLayer(t, configPath, `{"Mds": {"CommandWorkersLimit": 2, "Endpoint": "mds.example.com"}, "Agent": {"Region": "us-east-1"}}`)
+	writeLayer(t, filepath.Join(dropIn, "20-workers.json"), `{"Mds": {"CommandWorkersLimit": 4}}`)
+	writeLayer(t, filepath.Join(dropIn, "10-workers.json"), `{"mds": {"commandworkerslimit": 3}, "Ssm": {"HealthFrequencyMinutes": 10}}`)
+	writeLayer(t, filepath.Join(dropIn, "30-ignored.txt"), `{"Mds": {"CommandWorkersLimit": 8}}`)
+	environ := []string{
+		"SSM_AGENT_AGENT_REGION=us-west-2",
+		"SSM_AGENT_SSM_HEALTHFREQUENCYMINUTES=20",
+		"SSM_AGENT_AGENTUPDATE_HEALTHGATES=ServiceRunning, Script",
+		"SSM_AGENT_UNKNOWN_SETTING=1",
+	}
+
+	layered, err := loadLayered(configPath, environ)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, layered.Config.Mds.CommandWorkersLimit)
+	assert.Equal(t, "mds.example.com", layered.Config.Mds.Endpoint)
+	assert.Equal(t, "us-west-2", layered.Config.Agent.Region)
+	assert.Equal(t, 20, layered.Config.Ssm.HealthFrequencyMinutes)
+	assert.Equal(t, []string{"ServiceRunning", "Script"}, layered.Config.AgentUpdate.HealthGates)
+	assert.Equal(t, []string{"SSM_AGENT_UNKNOWN_SETTING"}, layered.Ignored)
+
+	assert.Equal(t, []string{
+		configPath,
+		filepath.Join(dropIn, "10-workers.json"),
+		filepath.Join(dropIn, "20-workers.json"),
+		"environment variable SSM_AGENT_AGENTUPDATE_HEALTHGATES",
+		"environment variable SSM_AGENT_AGENT_REGION",
+		"environment variable SSM_AGENT_SSM_HEALTHFREQUENCYMINUTES",
+	}, layered.Layers)
+	assert.Equal(t, map[string]string{
+		"Mds.CommandWorkersLimit":    filepath.Join(dropIn, "20-workers.json"),
+		"Mds.Endpoint":               configPath,
+		"Agent.Region":               "environment variable SSM_AGENT_AGENT_REGION",
+		"Ssm.HealthFrequencyMinutes": "environment variable SSM_AGENT_SSM_HEALTHFREQUENCYMINUTES",
+		"AgentUpdate.HealthGates":    "environment variable SSM_AGENT_AGENTUPDATE_HEALTHGATES",
+	}, layered.Sources)
+}
+
+func TestLoadLayeredInvalidDropIn(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, AppConfigFileName)
+	writeLayer(t, filepath.Join(dropInDir(configPath), "10-workers.json"), `{"Mds": {"CommandWorkersLimit": "many"}}`)
+
+	_, err := loadLayered(configPath, nil)
+
+	assert.Error(t, err)
+}
+
+func TestLoadLayeredInvalidEnvironmentVariable(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, AppConfigFileName)
+
+	_, err := loadLayered(configPath, []string{"SSM_AGENT_MDS_COMMANDWORKERSLIMIT=many"})
+
+	assert.Error(t, err)
+}
+
+func TestLayeredConfigSettings(t *testing.T) {
+	layered := LayeredConfig{
+		Config:  DefaultConfig(),
+		Sources: map[string]string{"Mds.CommandWorkersLimit": "environment variable SSM_AGENT_MDS_COMMANDWORKERSLIMIT"},
+	}
+	layered.Config.Mds.CommandWorkersLimit = 10
+
+	settings := map[string]Setting{}
+	for _, setting := range layered.Settings() {
+		settings[setting.Name] = setting
+	}
+
+	assert.Equal(t, Setting{
+		Name:   "Mds.CommandWorkersLimit",
+		Value:  10,
+		Source: "environment variable SSM_AGENT_MDS_COMMANDWORKERSLIMIT",
+	}, settings["Mds.CommandWorkersLimit"])
+	assert.Equal(t, AppConfigSourceDefault, settings["Mds.StopTimeoutMillis"].Source)
+	assert.Contains(t, settings, "Tracing.Exporter")
+}
@@ -23,10 +23,22 @@ import (
 func parser(config *SsmagentConfig) {
 	log.Printf("processing appconfig overrides")
 
+	// Profile config
+	config.Profile.SessionDurationSeconds = getNumericValue(
+		config.Profile.SessionDurationSeconds,
+		DefaultCredentialSessionDurationSecondsMin,
+		DefaultCredentialSessionDurationSecondsMax,
+		DefaultCredentialSessionDurationSeconds)
+
 	// Agent config
 	config.Agent.Name = getStringValue(config.Agent.Name, DefaultAgentName)
 	config.Agent.OrchestrationRootDir = getStringValue(config.Agent.OrchestrationRootDir, defaultOrchestrationRootDirName)
 	config.Agent.Region = getStringValue(config.Agent.Region, "")
+	config.Agent.DrainTimeoutSeconds = getNumericValue(
+		config.Agent.DrainTimeoutSeconds,
+		DefaultDrainTimeoutSecondsMin,
+		DefaultDrainTimeoutSecondsMax,
+		DefaultDrainTimeoutSeconds)
 
 	// MDS config
 	config.Mds.CommandWorkersLimit = getNumericValue(
@@ -48,6 +60,7 @@ func parser(config *SsmagentConfig) {
 
 	// SSM config
 	config.Ssm.Endpoint = getStringValue(config.Ssm.Endpoint, "")
+	config.Ssm.ExternalPluginDirectory = getStringValue(config.Ssm.ExternalPluginDirectory, DefaultExternalPluginPath)
 	config.Ssm.HealthFrequencyMinutes = getNumericValue(
 		config.Ssm.HealthFrequencyMinutes,
 		DefaultSsmHealthFrequencyMinutesMin,
@@ -67,6 +80,38 @@ func parser(config *SsmagentConfig) {
 		DefaultStateOrchestrationLogsRetentionDurationHoursMin,
 		DefaultRunCommandLogsRetentionDurationHours)
 
+	// Agent update config
+	config.AgentUpdate.HealthGateWindowSeconds = getNumericValue(
+		config.AgentUpdate.HealthGateWindowSeconds,
+		DefaultHealthGateWindowSecondsMin,
+		DefaultHealthGateWindowSecondsMax,
+		DefaultHealthGateWindowSeconds)
+	config.AgentUpdate.CrashLoopRestartLimit = getNumericValue(
+		config.AgentUpdate.CrashLoopRestartLimit,
+		DefaultCrashLoopRestartLimitMin,
+		DefaultCrashLoopRestartLimitMax,
+		DefaultCrashLoopRestartLimit)
+
+	// Tracing config
+	config.Tracing.Endpoint = getStringValue(config.Tracing.Endpoint, DefaultTracingEndpoint)
+
+	// Execution config, out of range limits are unlimited
+	config.Execution.CPUQuotaPercent = getNumericValueAboveMin(config.Execution.CPUQuotaPercent, 0, 0)
+	config.Execution.MemoryMaxMB = getNumericValueAboveMin(config.Execution.MemoryMaxMB, 0, 0)
+	config.Execution.PidsMax = getNumericValueAboveMin(config.Execution.PidsMax, 0, 0)
+	config.Execution.IOWeight = getNumericValue(config.Execution.IOWeight, 0, IOWeightMax, 0)
+
+	// Output config
+	config.Output.SyslogFacility = getNumericValue(config.Output.SyslogFacility, 1, SyslogFacilityMax, DefaultSyslogFacility)
+	config.Output.WebhookBatchSize = getNumericValue(config.Output.WebhookBatchSize, 1, WebhookBatchSizeMax, DefaultWebhookBatchSize)
+	config.Output.WebhookBatchIntervalSeconds = getNumericValue(
+		config.Output.WebhookBatchIntervalSeconds,
+		1,
+		WebhookBatchIntervalSecondsMax,
+		DefaultWebhookBatchIntervalSeconds)
+	config.Output.WebhookMaxRetries = getNumericValue(config.Output.WebhookMaxRetries, 0, WebhookMaxRetriesMax, DefaultWebhookMaxRetries)
+	config.Output.ArchiveMaxSizeMB = getNumericValueAboveMin(config.Output.ArchiveMaxSizeMB, 1, DefaultArchiveMaxSizeMB)
+	config.Output.ArchiveMaxFiles = getNumericValue(config.Output.ArchiveMaxFiles, 1, ArchiveMaxFilesMax, DefaultArchiveMaxFiles)
 }
 
 // getStringValue returns the default value if config is empty, else the config value
@@ -0,0 +1,44 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package configwatcher
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// changedSettings returns the settings, named Section.Key, whose values differ between the configurations
+func changedSettings(previous appconfig.SsmagentConfig, current appconfig.SsmagentConfig) (settings []string) {
+	prev := appconfig.Settings(previous)
+	cur := appconfig.Settings(current)
+	for i := range prev {
+		if !reflect.DeepEqual(prev[i].Value, cur[i].Value) {
+			settings = append(settings, prev[i].Name)
+		}
+	}
+	return settings
+}
+
+// copySetting sets the setting, named Section.Key, of the configuration to its value in source
+func copySetting(config *appconfig.SsmagentConfig, source appconfig.SsmagentConfig, setting string) {
+	dst := reflect.ValueOf(config).Elem()
+	src := reflect.ValueOf(source)
+	for _, name := range strings.Split(setting, ".") {
+		dst = dst.FieldByName(name)
+		src = src.FieldByName(name)
+	}
+	dst.Set(src)
+}
@@ -0,0 +1,273 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package configwatcher reloads the configuration of the agent when amazon-ssm-agent.json or its drop-in files change,
+// and notifies the modules of the settings that changed.
+package configwatcher
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay lets an editor finish writing the config file before it's reloaded
+var reloadDelay = time.Second
+
+// liveSettings are read from the configuration each time they're used, so they apply without notification
+var liveSettings = map[string]bool{
+	"Ssm.RunCommandLogsRetentionDurationHours":  true,
+	"Ssm.AssociationLogsRetentionDurationHours": true,
+	"Ssm.SessionLogsRetentionDurationHours":     true,
+}
+
+// runtimeSettings are set by the agent itself rather than read from the config file
+var runtimeSettings = map[string]bool{
+	"Agent.Version": true,
+	"Os.Name":       true,
+}
+
+// Change describes a reload of the configuration
+type Change struct {
+	// Previous is the configuration before the reload
+	Previous appconfig.SsmagentConfig
+	// Current is the configuration in effect after the reload
+	Current appconfig.SsmagentConfig
+	// Applied are the settings which changed and now have their new value, e.g. Mds.CommandWorkersLimit
+	Applied []string
+	// RestartRequired are the settings which changed but keep their previous value until the agent restarts
+	RestartRequired []string
+}
+
+// Handler applies the settings which changed to a module
+type Handler func(change Change)
+
+type subscription struct {
+	name     string
+	settings []string
+	handler  Handler
+}
+
+// Watcher holds the configuration of the agent and reloads it when the config file changes or the agent receives SIGHUP.
+// A nil Watcher never reloads the configuration.
+type Watcher struct {
+	log       log.T
+	path      string
+	dropInDir string
+	load      func() (appconfig.SsmagentConfig, error)
+
+	mu            sync.RWMutex
+	config        appconfig.SsmagentConfig
+	subscriptions []subscription
+
+	reloadLock  sync.Mutex
+	fileWatcher *fsnotify.Watcher
+	signals     chan os.Signal
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewWatcher creates a watcher holding the configuration the agent started with
+func NewWatcher(log log.T, config appconfig.SsmagentConfig) *Watcher {
+	return &Watcher{
+		log:       log,
+		path:      appconfig.AppConfigPath,
+		dropInDir: appconfig.DropInDir(),
+		load:      appconfig.Load,
+		config:    config,
+	}
+}
+
+// Config returns the configuration in effect
+func (w *Watcher) Config() appconfig.SsmagentConfig {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.config
+}
+
+// Subscribe calls the handler after a reload changed any of the settings, named Section.Key, e.g. Mds.CommandWorkersLimit.
+// Settings nobody subscribes to, and which are not read on each use, are reported as requiring a restart.
+func (w *Watcher) Subscribe(name string, settings []string, handler Handler) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscriptions = append(w.subscriptions, subscription{name: name, settings: settings, handler: handler})
+}
+
+// Start watches the config file and SIGHUP to reload the configuration
+func (w *Watcher) Start() {
+	if w == nil {
+		return
+	}
+	w.stop = make(chan struct{})
+	w.signals = make(chan os.Signal, 1)
+	notifyReload(w.signals)
+
+	// the config file may not exist yet, so the directory is watched rather than the file
+	var events chan fsnotify.Event
+	if fileWatcher, err := fsnotify.NewWatcher(); err != nil {
+		w.log.Errorf("failed to watch the config file, %v", err)
+	} else if err = fileWatcher.Add(filepath.Dir(w.path)); err != nil {
+		w.log.Errorf("failed to watch the config file, %v", err)
+		fileWatcher.Close()
+	} else {
+		w.fileWatcher = fileWatcher
+		events = fileWatcher.Events
+		// drop-in files are optional, a directory created later is picked up on SIGHUP
+		if _, err = os.Stat(w.dropInDir); err == nil {
+			if err = fileWatcher.Add(w.dropInDir); err != nil {
+				w.log.Errorf("failed to watch the drop-in config directory, %v", err)
+			}
+		}
+	}
+
+	w.log.Infof("Watching %v and %v for configuration changes", w.path, w.dropInDir)
+	go w.watch(events)
+}
+
+// Stop stops watching the config file and SIGHUP
+func (w *Watcher) Stop() {
+	if w == nil || w.stop == nil {
+		return
+	}
+	w.stopOnce.Do(func() {
+		signal.Stop(w.signals)
+		if w.fileWatcher != nil {
+			w.fileWatcher.Close()
+		}
+		close(w.stop)
+	})
+}
+
+func (w *Watcher) watch(events chan fsnotify.Event) {
+	var pending <-chan time.Time
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if w.isConfigFile(event.Name) &&
+				event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+				pending = time.After(reloadDelay)
+			}
+		case <-pending:
+			pending = nil
+			w.log.Info("Configuration files changed, reloading the configuration")
+			w.Reload()
+		case <-w.signals:
+			w.log.Info("Received SIGHUP, reloading the configuration")
+			w.Reload()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// isConfigFile returns true for the config file and the json files of the drop-in directory
+func (w *Watcher) isConfigFile(name string) bool {
+	name = filepath.Clean(name)
+	if name == filepath.Clean(w.path) {
+		return true
+	}
+	return filepath.Dir(name) == filepath.Clean(w.dropInDir) && filepath.Ext(name) == ".json"
+}
+
+// Reload reads the layers of the configuration and applies the settings which can change without a restart.
+// The current configuration is kept when any layer is invalid.
+func (w *Watcher) Reload() (change Change, err error) {
+	if w == nil {
+		return
+	}
+	w.reloadLock.Lock()
+	defer w.reloadLock.Unlock()
+
+	loaded, err := w.load()
+	if err != nil {
+		w.log.Errorf("configuration is invalid, keeping the current configuration, %v", err)
+		return
+	}
+
+	w.mu.RLock()
+	previous := w.config
+	subscriptions := append([]subscription{}, w.subscriptions...)
+	w.mu.RUnlock()
+
+	change = Change{Previous: previous, Current: previous}
+	for _, setting := range changedSettings(previous, loaded) {
+		if runtimeSettings[setting] {
+			continue
+		}
+		if liveSettings[setting] || isSubscribed(subscriptions, setting) {
+			copySetting(&change.Current, loaded, setting)
+			change.Applied = append(change.Applied, setting)
+		} else {
+			change.RestartRequired = append(change.RestartRequired, setting)
+		}
+	}
+	if len(change.RestartRequired) > 0 {
+		w.log.Warnf("Configuration changes to %v require a restart of the agent, they keep their previous value until then",
+			strings.Join(change.RestartRequired, ", "))
+	}
+	if len(change.Applied) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	w.config = change.Current
+	w.mu.Unlock()
+	w.log.Infof("Applied configuration changes to %v", strings.Join(change.Applied, ", "))
+
+	for _, sub := range subscriptions {
+		if changesAny(change.Applied, sub.settings) {
+			w.log.Debugf("Notifying %v of the configuration changes", sub.name)
+			sub.handler(change)
+		}
+	}
+	return
+}
+
+// Changed returns whether the setting changed and has its new value
+func (c Change) Changed(setting string) bool {
+	return changesAny(c.Applied, []string{setting})
+}
+
+func isSubscribed(subscriptions []subscription, setting string) bool {
+	for _, sub := range subscriptions {
+		if changesAny([]string{setting}, sub.settings) {
+			return true
+		}
+	}
+	return false
+}
+
+func changesAny(changed []string, settings []string) bool {
+	for _, c := range changed {
+		for _, s := range settings {
+			if c == s {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package configwatcher
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestWatcher(loaded *appconfig.SsmagentConfig) *Watcher {
+	watcher := NewWatcher(log.NewMockLog(), appconfig.DefaultConfig())
+	watcher.load = func() (appconfig.SsmagentConfig, error) {
+		return *loaded, nil
+	}
+	return watcher
+}
+
+func TestChangedSettings(t *testing.T) {
+	previous := appconfig.DefaultConfig()
+	current := appconfig.DefaultConfig()
+	current.Mds.CommandWorkersLimit = 10
+	current.AgentUpdate.HealthGates = []string{"ServiceRunning", "Script"}
+
+	assert.Equal(t, []string{"Mds.CommandWorkersLimit", "AgentUpdate.HealthGates"}, changedSettings(previous, current))
+	assert.Empty(t, changedSettings(previous, appconfig.DefaultConfig()))
+}
+
+func TestReloadAppliesLiveAndSubscribedSettings(t *testing.T) {
+	loaded := appconfig.DefaultConfig()
+	loaded.Mds.CommandWorkersLimit = 10
+	loaded.Ssm.RunCommandLogsRetentionDurationHours = 24
+	loaded.Agent.Region = "us-west-2"
+	loaded.Agent.Version = "9.9.9.9"
+	watcher := newTestWatcher(&loaded)
+
+	var notified []Change
+	watcher.Subscribe("MessageDeliveryService", []string{"Mds.CommandWorkersLimit"}, func(change Change) {
+		notified = append(notified, change)
+	})
+	watcher.Subscribe("HealthCheck", []string{"Ssm.HealthFrequencyMinutes"}, func(change Change) {
+		assert.Fail(t, "notified of a setting which didn't change")
+	})
+
+	change, err := watcher.Reload()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Mds.CommandWorkersLimit", "Ssm.RunCommandLogsRetentionDurationHours"}, change.Applied)
+	assert.Equal(t, []string{"Agent.Region"}, change.RestartRequired)
+	assert.Len(t, notified, 1)
+	assert.True(t, notified[0].Changed("Mds.CommandWorkersLimit"))
+
+	config := watcher.Config()
+	assert.Equal(t, 10, config.Mds.CommandWorkersLimit)
+	assert.Equal(t, 24, config.Ssm.RunCommandLogsRetentionDurationHours)
+	assert.Equal(t, "", config.Agent.Region, "settings requiring a restart keep their previous value")
+
+	// reloading the same file changes nothing
+	change, err = watcher.Reload()
+	assert.NoError(t, err)
+	assert.Empty(t, change.Applied)
+	assert.Len(t, notified, 1)
+}
+
+func TestReloadKeepsConfigurationWhenInvalid(t *testing.T) {
+	watcher := NewWatcher(log.NewMockLog(), appconfig.DefaultConfig())
+	watcher.load = func() (appconfig.SsmagentConfig, error) {
+		config := appconfig.DefaultConfig()
+		config.Ssm.RunCommandLogsRetentionDurationHours = 1
+		return config, errors.New("invalid character '}'")
+	}
+
+	_, err := watcher.Reload()
+
+	assert.Error(t, err)
+	assert.Equal(t, appconfig.DefaultConfig(), watcher.Config())
+}
+
+func TestWatcherReloadsWhenConfigFileChanges(t *testing.T) {
+	reloadDelay = 10 * time.Millisecond
+	loaded := appconfig.DefaultConfig()
+	watcher := newTestWatcher(&loaded)
+	dir, err := ioutil.TempDir("", "configwatcher")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	watcher.path = filepath.Join(dir, appconfig.AppConfigFileName)
+
+	notified := make(chan Change, 1)
+	watcher.Subscribe("MessageDeliveryService", []string{"Mds.CommandWorkersLimit"}, func(change Change) {
+		notified <- change
+	})
+	watcher.Start()
+	defer watcher.Stop()
+
+	loaded.Mds.CommandWorkersLimit = 10
+	assert.NoError(t, ioutil.WriteFile(watcher.path, []byte("{}"), 0600))
+
+	select {
+	case change := <-notified:
+		assert.Equal(t, 10, change.Current.Mds.CommandWorkersLimit)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "configuration not reloaded")
+	}
+}
+
+func TestWatcherReloadsWhenDropInFileChanges(t *testing.T) {
+	reloadDelay = 10 * time.Millisecond
+	loaded := appconfig.DefaultConfig()
+	watcher := newTestWatcher(&loaded)
+	dir, err := ioutil.TempDir("", "configwatcher")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	watcher.path = filepath.Join(dir, appconfig.AppConfigFileName)
+	watcher.dropInDir = filepath.Join(dir, appconfig.AppConfigDropInDirName)
+	assert.NoError(t, os.Mkdir(watcher.dropInDir, 0700))
+
+	notified := make(chan Change, 1)
+	watcher.Subscribe("MessageDeliveryService", []string{"Mds.CommandWorkersLimit"}, func(change Change) {
+		notified <- change
+	})
+	watcher.Start()
+	defer watcher.Stop()
+
+	loaded.Mds.CommandWorkersLimit = 10
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(watcher.dropInDir, "10-workers.json"), []byte("{}"), 0600))
+
+	select {
+	case change := <-notified:
+		assert.Equal(t, 10, change.Current.Mds.CommandWorkersLimit)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "configuration not reloaded")
+	}
+}
+
+func TestIsConfigFile(t *testing.T) {
+	watcher := NewWatcher(log.NewMockLog(), appconfig.DefaultConfig())
+	watcher.path = filepath.Join("etc", "amazon", "ssm", appconfig.AppConfigFileName)
+	watcher.dropInDir = filepath.Join("etc", "amazon", "ssm", appconfig.AppConfigDropInDirName)
+
+	assert.True(t, watcher.isConfigFile(watcher.path))
+	assert.True(t, watcher.isConfigFile(filepath.Join(watcher.dropInDir, "10-workers.json")))
+	assert.False(t, watcher.isConfigFile(filepath.Join(watcher.dropInDir, "10-workers.json.swp")))
+	assert.False(t, watcher.isConfigFile(filepath.Join("etc", "amazon", "ssm", "seelog.xml")))
+}
+
+func TestNilWatcher(t *testing.T) {
+	var watcher *Watcher
+	watcher.Subscribe("HealthCheck", nil, func(Change) {})
+	watcher.Start()
+	watcher.Stop()
+	_, err := watcher.Reload()
+	assert.NoError(t, err)
+}
@@ -40,6 +40,52 @@ const (
 	DefaultSsmAssociationFrequencyMinutesMin = 5
 	DefaultSsmAssociationFrequencyMinutesMax = 60
 
+	// Agent update defaults
+	DefaultHealthGateWindowSeconds    = 300
+	DefaultHealthGateWindowSecondsMin = 30
+	DefaultHealthGateWindowSecondsMax = 3600
+
+	DefaultCrashLoopRestartLimit    = 3
+	DefaultCrashLoopRestartLimitMin = 1
+	DefaultCrashLoopRestartLimitMax = 100
+
+	// Credential session defaults
+	DefaultCredentialSessionDurationSeconds    = 3600
+	DefaultCredentialSessionDurationSecondsMin = 900
+	DefaultCredentialSessionDurationSecondsMax = 43200
+
+	// IOWeightMax is the highest io.weight of a cgroup
+	IOWeightMax = 10000
+
+	// Drain defaults
+	DefaultDrainTimeoutSeconds    = 600
+	DefaultDrainTimeoutSecondsMin = 1
+	DefaultDrainTimeoutSecondsMax = 86400
+
+	// DrainRequestFileName is the file in DrainRoot which requests a drain of the agent
+	DrainRequestFileName = "request.json"
+	// DrainStatusFileName is the file in DrainRoot where the agent reports the progress of a drain
+	DrainStatusFileName = "status.json"
+
+	// Output sink defaults
+	DefaultSyslogFacility              = 1
+	SyslogFacilityMax                  = 23
+	DefaultWebhookBatchSize            = 100
+	WebhookBatchSizeMax                = 10000
+	DefaultWebhookBatchIntervalSeconds = 5
+	WebhookBatchIntervalSecondsMax     = 300
+	DefaultWebhookMaxRetries           = 3
+	WebhookMaxRetriesMax               = 10
+	DefaultArchiveMaxSizeMB            = 10
+	DefaultArchiveMaxFiles             = 5
+	ArchiveMaxFilesMax                 = 100
+
+	// Tracing defaults
+	TracingExporterOTLP    = "otlp"
+	TracingExporterFile    = "file"
+	DefaultTracingEndpoint = "http://127.0.0.1:4318"
+	DefaultTracingFileName = "traces.json"
+
 	//aws-ssm-agent bookkeeping constants
 	DefaultLocationOfPending     = "pending"
 	DefaultLocationOfCurrent     = "current"
@@ -159,6 +205,15 @@ const (
 	AppConfigFileName    = "amazon-ssm-agent.json"
 	SeelogConfigFileName = "seelog.xml"
 
+	// AppConfigDropInDirName is the directory, next to the config file, whose *.json files override it in lexical order
+	AppConfigDropInDirName = "amazon-ssm-agent.d"
+
+	// AppConfigEnvPrefix prefixes the environment variables overriding a setting, named SSM_AGENT_<SECTION>_<KEY>
+	AppConfigEnvPrefix = "SSM_AGENT_"
+
+	// AppConfigSourceDefault is the source of the settings no layer overrides
+	AppConfigSourceDefault = "default"
+
 	// Output truncation limits
 	MaxStdoutLength = 24000
 	MaxStderrLength = 8000
@@ -38,6 +38,9 @@ const (
 	// LocalCommandRoot specifies the directory where users can submit command documents offline
 	LocalCommandRoot = DefaultProgramFolder + "localcommands"
 
+	// DrainRoot specifies the directory where ssm-cli requests a drain of the agent and reads its progress
+	DrainRoot = DefaultProgramFolder + "drain"
+
 	// LocalCommandRootSubmitted is the directory where locally submitted command documents
 	// are moved when they have been picked up
 	LocalCommandRootSubmitted = DefaultProgramFolder + "localcommands/submitted"
@@ -68,6 +71,9 @@ const (
 	// ManifestCacheDirectory represents the directory for storing all downloaded manifest files
 	ManifestCacheDirectory = DefaultProgramFolder + "manifests"
 
+	// DefaultExternalPluginPath represents the directory of the executables of the out-of-tree plugins
+	DefaultExternalPluginPath = DefaultProgramFolder + "external-plugins"
+
 	// List all plugin names, unfortunately golang doesn't support const arrays of strings
 
 	// RebootExitCode that would trigger a Soft Reboot
@@ -40,6 +40,9 @@ const (
 	// LocalCommandRoot specifies the directory where users can submit command documents offline
 	LocalCommandRoot = "/var/lib/amazon/ssm/localcommands"
 
+	// DrainRoot specifies the directory where ssm-cli requests a drain of the agent and reads its progress
+	DrainRoot = "/var/lib/amazon/ssm/drain"
+
 	// LocalCommandRootSubmitted is the directory where locally submitted command documents
 	// are moved when they have been picked up
 	LocalCommandRootSubmitted = "/var/lib/amazon/ssm/localcommands/submitted"
@@ -70,6 +73,9 @@ const (
 	// ManifestCacheDirectory represents the directory for storing all downloaded manifest files
 	ManifestCacheDirectory = "/var/lib/amazon/ssm/manifests"
 
+	// DefaultExternalPluginPath represents the directory of the executables of the out-of-tree plugins
+	DefaultExternalPluginPath = "/usr/local/lib/amazon/ssm/plugins"
+
 	// List all plugin names, unfortunately golang doesn't support const arrays of strings
 
 	// RebootExitCode that would trigger a Soft Reboot
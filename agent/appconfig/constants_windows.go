@@ -95,6 +95,9 @@ var DaemonRoot string
 // LocalCommandRoot specifies the directory where users can submit command documents offline
 var LocalCommandRoot string
 
+// DrainRoot specifies the directory where ssm-cli requests a drain of the agent and reads its progress
+var DrainRoot string
+
 // LocalCommandRootSubmitted is the directory where locally submitted command documents
 // are moved when they have been picked up
 var LocalCommandRootSubmitted string
@@ -110,6 +113,9 @@ var DefaultPluginPath string
 // ManifestCacheDirectory represents the directory for storing all downloaded manifest files
 var ManifestCacheDirectory string
 
+// DefaultExternalPluginPath represents the directory of the executables of the out-of-tree plugins
+var DefaultExternalPluginPath string
+
 // DownloadRoot specifies the directory under which files will be downloaded
 var DownloadRoot string
 
@@ -170,6 +176,7 @@ func init() {
 	DefaultSessionWorker = filepath.Join(DefaultProgramFolder, "ssm-session-worker.exe")
 	DefaultSessionLogger = fmt.Sprintf("&'%s'", filepath.Join(DefaultProgramFolder, "ssm-session-logger.exe"))
 	ManifestCacheDirectory = filepath.Join(EnvProgramFiles, ManifestCacheFolder)
+	DefaultExternalPluginPath = filepath.Join(EnvProgramFiles, SSMFolder, "ExternalPlugins")
 	AppConfigPath = filepath.Join(DefaultProgramFolder, AppConfigFileName)
 	DefaultDataStorePath = filepath.Join(SSMDataPath, "InstanceData")
 	PackageRoot = filepath.Join(SSMDataPath, "Packages")
@@ -179,6 +186,7 @@ func init() {
 	LocalCommandRootSubmitted = filepath.Join(LocalCommandRoot, "Submitted")
 	LocalCommandRootCompleted = filepath.Join(LocalCommandRoot, "Completed")
 	LocalCommandRootInvalid = filepath.Join(LocalCommandRoot, "Invalid")
+	DrainRoot = filepath.Join(SSMDataPath, "Drain")
 	DownloadRoot = filepath.Join(temp, SSMFolder, "Download")
 	UpdaterArtifactsRoot = filepath.Join(temp, SSMFolder, "Update")
 	EC2UpdateArtifactsRoot = filepath.Join(EnvWinDir, EC2ConfigServiceFolder, "Update")
@@ -14,10 +14,31 @@
 // Package appconfig manages the configuration of the agent.
 package appconfig
 
-// CredentialProfile represents configurations for aws credential profile
+// CredentialProfile represents configurations for aws credential profile and the providers of the agent credentials
 type CredentialProfile struct {
 	ShareCreds   bool
 	ShareProfile string
+	// Providers lists the credential providers to try in order, the first one to return credentials is used:
+	// ManagedInstance, X509, CredentialProcess, WebIdentity and InstanceProfile. When empty, the agent uses the
+	// managed instance credentials if the instance is registered, else the EC2 or ECS credentials.
+	Providers []string
+	// CredentialProcess is the command run by the CredentialProcess provider, it prints the credentials
+	// in the format of the credential_process setting of the AWS CLI
+	CredentialProcess string
+	// WebIdentityTokenFile is the path of the OIDC token the WebIdentity provider exchanges for credentials of WebIdentityRoleArn
+	WebIdentityTokenFile string
+	WebIdentityRoleArn   string
+	// X509CertificateFile and X509PrivateKeyFile are the PEM files of the client certificate the X509 provider signs
+	// IAM Roles Anywhere session requests with
+	X509CertificateFile string
+	X509PrivateKeyFile  string
+	X509TrustAnchorArn  string
+	X509ProfileArn      string
+	X509RoleArn         string
+	// X509Endpoint overrides the regional IAM Roles Anywhere endpoint
+	X509Endpoint string
+	// SessionDurationSeconds is the lifetime of the credentials requested by the X509 provider
+	SessionDurationSeconds int
 }
 
 // MdsCfg represents configuration for Message delivery service (MDS)
@@ -41,6 +62,18 @@ type SsmCfg struct {
 	AssociationLogsRetentionDurationHours int
 	RunCommandLogsRetentionDurationHours  int
 	SessionLogsRetentionDurationHours     int
+	// RunAsAllowedUsers lists the local users the script steps of command and association documents
+	// may run as with runAsUser. Documents with runAsUser fail when the list is empty.
+	RunAsAllowedUsers []string
+	// OutputRedactionPatterns are regular expressions whose matches are replaced with **** in the output
+	// of the plugins, before it's written to files, S3 or CloudWatch. A match can't span lines.
+	OutputRedactionPatterns []string
+	// ExternalPluginDirectory holds the executables of the out-of-tree plugins, in a sub-directory per action prefix:
+	// the acme:deployService action runs <ExternalPluginDirectory>/acme/deployService.
+	ExternalPluginDirectory string
+	// ExternalPluginPublicKeys are base64 encoded ed25519 public keys. When set, an external plugin only runs
+	// if the .sig file next to its executable holds a signature of the executable by one of the keys.
+	ExternalPluginPublicKeys []string
 }
 
 // AgentInfo represents metadata for amazon-ssm-agent
@@ -51,6 +84,14 @@ type AgentInfo struct {
 	OrchestrationRootDir string
 	DownloadRootDir      string
 	ContainerMode        bool
+	// HealthEndpoint is the local address serving the health of the core modules as JSON,
+	// either a loopback host:port or unix:<socket path>. The endpoint is disabled when empty.
+	HealthEndpoint string
+	// MetricsEndpoint is the local address serving the metrics of the agent in the OpenMetrics text format,
+	// either a loopback host:port or unix:<socket path>. The endpoint is disabled when empty.
+	MetricsEndpoint string
+	// DrainTimeoutSeconds is how long a drain waits for the documents and sessions in flight to complete
+	DrainTimeoutSeconds int
 }
 
 // MgsConfig represents configuration for Message Gateway service
@@ -86,17 +127,116 @@ type BirdwatcherCfg struct {
 	ForceEnable bool
 }
 
+// PackageSigningCfg represents configuration for verifying publisher signatures of ConfigurePackage packages
+type PackageSigningCfg struct {
+	// RequireSignature refuses any package version that is not signed by a trusted publisher
+	RequireSignature bool
+	// TrustedEd25519Keys are base64 encoded ed25519 public keys of trusted publishers
+	TrustedEd25519Keys []string
+	// TrustedCertificateFiles are paths to PEM files with root certificates trusted to issue publisher certificates
+	TrustedCertificateFiles []string
+}
+
+// AgentUpdateCfg represents configuration for the agent self-update: where updates are downloaded from and
+// the health gates a new agent version must pass before its self-update is committed
+type AgentUpdateCfg struct {
+	// MirrorURL is an HTTP(S) URL or a local directory serving the update manifest and package archives
+	// instead of the regional S3 bucket
+	MirrorURL string
+	// MirrorManifestPublicKey is the base64 encoded ed25519 public key the mirrored manifest must be signed with
+	MirrorManifestPublicKey string
+	// HealthGates lists the gates to evaluate: ServiceRunning, ControlChannel, HealthPing, NoCrashLoop and Script
+	HealthGates []string
+	// HealthGateWindowSeconds is the time the new agent version has to pass all gates before the update is rolled back
+	HealthGateWindowSeconds int
+	// HealthGateScript is the path of the custom check run by the Script gate, it must exit 0 for the gate to pass
+	HealthGateScript string
+	// CrashLoopRestartLimit is the number of agent restarts after the update tolerated by the NoCrashLoop gate
+	CrashLoopRestartLimit int
+}
+
+// ExecutionCfg represents the default resource limits of the commands run by the script plugins, a step of a
+// document can set its own. On Linux each command runs in its own cgroup v2 child of the agent cgroup, the limits
+// aren't applied on other platforms. A limit of 0 means unlimited.
+// It also holds how far a step may loosen its sandbox.
+type ExecutionCfg struct {
+	// CPUQuotaPercent is the share of one CPU the command may use, 200 allows two full CPUs
+	CPUQuotaPercent int
+	// MemoryMaxMB is the memory the command may use before it is OOM-killed
+	MemoryMaxMB int
+	// PidsMax is the number of processes and threads the command may have at once
+	PidsMax int
+	// IOWeight is the proportional share of block IO of the command, between 1 and 10000
+	IOWeight int
+	// SandboxAllowUnconfinedSeccomp lets the sandbox of a step run without the seccomp filter
+	SandboxAllowUnconfinedSeccomp bool
+	// SandboxAllowedCapabilities are the capabilities the sandbox of a step may keep, e.g. CAP_NET_BIND_SERVICE.
+	// A step keeping another capability fails, all are dropped when the list is empty.
+	SandboxAllowedCapabilities []string
+	// SandboxAllowedWritablePaths are the directories, with the paths below them, the sandbox of a step may keep
+	// writable. The orchestration and working directories of the step are always allowed, and are the only ones
+	// when the list is empty.
+	SandboxAllowedWritablePaths []string
+}
+
+// OutputCfg represents the output sinks of all the documents, in addition to the orchestration directory, S3 and
+// CloudWatch. A document can set its own sinks, which replace the ones of the same type.
+type OutputCfg struct {
+	// SyslogEnabled sends each line of output as an RFC 5424 message
+	SyslogEnabled bool
+	// SyslogNetwork is udp, tcp or tls, the local syslog daemon is used when it's empty
+	SyslogNetwork string
+	// SyslogAddress is the host:port of the syslog server
+	SyslogAddress string
+	// SyslogFacility is the syslog facility, 1 (user-level messages) by default
+	SyslogFacility int
+
+	// WebhookURL is the endpoint batches of lines of output are posted to, the webhook is disabled when it's empty
+	WebhookURL                  string
+	WebhookBatchSize            int
+	WebhookBatchIntervalSeconds int
+	WebhookMaxRetries           int
+	// WebhookCertificateFile and WebhookPrivateKeyFile are the client certificate of mutual TLS
+	WebhookCertificateFile string
+	WebhookPrivateKeyFile  string
+	// WebhookCACertificateFile replaces the system roots to verify the endpoint
+	WebhookCACertificateFile string
+
+	// ArchiveDirectory holds a local archive of the output rotated by size, the archive is disabled when it's empty
+	ArchiveDirectory string
+	ArchiveMaxSizeMB int
+	ArchiveMaxFiles  int
+	// ArchiveRoot holds the archives of the documents, the directory of the archive of a document is relative to it.
+	// The archives of the documents are ignored when it's empty.
+	ArchiveRoot string
+}
+
+// TracingCfg represents configuration for tracing the execution of documents
+type TracingCfg struct {
+	// Exporter is otlp to send the spans to a collector, file to append them to a file, or empty to disable tracing
+	Exporter string
+	// Endpoint is the OTLP/HTTP endpoint of the collector, usually a collector running on the instance
+	Endpoint string
+	// FilePath is the file the file exporter appends the spans to, one OTLP JSON export request per line
+	FilePath string
+}
+
 // SsmagentConfig stores agent configuration values.
 type SsmagentConfig struct {
-	Profile     CredentialProfile
-	Mds         MdsCfg
-	Ssm         SsmCfg
-	Mgs         MgsConfig
-	Agent       AgentInfo
-	Os          OsInfo
-	S3          S3Cfg
-	Birdwatcher BirdwatcherCfg
-	Kms         KmsConfig
+	Profile        CredentialProfile
+	Mds            MdsCfg
+	Ssm            SsmCfg
+	Mgs            MgsConfig
+	Agent          AgentInfo
+	Os             OsInfo
+	S3             S3Cfg
+	Birdwatcher    BirdwatcherCfg
+	Kms            KmsConfig
+	PackageSigning PackageSigningCfg
+	AgentUpdate    AgentUpdateCfg
+	Tracing        TracingCfg
+	Execution      ExecutionCfg
+	Output         OutputCfg
 }
 
 // AppConstants represents some run time constant variable for various module.
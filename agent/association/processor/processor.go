@@ -35,6 +35,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/times"
 	"github.com/carlescere/scheduler"
@@ -59,6 +60,9 @@ type Processor struct {
 	proc               processor.Processor
 	resChan            chan contracts.DocumentResult
 	onBoot             bool
+	health             *contracts.HealthTracker
+	drainLock          sync.Mutex
+	draining           bool
 }
 
 var lock sync.RWMutex
@@ -91,6 +95,7 @@ func NewAssociationProcessor(context context.T) *Processor {
 		agentInfo:          &agentInfo,
 		proc:               proc,
 		onBoot:             true,
+		health:             contracts.NewHealthTracker(name),
 	}
 }
 
@@ -111,12 +116,37 @@ func (p *Processor) ModuleExecute(context context.T) {
 	p.SetPollJob(job)
 }
 func (p *Processor) ModuleRequestStop(stopType contracts.StopType) (err error) {
-	assocScheduler.Stop(p.pollJob)
+	p.drainLock.Lock()
+	// the poller is already stopped when draining
+	if !p.draining {
+		assocScheduler.Stop(p.pollJob)
+	}
+	p.drainLock.Unlock()
 	signal.Stop()
 	p.proc.Stop(stopType)
 	return nil
 }
 
+// ModuleDrain stops polling and scheduling associations, the associations in progress run to completion
+func (p *Processor) ModuleDrain() {
+	p.drainLock.Lock()
+	defer p.drainLock.Unlock()
+	if p.draining {
+		return
+	}
+	p.draining = true
+
+	p.context.Log().Info("Draining, no longer polling nor scheduling associations")
+	assocScheduler.Stop(p.pollJob)
+	signal.Stop()
+	p.proc.Drain()
+}
+
+// ModuleInFlight returns the number of associations in progress
+func (p *Processor) ModuleInFlight() int {
+	return p.proc.InFlight()
+}
+
 // StartAssociationWorker starts worker to process scheduled association
 func (p *Processor) InitializeAssociationProcessor() {
 	log := p.context.Log()
@@ -145,6 +175,16 @@ func (p *Processor) SetPollJob(job *scheduler.Job) {
 	p.pollJob = job
 }
 
+// ModuleHealth returns the health of the association polling, the backlog is the number of overdue associations
+func (p *Processor) ModuleHealth() contracts.ModuleHealth {
+	health := p.health.Health()
+	if health.Name == "" {
+		health.Name = name
+	}
+	health.Backlog = schedulemanager.CountOverdueAssociations(time.Now())
+	return health
+}
+
 // ProcessAssociation poll and process all the associations
 func (p *Processor) ProcessAssociation() {
 	log := p.context.Log()
@@ -155,16 +195,25 @@ func (p *Processor) ProcessAssociation() {
 	instanceID, err := sys.InstanceID()
 	if err != nil {
 		log.Error("Unable to retrieve instance id", err)
+		p.health.RecordError(err)
 		return
 	}
 
 	p.assocSvc.CreateNewServiceIfUnHealthy(log)
 	p.complianceUploader.CreateNewServiceIfUnHealthy(log)
 
-	if associations, err = p.assocSvc.ListInstanceAssociations(log, instanceID); err != nil {
+	listStart := time.Now()
+	associations, err = p.assocSvc.ListInstanceAssociations(log, instanceID)
+	p.context.Metrics().Histogram(metrics.MessagePollDuration,
+		"Latency of polling the message service.",
+		metrics.DefaultDurationBuckets,
+		metrics.Labels{"service": name}).ObserveSince(listStart)
+	if err != nil {
 		log.Errorf("Unable to load instance associations, %v", err)
+		p.health.RecordError(err)
 		return
 	}
+	p.health.RecordSuccess()
 
 	// to account for any tag expansion delays on boot, call list associations again
 	if p.onBoot {
@@ -174,6 +223,7 @@ func (p *Processor) ProcessAssociation() {
 			time.Sleep(defaultRetryWaitOnBootInSeconds * time.Second)
 			if associations, err = p.assocSvc.ListInstanceAssociations(log, instanceID); err != nil {
 				log.Errorf("Unable to load instance associations, %v", err)
+				p.health.RecordError(err)
 				return
 			}
 		}
@@ -364,7 +414,9 @@ func (p *Processor) runScheduledAssociation(log log.T) {
 	}
 
 	updatePluginAssociationInstances(*scheduledAssociation.Association.AssociationId, docState)
-	log = p.context.With("[associationId=" + docState.DocumentInformation.AssociationID + "]").Log()
+	log = p.context.With("[associationId=" + docState.DocumentInformation.AssociationID + "]").
+		WithFields(associationFields(docState.DocumentInformation.AssociationID)).
+		Log()
 	instanceID, _ := sys.InstanceID()
 	p.assocSvc.UpdateInstanceAssociationStatus(
 		log,
@@ -403,10 +455,16 @@ func isAssociationTimedOut(assoc *model.InstanceAssociation) bool {
 	return (*assoc.Association.LastExecutionDate).Add(documentLevelTimeOutDurationHour * time.Hour).UTC().Before(currentTime)
 }
 
+// associationFields returns the log fields identifying an association
+func associationFields(associationID string) log.Fields {
+	return log.Fields{log.FieldAssociationID: associationID}
+}
+
 // parseAssociation parses the association to the document state
 func (p *Processor) parseAssociation(rawData *model.InstanceAssociation) (*contracts.DocumentState, error) {
 	// create separate logger that includes messageID with every log message
-	context := p.context.With("[associationId=" + *rawData.Association.AssociationId + "]")
+	context := p.context.With("[associationId=" + *rawData.Association.AssociationId + "]").
+		WithFields(associationFields(*rawData.Association.AssociationId))
 	log := context.Log()
 	docState := contracts.DocumentState{}
 
@@ -546,6 +604,9 @@ func (r *Processor) listenToResponses() {
 		if res.LastPlugin == "" {
 			log.Debug("Association execution completion: ", res.AssociationID)
 			log.Debug("Association execution status is ", res.Status)
+			r.context.Metrics().Counter(metrics.AssociationsProcessed,
+				"Association runs completed, by final status.",
+				metrics.Labels{"status": string(res.Status)}).Inc()
 			if res.Status == contracts.ResultStatusFailed {
 				r.associationExecutionReport(
 					log,
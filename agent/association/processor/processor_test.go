@@ -72,6 +72,8 @@ func TestProcessAssociationUnableToGetAssociation(t *testing.T) {
 	assert.True(t, svcMock.AssertNumberOfCalls(t, "CreateNewServiceIfUnHealthy", 1))
 	assert.True(t, svcMock.AssertNumberOfCalls(t, "ListInstanceAssociations", 1))
 	assert.True(t, svcMock.AssertNumberOfCalls(t, "LoadAssociationDetail", 0))
+	assert.True(t, processor.ModuleHealth().IsFailing())
+	assert.Equal(t, "unable to load association", processor.ModuleHealth().LastError)
 }
 
 func TestProcessAssociationUnableToLoadAssociationDetail(t *testing.T) {
@@ -295,6 +297,7 @@ func mockParser(parserMock *parserMock, payload *messageContracts.SendCommandPay
 func createProcessor() *Processor {
 	processor := Processor{}
 	processor.context = context.NewMockDefault()
+	processor.health = contracts.NewHealthTracker(name)
 	return &processor
 }
 
@@ -123,6 +123,20 @@ func LoadNextScheduledDate(log log.T) *time.Time {
 	return nextScheduleDate
 }
 
+// CountOverdueAssociations returns the number of associations whose scheduled date has passed
+func CountOverdueAssociations(now time.Time) int {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	overdue := 0
+	for _, assoc := range associations {
+		if assoc.NextScheduledDate != nil && assoc.NextScheduledDate.Before(now) {
+			overdue++
+		}
+	}
+	return overdue
+}
+
 // UpdateNextScheduledDate sets next scheduled date for the given association
 func UpdateNextScheduledDate(log log.T, associationID string) {
 	lock.Lock()
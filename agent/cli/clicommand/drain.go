@@ -0,0 +1,247 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+const (
+	drainCommand               = "drain"
+	drainCommandTimeoutSeconds = "timeout-seconds"
+	drainCommandWait           = "wait"
+	drainCommandStatus         = "status"
+
+	// drainPickupTimeout is how long to wait for the agent to pick up the request, it looks for one every 5 seconds
+	drainPickupTimeout = 15 * time.Second
+	drainPollInterval  = 500 * time.Millisecond
+)
+
+const drainCommandHelp = `NAME:
+    {{.DrainCommandName}}
+
+DESCRIPTION
+    Drains the local amazon-ssm-agent service. The agent stops polling for commands and
+    associations and stops starting new sessions, while the documents and sessions in flight
+    get up to the timeout to complete. The agent keeps running once drained, restart it to
+    take new work again.
+
+    On Linux and macOS, sending SIGUSR1 to the agent also drains it with the configured timeout.
+
+SYNOPSIS
+    {{.DrainCommandName}}
+    [{{.TimeoutSecondsFlag}} <value>]
+    [{{.WaitFlag}}]
+    [{{.StatusFlag}}]
+
+PARAMETERS
+    {{.TimeoutSecondsFlag}} (integer) How long the documents and sessions in flight get to complete.
+    Defaults to Agent.DrainTimeoutSeconds in amazon-ssm-agent.json.
+
+    {{.WaitFlag}} (boolean) Wait until the work in flight completed or the timeout elapsed.
+
+    {{.StatusFlag}} (boolean) Only print the progress of the drain in progress, if any.
+
+EXAMPLES
+    This example drains the agent before the instance is terminated.
+
+    Command:
+
+      {{.SsmCliName}} {{.DrainCommandName}} {{.TimeoutSecondsFlag}} 300 {{.WaitFlag}}
+
+    Output:
+      {
+        "State": "Drained",
+        "StartedAt": "2018-01-01T00:00:00Z",
+        "Deadline": "2018-01-01T00:05:00Z",
+        "UpdatedAt": "2018-01-01T00:01:12Z",
+        "InFlight": 0,
+        "Modules": [
+          {
+            "Name": "MessageDeliveryService",
+            "InFlight": 0
+          }
+        ]
+      }
+
+OUTPUT
+    Progress of the drain in JSON format, the state is Draining, Drained or TimedOut
+`
+
+type drainHelpParams struct {
+	SsmCliName         string
+	DrainCommandName   string
+	TimeoutSecondsFlag string
+	WaitFlag           string
+	StatusFlag         string
+}
+
+func init() {
+	cliutil.Register(&DrainCommand{})
+}
+
+type DrainCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the drain cli command
+func (c *DrainCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation, request, wait, statusOnly := c.validateDrainCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	if statusOnly {
+		status, found := readDrainStatus()
+		if !found {
+			return nil, "The agent is not draining"
+		}
+		return formatDrainStatus(status)
+	}
+
+	if err := submitDrainRequest(request); err != nil {
+		return err, ""
+	}
+	status, found := waitForDrainStatus(drainPickupTimeout, false)
+	if !found {
+		// the agent may pick up the request later, don't leave it around
+		fileutil.DeleteFile(filepath.Join(appconfig.DrainRoot, appconfig.DrainRequestFileName))
+		return errors.New("the agent did not pick up the drain request, make sure amazon-ssm-agent is running"), ""
+	}
+	if wait {
+		// the deadline is set by the agent, the drain ends by then
+		status, _ = waitForDrainStatus(status.Deadline.Sub(time.Now())+drainPickupTimeout, true)
+	}
+	return formatDrainStatus(status)
+}
+
+// Help prints help for the drain cli command
+func (c *DrainCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("DrainCommandHelp").Parse(drainCommandHelp)
+		params := drainHelpParams{
+			cliutil.SsmCliName,
+			drainCommand,
+			cliutil.FormatFlag(drainCommandTimeoutSeconds),
+			cliutil.FormatFlag(drainCommandWait),
+			cliutil.FormatFlag(drainCommandStatus),
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (DrainCommand) Name() string {
+	return drainCommand
+}
+
+// validateDrainCommandInput checks the subcommands and parameters for required values, format, and unsupported values
+func (DrainCommand) validateDrainCommandInput(subcommands []string, parameters map[string][]string) (validation []string, request contracts.DrainRequest, wait bool, statusOnly bool) {
+	validation = make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", drainCommand, subcommands), "")
+		return // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	if values, exists := parameters[drainCommandTimeoutSeconds]; exists {
+		if len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(drainCommandTimeoutSeconds)))
+		} else if seconds, err := strconv.Atoi(values[0]); err != nil || seconds < appconfig.DefaultDrainTimeoutSecondsMin || seconds > appconfig.DefaultDrainTimeoutSecondsMax {
+			validation = append(validation, fmt.Sprintf("%v should be a number of seconds between %v and %v",
+				cliutil.FormatFlag(drainCommandTimeoutSeconds), appconfig.DefaultDrainTimeoutSecondsMin, appconfig.DefaultDrainTimeoutSecondsMax))
+		} else {
+			request.TimeoutSeconds = seconds
+		}
+	}
+	for _, flag := range []string{drainCommandWait, drainCommandStatus} {
+		if values, exists := parameters[flag]; exists && len(values) > 0 {
+			validation = append(validation, fmt.Sprintf("flag %v should not have any values", cliutil.FormatFlag(flag)))
+		}
+	}
+	_, wait = parameters[drainCommandWait]
+	_, statusOnly = parameters[drainCommandStatus]
+	if statusOnly && len(parameters) > 1 {
+		validation = append(validation, fmt.Sprintf("flag %v can't be combined with other parameters", cliutil.FormatFlag(drainCommandStatus)))
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		if key != drainCommandTimeoutSeconds && key != drainCommandWait && key != drainCommandStatus {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return
+}
+
+// submitDrainRequest writes the drain request file the agent looks for
+func submitDrainRequest(request contracts.DrainRequest) error {
+	content, err := jsonutil.Marshal(request)
+	if err != nil {
+		return err
+	}
+	if err = fileutil.MakeDirs(appconfig.DrainRoot); err != nil {
+		return errors.New("failed to submit the drain request")
+	}
+	return fileutil.WriteAllText(filepath.Join(appconfig.DrainRoot, appconfig.DrainRequestFileName), content)
+}
+
+// waitForDrainStatus polls the status file of the agent until the drain started, or ended if untilDone is true
+func waitForDrainStatus(timeout time.Duration, untilDone bool) (status contracts.DrainStatus, found bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		status, found = readDrainStatus()
+		if found && (!untilDone || status.State != contracts.DrainStateDraining) {
+			return
+		}
+		if time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(drainPollInterval)
+	}
+}
+
+// readDrainStatus reads the progress of the drain reported by the agent
+func readDrainStatus() (status contracts.DrainStatus, found bool) {
+	path := filepath.Join(appconfig.DrainRoot, appconfig.DrainStatusFileName)
+	if !fileutil.Exists(path) {
+		return status, false
+	}
+	return status, jsonutil.UnmarshalFile(path, &status) == nil
+}
+
+func formatDrainStatus(status contracts.DrainStatus) (error, string) {
+	result, err := jsonutil.MarshalIndent(status)
+	if err != nil {
+		return err, ""
+	}
+	return nil, result
+}
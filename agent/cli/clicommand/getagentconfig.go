@@ -0,0 +1,173 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+const (
+	getAgentConfigCommand = "get-agent-config"
+)
+
+const getAgentConfigCommandHelp = `NAME:
+    {{.GetAgentConfigCommandName}}
+
+DESCRIPTION
+    Prints the configuration the agent loads, with the layer each setting comes from.
+    The layers are applied in order, each one overriding the previous ones:
+
+      1. the built-in defaults
+      2. {{.AppConfigPath}}
+      3. {{.DropInDir}}/*.json, in lexical order
+      4. {{.EnvPrefix}}<SECTION>_<KEY> environment variables, e.g. {{.EnvPrefix}}MDS_COMMANDWORKERSLIMIT
+
+    Environment variables are read from the environment of this CLI, which may differ from the
+    environment of the agent service.
+
+SYNOPSIS
+    {{.GetAgentConfigCommandName}}
+
+EXAMPLES
+    This example prints the effective configuration, where the number of command workers
+    is overridden by a drop-in file.
+
+    Command:
+
+      {{.SsmCliName}} {{.GetAgentConfigCommandName}}
+
+    Output:
+      {
+        "settings": [
+          {
+            "name": "Mds.CommandWorkersLimit",
+            "value": 10,
+            "source": "{{.DropInDir}}/10-workers.json"
+          },
+          {
+            "name": "Mds.StopTimeoutMillis",
+            "value": 20000,
+            "source": "default"
+          },
+          ...
+        ]
+      }
+
+OUTPUT
+    Every setting with its value and source in JSON format, along with the environment
+    variables with the {{.EnvPrefix}} prefix which don't name a setting
+`
+
+type getAgentConfigHelpParams struct {
+	SsmCliName                string
+	GetAgentConfigCommandName string
+	AppConfigPath             string
+	DropInDir                 string
+	EnvPrefix                 string
+}
+
+type agentConfigSetting struct {
+	Name   string      `json:"name"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+type agentConfigOutput struct {
+	Settings []agentConfigSetting `json:"settings"`
+	Ignored  []string             `json:"ignored,omitempty"`
+}
+
+func init() {
+	cliutil.Register(&GetAgentConfigCommand{})
+}
+
+type GetAgentConfigCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the get-agent-config cli command
+func (c *GetAgentConfigCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateGetAgentConfigCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	layered, err := appconfig.LoadLayered()
+	if err != nil {
+		return err, ""
+	}
+
+	output := agentConfigOutput{Ignored: layered.Ignored}
+	for _, setting := range layered.Settings() {
+		output.Settings = append(output.Settings, agentConfigSetting{
+			Name:   setting.Name,
+			Value:  setting.Value,
+			Source: setting.Source,
+		})
+	}
+
+	result, err := jsonutil.MarshalIndent(output)
+	if err != nil {
+		return err, ""
+	}
+	return nil, result
+}
+
+// Help prints help for the get-agent-config cli command
+func (c *GetAgentConfigCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("GetAgentConfigCommandHelp").Parse(getAgentConfigCommandHelp)
+		params := getAgentConfigHelpParams{
+			SsmCliName:                cliutil.SsmCliName,
+			GetAgentConfigCommandName: getAgentConfigCommand,
+			AppConfigPath:             appconfig.AppConfigPath,
+			DropInDir:                 appconfig.DropInDir(),
+			EnvPrefix:                 appconfig.AppConfigEnvPrefix,
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (GetAgentConfigCommand) Name() string {
+	return getAgentConfigCommand
+}
+
+// validateGetAgentConfigCommandInput checks the subcommands and parameters for unsupported values
+func (GetAgentConfigCommand) validateGetAgentConfigCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", getAgentConfigCommand, subcommands), "")
+		return validation // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+	}
+	return validation
+}
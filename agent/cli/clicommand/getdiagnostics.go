@@ -0,0 +1,200 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/cli/diagnostics"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+const (
+	getDiagnosticsCommand           = "get-diagnostics"
+	getDiagnosticsCommandOutput     = "output"
+	getDiagnosticsCommandSkipBundle = "skip-bundle"
+)
+
+const getDiagnosticsCommandHelp = `NAME:
+    {{.GetDiagnosticsCommandName}}
+
+DESCRIPTION
+    Troubleshoots the agent on this instance. It checks where the agent gets its credentials from,
+    whether the ssm, ec2messages, ssmmessages and s3 endpoints the agent uses can be reached, and
+    whether the clock of the instance is in sync with the clock of the service.
+
+    It then collects a bundle to attach to a support case, a gzipped tarball with:
+      - the logs of the agent
+      - amazon-ssm-agent.json, its drop-in files and seelog.xml
+      - the state files of the pending, current and completed documents
+      - the orchestration directories of the documents
+      - the datastore of the long running plugins
+      - the report of the checks, with the version of the agent and the registration of the instance
+
+    Passwords, tokens, keys and ssm-secure parameter references are redacted from the bundle,
+    binary files are left out and only the end of large files is kept. Review the bundle before
+    sharing it.
+
+SYNOPSIS
+    {{.GetDiagnosticsCommandName}}
+    [{{.OutputFlag}} <value>]
+    [{{.SkipBundleFlag}}]
+
+PARAMETERS
+    {{.OutputFlag}} (string) Path of the bundle.
+    Defaults to amazon-ssm-agent-diagnostics-<time>.tar.gz in the temporary directory.
+
+    {{.SkipBundleFlag}} (boolean) Only run the checks.
+
+EXAMPLES
+    This example checks the agent and collects a bundle.
+
+    Command:
+
+      {{.SsmCliName}} {{.GetDiagnosticsCommandName}} {{.OutputFlag}} /tmp/diagnostics.tar.gz
+
+    Output:
+      {
+        "generatedAt": "2018-01-01T00:00:00Z",
+        "version": "2.0.0.0",
+        "os": "linux",
+        "arch": "amd64",
+        "instanceId": "i-12345678",
+        "region": "us-west-2",
+        "managedInstance": false,
+        "checks": [
+          {
+            "name": "credentials",
+            "status": "Passed",
+            "details": "credentials from the instance profile of the EC2 instance, provider EC2RoleProvider, expiring at 2018-01-01T06:00:00Z"
+          },
+          {
+            "name": "connectivity to ssm",
+            "status": "Failed",
+            "details": "ssm.us-west-2.amazonaws.com resolves to 52.94.210.0; HTTPS request failed, ..."
+          },
+          ...
+        ],
+        "bundle": "/tmp/diagnostics.tar.gz"
+      }
+
+OUTPUT
+    The report of the checks in JSON format, each check is Passed, Warning or Failed
+`
+
+type getDiagnosticsHelpParams struct {
+	SsmCliName                string
+	GetDiagnosticsCommandName string
+	OutputFlag                string
+	SkipBundleFlag            string
+}
+
+func init() {
+	cliutil.Register(&GetDiagnosticsCommand{})
+}
+
+type GetDiagnosticsCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the get-diagnostics cli command
+func (c *GetDiagnosticsCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation, bundlePath, skipBundle := c.validateGetDiagnosticsCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	report := diagnostics.RunChecks()
+	if !skipBundle {
+		if bundlePath == "" {
+			bundlePath = filepath.Join(os.TempDir(),
+				fmt.Sprintf("amazon-ssm-agent-diagnostics-%v.tar.gz", report.GeneratedAt.Format("20060102T150405Z")))
+		}
+		if err := diagnostics.WriteBundle(bundlePath, &report); err != nil {
+			return fmt.Errorf("failed to write the diagnostics bundle %v, %v", bundlePath, err), ""
+		}
+	}
+
+	result, err := jsonutil.MarshalIndent(report)
+	if err != nil {
+		return err, ""
+	}
+	return nil, result
+}
+
+// Help prints help for the get-diagnostics cli command
+func (c *GetDiagnosticsCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("GetDiagnosticsCommandHelp").Parse(getDiagnosticsCommandHelp)
+		params := getDiagnosticsHelpParams{
+			cliutil.SsmCliName,
+			getDiagnosticsCommand,
+			cliutil.FormatFlag(getDiagnosticsCommandOutput),
+			cliutil.FormatFlag(getDiagnosticsCommandSkipBundle),
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (GetDiagnosticsCommand) Name() string {
+	return getDiagnosticsCommand
+}
+
+// validateGetDiagnosticsCommandInput checks the subcommands and parameters for required values, format, and unsupported values
+func (GetDiagnosticsCommand) validateGetDiagnosticsCommandInput(subcommands []string, parameters map[string][]string) (validation []string, bundlePath string, skipBundle bool) {
+	validation = make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", getDiagnosticsCommand, subcommands), "")
+		return // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	if values, exists := parameters[getDiagnosticsCommandOutput]; exists {
+		if len(values) != 1 || values[0] == "" {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(getDiagnosticsCommandOutput)))
+		} else {
+			bundlePath = values[0]
+		}
+	}
+	if values, exists := parameters[getDiagnosticsCommandSkipBundle]; exists {
+		skipBundle = true
+		if len(values) > 0 {
+			validation = append(validation, fmt.Sprintf("flag %v should not have any values", cliutil.FormatFlag(getDiagnosticsCommandSkipBundle)))
+		}
+		if bundlePath != "" {
+			validation = append(validation, fmt.Sprintf("flag %v can't be combined with %v",
+				cliutil.FormatFlag(getDiagnosticsCommandSkipBundle), cliutil.FormatFlag(getDiagnosticsCommandOutput)))
+		}
+	}
+
+	// look for unsupported parameters
+	for key := range parameters {
+		if key != getDiagnosticsCommandOutput && key != getDiagnosticsCommandSkipBundle {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return
+}
@@ -0,0 +1,212 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diagnostics
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	logger "github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// reportFileName is the name of the report in the bundle
+const reportFileName = "diagnostics.json"
+
+var (
+	// maxFileSize is the size of the end of a file which is kept in the bundle
+	maxFileSize int64 = 10 * 1024 * 1024
+
+	// maxBundleSize is the total size of the files in the bundle, before compression
+	maxBundleSize int64 = 200 * 1024 * 1024
+)
+
+// source is a file or a directory collected in the bundle, directories are collected recursively
+type source struct {
+	path string
+	// name is the path of the source in the bundle
+	name string
+}
+
+// defaultSources returns the logs, the configuration and the state of the documents and of the long running plugins.
+// The registration of a managed instance isn't collected, the report describes it.
+func defaultSources(config appconfig.SsmagentConfig) []source {
+	sources := []source{
+		{path: logger.DefaultLogDir, name: "logs"},
+		{path: appconfig.AppConfigPath, name: path.Join("config", appconfig.AppConfigFileName)},
+		{path: appconfig.DropInDir(), name: path.Join("config", appconfig.AppConfigDropInDirName)},
+		{path: logger.DefaultSeelogConfigFilePath, name: path.Join("config", appconfig.SeelogConfigFileName)},
+	}
+
+	// the agent stores its data in a directory per instance id
+	instanceDirs, _ := filepath.Glob(filepath.Join(appconfig.DefaultDataStorePath, "*"))
+	sort.Strings(instanceDirs)
+	for _, instanceDir := range instanceDirs {
+		name := path.Join("data", filepath.Base(instanceDir))
+		sources = append(sources,
+			source{
+				path: filepath.Join(instanceDir, appconfig.DefaultDocumentRootDirName, appconfig.DefaultLocationOfState),
+				name: path.Join(name, appconfig.DefaultDocumentRootDirName, appconfig.DefaultLocationOfState),
+			},
+			source{
+				path: filepath.Join(instanceDir, appconfig.DefaultDocumentRootDirName, config.Agent.OrchestrationRootDir),
+				name: path.Join(name, appconfig.DefaultDocumentRootDirName, config.Agent.OrchestrationRootDir),
+			},
+			source{
+				path: filepath.Join(instanceDir, appconfig.LongRunningPluginsLocation, appconfig.LongRunningPluginDataStoreLocation),
+				name: path.Join(name, appconfig.LongRunningPluginsLocation, appconfig.LongRunningPluginDataStoreLocation),
+			})
+	}
+	return sources
+}
+
+// WriteBundle collects the logs, the configuration and the state of the agent with the secrets redacted,
+// and writes them along with the report to a gzipped tarball
+func WriteBundle(bundlePath string, report *Report) error {
+	config, _ := appconfig.Config(false)
+	return writeBundle(bundlePath, report, defaultSources(config))
+}
+
+func writeBundle(bundlePath string, report *Report, sources []source) (err error) {
+	file, err := os.OpenFile(bundlePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	compressed := gzip.NewWriter(file)
+	archive := tar.NewWriter(compressed)
+
+	bundle := &bundleWriter{archive: archive, report: report}
+	for _, source := range sources {
+		if err = bundle.addSource(source); err != nil {
+			return err
+		}
+	}
+
+	report.Bundle = bundlePath
+	content, err := jsonutil.MarshalIndent(report)
+	if err != nil {
+		return err
+	}
+	if err = bundle.write(reportFileName, []byte(content), report.GeneratedAt); err != nil {
+		return err
+	}
+	if err = archive.Close(); err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+// bundleWriter adds the files of the sources to the archive within the size limits
+type bundleWriter struct {
+	archive *tar.Writer
+	report  *Report
+	size    int64
+}
+
+// addSource adds a file or the files of a directory, a missing source is ignored
+func (b *bundleWriter) addSource(source source) error {
+	if _, err := os.Lstat(source.path); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.Walk(source.path, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			b.skip(filePath, err.Error())
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		name := source.name
+		if relative, relErr := filepath.Rel(source.path, filePath); relErr == nil && relative != "." {
+			name = path.Join(source.name, filepath.ToSlash(relative))
+		}
+		return b.addFile(filePath, name, info)
+	})
+}
+
+// addFile adds the redacted end of a text file
+func (b *bundleWriter) addFile(filePath string, name string, info os.FileInfo) error {
+	if b.size >= maxBundleSize {
+		b.skip(filePath, fmt.Sprintf("the bundle reached %v bytes", maxBundleSize))
+		return nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		b.skip(filePath, err.Error())
+		return nil
+	}
+	defer file.Close()
+	if info.Size() > maxFileSize {
+		if _, err = file.Seek(info.Size()-maxFileSize, io.SeekStart); err != nil {
+			b.skip(filePath, err.Error())
+			return nil
+		}
+		b.report.Truncated = append(b.report.Truncated, filePath)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(file, maxFileSize))
+	if err != nil {
+		b.skip(filePath, err.Error())
+		return nil
+	}
+	if isBinary(content) {
+		b.skip(filePath, "binary file")
+		return nil
+	}
+
+	content = redact(content)
+	b.size += int64(len(content))
+	return b.write(name, content, info.ModTime())
+}
+
+func (b *bundleWriter) write(name string, content []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(content)),
+		ModTime: modTime,
+	}
+	if err := b.archive.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := b.archive.Write(content)
+	return err
+}
+
+func (b *bundleWriter) skip(filePath string, reason string) {
+	b.report.Skipped = append(b.report.Skipped, fmt.Sprintf("%v: %v", filePath, reason))
+}
+
+// isBinary detects binary content from a NUL byte in its beginning
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diagnostics
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// readBundle returns the content of the files in the bundle by name
+func readBundle(t *testing.T, bundlePath string) map[string]string {
+	file, err := os.Open(bundlePath)
+	assert.NoError(t, err)
+	defer file.Close()
+	compressed, err := gzip.NewReader(file)
+	assert.NoError(t, err)
+
+	files := make(map[string]string)
+	archive := tar.NewReader(compressed)
+	for {
+		header, err := archive.Next()
+		if err != nil {
+			break
+		}
+		content, err := ioutil.ReadAll(archive)
+		assert.NoError(t, err)
+		files[header.Name] = string(content)
+	}
+	return files
+}
+
+func writeFile(t *testing.T, filePath string, content string) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0700))
+	assert.NoError(t, ioutil.WriteFile(filePath, []byte(content), 0600))
+}
+
+func TestWriteBundle(t *testing.T) {
+	root, err := ioutil.TempDir("", "diagnostics")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	logDir := filepath.Join(root, "logs")
+	stateDir := filepath.Join(root, "state")
+	writeFile(t, filepath.Join(logDir, "amazon-ssm-agent.log"), "INFO using password=hunter2\n")
+	writeFile(t, filepath.Join(logDir, "core.bin"), "\x7fELF\x00\x01")
+	writeFile(t, filepath.Join(stateDir, "completed", "command-1"), `{"DocumentInformation": {"DocumentStatus": "Success"}, "Token": "abc"}`)
+	configPath := filepath.Join(root, "amazon-ssm-agent.json")
+	writeFile(t, configPath, `{"Agent": {"Region": "us-east-1"}}`)
+	bundlePath := filepath.Join(root, "bundle.tar.gz")
+
+	report := &Report{GeneratedAt: time.Now().UTC(), Version: "2.0.0.0", Checks: []Check{{Name: "clock skew", Status: CheckStatusPassed}}}
+	err = writeBundle(bundlePath, report, []source{
+		{path: logDir, name: "logs"},
+		{path: configPath, name: "config/amazon-ssm-agent.json"},
+		{path: stateDir, name: "data/i-123/document/state"},
+		{path: filepath.Join(root, "missing"), name: "missing"},
+	})
+	assert.NoError(t, err)
+
+	files := readBundle(t, bundlePath)
+	assert.Equal(t, "INFO using password=<redacted>\n", files["logs/amazon-ssm-agent.log"])
+	assert.NotContains(t, files, "logs/core.bin")
+	assert.Contains(t, files["config/amazon-ssm-agent.json"], "us-east-1")
+	assert.Contains(t, files["data/i-123/document/state/completed/command-1"], `"Token": "<redacted>"`)
+	assert.Contains(t, files["data/i-123/document/state/completed/command-1"], `"DocumentStatus": "Success"`)
+
+	var bundled Report
+	assert.NoError(t, jsonutil.Unmarshal(files[reportFileName], &bundled))
+	assert.Equal(t, bundlePath, bundled.Bundle)
+	assert.Equal(t, report.Checks, bundled.Checks)
+	assert.Equal(t, []string{filepath.Join(logDir, "core.bin") + ": binary file"}, bundled.Skipped)
+}
+
+func TestWriteBundleLimits(t *testing.T) {
+	defer func(fileSize, bundleSize int64) {
+		maxFileSize, maxBundleSize = fileSize, bundleSize
+	}(maxFileSize, maxBundleSize)
+	maxFileSize = 8
+	maxBundleSize = 12
+
+	root, err := ioutil.TempDir("", "diagnostics")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	writeFile(t, filepath.Join(root, "logs", "a.log"), "old lines\nnew line")
+	writeFile(t, filepath.Join(root, "logs", "b.log"), "short")
+	writeFile(t, filepath.Join(root, "logs", "c.log"), "skipped")
+	bundlePath := filepath.Join(root, "bundle.tar.gz")
+
+	report := &Report{}
+	assert.NoError(t, writeBundle(bundlePath, report, []source{{path: filepath.Join(root, "logs"), name: "logs"}}))
+
+	files := readBundle(t, bundlePath)
+	assert.Equal(t, "new line", files["logs/a.log"])
+	assert.Equal(t, "short", files["logs/b.log"])
+	assert.NotContains(t, files, "logs/c.log")
+	assert.Equal(t, []string{filepath.Join(root, "logs", "a.log")}, report.Truncated)
+	assert.Len(t, report.Skipped, 1)
+	assert.True(t, strings.HasPrefix(report.Skipped[0], filepath.Join(root, "logs", "c.log")))
+}
@@ -0,0 +1,186 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diagnostics
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+)
+
+const (
+	// requestTimeout bounds each connectivity check
+	requestTimeout = 10 * time.Second
+
+	// maxClockSkew is the clock skew beyond which the service rejects the signed requests of the agent
+	maxClockSkew = 5 * time.Minute
+	// warnClockSkew is the clock skew which is reported before it breaks the agent
+	warnClockSkew = time.Minute
+)
+
+// lookupHost resolves the endpoints, it's replaced in tests
+var lookupHost = net.LookupHost
+
+// endpoint is a service endpoint the agent connects to
+type endpoint struct {
+	service string
+	host    string
+}
+
+// resolveEndpoints returns the endpoints the agent uses, the ones configured in amazon-ssm-agent.json
+// or the default ones of the region
+func resolveEndpoints(config appconfig.SsmagentConfig, region string) []endpoint {
+	defaultHost := func(service string) string {
+		if region == "" {
+			return ""
+		}
+		return rip.GetDefaultServiceEndpoint(region, service)
+	}
+	return []endpoint{
+		{service: "ssm", host: endpointHost(config.Ssm.Endpoint, defaultHost("ssm"))},
+		{service: "ec2messages", host: endpointHost(config.Mds.Endpoint, defaultHost("ec2messages"))},
+		{service: "ssmmessages", host: rip.GetMgsEndpoint(region)},
+		{service: "s3", host: endpointHost(config.S3.Endpoint, defaultHost("s3"))},
+	}
+}
+
+// endpointHost returns the host of the configured endpoint, which may be a URL, or the default host
+func endpointHost(configured string, defaultHost string) string {
+	if configured == "" {
+		return defaultHost
+	}
+	if parsed, err := url.Parse(configured); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return configured
+}
+
+// newHTTPClient creates a client which goes through the proxy of the environment, like the agent
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout:   requestTimeout,
+		Transport: &http.Transport{Proxy: http.ProxyFromEnvironment},
+		// the response of the endpoint itself is what matters
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkConnectivity resolves the endpoint and sends it an HTTPS request.
+// The clock skew is measured from the date of the response when there is one.
+func checkConnectivity(client *http.Client, endpoint endpoint) (check Check, skew *time.Duration) {
+	check = Check{Name: "connectivity to " + endpoint.service}
+	if endpoint.host == "" {
+		check.Status = CheckStatusFailed
+		check.Details = "the endpoint can't be resolved, the region of the instance is unknown"
+		return
+	}
+
+	details := []string{}
+	hostname := endpoint.host
+	if host, _, err := net.SplitHostPort(endpoint.host); err == nil {
+		hostname = host
+	}
+	// the proxy may resolve the endpoint when the instance can't, the request decides the outcome
+	if addresses, err := lookupHost(hostname); err != nil {
+		details = append(details, fmt.Sprintf("%v doesn't resolve, %v", hostname, err))
+	} else {
+		details = append(details, fmt.Sprintf("%v resolves to %v", hostname, strings.Join(addresses, ", ")))
+	}
+
+	sent := time.Now()
+	response, err := client.Head("https://" + endpoint.host + "/")
+	if err != nil {
+		check.Status = CheckStatusFailed
+		check.Details = strings.Join(append(details, fmt.Sprintf("HTTPS request failed, %v", err)), "; ")
+		return
+	}
+	received := time.Now()
+	response.Body.Close()
+
+	check.Status = CheckStatusPassed
+	check.Details = strings.Join(append(details, fmt.Sprintf("HTTPS request answered with status %v in %v",
+		response.StatusCode, received.Sub(sent).Round(time.Millisecond))), "; ")
+	if serverTime, err := http.ParseTime(response.Header.Get("Date")); err == nil {
+		// the server dated the response about halfway through the request
+		local := sent.Add(received.Sub(sent) / 2)
+		measured := serverTime.Sub(local)
+		skew = &measured
+	}
+	return
+}
+
+// checkClockSkew compares the clock of the instance with the clock of the service
+func checkClockSkew(skew *time.Duration) Check {
+	check := Check{Name: "clock skew"}
+	if skew == nil {
+		check.Status = CheckStatusWarning
+		check.Details = "no endpoint answered with a date to compare the clock of the instance with"
+		return check
+	}
+
+	magnitude := *skew
+	if magnitude < 0 {
+		magnitude = -magnitude
+	}
+	direction := "behind"
+	if *skew < 0 {
+		direction = "ahead of"
+	}
+	check.Details = fmt.Sprintf("the clock of the instance is %v %v the clock of the service", magnitude.Round(time.Second), direction)
+	switch {
+	case magnitude > maxClockSkew:
+		check.Status = CheckStatusFailed
+		check.Details += fmt.Sprintf(", the service rejects requests signed more than %v away from its clock", maxClockSkew)
+	case magnitude > warnClockSkew:
+		check.Status = CheckStatusWarning
+	default:
+		check.Status = CheckStatusPassed
+	}
+	return check
+}
+
+// checkCredentials gets the credentials the agent uses and reports where they come from
+func checkCredentials(managedInstance bool, containerMode bool) Check {
+	check := Check{Name: "credentials"}
+	source := "the instance profile of the EC2 instance"
+	if managedInstance {
+		source = "the registration of the managed instance"
+	} else if containerMode {
+		source = "the default credential chain of the container"
+	}
+
+	credentials := sdkutil.AwsConfig().Credentials
+	value, err := credentials.Get()
+	if err != nil {
+		check.Status = CheckStatusFailed
+		check.Details = fmt.Sprintf("no credentials from %v, %v", source, err)
+		return check
+	}
+
+	check.Status = CheckStatusPassed
+	check.Details = fmt.Sprintf("credentials from %v, provider %v", source, value.ProviderName)
+	if expiresAt, err := credentials.ExpiresAt(); err == nil {
+		check.Details += fmt.Sprintf(", expiring at %v", expiresAt.UTC().Format(time.RFC3339))
+	}
+	return check
+}
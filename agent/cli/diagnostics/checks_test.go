@@ -0,0 +1,95 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diagnostics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointHost(t *testing.T) {
+	assert.Equal(t, "ssm.us-east-1.amazonaws.com", endpointHost("", "ssm.us-east-1.amazonaws.com"))
+	assert.Equal(t, "vpce-1.ssm.us-east-1.vpce.amazonaws.com", endpointHost("https://vpce-1.ssm.us-east-1.vpce.amazonaws.com", "ssm.us-east-1.amazonaws.com"))
+	assert.Equal(t, "ssm.example.com", endpointHost("ssm.example.com", "ssm.us-east-1.amazonaws.com"))
+}
+
+func TestResolveEndpointsWithOverride(t *testing.T) {
+	config := appconfig.DefaultConfig()
+	config.Ssm.Endpoint = "https://ssm.example.com"
+
+	endpoints := resolveEndpoints(config, "us-east-1")
+
+	assert.Equal(t, endpoint{service: "ssm", host: "ssm.example.com"}, endpoints[0])
+	assert.Equal(t, endpoint{service: "ec2messages", host: "ec2messages.us-east-1.amazonaws.com"}, endpoints[1])
+}
+
+func TestResolveEndpointsWithoutRegion(t *testing.T) {
+	endpoints := resolveEndpoints(appconfig.DefaultConfig(), "")
+
+	assert.Equal(t, endpoint{service: "ssm"}, endpoints[0])
+}
+
+func TestCheckConnectivity(t *testing.T) {
+	serverTime := time.Now().Add(-10 * time.Minute)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", serverTime.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	check, skew := checkConnectivity(server.Client(), endpoint{service: "ssm", host: host})
+
+	assert.Equal(t, CheckStatusPassed, check.Status)
+	assert.Contains(t, check.Details, "status 404")
+	assert.InDelta(t, float64(-10*time.Minute), float64(*skew), float64(2*time.Second))
+	assert.Equal(t, CheckStatusFailed, checkClockSkew(skew).Status)
+}
+
+func TestCheckConnectivityFails(t *testing.T) {
+	defer func(lookup func(string) ([]string, error)) { lookupHost = lookup }(lookupHost)
+	lookupHost = func(host string) ([]string, error) {
+		return nil, errors.New("no such host")
+	}
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	host := strings.TrimPrefix(server.URL, "https://")
+	server.Close()
+
+	check, skew := checkConnectivity(server.Client(), endpoint{service: "ssm", host: host})
+
+	assert.Equal(t, CheckStatusFailed, check.Status)
+	assert.Contains(t, check.Details, "doesn't resolve")
+	assert.Nil(t, skew)
+
+	check, _ = checkConnectivity(server.Client(), endpoint{service: "ssmmessages"})
+	assert.Equal(t, CheckStatusFailed, check.Status)
+}
+
+func TestCheckClockSkew(t *testing.T) {
+	skew := func(d time.Duration) *time.Duration { return &d }
+
+	assert.Equal(t, CheckStatusWarning, checkClockSkew(nil).Status)
+	assert.Equal(t, CheckStatusPassed, checkClockSkew(skew(2*time.Second)).Status)
+	assert.Equal(t, CheckStatusWarning, checkClockSkew(skew(-2*time.Minute)).Status)
+	check := checkClockSkew(skew(6 * time.Minute))
+	assert.Equal(t, CheckStatusFailed, check.Status)
+	assert.Contains(t, check.Details, "6m0s behind")
+}
@@ -0,0 +1,91 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package diagnostics checks whether the agent can reach the service and collects the files
+// needed to troubleshoot it into a bundle, with the secrets redacted.
+package diagnostics
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+// CheckStatus is the outcome of a diagnostics check
+type CheckStatus string
+
+const (
+	// CheckStatusPassed means the check found no problem
+	CheckStatusPassed CheckStatus = "Passed"
+	// CheckStatusWarning means the check found something which may cause problems
+	CheckStatusWarning CheckStatus = "Warning"
+	// CheckStatusFailed means the check found a problem which prevents the agent from working
+	CheckStatusFailed CheckStatus = "Failed"
+)
+
+// Check is the outcome of a diagnostics check
+type Check struct {
+	Name    string      `json:"name"`
+	Status  CheckStatus `json:"status"`
+	Details string      `json:"details"`
+}
+
+// Report describes the instance, the outcome of the checks and the content of the bundle
+type Report struct {
+	GeneratedAt     time.Time `json:"generatedAt"`
+	Version         string    `json:"version"`
+	OS              string    `json:"os"`
+	Arch            string    `json:"arch"`
+	InstanceID      string    `json:"instanceId,omitempty"`
+	Region          string    `json:"region,omitempty"`
+	ManagedInstance bool      `json:"managedInstance"`
+	Checks          []Check   `json:"checks"`
+	// Bundle is the path of the bundle, empty when it wasn't created
+	Bundle string `json:"bundle,omitempty"`
+	// Truncated lists the files of which only the end is in the bundle
+	Truncated []string `json:"truncated,omitempty"`
+	// Skipped lists the files left out of the bundle, with the reason
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// RunChecks checks the credentials of the agent, the connectivity to the service endpoints and the clock of the instance
+func RunChecks() Report {
+	report := Report{
+		GeneratedAt: time.Now().UTC(),
+		Version:     version.Version,
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+	}
+	report.InstanceID, _ = platform.InstanceID()
+	report.Region, _ = platform.Region()
+	report.ManagedInstance, _ = registration.HasManagedInstancesCredentials()
+
+	config, _ := appconfig.Config(false)
+	report.Checks = append(report.Checks, checkCredentials(report.ManagedInstance, config.Agent.ContainerMode))
+
+	client := newHTTPClient()
+	var skew *time.Duration
+	for _, endpoint := range resolveEndpoints(config, report.Region) {
+		check, endpointSkew := checkConnectivity(client, endpoint)
+		report.Checks = append(report.Checks, check)
+		if skew == nil {
+			skew = endpointSkew
+		}
+	}
+	report.Checks = append(report.Checks, checkClockSkew(skew))
+	return report
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diagnostics
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+)
+
+// redacted replaces the secrets in the bundle
+const redacted = "<redacted>"
+
+// secretNamePattern matches the names of the settings and fields which hold secrets
+const secretNamePattern = `password|passwd|secret|token|privatekey|private_key|accesskey|access_key|apikey|api_key`
+
+var (
+	// secretField matches the JSON fields which hold secrets
+	secretField = regexp.MustCompile(`(?i)` + secretNamePattern + `|credential`)
+
+	// secretAssignment matches secrets in key=value or "key": "value" form
+	secretAssignment = regexp.MustCompile(`(?i)([\w-]*(?:` + secretNamePattern + `)[\w-]*)(["']?\s*[:=]\s*["']?)([^\s"',;&]+)`)
+
+	textRedactions = []struct {
+		pattern     *regexp.Regexp
+		replacement string
+	}{
+		// PEM private keys, e.g. the key of a managed instance registration
+		{regexp.MustCompile(`(?s)-----BEGIN ([A-Z ]*)PRIVATE KEY-----.*?-----END ([A-Z ]*)PRIVATE KEY-----`),
+			"-----BEGIN ${1}PRIVATE KEY-----" + redacted + "-----END ${2}PRIVATE KEY-----"},
+		// AWS access key ids
+		{regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`), redacted},
+		// passwords in URLs
+		{regexp.MustCompile(`(\w+://[^\s/:@]+:)[^\s/@]+@`), "${1}" + redacted + "@"},
+		// SecureString parameters referenced by documents
+		{regexp.MustCompile(`\{\{\s*ssm-secure:[\w-./]+\s*\}\}`), "{{ssm-secure:" + redacted + "}}"},
+	}
+)
+
+// redact replaces the secrets in the content of a file.
+// The values of the secret fields of JSON content are replaced whatever their type.
+func redact(content []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err == nil && !decoder.More() {
+		if _, isObject := value.(map[string]interface{}); isObject {
+			var redactedContent bytes.Buffer
+			encoder := json.NewEncoder(&redactedContent)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(redactValue(value)); err == nil {
+				return redactedContent.Bytes()
+			}
+		}
+	}
+	return redactText(content)
+}
+
+// redactValue replaces the values of the secret fields of decoded JSON content
+func redactValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, field := range typed {
+			if secretField.MatchString(key) {
+				typed[key] = redacted
+			} else {
+				typed[key] = redactValue(field)
+			}
+		}
+		return typed
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = redactValue(item)
+		}
+		return typed
+	case string:
+		return string(redactText([]byte(typed)))
+	}
+	return value
+}
+
+// redactText replaces the secrets found in text content
+func redactText(content []byte) []byte {
+	for _, redaction := range textRedactions {
+		content = redaction.pattern.ReplaceAll(content, []byte(redaction.replacement))
+	}
+	return secretAssignment.ReplaceAllFunc(content, func(assignment []byte) []byte {
+		parts := secretAssignment.FindSubmatch(assignment)
+		// error codes like ExpiredTokenException are followed by a message, not a secret
+		key := bytes.ToLower(parts[1])
+		if bytes.HasSuffix(key, []byte("exception")) || bytes.HasSuffix(key, []byte("error")) {
+			return assignment
+		}
+		result := make([]byte, 0, len(parts[1])+len(parts[2])+len(redacted))
+		return append(append(append(result, parts[1]...), parts[2]...), redacted...)
+	})
+}
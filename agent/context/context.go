@@ -17,7 +17,10 @@ package context
 
 import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig/configwatcher"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
+	"github.com/aws/amazon-ssm-agent/agent/tracing"
 )
 
 // T transfers context specific data across different execution boundaries.
@@ -27,8 +30,12 @@ type T interface {
 	Log() log.T
 	AppConfig() appconfig.SsmagentConfig
 	With(context string) T
+	WithFields(fields log.Fields) T
 	CurrentContext() []string
 	AppConstants() *appconfig.AppConstants
+	Metrics() *metrics.Registry
+	Tracer() *tracing.Tracer
+	ConfigWatcher() *configwatcher.Watcher
 }
 
 // Default returns an empty context that use the default logger and appconfig.
@@ -38,15 +45,18 @@ func Default(logger log.T, ssmAppconfig appconfig.SsmagentConfig) T {
 		MinHealthFrequencyMinutes: appconfig.DefaultSsmHealthFrequencyMinutesMin,
 		MaxHealthFrequencyMinutes: appconfig.DefaultSsmHealthFrequencyMinutesMax,
 	}
-	ctx := &defaultContext{log: logger, appconfig: ssmAppconfig, appconst: appconst}
+	ctx := &defaultContext{log: logger, appconfig: configwatcher.NewWatcher(logger, ssmAppconfig), appconst: appconst,
+		metrics: metrics.NewRegistry(), tracer: tracing.NewTracer(logger, ssmAppconfig.Tracing)}
 	return ctx
 }
 
 type defaultContext struct {
 	context   []string
 	log       log.T
-	appconfig appconfig.SsmagentConfig
+	appconfig *configwatcher.Watcher
 	appconst  appconfig.AppConstants
+	metrics   *metrics.Registry
+	tracer    *tracing.Tracer
 }
 
 func (c *defaultContext) With(logContext string) T {
@@ -56,6 +66,21 @@ func (c *defaultContext) With(logContext string) T {
 		log:       c.log.WithContext(contextSlice...),
 		appconfig: c.appconfig,
 		appconst:  c.appconst,
+		metrics:   c.metrics,
+		tracer:    c.tracer,
+	}
+	return newContext
+}
+
+// WithFields returns a context whose logger attaches the fields to every message
+func (c *defaultContext) WithFields(fields log.Fields) T {
+	newContext := &defaultContext{
+		context:   c.context,
+		log:       c.log.WithFields(fields),
+		appconfig: c.appconfig,
+		appconst:  c.appconst,
+		metrics:   c.metrics,
+		tracer:    c.tracer,
 	}
 	return newContext
 }
@@ -64,8 +89,9 @@ func (c *defaultContext) Log() log.T {
 	return c.log
 }
 
+// AppConfig returns the configuration in effect, which changes when the configuration is reloaded
 func (c *defaultContext) AppConfig() appconfig.SsmagentConfig {
-	return c.appconfig
+	return c.appconfig.Config()
 }
 
 func (c *defaultContext) CurrentContext() []string {
@@ -75,3 +101,18 @@ func (c *defaultContext) CurrentContext() []string {
 func (c *defaultContext) AppConstants() *appconfig.AppConstants {
 	return &c.appconst
 }
+
+// Metrics returns the metrics registry shared by all contexts derived from the same default context
+func (c *defaultContext) Metrics() *metrics.Registry {
+	return c.metrics
+}
+
+// Tracer returns the tracer shared by all contexts derived from the same default context, nil when tracing is disabled
+func (c *defaultContext) Tracer() *tracing.Tracer {
+	return c.tracer
+}
+
+// ConfigWatcher returns the watcher reloading the configuration shared by all contexts derived from the same default context
+func (c *defaultContext) ConfigWatcher() *configwatcher.Watcher {
+	return c.appconfig
+}
@@ -17,7 +17,10 @@ import (
 	"strings"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig/configwatcher"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
+	"github.com/aws/amazon-ssm-agent/agent/tracing"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -41,8 +44,12 @@ func NewMockDefault() *Mock {
 	ctx.On("Log").Return(log)
 	ctx.On("AppConfig").Return(config)
 	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	ctx.On("WithFields", mock.Anything).Return(ctx)
 	ctx.On("CurrentContext").Return([]string{})
 	ctx.On("AppConstants").Return(&appconst)
+	ctx.On("Metrics").Return(metrics.NewRegistry())
+	ctx.On("Tracer").Return((*tracing.Tracer)(nil))
+	ctx.On("ConfigWatcher").Return((*configwatcher.Watcher)(nil))
 	return ctx
 }
 
@@ -58,8 +65,12 @@ func NewMockDefaultWithContext(context []string) *Mock {
 	ctx.On("Log").Return(log)
 	ctx.On("AppConfig").Return(config)
 	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	ctx.On("WithFields", mock.Anything).Return(ctx)
 	ctx.On("CurrentContext").Return(context)
 	ctx.On("AppConstants").Return(&appconst)
+	ctx.On("Metrics").Return(metrics.NewRegistry())
+	ctx.On("Tracer").Return((*tracing.Tracer)(nil))
+	ctx.On("ConfigWatcher").Return((*configwatcher.Watcher)(nil))
 	return ctx
 }
 
@@ -81,6 +92,12 @@ func (m *Mock) With(ctx string) T {
 	return args.Get(0).(T)
 }
 
+// WithFields mocks the WithFields function.
+func (m *Mock) WithFields(fields log.Fields) T {
+	args := m.Called(fields)
+	return args.Get(0).(T)
+}
+
 // CurrentContext mocks the CurrentContext function.
 func (m *Mock) CurrentContext() []string {
 	args := m.Called()
@@ -92,3 +109,21 @@ func (m *Mock) AppConstants() *appconfig.AppConstants {
 	args := m.Called()
 	return args.Get(0).(*appconfig.AppConstants)
 }
+
+// Metrics mocks the Metrics function.
+func (m *Mock) Metrics() *metrics.Registry {
+	args := m.Called()
+	return args.Get(0).(*metrics.Registry)
+}
+
+// Tracer mocks the Tracer function.
+func (m *Mock) Tracer() *tracing.Tracer {
+	args := m.Called()
+	return args.Get(0).(*tracing.Tracer)
+}
+
+// ConfigWatcher mocks the ConfigWatcher function.
+func (m *Mock) ConfigWatcher() *configwatcher.Watcher {
+	args := m.Called()
+	return args.Get(0).(*configwatcher.Watcher)
+}
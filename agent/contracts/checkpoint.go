@@ -0,0 +1,78 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package contracts contains objects for parsing and encoding MDS/SSM messages.
+package contracts
+
+import (
+	"sync"
+)
+
+// PluginCheckpoint is a progress marker of a plugin, which is persisted so that the plugin can resume from it
+// when the document worker is restarted
+type PluginCheckpoint struct {
+	PluginID   string `json:"pluginID"`
+	Checkpoint string `json:"checkpoint"`
+}
+
+// CheckpointRecorder persists the checkpoints of the plugins of a document
+type CheckpointRecorder func(checkpoint PluginCheckpoint)
+
+// Checkpointer holds the last checkpoint of a running plugin and records the new ones
+type Checkpointer struct {
+	lock       sync.RWMutex
+	pluginID   string
+	checkpoint string
+	recorder   CheckpointRecorder
+}
+
+// NewCheckpointer creates the checkpointer of a plugin, starting from the checkpoint of its previous run,
+// the checkpoints are only kept in memory when recorder is nil
+func NewCheckpointer(pluginID string, checkpoint string, recorder CheckpointRecorder) *Checkpointer {
+	return &Checkpointer{
+		pluginID:   pluginID,
+		checkpoint: checkpoint,
+		recorder:   recorder,
+	}
+}
+
+// Set replaces the checkpoint of the plugin and records it
+func (c *Checkpointer) Set(checkpoint string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.checkpoint = checkpoint
+	if c.recorder != nil {
+		c.recorder(PluginCheckpoint{PluginID: c.pluginID, Checkpoint: checkpoint})
+	}
+}
+
+// Get returns the last checkpoint of the plugin, a nil checkpointer has none
+func (c *Checkpointer) Get() string {
+	if c == nil {
+		return ""
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.checkpoint
+}
+
+// UpdateCheckpoint stores the checkpoint of a plugin in the document state, it returns false when the plugin isn't found
+func UpdateCheckpoint(checkpoint PluginCheckpoint, docState *DocumentState) bool {
+	for i := range docState.InstancePluginsInformation {
+		if docState.InstancePluginsInformation[i].Id == checkpoint.PluginID {
+			docState.InstancePluginsInformation[i].Checkpoint = checkpoint.Checkpoint
+			return true
+		}
+	}
+	return false
+}
@@ -47,6 +47,8 @@ type PluginState struct {
 	//TODO truncate this struct
 	Result PluginResult
 	Id     string
+	// Checkpoint is the last progress marker persisted by the plugin, it is handed back to the plugin when it runs again
+	Checkpoint string `json:",omitempty"`
 }
 
 // OSProcInfo represents information about the new process for outofproc
@@ -75,6 +77,8 @@ type DocumentInfo struct {
 	ProcInfo        OSProcInfo
 	ClientId        string
 	RunAsUser       string
+	// TraceParent identifies the span the document runs under, in the W3C traceparent format
+	TraceParent string `json:",omitempty"`
 }
 
 //CloudWatchConfiguration represents information relevant to command output in cloudWatch
@@ -90,6 +94,58 @@ type IOConfiguration struct {
 	OutputS3BucketName     string
 	OutputS3KeyPrefix      string
 	CloudWatchConfig       CloudWatchConfiguration
+	// Secrets are shared by the plugins of the document, they are never persisted
+	Secrets *DocumentSecrets `json:"-"`
+	// CheckpointRecorder persists the checkpoints of the plugins of the document, it is never persisted itself
+	CheckpointRecorder CheckpointRecorder `json:"-"`
+	// Checkpointer holds the checkpoint of the running plugin, it is set for each plugin of the document
+	Checkpointer *Checkpointer `json:"-"`
+	// OutputSinks of the document, they replace the sinks of the same type of the agent configuration
+	OutputSinks []OutputSink
+}
+
+// Output sink types
+const (
+	// OutputSinkSyslog sends each line of output as an RFC 5424 message
+	OutputSinkSyslog = "syslog"
+	// OutputSinkWebhook posts batches of lines of output to an HTTP endpoint
+	OutputSinkWebhook = "webhook"
+	// OutputSinkArchive appends the output to a local file which is rotated by size
+	OutputSinkArchive = "archive"
+)
+
+// OutputSink configures an additional destination of the output of the plugins, the fields of its type apply
+type OutputSink struct {
+	Type string `json:"type" yaml:"type"`
+
+	// Network is udp, tcp or tls, the local syslog daemon is used when it's empty
+	Network string `json:"network,omitempty" yaml:"network,omitempty"`
+	// Address is the host:port of the syslog server
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+	// Facility is the syslog facility, 1 (user-level messages) by default
+	Facility int `json:"facility,omitempty" yaml:"facility,omitempty"`
+
+	// URL is the webhook endpoint the lines are posted to
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+	// BatchSize is the most lines posted at once
+	BatchSize int `json:"batchSize,omitempty" yaml:"batchSize,omitempty"`
+	// BatchIntervalSeconds is the longest a line waits before it's posted
+	BatchIntervalSeconds int `json:"batchIntervalSeconds,omitempty" yaml:"batchIntervalSeconds,omitempty"`
+	// MaxRetries is how many times a batch is posted again after a failure
+	MaxRetries int `json:"maxRetries,omitempty" yaml:"maxRetries,omitempty"`
+	// CertificateFile and PrivateKeyFile are the client certificate of mutual TLS, and CACertificateFile replaces
+	// the system roots to verify the endpoint. They only come from the agent configuration, a document can't set them.
+	CertificateFile   string `json:"-" yaml:"-"`
+	PrivateKeyFile    string `json:"-" yaml:"-"`
+	CACertificateFile string `json:"-" yaml:"-"`
+
+	// Directory holds the archive, output.log and its rotated files output.log.1 to output.log.<MaxFiles>.
+	// The directory of a document is relative to Output.ArchiveRoot of the agent configuration.
+	Directory string `json:"directory,omitempty" yaml:"directory,omitempty"`
+	// MaxSizeMB is the size after which the archive is rotated
+	MaxSizeMB int `json:"maxSizeMB,omitempty" yaml:"maxSizeMB,omitempty"`
+	// MaxFiles is the number of rotated files kept
+	MaxFiles int `json:"maxFiles,omitempty" yaml:"maxFiles,omitempty"`
 }
 
 // DocumentState represents information relevant to a command that gets executed by agent
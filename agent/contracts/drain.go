@@ -0,0 +1,61 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package contracts contains objects for parsing and encoding MDS/SSM messages.
+package contracts
+
+import (
+	"time"
+)
+
+// DrainState is the progress of a drain of the agent
+type DrainState string
+
+const (
+	// DrainStateDraining means the agent no longer takes new work and waits for the work in flight
+	DrainStateDraining DrainState = "Draining"
+	// DrainStateDrained means all the work in flight completed
+	DrainStateDrained DrainState = "Drained"
+	// DrainStateTimedOut means work was still in flight when the completion window elapsed
+	DrainStateTimedOut DrainState = "TimedOut"
+)
+
+// DrainRequest asks the agent to drain, it's written by ssm-cli to the drain request file
+type DrainRequest struct {
+	// TimeoutSeconds is the completion window of the work in flight, the agent default applies when zero
+	TimeoutSeconds int `json:"TimeoutSeconds,omitempty"`
+}
+
+// ModuleDrainStatus is the work still in flight in a core module
+type ModuleDrainStatus struct {
+	Name     string `json:"Name"`
+	InFlight int    `json:"InFlight"`
+}
+
+// DrainStatus is the progress of a drain of the agent
+type DrainStatus struct {
+	State     DrainState          `json:"State"`
+	StartedAt time.Time           `json:"StartedAt"`
+	Deadline  time.Time           `json:"Deadline"`
+	UpdatedAt time.Time           `json:"UpdatedAt"`
+	InFlight  int                 `json:"InFlight"`
+	Modules   []ModuleDrainStatus `json:"Modules"`
+}
+
+// IModuleDrain is implemented by the core modules which can stop taking new work while the work in flight completes
+type IModuleDrain interface {
+	// ModuleDrain stops taking new work, it returns without waiting for the work in flight
+	ModuleDrain()
+	// ModuleInFlight returns the number of documents or sessions which haven't completed yet
+	ModuleInFlight() int
+}
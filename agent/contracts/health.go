@@ -0,0 +1,134 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package contracts contains objects for parsing and encoding MDS/SSM messages.
+package contracts
+
+import (
+	"sync"
+	"time"
+)
+
+// AgentStatus is the aggregated status of the agent reported to SSM
+type AgentStatus string
+
+const (
+	// AgentStatusActive means all core modules are working
+	AgentStatusActive AgentStatus = "Active"
+	// AgentStatusDegraded means some core modules are failing
+	AgentStatusDegraded AgentStatus = "Degraded"
+	// AgentStatusInactive means all core modules are failing
+	AgentStatusInactive AgentStatus = "Inactive"
+	// AgentStatusHibernating means the agent can't reach the service and hasn't started the core modules,
+	// it's only reported on the local health endpoint
+	AgentStatusHibernating AgentStatus = "Hibernating"
+)
+
+// ModuleHealth represents the health of a core module or of a component run by a core module
+type ModuleHealth struct {
+	Name          string         `json:"Name"`
+	LastSuccess   *time.Time     `json:"LastSuccess,omitempty"`
+	LastError     string         `json:"LastError,omitempty"`
+	LastErrorTime *time.Time     `json:"LastErrorTime,omitempty"`
+	Backlog       int            `json:"Backlog"`
+	Components    []ModuleHealth `json:"Components,omitempty"`
+}
+
+// IsFailing returns true if the last outcome of the module or of one of its components is an error
+func (h ModuleHealth) IsFailing() bool {
+	if h.LastErrorTime != nil && (h.LastSuccess == nil || h.LastErrorTime.After(*h.LastSuccess)) {
+		return true
+	}
+	for _, component := range h.Components {
+		if component.IsFailing() {
+			return true
+		}
+	}
+	return false
+}
+
+// AgentHealth represents the aggregated health of the core modules
+type AgentHealth struct {
+	Status    AgentStatus    `json:"Status"`
+	CheckedAt time.Time      `json:"CheckedAt"`
+	Modules   []ModuleHealth `json:"Modules"`
+	// Drain is the progress of the drain of the agent, if any
+	Drain *DrainStatus `json:"Drain,omitempty"`
+	// Hibernation is the progress of the hibernation of the agent, if any
+	Hibernation *HibernationStatus `json:"Hibernation,omitempty"`
+}
+
+// IModuleHealth is implemented by the core modules which report their health
+type IModuleHealth interface {
+	ModuleHealth() ModuleHealth
+}
+
+// IAgentHealthAware is implemented by the core modules which need the aggregated health of the agent
+type IAgentHealthAware interface {
+	SetAgentHealthProvider(provider func() AgentHealth)
+}
+
+// HealthTracker records the outcome of the work of a core module.
+// It is safe for concurrent use, and a nil tracker ignores all records.
+type HealthTracker struct {
+	lock   sync.RWMutex
+	health ModuleHealth
+}
+
+// NewHealthTracker creates a tracker for the named module
+func NewHealthTracker(name string) *HealthTracker {
+	return &HealthTracker{health: ModuleHealth{Name: name}}
+}
+
+// RecordSuccess records that the module successfully completed its work
+func (t *HealthTracker) RecordSuccess() {
+	if t == nil {
+		return
+	}
+	now := time.Now().UTC()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.health.LastSuccess = &now
+}
+
+// RecordError records that the module failed to complete its work
+func (t *HealthTracker) RecordError(err error) {
+	if t == nil || err == nil {
+		return
+	}
+	now := time.Now().UTC()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.health.LastError = err.Error()
+	t.health.LastErrorTime = &now
+}
+
+// SetBacklog records the amount of work the module has not processed yet
+func (t *HealthTracker) SetBacklog(backlog int) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.health.Backlog = backlog
+}
+
+// Health returns a snapshot of the recorded health
+func (t *HealthTracker) Health() ModuleHealth {
+	if t == nil {
+		return ModuleHealth{}
+	}
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.health
+}
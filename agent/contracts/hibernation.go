@@ -0,0 +1,46 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package contracts contains objects for parsing and encoding MDS/SSM messages.
+package contracts
+
+import (
+	"time"
+)
+
+// HibernationReason is why the agent can't reach the service while it hibernates
+type HibernationReason string
+
+const (
+	// HibernationReasonNoCredentials means no credential provider returned credentials
+	HibernationReasonNoCredentials HibernationReason = "NoCredentials"
+	// HibernationReasonAccessDenied means the service rejected the credentials of the agent
+	HibernationReasonAccessDenied HibernationReason = "AccessDenied"
+	// HibernationReasonEndpointUnreachable means the agent couldn't connect to the service endpoint
+	HibernationReasonEndpointUnreachable HibernationReason = "EndpointUnreachable"
+	// HibernationReasonUnknown means the health ping failed for another reason
+	HibernationReasonUnknown HibernationReason = "Unknown"
+)
+
+// HibernationStatus is the progress of the hibernation of the agent
+type HibernationStatus struct {
+	Reason    HibernationReason `json:"Reason"`
+	LastError string            `json:"LastError,omitempty"`
+	Since     time.Time         `json:"Since"`
+	LastCheck time.Time         `json:"LastCheck"`
+	// NextCheck is when the next health ping is scheduled, the agent wakes up earlier when a watched change occurs
+	NextCheck time.Time `json:"NextCheck"`
+	// LastWake describes the last change which triggered an early health ping, if any
+	LastWake     string     `json:"LastWake,omitempty"`
+	LastWakeTime *time.Time `json:"LastWakeTime,omitempty"`
+}
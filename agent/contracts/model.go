@@ -190,6 +190,10 @@ type DocumentContent struct {
 	RuntimeConfig map[string]*PluginConfig `json:"runtimeConfig" yaml:"runtimeConfig"`
 	MainSteps     []*InstancePluginConfig  `json:"mainSteps" yaml:"mainSteps"`
 	Parameters    map[string]*Parameter    `json:"parameters" yaml:"parameters"`
+	// RunAsUser is the local user the script steps run as, unless a step sets its own runAsUser
+	RunAsUser string `json:"runAsUser,omitempty" yaml:"runAsUser,omitempty"`
+	// OutputSinks are destinations of the output of the plugins in addition to the orchestration directory, S3 and CloudWatch
+	OutputSinks []OutputSink `json:"outputSinks,omitempty" yaml:"outputSinks,omitempty"`
 }
 
 // SessionInputs stores session configuration
@@ -263,4 +267,6 @@ type DocumentResult struct {
 	Status          ResultStatus
 	LastPlugin      string
 	NPlugins        int
+	// TraceParent identifies the span the document ran under, so that replying the result joins the trace
+	TraceParent string `json:",omitempty"`
 }
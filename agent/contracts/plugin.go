@@ -41,6 +41,24 @@ type PluginResult struct {
 	Error              string       `json:"error"`
 	StandardOutput     string       `json:"standardOutput"`
 	StandardError      string       `json:"standardError"`
+
+	StandardOutputSummary *OutputSummary `json:"standardOutputSummary,omitempty"`
+	StandardErrorSummary  *OutputSummary `json:"standardErrorSummary,omitempty"`
+
+	// ResumedFromCheckpoint is the checkpoint of a previous run of the plugin that it was handed when it started
+	ResumedFromCheckpoint string `json:"resumedFromCheckpoint,omitempty"`
+}
+
+// OutputSummary describes the whole output of a stream when only part of it is kept in memory.
+type OutputSummary struct {
+	// Bytes is the size of the whole output
+	Bytes int64 `json:"bytes"`
+	// SHA256 is the hex encoded hash of the whole output
+	SHA256 string `json:"sha256,omitempty"`
+	// Truncated is true when the middle of the output was dropped from the standard output or error
+	Truncated bool `json:"truncated"`
+	// File is the path of the file holding the whole output, if it was spilled to disk
+	File string `json:"file,omitempty"`
 }
 
 // IPlugin is interface for authoring a functionality of work.
@@ -87,6 +105,8 @@ type Configuration struct {
 	KmsKeyId                    string
 	RunAsEnabled                bool
 	RunAsUser                   string
+	// TraceParent identifies the span the plugin runs under, in the W3C traceparent format
+	TraceParent string `json:",omitempty"`
 }
 
 // Plugin wraps the plugin configuration and plugin result.
@@ -0,0 +1,60 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package contracts contains objects for parsing and encoding MDS/SSM messages.
+package contracts
+
+import (
+	"sync"
+)
+
+// DocumentSecrets holds the secure values resolved while a document runs, they are redacted from the output of its plugins
+type DocumentSecrets struct {
+	lock   sync.RWMutex
+	values []string
+}
+
+// NewDocumentSecrets creates an empty set of secrets
+func NewDocumentSecrets() *DocumentSecrets {
+	return &DocumentSecrets{}
+}
+
+// Add adds the secrets to the set, the empty and known ones are ignored
+func (s *DocumentSecrets) Add(values ...string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, value := range values {
+		if value != "" && !containsString(s.values, value) {
+			s.values = append(s.values, value)
+		}
+	}
+}
+
+// Values returns the secrets of the set, a nil set has none
+func (s *DocumentSecrets) Values() []string {
+	if s == nil {
+		return nil
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return append([]string(nil), s.values...)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
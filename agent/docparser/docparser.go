@@ -89,6 +89,7 @@ func (docContent *DocContent) GetIOConfiguration(parserInfo DocumentParserInfo)
 		OutputS3BucketName:     parserInfo.S3Bucket,
 		OutputS3KeyPrefix:      parserInfo.S3Prefix,
 		CloudWatchConfig:       parserInfo.CloudWatchConfig,
+		OutputSinks:            docContent.OutputSinks,
 	}
 }
 
@@ -272,6 +273,7 @@ func parsePluginStateForV10Schema(
 			PluginName:              pluginName,
 			PluginID:                pluginName,
 			DefaultWorkingDirectory: defaultWorkingDir,
+			RunAsUser:               docContent.RunAsUser,
 		}
 		pluginConfigurations = append(pluginConfigurations, &config)
 	}
@@ -316,6 +318,7 @@ func parsePluginStateForV20Schema(
 			Preconditions:           instancePluginConfig.Preconditions,
 			IsPreconditionEnabled:   isPreconditionEnabled,
 			DefaultWorkingDirectory: defaultWorkingDir,
+			RunAsUser:               docContent.RunAsUser,
 		}
 
 		var plugin contracts.PluginState
@@ -426,6 +429,10 @@ func replaceValidatedPluginParameters(
 	logger log.T) error {
 	var err error
 
+	if runAsUser, ok := parameters.ReplaceParameters(docContent.RunAsUser, params, logger).(string); ok {
+		docContent.RunAsUser = runAsUser
+	}
+
 	//TODO: Refactor this to not not reparse the docContent
 	runtimeConfig := docContent.RuntimeConfig
 	// we assume that one of the runtimeConfig and mainSteps should be nil
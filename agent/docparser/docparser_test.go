@@ -162,6 +162,20 @@ func TestInitializeDocState_Valid(t *testing.T) {
 	assert.Equal(t, testLogStreamPrefix, docState.IOConfig.CloudWatchConfig.LogStreamPrefix)
 }
 
+func TestGetIOConfiguration_OutputSinks(t *testing.T) {
+	testDocContent := DocContent{
+		SchemaVersion: "2.2",
+		OutputSinks: []contracts.OutputSink{
+			{Type: contracts.OutputSinkSyslog, Network: "tcp", Address: "syslog.example.com:514"},
+		},
+	}
+
+	ioConfig := testDocContent.GetIOConfiguration(DocumentParserInfo{OrchestrationDir: testOrchDir})
+
+	assert.Equal(t, testOrchDir, ioConfig.OrchestrationDirectory)
+	assert.Equal(t, testDocContent.OutputSinks, ioConfig.OutputSinks)
+}
+
 func TestInitializeDocStateForStartSessionDocument_Valid(t *testing.T) {
 	mockLog := log.NewMockLog()
 
@@ -0,0 +1,29 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux,go1.20
+
+package executers
+
+import "syscall"
+
+// cloneIntoCgroupSupported tells if the kernel can start a process in a cgroup, which Linux supports from 5.7
+var cloneIntoCgroupSupported = func() bool {
+	return kernelAtLeast(5, 7)
+}
+
+// setCgroupFD makes the process start in the cgroup open as fd
+func setCgroupFD(attr *syscall.SysProcAttr, fd int) {
+	attr.UseCgroupFD = true
+	attr.CgroupFD = fd
+}
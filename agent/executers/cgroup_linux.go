@@ -0,0 +1,374 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/twinj/uuid"
+)
+
+const (
+	// cpuPeriodMicroseconds is the period of the cpu.max quota of the commands
+	cpuPeriodMicroseconds = 100000
+
+	// agentLeafCgroup is the child cgroup the agent moves its own processes to, a cgroup v2 with
+	// processes can't enable controllers for its children
+	agentLeafCgroup = "agent"
+
+	commandCgroupPrefix = "command-"
+	cgroupRemoveRetries = 20
+	cgroupRemoveDelay   = 50 * time.Millisecond
+)
+
+var (
+	// cgroupMountPoint is where the unified cgroup v2 hierarchy is mounted
+	cgroupMountPoint = "/sys/fs/cgroup"
+	// procSelfCgroup lists the cgroups of the agent process
+	procSelfCgroup = "/proc/self/cgroup"
+
+	// delegatedRootOnce looks up the cgroup the agent delegated the controllers of once per process
+	delegatedRootOnce sync.Once
+	delegatedRoot     string
+	delegatedRootErr  error
+
+	// activeCgroups holds the command cgroups in use, the other ones are removed once their processes exited
+	activeCgroups     = make(map[string]bool)
+	activeCgroupsLock sync.Mutex
+)
+
+// commandCgroup is the transient cgroup a command runs in
+type commandCgroup struct {
+	path   string
+	limits ResourceLimits
+}
+
+// DelegateCommandCgroups enables the controllers of the agent cgroup for the command cgroups.
+// The agent calls it once when it starts, the document workers it starts inherit its cgroup and only look it up.
+func DelegateCommandCgroups(log log.T) {
+	if _, err := delegateControllers(log); err != nil {
+		log.Debugf("Commands can't run with resource limits, %v", err)
+	}
+}
+
+// newCommandCgroup creates a child of the agent cgroup with the limits
+func newCommandCgroup(log log.T, limits ResourceLimits) (*commandCgroup, error) {
+	delegatedRootOnce.Do(func() {
+		delegatedRoot, delegatedRootErr = findDelegatedRoot()
+	})
+	if delegatedRootErr != nil {
+		return nil, delegatedRootErr
+	}
+
+	cgroup := &commandCgroup{
+		path:   filepath.Join(delegatedRoot, commandCgroupPrefix+uuid.NewV4().String()),
+		limits: limits,
+	}
+	reapCommandCgroups(delegatedRoot)
+	if err := os.Mkdir(cgroup.path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup %v: %v", cgroup.path, err)
+	}
+	activeCgroupsLock.Lock()
+	activeCgroups[cgroup.path] = true
+	activeCgroupsLock.Unlock()
+	if err := cgroup.applyLimits(); err != nil {
+		cgroup.remove(log, false)
+		return nil, err
+	}
+	return cgroup, nil
+}
+
+// reapCommandCgroups removes the command cgroups left behind by background processes of earlier commands
+// once these exited, removing a cgroup with processes fails
+func reapCommandCgroups(root string) {
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return
+	}
+	activeCgroupsLock.Lock()
+	defer activeCgroupsLock.Unlock()
+	for _, entry := range entries {
+		path := filepath.Join(root, entry.Name())
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), commandCgroupPrefix) && !activeCgroups[path] {
+			os.Remove(path)
+		}
+	}
+}
+
+// delegateControllers finds the cgroup of the agent and enables the controllers for its children.
+// The processes of the agent cgroup move to a leaf child first, as required by cgroup v2.
+func delegateControllers(log log.T) (string, error) {
+	root, err := agentCgroupRoot()
+	if err != nil {
+		return "", err
+	}
+
+	available, err := ioutil.ReadFile(filepath.Join(root, "cgroup.controllers"))
+	if err != nil {
+		return "", fmt.Errorf("cgroup v2 is not available: %v", err)
+	}
+
+	procs, err := ioutil.ReadFile(filepath.Join(root, "cgroup.procs"))
+	if err != nil {
+		return "", err
+	}
+	if pids := strings.Fields(string(procs)); len(pids) > 0 {
+		leaf := filepath.Join(root, agentLeafCgroup)
+		if err = os.MkdirAll(leaf, 0755); err != nil {
+			return "", fmt.Errorf("failed to create cgroup %v: %v", leaf, err)
+		}
+		for _, pid := range pids {
+			if err = writeCgroupFile(leaf, "cgroup.procs", pid); err != nil {
+				log.Debugf("failed to move process %v to cgroup %v: %v", pid, leaf, err)
+			}
+		}
+	}
+
+	var controllers []string
+	for _, controller := range strings.Fields(string(available)) {
+		switch controller {
+		case "cpu", "io", "memory", "pids":
+			controllers = append(controllers, "+"+controller)
+		}
+	}
+	if len(controllers) > 0 {
+		if err = writeCgroupFile(root, "cgroup.subtree_control", strings.Join(controllers, " ")); err != nil {
+			return "", fmt.Errorf("failed to enable the controllers of cgroup %v, the agent service may need Delegate=yes: %v", root, err)
+		}
+	}
+	log.Infof("Running commands with resource limits in cgroup %v with controllers %v", root, controllers)
+	return root, nil
+}
+
+// findDelegatedRoot returns the agent cgroup once the agent enabled the controllers for its children
+func findDelegatedRoot() (string, error) {
+	root, err := agentCgroupRoot()
+	if err != nil {
+		return "", err
+	}
+	enabled, err := ioutil.ReadFile(filepath.Join(root, "cgroup.subtree_control"))
+	if err != nil {
+		return "", fmt.Errorf("cgroup v2 is not available: %v", err)
+	}
+	if len(strings.Fields(string(enabled))) == 0 {
+		return "", fmt.Errorf("the agent did not enable the controllers of cgroup %v, the agent service may need Delegate=yes", root)
+	}
+	return root, nil
+}
+
+// agentCgroupRoot returns the cgroup of the agent service, the parent of the leaf the agent moved its processes to
+func agentCgroupRoot() (string, error) {
+	relative, err := unifiedCgroupPath()
+	if err != nil {
+		return "", err
+	}
+	root := filepath.Join(cgroupMountPoint, relative)
+	if filepath.Base(root) == agentLeafCgroup {
+		root = filepath.Dir(root)
+	}
+	return root, nil
+}
+
+// unifiedCgroupPath returns the path of the agent in the cgroup v2 hierarchy
+func unifiedCgroupPath() (string, error) {
+	file, err := os.Open(procSelfCgroup)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// the unified hierarchy has the id 0 and no controller list, e.g. 0::/system.slice/amazon-ssm-agent.service
+		if strings.HasPrefix(scanner.Text(), "0::") {
+			return strings.TrimPrefix(scanner.Text(), "0::"), nil
+		}
+	}
+	return "", errors.New("cgroup v2 is not available, the agent isn't in the unified hierarchy")
+}
+
+// applyLimits writes the limits to the interface files of the controllers
+func (c *commandCgroup) applyLimits() error {
+	if c.limits.CPUQuotaPercent > 0 {
+		quota := c.limits.CPUQuotaPercent * cpuPeriodMicroseconds / 100
+		if err := writeCgroupFile(c.path, "cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriodMicroseconds)); err != nil {
+			return err
+		}
+	}
+	if c.limits.MemoryMaxMB > 0 {
+		if err := writeCgroupFile(c.path, "memory.max", strconv.FormatInt(int64(c.limits.MemoryMaxMB)*1024*1024, 10)); err != nil {
+			return err
+		}
+		// without swap the memory limit is the one the command gets killed at
+		if err := writeCgroupFile(c.path, "memory.swap.max", "0"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if c.limits.PidsMax > 0 {
+		if err := writeCgroupFile(c.path, "pids.max", strconv.Itoa(c.limits.PidsMax)); err != nil {
+			return err
+		}
+	}
+	if c.limits.IOWeight > 0 {
+		if err := writeCgroupFile(c.path, "io.weight", fmt.Sprintf("default %d", c.limits.IOWeight)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// start starts the command inside the cgroup so that no process of the command runs outside of it.
+// When the kernel or the Go release the agent is built with can't start a process in a cgroup, the command
+// is moved to it right after it started.
+func (c *commandCgroup) start(log log.T, command *exec.Cmd) error {
+	if c == nil {
+		return command.Start()
+	}
+	if !cloneIntoCgroupSupported() {
+		if err := command.Start(); err != nil {
+			return err
+		}
+		if err := c.addProcess(command.Process.Pid); err != nil {
+			log.Warnf("running the command without resource limits, %v", err)
+		}
+		return nil
+	}
+
+	dir, err := os.Open(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to open cgroup %v: %v", c.path, err)
+	}
+	defer dir.Close()
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	setCgroupFD(command.SysProcAttr, int(dir.Fd()))
+	return command.Start()
+}
+
+// addProcess moves the process to the cgroup, its children inherit the cgroup
+func (c *commandCgroup) addProcess(pid int) error {
+	return writeCgroupFile(c.path, "cgroup.procs", strconv.Itoa(pid))
+}
+
+// reportEvents logs and writes to the error output of the command the limits which killed processes
+// of the command or denied it resources
+func (c *commandCgroup) reportEvents(log log.T, stderr io.Writer) {
+	if c == nil {
+		return
+	}
+	var messages []string
+	if c.limits.MemoryMaxMB > 0 {
+		if events := readCgroupEvents(c.path, "memory.events"); events["oom_kill"] > 0 {
+			messages = append(messages, fmt.Sprintf("%d processes of the command were killed after reaching the memory limit of %d MB",
+				events["oom_kill"], c.limits.MemoryMaxMB))
+		}
+	}
+	if c.limits.PidsMax > 0 {
+		if events := readCgroupEvents(c.path, "pids.events"); events["max"] > 0 {
+			messages = append(messages, fmt.Sprintf("the command was denied %d new processes by the limit of %d processes",
+				events["max"], c.limits.PidsMax))
+		}
+	}
+	for _, message := range messages {
+		log.Warnf("Resource limit reached: %v", message)
+		if stderr != nil {
+			fmt.Fprintf(stderr, "\n%v\n", message)
+		}
+	}
+}
+
+// remove deletes the cgroup. The processes left in it are killed first when kill is set, which is done when the command
+// timed out or was canceled. Otherwise the background processes the command started keep running and the cgroup is
+// removed once they exited.
+func (c *commandCgroup) remove(log log.T, kill bool) {
+	if c == nil {
+		return
+	}
+	activeCgroupsLock.Lock()
+	delete(activeCgroups, c.path)
+	activeCgroupsLock.Unlock()
+
+	if !kill {
+		if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+			log.Debugf("cgroup %v still has processes, it is removed once they exited", c.path)
+		}
+		return
+	}
+	// cgroup.kill is only available from Linux 5.14
+	writeCgroupFile(c.path, "cgroup.kill", "1")
+	var err error
+	for i := 0; i < cgroupRemoveRetries; i++ {
+		if err = os.Remove(c.path); err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(cgroupRemoveDelay)
+	}
+	log.Warnf("failed to remove cgroup %v: %v", c.path, err)
+}
+
+// kernelAtLeast tells if the version of the running kernel is at least major.minor
+func kernelAtLeast(major int, minor int) bool {
+	var uname syscall.Utsname
+	if err := syscall.Uname(&uname); err != nil {
+		return false
+	}
+	var release []byte
+	for _, c := range uname.Release {
+		if c == 0 {
+			break
+		}
+		release = append(release, byte(c))
+	}
+	var kernelMajor, kernelMinor int
+	if _, err := fmt.Sscanf(string(release), "%d.%d", &kernelMajor, &kernelMinor); err != nil {
+		return false
+	}
+	return kernelMajor > major || (kernelMajor == major && kernelMinor >= minor)
+}
+
+// readCgroupEvents parses the "key value" lines of an events file of the cgroup
+func readCgroupEvents(path string, name string) map[string]int64 {
+	events := make(map[string]int64)
+	content, err := ioutil.ReadFile(filepath.Join(path, name))
+	if err != nil {
+		return events
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			if value, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				events[fields[0]] = value
+			}
+		}
+	}
+	return events
+}
+
+func writeCgroupFile(path string, name string, value string) error {
+	return ioutil.WriteFile(filepath.Join(path, name), []byte(value), 0644)
+}
@@ -0,0 +1,201 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// setCgroupHierarchy fakes the cgroup v2 hierarchy of the agent service in a temporary directory.
+// It returns the cgroup of the service and a function restoring the real hierarchy.
+func setCgroupHierarchy(t *testing.T, controllers string) (root string, restore func()) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	assert.NoError(t, err)
+	cgroupMountPoint = filepath.Join(dir, "sys", "fs", "cgroup")
+	procSelfCgroup = filepath.Join(dir, "cgroup")
+	delegatedRootOnce = sync.Once{}
+	restore = func() {
+		os.RemoveAll(dir)
+		cgroupMountPoint = "/sys/fs/cgroup"
+		procSelfCgroup = "/proc/self/cgroup"
+		delegatedRootOnce = sync.Once{}
+	}
+
+	root = filepath.Join(cgroupMountPoint, "system.slice", "amazon-ssm-agent.service")
+	assert.NoError(t, os.MkdirAll(root, 0755))
+	assert.NoError(t, ioutil.WriteFile(procSelfCgroup, []byte("0::/system.slice/amazon-ssm-agent.service\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte(controllers), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "cgroup.procs"), []byte("1234\n"), 0644))
+	return root, restore
+}
+
+// setDelegatedCgroupHierarchy fakes the cgroup v2 hierarchy once the agent delegated the controllers
+func setDelegatedCgroupHierarchy(t *testing.T, controllers string) (root string, restore func()) {
+	root, restore = setCgroupHierarchy(t, controllers)
+	DelegateCommandCgroups(log.NewMockLog())
+	return root, restore
+}
+
+func readCgroupFile(t *testing.T, path string, name string) string {
+	content, err := ioutil.ReadFile(filepath.Join(path, name))
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func TestDelegateCommandCgroups(t *testing.T) {
+	root, restore := setCgroupHierarchy(t, "cpuset cpu io memory hugetlb pids rdma misc\n")
+	defer restore()
+
+	DelegateCommandCgroups(log.NewMockLog())
+
+	// the processes of the agent moved to a leaf and the controllers are enabled for the children
+	assert.Equal(t, "1234", readCgroupFile(t, filepath.Join(root, agentLeafCgroup), "cgroup.procs"))
+	assert.Equal(t, "+cpu +io +memory +pids", readCgroupFile(t, root, "cgroup.subtree_control"))
+}
+
+func TestNewCommandCgroupWithoutDelegation(t *testing.T) {
+	root, restore := setCgroupHierarchy(t, "memory pids\n")
+	defer restore()
+
+	_, err := newCommandCgroup(log.NewMockLog(), ResourceLimits{MemoryMaxMB: 256})
+
+	assert.Error(t, err)
+	// a command never moves the processes of the agent
+	_, err = os.Stat(filepath.Join(root, agentLeafCgroup))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNewCommandCgroupFromAgentLeaf(t *testing.T) {
+	root, restore := setDelegatedCgroupHierarchy(t, "memory pids\n")
+	defer restore()
+	// the document workers started after the delegation are in the leaf of the agent
+	assert.NoError(t, ioutil.WriteFile(procSelfCgroup, []byte("0::/system.slice/amazon-ssm-agent.service/agent\n"), 0644))
+
+	cgroup, err := newCommandCgroup(log.NewMockLog(), ResourceLimits{MemoryMaxMB: 256})
+
+	assert.NoError(t, err)
+	assert.Equal(t, root, filepath.Dir(cgroup.path))
+}
+
+func TestNewCommandCgroup(t *testing.T) {
+	root, restore := setDelegatedCgroupHierarchy(t, "cpuset cpu io memory hugetlb pids rdma misc\n")
+	defer restore()
+
+	cgroup, err := newCommandCgroup(log.NewMockLog(), ResourceLimits{CPUQuotaPercent: 50, MemoryMaxMB: 256, PidsMax: 64, IOWeight: 200})
+
+	assert.NoError(t, err)
+	assert.Equal(t, root, filepath.Dir(cgroup.path))
+
+	assert.Equal(t, "50000 100000", readCgroupFile(t, cgroup.path, "cpu.max"))
+	assert.Equal(t, strconv.Itoa(256*1024*1024), readCgroupFile(t, cgroup.path, "memory.max"))
+	assert.Equal(t, "0", readCgroupFile(t, cgroup.path, "memory.swap.max"))
+	assert.Equal(t, "64", readCgroupFile(t, cgroup.path, "pids.max"))
+	assert.Equal(t, "default 200", readCgroupFile(t, cgroup.path, "io.weight"))
+
+	assert.NoError(t, cgroup.addProcess(4321))
+	assert.Equal(t, "4321", readCgroupFile(t, cgroup.path, "cgroup.procs"))
+}
+
+func TestNewCommandCgroupWithoutUnifiedHierarchy(t *testing.T) {
+	_, restore := setCgroupHierarchy(t, "")
+	defer restore()
+	assert.NoError(t, ioutil.WriteFile(procSelfCgroup, []byte("12:memory:/system.slice/amazon-ssm-agent.service\n"), 0644))
+
+	_, err := newCommandCgroup(log.NewMockLog(), ResourceLimits{MemoryMaxMB: 256})
+
+	assert.Error(t, err)
+}
+
+func TestStartWithoutCloneIntoCgroup(t *testing.T) {
+	_, restore := setDelegatedCgroupHierarchy(t, "memory pids\n")
+	defer restore()
+	defaultCloneIntoCgroupSupported := cloneIntoCgroupSupported
+	cloneIntoCgroupSupported = func() bool { return false }
+	defer func() { cloneIntoCgroupSupported = defaultCloneIntoCgroupSupported }()
+	cgroup, err := newCommandCgroup(log.NewMockLog(), ResourceLimits{MemoryMaxMB: 256})
+	assert.NoError(t, err)
+	command := exec.Command("true")
+
+	assert.NoError(t, cgroup.start(log.NewMockLog(), command))
+	command.Wait()
+
+	assert.Equal(t, strconv.Itoa(command.Process.Pid), readCgroupFile(t, cgroup.path, "cgroup.procs"))
+}
+
+func TestRemoveLeavesProcessesRunning(t *testing.T) {
+	_, restore := setDelegatedCgroupHierarchy(t, "memory pids\n")
+	defer restore()
+	cgroup, err := newCommandCgroup(log.NewMockLog(), ResourceLimits{MemoryMaxMB: 256})
+	assert.NoError(t, err)
+
+	cgroup.remove(log.NewMockLog(), false)
+
+	// the cgroup is not empty, its processes keep running and it is kept until the next command reaps it
+	_, err = os.Stat(filepath.Join(cgroup.path, "cgroup.kill"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(cgroup.path)
+	assert.NoError(t, err)
+}
+
+func TestRemoveKillsProcesses(t *testing.T) {
+	_, restore := setDelegatedCgroupHierarchy(t, "memory pids\n")
+	defer restore()
+	cgroup, err := newCommandCgroup(log.NewMockLog(), ResourceLimits{MemoryMaxMB: 256})
+	assert.NoError(t, err)
+
+	cgroup.remove(log.NewMockLog(), true)
+
+	assert.Equal(t, "1", readCgroupFile(t, cgroup.path, "cgroup.kill"))
+}
+
+func TestNewCommandCgroupReapsEmptyCgroups(t *testing.T) {
+	root, restore := setDelegatedCgroupHierarchy(t, "memory pids\n")
+	defer restore()
+	leftover := filepath.Join(root, commandCgroupPrefix+"leftover")
+	assert.NoError(t, os.Mkdir(leftover, 0755))
+
+	cgroup, err := newCommandCgroup(log.NewMockLog(), ResourceLimits{MemoryMaxMB: 256})
+
+	assert.NoError(t, err)
+	_, err = os.Stat(leftover)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(cgroup.path)
+	assert.NoError(t, err)
+}
+
+func TestReportEvents(t *testing.T) {
+	path, err := ioutil.TempDir("", "cgroup")
+	assert.NoError(t, err)
+	defer os.RemoveAll(path)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(path, "memory.events"), []byte("low 0\nhigh 0\nmax 12\noom 1\noom_kill 1\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(path, "pids.events"), []byte("max 0\n"), 0644))
+	cgroup := &commandCgroup{path: path, limits: ResourceLimits{MemoryMaxMB: 256, PidsMax: 64}}
+	var stderr bytes.Buffer
+
+	cgroup.reportEvents(log.NewMockLog(), &stderr)
+
+	assert.Equal(t, "\n1 processes of the command were killed after reaching the memory limit of 256 MB\n", stderr.String())
+}
@@ -0,0 +1,49 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build !linux
+
+package executers
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// commandCgroup is the transient cgroup a command runs in, cgroups only exist on Linux
+type commandCgroup struct{}
+
+// DelegateCommandCgroups does nothing, cgroups only exist on Linux
+func DelegateCommandCgroups(log log.T) {
+}
+
+func newCommandCgroup(log log.T, limits ResourceLimits) (*commandCgroup, error) {
+	return nil, errors.New("resource limits are only supported on Linux")
+}
+
+func (c *commandCgroup) start(log log.T, command *exec.Cmd) error {
+	return command.Start()
+}
+
+func (c *commandCgroup) addProcess(pid int) error {
+	return nil
+}
+
+func (c *commandCgroup) reportEvents(log log.T, stderr io.Writer) {
+}
+
+func (c *commandCgroup) remove(log log.T, kill bool) {
+}
@@ -15,13 +15,12 @@
 package executers
 
 import (
-	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"os/exec"
 	"runtime"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -29,6 +28,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/outputcapture"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
@@ -36,6 +36,14 @@ const (
 	// envVar* constants are names of environment variables set for processes executed by ssm agent and should start with AWS_SSM_
 	envVarInstanceID = "AWS_SSM_INSTANCE_ID"
 	envVarRegionName = "AWS_SSM_REGION_NAME"
+
+	// outputTruncatedMarker replaces the middle of output too long to be returned by Execute
+	outputTruncatedMarker = "\n--output truncated--\n"
+
+	// SeccompProfileDefault denies the syscalls which change the system, like mount, reboot or loading kernel modules
+	SeccompProfileDefault = "default"
+	// SeccompProfileUnconfined doesn't filter syscalls
+	SeccompProfileUnconfined = "unconfined"
 )
 
 // T is the interface type for ShellCommandExecuter.
@@ -43,9 +51,57 @@ type T interface {
 	//TODO: Remove Execute and rename NewExecute to Execute.
 	Execute(log.T, string, string, string, task.CancelFlag, int, string, []string) (io.Reader, io.Reader, int, []error)
 	NewExecute(log.T, string, io.Writer, io.Writer, task.CancelFlag, int, string, []string) (int, error)
+	ExecuteWithOptions(log.T, string, io.Writer, io.Writer, task.CancelFlag, int, string, []string, ExecuteOptions) (int, error)
 	StartExe(log.T, string, io.Writer, io.Writer, task.CancelFlag, string, []string) (*os.Process, int, error)
 }
 
+// ExecuteOptions holds the optional settings of a command execution
+type ExecuteOptions struct {
+	// RunAsUser is the local user the command runs as, the command runs as the agent's user when empty
+	RunAsUser string
+	// Limits are the resources the command and its descendants may use
+	Limits ResourceLimits
+	// Sandbox restricts what the command can access
+	Sandbox SandboxOptions
+	// Env are environment variables added to the ones the command inherits, their values are never logged
+	Env map[string]string
+}
+
+// SandboxOptions configure the sandbox of a command, only supported on Linux. The command runs in a private
+// mount namespace where the root is read-only but the working directory, the writable paths and a private tmp,
+// with its capabilities dropped and a seccomp filter.
+type SandboxOptions struct {
+	Enabled bool
+	// ReadOnlyPaths stay read-only even under a writable path, and are kept visible under the private tmp
+	ReadOnlyPaths []string
+	// WritablePaths stay writable in addition to the working directory
+	WritablePaths []string
+	// IsolateNetwork runs the command in a network namespace with only a loopback interface
+	IsolateNetwork bool
+	// SeccompProfile is default, which denies the syscalls changing the system, or unconfined
+	SeccompProfile string
+	// Capabilities the command keeps when running as root, e.g. CAP_NET_BIND_SERVICE, all others are dropped
+	Capabilities []string
+}
+
+// ResourceLimits are the resource controls of a command, a limit of 0 means unlimited.
+// They are applied with a cgroup v2 on Linux and ignored on other platforms.
+type ResourceLimits struct {
+	// CPUQuotaPercent is the share of one CPU the command may use, 200 allows two full CPUs
+	CPUQuotaPercent int
+	// MemoryMaxMB is the memory the command may use before it is OOM-killed
+	MemoryMaxMB int
+	// PidsMax is the number of processes and threads the command may have at once
+	PidsMax int
+	// IOWeight is the proportional share of block IO of the command, between 1 and 10000
+	IOWeight int
+}
+
+// IsEmpty returns true when no limit is set
+func (l ResourceLimits) IsEmpty() bool {
+	return l == ResourceLimits{}
+}
+
 // ShellCommandExecuter is specially added for testing purposes
 type ShellCommandExecuter struct {
 }
@@ -57,19 +113,16 @@ type timeoutSignal struct {
 }
 
 // Execute executes a list of shell commands in the given working directory.
-// If no file path is provided for either stdout or stderr, output will be written to a byte buffer.
+// If no file path is provided for either stdout or stderr, output will be captured in memory.
 // Returns readers for the standard output and standard error streams, process exit code, and a set of errors.
 // The errors need not be fatal - the output streams may still have data
 // even though some errors are reported. For example, if the command got killed while executing,
 // the streams will have whatever data was printed up to the kill point, and the errors will
 // indicate that the process got terminated.
 //
-// For files, the reader returned will not contain more than appconfig.MaxStdoutLength and appconfig.MaxStderrLength respectively
-// so if the caller needs to process more output than that, it should open its own reader on the output files.
-//
-// For byte buffer output, the reader will be a reader over the buffer, which will accumulate the entire output.  Be careful
-// not to use the byte buffer approach for extremely large output (or unknown output) because it could take up a large amount
-// of memory.
+// The readers returned will not contain more than appconfig.MaxStdoutLength and appconfig.MaxStderrLength respectively:
+// longer output is replaced by its beginning and its end, so if the caller needs to process more output than that,
+// it should provide file paths and open its own reader on the output files.
 func (ShellCommandExecuter) Execute(
 	log log.T,
 	workingDir string,
@@ -82,7 +135,7 @@ func (ShellCommandExecuter) Execute(
 ) (stdout io.Reader, stderr io.Reader, exitCode int, errs []error) {
 
 	var stdoutWriter io.Writer
-	var stdoutBuf *bytes.Buffer
+	stdoutCapture := newOutputCapture(appconfig.MaxStdoutLength)
 	if stdoutFilePath != "" {
 		// create stdout file
 		// fix the permissions appropriately
@@ -94,12 +147,11 @@ func (ShellCommandExecuter) Execute(
 		stdoutWriter = stdoutFileWriter
 		defer stdoutFileWriter.Close()
 	} else {
-		stdoutBuf = bytes.NewBuffer(nil)
-		stdoutWriter = stdoutBuf
+		stdoutWriter = stdoutCapture
 	}
 
 	var stderrWriter io.Writer
-	var stderrBuf *bytes.Buffer
+	stderrCapture := newOutputCapture(appconfig.MaxStderrLength)
 	if stderrFilePath != "" {
 		// create stderr file
 		// fix the permissions appropriately
@@ -111,8 +163,7 @@ func (ShellCommandExecuter) Execute(
 		stderrWriter = stderrFileWriter
 		defer stderrFileWriter.Close() // ExecuteCommand creates a copy of the handle
 	} else {
-		stderrBuf = bytes.NewBuffer(nil)
-		stderrWriter = stderrBuf
+		stderrWriter = stderrCapture
 	}
 
 	// NOTE: Regarding the defer close of the file writers.
@@ -127,37 +178,47 @@ func (ShellCommandExecuter) Execute(
 		errs = append(errs, err)
 	}
 
-	// create reader from stdout, if it exist, otherwise use the buffer
+	// read the output back from the files, if they exist, otherwise use the captures
 	if fileutil.Exists(stdoutFilePath) {
-		stdoutReader, err := os.Open(stdoutFilePath)
-		if err != nil {
+		if err := captureFile(stdoutCapture, stdoutFilePath); err != nil {
 			// some unexpected error (file should exist)
 			errs = append(errs, err)
 		}
-		defer stdoutReader.Close()
-		stdoutString, _ := ioutil.ReadAll(io.LimitReader(stdoutReader, appconfig.MaxStdoutLength))
-		stdout = bytes.NewReader([]byte(stdoutString))
-	} else {
-		stdout = bytes.NewReader(stdoutBuf.Bytes())
 	}
+	stdout = strings.NewReader(stdoutCapture.String())
 
-	// create reader from stderr, if it exist, otherwise use the buffer
 	if fileutil.Exists(stderrFilePath) {
-		stderrReader, err := os.Open(stderrFilePath)
-		if err != nil {
+		if err := captureFile(stderrCapture, stderrFilePath); err != nil {
 			// some unexpected error (file should exist)
 			errs = append(errs, err)
 		}
-		defer stderrReader.Close()
-		stderrString, _ := ioutil.ReadAll(io.LimitReader(stderrReader, appconfig.MaxStderrLength))
-		stderr = bytes.NewReader([]byte(stderrString))
-	} else {
-		stderr = bytes.NewReader(stderrBuf.Bytes())
 	}
+	stderr = strings.NewReader(stderrCapture.String())
 
 	return
 }
 
+// newOutputCapture returns a capture keeping the beginning and the end of the output within maxLength bytes
+func newOutputCapture(maxLength int) *outputcapture.Capture {
+	available := maxLength - len(outputTruncatedMarker)
+	return outputcapture.New(outputcapture.Config{
+		HeadSize: available / 2,
+		TailSize: available - available/2,
+		Marker:   outputTruncatedMarker,
+	})
+}
+
+// captureFile streams the content of the file at filePath into capture
+func captureFile(capture *outputcapture.Capture, filePath string) error {
+	reader, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	_, err = io.Copy(capture, reader)
+	return err
+}
+
 // NewExecute executes a list of shell commands in the given working directory and provides the stdout and stderr writers.
 func (ShellCommandExecuter) NewExecute(
 	log log.T,
@@ -173,6 +234,23 @@ func (ShellCommandExecuter) NewExecute(
 	return
 }
 
+// ExecuteWithOptions executes a list of shell commands in the given working directory with the given options
+// and provides the stdout and stderr writers.
+func (ShellCommandExecuter) ExecuteWithOptions(
+	log log.T,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	cancelFlag task.CancelFlag,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+	options ExecuteOptions,
+) (exitCode int, err error) {
+	exitCode, err = executeCommand(log, cancelFlag, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments, options)
+	return
+}
+
 // StartExe starts a list of shell commands in the given working directory.
 // Returns process started, an exit code (0 if successfully launch, 1 if error launching process), and a set of errors.
 // The errors need not be fatal - the output streams may still have data
@@ -254,6 +332,19 @@ func ExecuteCommand(log log.T,
 	commandName string,
 	commandArguments []string,
 ) (exitCode int, err error) {
+	return executeCommand(log, cancelFlag, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments, ExecuteOptions{})
+}
+
+func executeCommand(log log.T,
+	cancelFlag task.CancelFlag,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+	options ExecuteOptions,
+) (exitCode int, err error) {
 
 	stdoutInterruptable, stopStdout := newWriter(stdoutWriter)
 	stderrInterruptable, stopStderr := newWriter(stderrWriter)
@@ -284,16 +375,58 @@ func ExecuteCommand(log log.T,
 
 	// configure environment variables
 	prepareEnvironment(command)
+	for _, name := range sortedKeys(options.Env) {
+		command.Env = setEnvVariable(command.Env, name, options.Env[name])
+	}
+
+	if options.RunAsUser != "" {
+		// drop the privileges of the agent to the ones of the user
+		if err = prepareRunAsUser(command, options.RunAsUser); err != nil {
+			log.Errorf("failed to run the command as %v: %v", options.RunAsUser, err)
+			exitCode = 1
+			return
+		}
+		log.Infof("Running the command as %v", options.RunAsUser)
+	}
+
+	if options.Sandbox.Enabled {
+		// the command starts through the sandbox init, which is replaced by the command once the sandbox is set up
+		if err = prepareSandbox(command, options.Sandbox); err != nil {
+			log.Errorf("failed to prepare the sandbox of the command: %v", err)
+			exitCode = 1
+			return
+		}
+		log.Infof("Running the command in a sandbox")
+	}
+
+	var cgroup *commandCgroup
+	if !options.Limits.IsEmpty() {
+		if cgroup, err = newCommandCgroup(log, options.Limits); err != nil {
+			log.Warnf("running the command without resource limits, %v", err)
+			cgroup, err = nil, nil
+		}
+	}
 
 	log.Debug()
 	log.Debugf("Running in directory %v, command: %v %v", workingDir, commandName, commandArguments)
 	log.Debug()
-	if err = command.Start(); err != nil {
+	if err = cgroup.start(log, command); err != nil {
 		log.Error("error occurred starting the command", err)
 		exitCode = 1
+		cgroup.remove(log, false)
 		return
 	}
 
+	// the processes left by a command which timed out or was canceled are killed with the cgroup
+	stopped := false
+	if cgroup != nil {
+		// the events of the cgroup tell if a limit killed the command, they are read once it stopped
+		defer func() {
+			cgroup.reportEvents(log, stderrWriter)
+			cgroup.remove(log, stopped)
+		}()
+	}
+
 	signal := timeoutSignal{}
 
 	cancelled := make(chan bool, 1)
@@ -313,6 +446,7 @@ func ExecuteCommand(log log.T,
 
 	select {
 	case <-time.After(time.Duration(executionTimeout) * time.Second):
+		stopped = true
 		stopStdout <- true
 		stopStderr <- true
 		if err = killProcess(command.Process, &signal); err != nil {
@@ -327,6 +461,7 @@ func ExecuteCommand(log log.T,
 	case <-cancelled:
 		// task has been asked to cancel, kill process
 		log.Debug("Process cancelled. Attempting to stop process.")
+		stopped = true
 		stopStdout <- true
 		stopStderr <- true
 		if err = killProcess(command.Process, &signal); err != nil {
@@ -460,6 +595,27 @@ func prepareEnvironment(command *exec.Cmd) {
 	validateEnvironmentVariables(command)
 }
 
+// setEnvVariable replaces the variable in the environment, or appends it if missing.
+func setEnvVariable(env []string, name string, val string) []string {
+	updated := make([]string, 0, len(env)+1)
+	for _, variable := range env {
+		if !strings.HasPrefix(variable, name+"=") {
+			updated = append(updated, variable)
+		}
+	}
+	return append(updated, fmtEnvVariable(name, val))
+}
+
+// sortedKeys returns the names of the variables in a stable order
+func sortedKeys(env map[string]string) []string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // fmtEnvVariable creates the string to append to the current set of environment variables.
 func fmtEnvVariable(name string, val string) string {
 	return fmt.Sprintf("%s=%s", name, val)
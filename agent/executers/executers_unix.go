@@ -16,14 +16,31 @@
 package executers
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 )
 
+const (
+	// runAsScriptDirMode keeps the directory of the script copied for the user private to the user
+	runAsScriptDirMode os.FileMode = 0700
+	// runAsScriptMode lets only the user read and run the script copied for it
+	runAsScriptMode os.FileMode = 0500
+	// searchAccess lets other users open a known path below a directory without listing it
+	searchAccess os.FileMode = 0001
+)
+
+// runAsSearchRoot is the topmost directory given search access so that the user reaches the script copied for it
+var runAsSearchRoot = appconfig.DefaultDataStorePath
+
 func prepareProcess(command *exec.Cmd) {
 	// make the process the leader of its process group
 	// (otherwise we cannot kill it properly)
@@ -64,3 +81,122 @@ func validateEnvironmentVariables(command *exec.Cmd) {
 		command.Env = env
 	}
 }
+
+// runAsCredential returns the uid, gid, supplementary groups and home directory of the user
+func runAsCredential(runAsUser string) (credential *syscall.Credential, home string, err error) {
+	account, err := user.Lookup(runAsUser)
+	if err != nil {
+		return nil, "", err
+	}
+	uid, err := strconv.ParseUint(account.Uid, 10, 32)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid uid %v: %v", account.Uid, err)
+	}
+	gid, err := strconv.ParseUint(account.Gid, 10, 32)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid gid %v: %v", account.Gid, err)
+	}
+	credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	groupIds, err := account.GroupIds()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list the groups of %v: %v", runAsUser, err)
+	}
+	for _, groupId := range groupIds {
+		if group, err := strconv.ParseUint(groupId, 10, 32); err == nil {
+			credential.Groups = append(credential.Groups, uint32(group))
+		}
+	}
+	return credential, account.HomeDir, nil
+}
+
+// prepareRunAsUser makes the process run with the uid, gid and supplementary groups of the user,
+// with the HOME, USER and LOGNAME variables of the user
+func prepareRunAsUser(command *exec.Cmd, runAsUser string) error {
+	credential, home, err := runAsCredential(runAsUser)
+	if err != nil {
+		return err
+	}
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	command.SysProcAttr.Credential = credential
+
+	env := setEnvVariable(command.Env, "HOME", home)
+	env = setEnvVariable(env, "USER", runAsUser)
+	command.Env = setEnvVariable(env, "LOGNAME", runAsUser)
+	return nil
+}
+
+// PrepareScriptForRunAsUser copies the script to a new directory next to it in the orchestration directory.
+// The copy and its directory belong to the user and only the user can read them, so that the orchestration
+// directory the agent writes the output to stays the agent's. The returned cleanup removes the copy once the
+// script completed.
+func PrepareScriptForRunAsUser(scriptPath string, runAsUser string) (userScriptPath string, cleanup func(), err error) {
+	credential, _, err := runAsCredential(runAsUser)
+	if err != nil {
+		return "", nil, err
+	}
+	content, err := ioutil.ReadFile(scriptPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := ioutil.TempDir(filepath.Dir(scriptPath), "runas-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+
+	userScriptPath = filepath.Join(dir, filepath.Base(scriptPath))
+	// the directory is new and still the agent's, so the file is created rather than opened through a link
+	file, err := os.OpenFile(userScriptPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, runAsScriptMode)
+	if err == nil {
+		_, err = file.Write(content)
+		if err == nil {
+			err = file.Chown(int(credential.Uid), int(credential.Gid))
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err == nil {
+		err = giveToUser(dir, credential, runAsScriptDirMode)
+	}
+	if err == nil {
+		err = grantSearchAccess(filepath.Dir(dir), runAsSearchRoot)
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return userScriptPath, cleanup, nil
+}
+
+// giveToUser makes the user the owner of the path, with the given mode
+func giveToUser(path string, credential *syscall.Credential, mode os.FileMode) error {
+	if err := os.Chown(path, int(credential.Uid), int(credential.Gid)); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+// grantSearchAccess lets other users traverse the agent's directories from root down to dir, without listing them.
+// The files the agent writes there stay readable only by the agent. Directories outside root are left as they are.
+func grantSearchAccess(dir string, root string) error {
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); dir == root || strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) == os.Getuid() && info.Mode().Perm()&searchAccess == 0 {
+			if err = os.Chmod(dir, info.Mode().Perm()|searchAccess); err != nil {
+				return err
+			}
+		}
+		if dir == root {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package executers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareRunAsUser(t *testing.T) {
+	current, err := user.Current()
+	assert.NoError(t, err)
+	command := exec.Command("id")
+	command.Env = []string{"HOME=/", "USER=root", "PATH=/usr/bin"}
+
+	err = prepareRunAsUser(command, current.Username)
+
+	assert.NoError(t, err)
+	assert.Equal(t, current.Uid, formatId(command.SysProcAttr.Credential.Uid))
+	assert.Equal(t, current.Gid, formatId(command.SysProcAttr.Credential.Gid))
+	assert.Equal(t, []string{"PATH=/usr/bin", "HOME=" + current.HomeDir, "USER=" + current.Username, "LOGNAME=" + current.Username}, command.Env)
+}
+
+func TestPrepareRunAsUnknownUser(t *testing.T) {
+	command := exec.Command("id")
+
+	err := prepareRunAsUser(command, "ssm-unknown-user")
+
+	assert.Error(t, err)
+	assert.Nil(t, command.SysProcAttr)
+}
+
+func TestPrepareScriptForRunAsUser(t *testing.T) {
+	current, err := user.Current()
+	assert.NoError(t, err)
+	orchestrationDir, err := ioutil.TempDir("", "orchestration")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	scriptPath := filepath.Join(orchestrationDir, "_script.sh")
+	assert.NoError(t, ioutil.WriteFile(scriptPath, []byte("echo hello"), 0600))
+
+	userScriptPath, cleanup, err := PrepareScriptForRunAsUser(scriptPath, current.Username)
+
+	assert.NoError(t, err)
+	assert.Equal(t, orchestrationDir, filepath.Dir(filepath.Dir(userScriptPath)))
+	content, err := ioutil.ReadFile(userScriptPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "echo hello", string(content))
+	for path, mode := range map[string]os.FileMode{filepath.Dir(userScriptPath): runAsScriptDirMode, userScriptPath: runAsScriptMode} {
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, mode, info.Mode().Perm())
+		assert.Equal(t, current.Uid, formatId(info.Sys().(*syscall.Stat_t).Uid))
+		assert.Equal(t, current.Gid, formatId(info.Sys().(*syscall.Stat_t).Gid))
+	}
+	// the original script is left untouched
+	info, err := os.Stat(scriptPath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	cleanup()
+	_, err = os.Stat(filepath.Dir(userScriptPath))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGrantSearchAccess(t *testing.T) {
+	root, err := ioutil.TempDir("", "datastore")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "document", "orchestration")
+	assert.NoError(t, os.MkdirAll(dir, 0700))
+	assert.NoError(t, os.Chmod(root, 0700))
+	parentInfo, err := os.Stat(filepath.Dir(root))
+	assert.NoError(t, err)
+
+	assert.NoError(t, grantSearchAccess(dir, root))
+
+	for _, path := range []string{root, filepath.Join(root, "document"), dir} {
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0701), info.Mode().Perm(), path)
+	}
+	// directories above the root are left as they are
+	info, err := os.Stat(filepath.Dir(root))
+	assert.NoError(t, err)
+	assert.Equal(t, parentInfo.Mode(), info.Mode())
+}
+
+func TestPrepareScriptForRunAsUnknownUser(t *testing.T) {
+	_, _, err := PrepareScriptForRunAsUser("/nonexistent/_script.sh", "ssm-unknown-user")
+
+	assert.Error(t, err)
+}
+
+func TestSetEnvVariable(t *testing.T) {
+	env := setEnvVariable([]string{"HOME=/", "HOMEPATH=/home", "TERM=xterm"}, "HOME", "/home/ec2-user")
+
+	assert.Equal(t, []string{"HOMEPATH=/home", "TERM=xterm", "HOME=/home/ec2-user"}, env)
+}
+
+func TestExecuteWithEnv(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	options := ExecuteOptions{Env: map[string]string{"DB_USER": "admin", "DB_PASSWORD": "pa$$ word"}}
+
+	exitCode, err := ShellCommandExecuter{}.ExecuteWithOptions(log.NewMockLog(), "", &stdout, &stderr, task.NewChanneledCancelFlag(), 10, "sh", []string{"-c", "echo \"$DB_USER:$DB_PASSWORD\""}, options)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "admin:pa$$ word\n", stdout.String())
+}
+
+func TestExecuteBoundsLargeOutput(t *testing.T) {
+	script := "echo begin; i=0; while [ $i -lt 4000 ]; do echo line $i; i=$((i+1)); done; echo end; echo error >&2"
+
+	stdout, stderr, exitCode, errs := ShellCommandExecuter{}.Execute(log.NewMockLog(), "", "", "", task.NewChanneledCancelFlag(), 10, "sh", []string{"-c", script})
+
+	assert.Empty(t, errs)
+	assert.Equal(t, 0, exitCode)
+	stdoutBytes, _ := ioutil.ReadAll(stdout)
+	stderrBytes, _ := ioutil.ReadAll(stderr)
+	assert.True(t, len(stdoutBytes) <= appconfig.MaxStdoutLength)
+	assert.True(t, strings.HasPrefix(string(stdoutBytes), "begin\nline 0\n"))
+	assert.True(t, strings.HasSuffix(string(stdoutBytes), "line 3999\nend\n"))
+	assert.Contains(t, string(stdoutBytes), outputTruncatedMarker)
+	assert.Equal(t, "error\n", string(stderrBytes))
+}
+
+func formatId(id uint32) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -16,6 +16,7 @@
 package executers
 
 import (
+	"errors"
 	"os"
 	"os/exec"
 )
@@ -38,3 +39,15 @@ func killProcess(process *os.Process, signal *timeoutSignal) error {
 // Running powershell on linux required the HOME env variable to be set and to remove the TERM env variable
 func validateEnvironmentVariables(command *exec.Cmd) {
 }
+
+// errRunAsNotSupported is returned when a command should run as another user, which the agent only does on Linux and macOS
+var errRunAsNotSupported = errors.New("running commands as another user is not supported on Windows")
+
+func prepareRunAsUser(command *exec.Cmd, runAsUser string) error {
+	return errRunAsNotSupported
+}
+
+// PrepareScriptForRunAsUser gives the user access to the script, which is not supported on Windows
+func PrepareScriptForRunAsUser(scriptPath string, runAsUser string) (userScriptPath string, cleanup func(), err error) {
+	return "", nil, errRunAsNotSupported
+}
@@ -0,0 +1,517 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// sandboxInitArg is the name the executer starts its own binary with to set up the sandbox of a command
+	sandboxInitArg = "ssm-sandbox-init"
+	// envVarSandboxConfig passes the sandbox configuration to the sandbox init, it is removed before the command runs
+	envVarSandboxConfig = "AWS_SSM_SANDBOX_CONFIG"
+	// privateTmpMode is the mode of the private tmp directories, writable by all users with the sticky bit
+	privateTmpMode = "1777"
+	// sandboxFailedExitCode is the exit code of the sandbox init when it fails to set up the sandbox
+	sandboxFailedExitCode = 126
+
+	linuxCapabilityVersion3 = 0x20080522
+	capLastCapFile          = "/proc/sys/kernel/cap_last_cap"
+	mountInfoFile           = "/proc/self/mountinfo"
+)
+
+// unchangedMountPaths keep their mounts in the sandbox, the devices and the process information aren't files a
+// command could modify
+var unchangedMountPaths = []string{"/proc", "/dev"}
+
+// privateTmpPaths get an empty tmpfs in the sandbox, the files the command writes there are gone when it exits
+var privateTmpPaths = []string{"/tmp", "/var/tmp", "/dev/shm"}
+
+// capabilities maps the names of the capabilities to their number
+var capabilities = map[string]uint{
+	"CAP_CHOWN": 0, "CAP_DAC_OVERRIDE": 1, "CAP_DAC_READ_SEARCH": 2, "CAP_FOWNER": 3, "CAP_FSETID": 4,
+	"CAP_KILL": 5, "CAP_SETGID": 6, "CAP_SETUID": 7, "CAP_SETPCAP": 8, "CAP_LINUX_IMMUTABLE": 9,
+	"CAP_NET_BIND_SERVICE": 10, "CAP_NET_BROADCAST": 11, "CAP_NET_ADMIN": 12, "CAP_NET_RAW": 13, "CAP_IPC_LOCK": 14,
+	"CAP_IPC_OWNER": 15, "CAP_SYS_MODULE": 16, "CAP_SYS_RAWIO": 17, "CAP_SYS_CHROOT": 18, "CAP_SYS_PTRACE": 19,
+	"CAP_SYS_PACCT": 20, "CAP_SYS_ADMIN": 21, "CAP_SYS_BOOT": 22, "CAP_SYS_NICE": 23, "CAP_SYS_RESOURCE": 24,
+	"CAP_SYS_TIME": 25, "CAP_SYS_TTY_CONFIG": 26, "CAP_MKNOD": 27, "CAP_LEASE": 28, "CAP_AUDIT_WRITE": 29,
+	"CAP_AUDIT_CONTROL": 30, "CAP_SETFCAP": 31, "CAP_MAC_OVERRIDE": 32, "CAP_MAC_ADMIN": 33, "CAP_SYSLOG": 34,
+	"CAP_WAKE_ALARM": 35, "CAP_BLOCK_SUSPEND": 36, "CAP_AUDIT_READ": 37, "CAP_PERFMON": 38, "CAP_BPF": 39,
+	"CAP_CHECKPOINT_RESTORE": 40,
+}
+
+// sandboxConfig is what the sandbox init needs to set up the sandbox and run the command
+type sandboxConfig struct {
+	Path           string
+	Args           []string
+	ReadOnlyPaths  []string
+	WritablePaths  []string
+	IsolateNetwork bool
+	SeccompProfile string
+	Capabilities   []uint
+	Credential     *syscall.Credential
+}
+
+func init() {
+	// the sandbox init never returns, it either runs the command or exits
+	if len(os.Args) > 0 && os.Args[0] == sandboxInitArg {
+		runSandboxInit()
+	}
+}
+
+// prepareSandbox makes the command start through the sandbox init in new namespaces. The sandbox init is
+// started with Setpgid as the command would be, and keeps its pid when it runs the command, so killing the
+// process group on cancel or timeout still kills the command and its descendants.
+func prepareSandbox(command *exec.Cmd, options SandboxOptions) error {
+	config := sandboxConfig{
+		Path:           command.Path,
+		Args:           command.Args,
+		ReadOnlyPaths:  options.ReadOnlyPaths,
+		WritablePaths:  options.WritablePaths,
+		IsolateNetwork: options.IsolateNetwork,
+		SeccompProfile: options.SeccompProfile,
+	}
+	if command.Dir != "" {
+		config.WritablePaths = append(config.WritablePaths, command.Dir)
+	}
+	switch config.SeccompProfile {
+	case "":
+		config.SeccompProfile = SeccompProfileDefault
+	case SeccompProfileDefault, SeccompProfileUnconfined:
+	default:
+		return fmt.Errorf("unknown seccomp profile %v, it should be %v or %v", config.SeccompProfile, SeccompProfileDefault, SeccompProfileUnconfined)
+	}
+	if config.SeccompProfile == SeccompProfileDefault && !seccompSupported {
+		return fmt.Errorf("the %v seccomp profile is not supported on %v", SeccompProfileDefault, runtime.GOARCH)
+	}
+	for _, name := range options.Capabilities {
+		capability, ok := capabilities[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return fmt.Errorf("unknown capability %v", name)
+		}
+		config.Capabilities = append(config.Capabilities, capability)
+	}
+
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	// the sandbox init needs the privileges of the agent, it switches to the user once the sandbox is set up
+	config.Credential = command.SysProcAttr.Credential
+	command.SysProcAttr.Credential = nil
+	command.SysProcAttr.Cloneflags = syscall.CLONE_NEWNS
+	if config.IsolateNetwork {
+		command.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if command.Env == nil {
+		command.Env = os.Environ()
+	}
+	command.Env = setEnvVariable(command.Env, envVarSandboxConfig, string(encoded))
+	command.Path = "/proc/self/exe"
+	command.Args = []string{sandboxInitArg}
+	return nil
+}
+
+// runSandboxInit sets up the sandbox and replaces the process by the command
+func runSandboxInit() {
+	// capabilities, no_new_privs and seccomp filters are attributes of the thread which runs the command
+	runtime.LockOSThread()
+
+	var config sandboxConfig
+	if err := json.Unmarshal([]byte(os.Getenv(envVarSandboxConfig)), &config); err != nil {
+		sandboxFailed(fmt.Errorf("invalid configuration: %v", err))
+	}
+	env := make([]string, 0, len(os.Environ()))
+	for _, variable := range os.Environ() {
+		if !strings.HasPrefix(variable, envVarSandboxConfig+"=") {
+			env = append(env, variable)
+		}
+	}
+
+	if err := setupSandbox(config); err != nil {
+		sandboxFailed(err)
+	}
+	sandboxFailed(syscall.Exec(config.Path, config.Args, env))
+}
+
+func sandboxFailed(err error) {
+	fmt.Fprintf(os.Stderr, "failed to set up the sandbox of the command: %v\n", err)
+	os.Exit(sandboxFailedExitCode)
+}
+
+func setupSandbox(config sandboxConfig) error {
+	// keep the mounts of the sandbox out of the namespace of the agent
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make the mounts private: %v", err)
+	}
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	// the paths under a private tmp are hidden once it's mounted, they're mounted back from a descriptor opened before
+	writablePaths, err := openPaths(config.WritablePaths)
+	if err != nil {
+		return err
+	}
+	readOnlyPaths, err := openPaths(config.ReadOnlyPaths)
+	if err != nil {
+		return err
+	}
+
+	if err := remountReadOnly("/"); err != nil {
+		return err
+	}
+	for _, path := range existingPaths(privateTmpPaths) {
+		if err := unix.Mount("tmpfs", path, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode="+privateTmpMode); err != nil {
+			return fmt.Errorf("failed to mount a private %v: %v", path, err)
+		}
+	}
+	// the read-only paths are mounted last so they stay read-only under a writable path
+	for _, path := range writablePaths {
+		if err := path.bindMount(false); err != nil {
+			return err
+		}
+	}
+	for _, path := range readOnlyPaths {
+		if err := path.bindMount(true); err != nil {
+			return err
+		}
+	}
+	// the working directory of the sandbox init is on the mount it had before the sandbox was set up
+	if err := os.Chdir(workingDir); err != nil {
+		return err
+	}
+	if config.IsolateNetwork {
+		if err := setLoopbackUp(); err != nil {
+			return fmt.Errorf("failed to set up the loopback interface: %v", err)
+		}
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %v", err)
+	}
+	if err := dropCapabilities(config.Capabilities, config.Credential); err != nil {
+		return err
+	}
+	if config.SeccompProfile == SeccompProfileDefault {
+		if err := loadSeccompFilter(); err != nil {
+			return fmt.Errorf("failed to load the seccomp filter: %v", err)
+		}
+	}
+	return nil
+}
+
+// existingPaths resolves the symbolic links of the paths and leaves out the missing and duplicate ones
+func existingPaths(paths []string) (resolved []string) {
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		real, err := filepath.EvalSymlinks(path)
+		if err != nil || seen[real] {
+			continue
+		}
+		seen[real] = true
+		resolved = append(resolved, real)
+	}
+	return resolved
+}
+
+// openedPath is a path with a descriptor which keeps access to it once other mounts hide it
+type openedPath struct {
+	path string
+	file *os.File
+}
+
+// openPaths opens the existing paths with O_PATH, the descriptors are closed when the command runs
+func openPaths(paths []string) (opened []openedPath, err error) {
+	for _, path := range existingPaths(paths) {
+		fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %v: %v", path, err)
+		}
+		opened = append(opened, openedPath{path: path, file: os.NewFile(uintptr(fd), path)})
+	}
+	return opened, nil
+}
+
+// bindMount mounts the opened path on its path with its submounts, read-only or writable. The mount point is
+// created when it's hidden by a private tmp.
+func (p openedPath) bindMount(readOnly bool) error {
+	info, err := p.file.Stat()
+	if err != nil {
+		return err
+	}
+	if err := createMountPoint(p.path, info.IsDir()); err != nil {
+		return err
+	}
+	source := fmt.Sprintf("/proc/self/fd/%d", p.file.Fd())
+	if err := unix.Mount(source, p.path, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to bind mount %v: %v", p.path, err)
+	}
+	if readOnly {
+		return remountReadOnly(p.path)
+	}
+	// the bind mount has the flags of the mount of the path, which is read-only once the root is
+	mounts, err := mountPointsUnder(p.path)
+	if err != nil {
+		return err
+	}
+	for _, mount := range mounts {
+		if mount.path == p.path {
+			return remount(mount, false)
+		}
+	}
+	return nil
+}
+
+// createMountPoint creates the directory or the empty file a path is mounted on if it's missing
+func createMountPoint(path string, isDir bool) error {
+	if _, err := os.Lstat(path); err == nil {
+		return nil
+	}
+	if isDir {
+		return os.MkdirAll(path, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// remountReadOnly makes the mounts at or under the path read-only, but the ones of the paths kept unchanged.
+// A read-only remount only applies to one mount, the submounts are remounted one by one.
+func remountReadOnly(path string) error {
+	mounts, err := mountPointsUnder(path)
+	if err != nil {
+		return err
+	}
+	for _, mount := range mounts {
+		if isUnder(mount.path, unchangedMountPaths) {
+			continue
+		}
+		if err := remount(mount, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remount changes the mount to read-only or writable, keeping its other flags
+func remount(mount mountPoint, readOnly bool) error {
+	flags := uintptr(unix.MS_BIND|unix.MS_REMOUNT) | mount.flags
+	if readOnly {
+		flags |= unix.MS_RDONLY
+	}
+	if err := unix.Mount("", mount.path, "", flags, ""); err != nil {
+		return fmt.Errorf("failed to remount %v: %v", mount.path, err)
+	}
+	return nil
+}
+
+// isUnder tells if the path is one of the parents or under one of them
+func isUnder(path string, parents []string) bool {
+	for _, parent := range parents {
+		if path == parent || strings.HasPrefix(path, strings.TrimSuffix(parent, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// mountPoint is a mount with the flags which should be kept when it's remounted
+type mountPoint struct {
+	path  string
+	flags uintptr
+}
+
+// mountPointsUnder lists the mount points at or under the path, parents first
+func mountPointsUnder(path string) ([]mountPoint, error) {
+	file, err := os.Open(mountInfoFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseMountInfo(file, path)
+}
+
+func parseMountInfo(reader io.Reader, path string) (mounts []mountPoint, err error) {
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		// e.g. 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		mountPath := unescapeMountPath(fields[4])
+		if !isUnder(mountPath, []string{path}) {
+			continue
+		}
+		var flags uintptr
+		for _, option := range strings.Split(fields[5], ",") {
+			switch option {
+			case "nosuid":
+				flags |= unix.MS_NOSUID
+			case "nodev":
+				flags |= unix.MS_NODEV
+			case "noexec":
+				flags |= unix.MS_NOEXEC
+			}
+		}
+		if !seen[mountPath] {
+			seen[mountPath] = true
+			mounts = append(mounts, mountPoint{path: mountPath, flags: flags})
+		} else {
+			// the mount stacked last is the visible one
+			for i := range mounts {
+				if mounts[i].path == mountPath {
+					mounts[i].flags = flags
+				}
+			}
+		}
+	}
+	sort.SliceStable(mounts, func(i, j int) bool { return len(mounts[i].path) < len(mounts[j].path) })
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPath decodes the octal escapes of the spaces, tabs, new lines and backslashes of mountinfo
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, "\\") {
+		return path
+	}
+	var unescaped strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if value, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				unescaped.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		unescaped.WriteByte(path[i])
+	}
+	return unescaped.String()
+}
+
+// setLoopbackUp brings up the loopback interface of the new network namespace
+func setLoopbackUp() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	// struct ifreq with the interface name followed by the flags
+	var request [40]byte
+	copy(request[:unix.IFNAMSIZ-1], "lo")
+	*(*uint16)(unsafe.Pointer(&request[unix.IFNAMSIZ])) = unix.IFF_UP | unix.IFF_RUNNING
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.SIOCSIFFLAGS, uintptr(unsafe.Pointer(&request[0]))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// dropCapabilities removes the capabilities but the kept ones from the bounding set, then switches to the user
+// if any, or else keeps only the kept capabilities of root
+func dropCapabilities(keep []uint, credential *syscall.Credential) error {
+	kept := make(map[uint]bool)
+	for _, capability := range keep {
+		kept[capability] = true
+	}
+	last := lastCapability()
+	for capability := uint(0); capability <= last; capability++ {
+		if kept[capability] {
+			continue
+		}
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(capability), 0, 0, 0); err != nil && err != unix.EINVAL {
+			return fmt.Errorf("failed to drop capability %v: %v", capability, err)
+		}
+	}
+
+	if credential != nil {
+		// the capabilities of root are cleared when switching to another user
+		return switchUser(credential)
+	}
+
+	header := struct {
+		version uint32
+		pid     int32
+	}{version: linuxCapabilityVersion3}
+	var data [2]struct {
+		effective   uint32
+		permitted   uint32
+		inheritable uint32
+	}
+	for capability := range kept {
+		data[capability/32].effective |= 1 << (capability % 32)
+		data[capability/32].permitted |= 1 << (capability % 32)
+	}
+	if _, _, errno := unix.RawSyscall(unix.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return fmt.Errorf("failed to set the capabilities: %v", errno)
+	}
+	return nil
+}
+
+// switchUser sets the groups, gid and uid of the thread, which are the ones of the command it runs
+func switchUser(credential *syscall.Credential) error {
+	groups := credential.Groups
+	var groupsPointer uintptr
+	if len(groups) > 0 {
+		groupsPointer = uintptr(unsafe.Pointer(&groups[0]))
+	}
+	if _, _, errno := unix.RawSyscall(setgroupsSyscall, uintptr(len(groups)), groupsPointer, 0); errno != 0 {
+		return fmt.Errorf("failed to set the groups: %v", errno)
+	}
+	if _, _, errno := unix.RawSyscall(setresgidSyscall, uintptr(credential.Gid), uintptr(credential.Gid), uintptr(credential.Gid)); errno != 0 {
+		return fmt.Errorf("failed to set the gid: %v", errno)
+	}
+	if _, _, errno := unix.RawSyscall(setresuidSyscall, uintptr(credential.Uid), uintptr(credential.Uid), uintptr(credential.Uid)); errno != 0 {
+		return fmt.Errorf("failed to set the uid: %v", errno)
+	}
+	return nil
+}
+
+func lastCapability() uint {
+	if content, err := ioutil.ReadFile(capLastCapFile); err == nil {
+		if last, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 32); err == nil {
+			return uint(last)
+		}
+	}
+	return capabilities["CAP_CHECKPOINT_RESTORE"]
+}
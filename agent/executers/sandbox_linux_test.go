@@ -0,0 +1,152 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestPrepareSandbox(t *testing.T) {
+	command := exec.Command("/bin/sh", "-c", "echo hello")
+	command.Dir = "/var/lib/work"
+	command.Env = []string{"PATH=/usr/bin"}
+	credential := &syscall.Credential{Uid: 1001, Gid: 1001}
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Credential: credential}
+
+	err := prepareSandbox(command, SandboxOptions{
+		Enabled:        true,
+		WritablePaths:  []string{"/tmp"},
+		IsolateNetwork: true,
+		Capabilities:   []string{"cap_net_bind_service"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/proc/self/exe", command.Path)
+	assert.Equal(t, []string{sandboxInitArg}, command.Args)
+	assert.True(t, command.SysProcAttr.Setpgid)
+	assert.Nil(t, command.SysProcAttr.Credential)
+	assert.Equal(t, uintptr(syscall.CLONE_NEWNS|syscall.CLONE_NEWNET), command.SysProcAttr.Cloneflags)
+
+	assert.Len(t, command.Env, 2)
+	assert.True(t, strings.HasPrefix(command.Env[1], envVarSandboxConfig+"="))
+	var config sandboxConfig
+	assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(command.Env[1], envVarSandboxConfig+"=")), &config))
+	assert.Equal(t, "/bin/sh", config.Path)
+	assert.Equal(t, []string{"/bin/sh", "-c", "echo hello"}, config.Args)
+	assert.Empty(t, config.ReadOnlyPaths)
+	assert.Equal(t, []string{"/tmp", "/var/lib/work"}, config.WritablePaths)
+	assert.True(t, config.IsolateNetwork)
+	assert.Equal(t, SeccompProfileDefault, config.SeccompProfile)
+	assert.Equal(t, []uint{10}, config.Capabilities)
+	assert.Equal(t, credential, config.Credential)
+}
+
+func TestPrepareSandboxInvalidOptions(t *testing.T) {
+	command := exec.Command("/bin/sh")
+	assert.Error(t, prepareSandbox(command, SandboxOptions{Enabled: true, Capabilities: []string{"CAP_UNKNOWN"}}))
+	assert.Error(t, prepareSandbox(command, SandboxOptions{Enabled: true, SeccompProfile: "permissive"}))
+	assert.Equal(t, []string{"/bin/sh"}, command.Args)
+}
+
+func TestParseMountInfo(t *testing.T) {
+	mountInfo := `22 1 259:1 / / rw,relatime shared:1 - ext4 /dev/root rw
+23 22 0:21 / /usr rw,nosuid,nodev shared:2 - ext4 /dev/usr rw
+24 23 0:22 / /usr/local/my\040dir rw,noexec shared:3 - tmpfs tmpfs rw
+25 22 0:23 / /usr2 rw shared:4 - tmpfs tmpfs rw
+26 23 0:24 / /usr rw shared:5 - tmpfs tmpfs rw
+`
+	mounts, err := parseMountInfo(strings.NewReader(mountInfo), "/usr")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []mountPoint{
+		{path: "/usr", flags: 0},
+		{path: "/usr/local/my dir", flags: unix.MS_NOEXEC},
+	}, mounts)
+}
+
+func TestSeccompFilter(t *testing.T) {
+	if !seccompSupported {
+		t.Skip("seccomp is not supported on this architecture")
+	}
+	filter := seccompFilter()
+
+	assert.Equal(t, unix.SockFilter{Code: bpfJeqK, Jt: 1, Jf: 0, K: auditArch}, filter[1])
+	assert.Equal(t, unix.SockFilter{Code: bpfRetK, K: seccompRetKill}, filter[2])
+	assert.Equal(t, unix.SockFilter{Code: bpfRetK, K: seccompRetAllow}, filter[len(filter)-1])
+
+	denied := make(map[uint32]bool)
+	for i, instruction := range filter {
+		if instruction.Code == bpfJeqK && i > 1 {
+			denied[instruction.K] = true
+			assert.Equal(t, unix.SockFilter{Code: bpfRetK, K: seccompRetErrno | uint32(unix.EPERM)}, filter[i+1])
+		}
+	}
+	assert.True(t, denied[unix.SYS_MOUNT])
+	assert.True(t, denied[unix.SYS_PTRACE])
+	assert.True(t, denied[unix.SYS_UNSHARE])
+	assert.False(t, denied[unix.SYS_READ])
+}
+
+func TestExecuteInSandbox(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("the sandbox needs root")
+	}
+	workingDir, err := ioutil.TempDir("", "sandbox")
+	assert.NoError(t, err)
+	defer os.RemoveAll(workingDir)
+	readOnlyDir, err := ioutil.TempDir("", "sandbox")
+	assert.NoError(t, err)
+	defer os.RemoveAll(readOnlyDir)
+	var stdout, stderr bytes.Buffer
+	options := ExecuteOptions{Sandbox: SandboxOptions{Enabled: true, IsolateNetwork: true, ReadOnlyPaths: []string{readOnlyDir}}}
+	script := "touch ok && echo created; touch /usr/ssm-sandbox-test /var/ssm-sandbox-test || echo denied; " +
+		"touch /tmp/ssm-sandbox-test && echo private tmp; test -d " + readOnlyDir + " && echo visible; " +
+		"touch " + readOnlyDir + "/test || echo read-only; unshare -m true || echo unshare denied"
+
+	exitCode, err := ShellCommandExecuter{}.ExecuteWithOptions(log.NewMockLog(), workingDir, &stdout, &stderr, task.NewChanneledCancelFlag(), 10, "sh", []string{"-c", script}, options)
+
+	if exitCode == sandboxFailedExitCode {
+		t.Skipf("namespaces are not available: %v", stderr.String())
+	}
+	assert.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "created\ndenied\nprivate tmp\nvisible\nread-only\nunshare denied\n", stdout.String())
+	_, err = os.Stat(workingDir + "/ok")
+	assert.NoError(t, err)
+	for _, path := range []string{"/usr/ssm-sandbox-test", "/var/ssm-sandbox-test", "/tmp/ssm-sandbox-test"} {
+		_, err = os.Stat(path)
+		assert.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestIsUnder(t *testing.T) {
+	assert.True(t, isUnder("/dev/pts", unchangedMountPaths))
+	assert.True(t, isUnder("/proc", unchangedMountPaths))
+	assert.False(t, isUnder("/devices", unchangedMountPaths))
+	assert.True(t, isUnder("/usr", []string{"/"}))
+}
@@ -0,0 +1,32 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import "golang.org/x/sys/unix"
+
+const (
+	// auditArch is AUDIT_ARCH_I386
+	auditArch          = 0x40000003
+	syscallNumberLimit = 0
+
+	// the 32 bit ids versions, the original ones take 16 bit ids
+	setgroupsSyscall = unix.SYS_SETGROUPS32
+	setresgidSyscall = unix.SYS_SETRESGID32
+	setresuidSyscall = unix.SYS_SETRESUID32
+)
+
+// archDeniedSyscalls are denied by the default seccomp profile in addition to commonDeniedSyscalls
+var archDeniedSyscalls = []uint32{unix.SYS_UMOUNT, unix.SYS_IOPL, unix.SYS_IOPERM}
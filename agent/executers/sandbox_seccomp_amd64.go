@@ -0,0 +1,32 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import "golang.org/x/sys/unix"
+
+const (
+	// auditArch is AUDIT_ARCH_X86_64
+	auditArch = 0xC000003E
+	// syscallNumberLimit is __X32_SYSCALL_BIT, the x32 syscalls are denied
+	syscallNumberLimit = 0x40000000
+
+	setgroupsSyscall = unix.SYS_SETGROUPS
+	setresgidSyscall = unix.SYS_SETRESGID
+	setresuidSyscall = unix.SYS_SETRESUID
+)
+
+// archDeniedSyscalls are denied by the default seccomp profile in addition to commonDeniedSyscalls
+var archDeniedSyscalls = []uint32{unix.SYS_KEXEC_FILE_LOAD, unix.SYS_IOPL, unix.SYS_IOPERM}
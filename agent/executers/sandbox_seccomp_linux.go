@@ -0,0 +1,83 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+// +build amd64 386 arm arm64
+
+package executers
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const seccompSupported = true
+
+// seccomp filter, see seccomp(2)
+const (
+	seccompRetKill  = 0x00000000
+	seccompRetErrno = 0x00050000
+	seccompRetAllow = 0x7fff0000
+
+	seccompDataNrOffset   = 0
+	seccompDataArchOffset = 4
+
+	bpfLdWAbs = unix.BPF_LD | unix.BPF_W | unix.BPF_ABS
+	bpfJeqK   = unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K
+	bpfJgeK   = unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K
+	bpfRetK   = unix.BPF_RET | unix.BPF_K
+)
+
+// commonDeniedSyscalls change the system, they are denied by the default seccomp profile on every architecture
+var commonDeniedSyscalls = []uint32{
+	unix.SYS_MOUNT, unix.SYS_UMOUNT2, unix.SYS_PIVOT_ROOT, unix.SYS_SWAPON, unix.SYS_SWAPOFF, unix.SYS_REBOOT,
+	unix.SYS_KEXEC_LOAD, unix.SYS_INIT_MODULE, unix.SYS_FINIT_MODULE, unix.SYS_DELETE_MODULE,
+	unix.SYS_UNSHARE, unix.SYS_SETNS, unix.SYS_KEYCTL, unix.SYS_ADD_KEY, unix.SYS_REQUEST_KEY, unix.SYS_ACCT,
+	unix.SYS_SETTIMEOFDAY, unix.SYS_CLOCK_SETTIME, unix.SYS_ADJTIMEX, unix.SYS_CLOCK_ADJTIME, unix.SYS_QUOTACTL,
+	unix.SYS_OPEN_BY_HANDLE_AT, unix.SYS_NAME_TO_HANDLE_AT, unix.SYS_PTRACE, unix.SYS_PERF_EVENT_OPEN, unix.SYS_BPF,
+	unix.SYS_USERFAULTFD, unix.SYS_SYSLOG, unix.SYS_LOOKUP_DCOOKIE,
+}
+
+// seccompFilter returns the program of the default profile: syscalls of another architecture kill the
+// process, the denied syscalls fail with EPERM and the others are allowed
+func seccompFilter() []unix.SockFilter {
+	denied := append(append([]uint32{}, commonDeniedSyscalls...), archDeniedSyscalls...)
+	filter := []unix.SockFilter{
+		{Code: bpfLdWAbs, K: seccompDataArchOffset},
+		{Code: bpfJeqK, Jt: 1, Jf: 0, K: auditArch},
+		{Code: bpfRetK, K: seccompRetKill},
+		{Code: bpfLdWAbs, K: seccompDataNrOffset},
+	}
+	if syscallNumberLimit > 0 {
+		// e.g. the x32 syscalls of amd64
+		filter = append(filter,
+			unix.SockFilter{Code: bpfJgeK, Jt: 0, Jf: 1, K: syscallNumberLimit},
+			unix.SockFilter{Code: bpfRetK, K: seccompRetErrno | uint32(unix.EPERM)})
+	}
+	for _, nr := range denied {
+		filter = append(filter,
+			unix.SockFilter{Code: bpfJeqK, Jt: 0, Jf: 1, K: nr},
+			unix.SockFilter{Code: bpfRetK, K: seccompRetErrno | uint32(unix.EPERM)})
+	}
+	return append(filter, unix.SockFilter{Code: bpfRetK, K: seccompRetAllow})
+}
+
+func loadSeccompFilter() error {
+	filter := seccompFilter()
+	program := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if err := unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&program)), 0, 0); err != nil {
+		return err
+	}
+	return nil
+}
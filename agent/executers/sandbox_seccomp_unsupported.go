@@ -0,0 +1,36 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+// +build !amd64,!386,!arm,!arm64
+
+package executers
+
+import (
+	"fmt"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	seccompSupported = false
+
+	setgroupsSyscall = unix.SYS_SETGROUPS
+	setresgidSyscall = unix.SYS_SETRESGID
+	setresuidSyscall = unix.SYS_SETRESUID
+)
+
+func loadSeccompFilter() error {
+	return fmt.Errorf("seccomp filters are not supported on %v", runtime.GOARCH)
+}
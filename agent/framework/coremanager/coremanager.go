@@ -15,6 +15,7 @@
 package coremanager
 
 import (
+	"net/http"
 	"path/filepath"
 	"sync"
 	"time"
@@ -50,6 +51,10 @@ type CoreManager struct {
 	coreModules         coremodules.ModuleRegistry
 	cloudwatchPublisher *cloudwatchlogspublisher.CloudWatchPublisher
 	rebooter            rebooter.IRebootType
+	localServers        []*http.Server
+	drainLock           sync.RWMutex
+	drainStatus         *contracts.DrainStatus
+	stopDrainWatch      chan struct{}
 }
 
 // NewCoreManager creates a new core module manager.
@@ -98,7 +103,7 @@ func NewCoreManager(context context.T, mr coremodules.ModuleRegistry, cwp *cloud
 
 	// Initialize the client diagnostics
 	cwp.Init(log)
-	context = context.With("[instanceID=" + instanceId + "]")
+	context = context.With("[instanceID=" + instanceId + "]").WithFields(logger.Fields{logger.FieldInstanceID: instanceId})
 	runpluginutil.SSMPluginRegistry = plugin.RegisteredWorkerPlugins(context)
 
 	return &CoreManager{
@@ -226,13 +231,26 @@ func initializeBookkeepingLocations(log logger.T, instanceID string) bool {
 // Start executes the registered core modules while watching for reboot request
 func (c *CoreManager) Start() {
 	go c.watchForReboot()
+	c.provideAgentHealth()
 	c.executeCoreModules()
+	c.startLocalEndpoints()
+	c.context.ConfigWatcher().Start()
+	removeDrainStatus()
+	c.stopDrainWatch = make(chan struct{})
+	go c.watchForDrain(c.stopDrainWatch)
 }
 
 // Stop requests the core modules to stop executing
 // Stop would be called by the agent and should be treated as hard stop
 func (c *CoreManager) Stop() {
+	c.context.ConfigWatcher().Stop()
+	if c.stopDrainWatch != nil {
+		close(c.stopDrainWatch)
+		c.stopDrainWatch = nil
+	}
+	c.stopLocalEndpoints()
 	c.stopCoreModules(contracts.StopTypeHardStop)
+	c.context.Tracer().Shutdown()
 }
 
 // executeCoreModules launches all the core modules
@@ -0,0 +1,204 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package coremanager
+
+import (
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	logger "github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+var (
+	// drainRoot is where ssm-cli requests a drain and reads its progress
+	drainRoot = appconfig.DrainRoot
+
+	// drainRequestPollInterval is how often the agent looks for a drain request of ssm-cli
+	drainRequestPollInterval = 5 * time.Second
+
+	// drainProgressInterval is how often the work in flight is checked during a drain
+	drainProgressInterval = time.Second
+)
+
+// Drain stops the core modules from taking new work, then reports the progress of the work in flight
+// until it completes or the timeout elapses. The agent keeps running once drained, a drain can't be undone
+// and requesting another one while draining has no effect.
+func (c *CoreManager) Drain(timeout time.Duration) {
+	log := c.context.Log()
+
+	c.drainLock.Lock()
+	if c.drainStatus != nil {
+		c.drainLock.Unlock()
+		log.Infof("Drain already requested at %v", c.drainStatus.StartedAt)
+		return
+	}
+	now := time.Now().UTC()
+	c.drainStatus = &contracts.DrainStatus{
+		State:     contracts.DrainStateDraining,
+		StartedAt: now,
+		Deadline:  now.Add(timeout),
+		UpdatedAt: now,
+	}
+	c.drainLock.Unlock()
+
+	log.Infof("Draining the agent, waiting up to %v for the documents and sessions in flight", timeout)
+	for _, module := range c.coreModules {
+		if drainer, ok := module.(contracts.IModuleDrain); ok {
+			drainer.ModuleDrain()
+		}
+	}
+	go c.reportDrainProgress()
+}
+
+// DrainStatus returns the progress of the drain, or nil if the agent isn't draining
+func (c *CoreManager) DrainStatus() *contracts.DrainStatus {
+	c.drainLock.RLock()
+	defer c.drainLock.RUnlock()
+	if c.drainStatus == nil {
+		return nil
+	}
+	status := *c.drainStatus
+	return &status
+}
+
+// removeDrainStatus removes the status left by a previous run of the agent, it no longer applies
+func removeDrainStatus() {
+	os.Remove(filepath.Join(drainRoot, appconfig.DrainStatusFileName))
+}
+
+// watchForDrain starts a drain when the agent receives the drain signal or ssm-cli requests it
+func (c *CoreManager) watchForDrain(stop chan struct{}) {
+	log := c.context.Log()
+
+	signals := make(chan os.Signal, 1)
+	notifyDrain(signals)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(drainRequestPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-signals:
+			log.Info("Received the drain signal")
+			c.Drain(c.drainTimeout(contracts.DrainRequest{}))
+		case <-ticker.C:
+			if request, found := readDrainRequest(log); found {
+				log.Info("Received a drain request")
+				c.Drain(c.drainTimeout(request))
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// reportDrainProgress logs and writes the progress of the drain to the status file until it ends
+func (c *CoreManager) reportDrainProgress() {
+	log := c.context.Log()
+	ticker := time.NewTicker(drainProgressInterval)
+	defer ticker.Stop()
+
+	inFlight := -1
+	for {
+		status := c.updateDrainStatus(time.Now().UTC())
+		writeDrainStatus(log, status)
+		switch status.State {
+		case contracts.DrainStateDrained:
+			log.Info("Drained, no document nor session in flight")
+			return
+		case contracts.DrainStateTimedOut:
+			log.Warnf("Drain timed out with %v documents and sessions in flight", status.InFlight)
+			return
+		}
+		if status.InFlight != inFlight {
+			inFlight = status.InFlight
+			log.Infof("Draining, %v documents and sessions in flight, %v left", inFlight, status.Deadline.Sub(status.UpdatedAt).Round(time.Second))
+		}
+		<-ticker.C
+	}
+}
+
+// updateDrainStatus counts the work in flight in the core modules
+func (c *CoreManager) updateDrainStatus(now time.Time) contracts.DrainStatus {
+	modules := []contracts.ModuleDrainStatus{}
+	total := 0
+	for _, module := range c.coreModules {
+		if drainer, ok := module.(contracts.IModuleDrain); ok {
+			inFlight := drainer.ModuleInFlight()
+			modules = append(modules, contracts.ModuleDrainStatus{Name: module.ModuleName(), InFlight: inFlight})
+			total += inFlight
+		}
+	}
+
+	c.drainLock.Lock()
+	defer c.drainLock.Unlock()
+	status := *c.drainStatus
+	status.UpdatedAt = now
+	status.InFlight = total
+	status.Modules = modules
+	if total == 0 {
+		status.State = contracts.DrainStateDrained
+	} else if !now.Before(status.Deadline) {
+		status.State = contracts.DrainStateTimedOut
+	}
+	c.drainStatus = &status
+	return status
+}
+
+// drainTimeout returns the completion window of the request, or the configured one
+func (c *CoreManager) drainTimeout(request contracts.DrainRequest) time.Duration {
+	seconds := c.context.AppConfig().Agent.DrainTimeoutSeconds
+	if request.TimeoutSeconds > 0 {
+		seconds = request.TimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// readDrainRequest reads and removes the drain request file of ssm-cli
+func readDrainRequest(log logger.T) (request contracts.DrainRequest, found bool) {
+	path := filepath.Join(drainRoot, appconfig.DrainRequestFileName)
+	if !fileutil.Exists(path) {
+		return request, false
+	}
+	defer os.Remove(path)
+	if err := jsonutil.UnmarshalFile(path, &request); err != nil {
+		log.Errorf("drain request %v is invalid, draining with the default timeout, %v", path, err)
+		request = contracts.DrainRequest{}
+	}
+	return request, true
+}
+
+// writeDrainStatus replaces the status file read by ssm-cli
+func writeDrainStatus(log logger.T, status contracts.DrainStatus) {
+	content, err := jsonutil.Marshal(status)
+	if err == nil {
+		err = fileutil.MakeDirs(drainRoot)
+	}
+	if err == nil {
+		path := filepath.Join(drainRoot, appconfig.DrainStatusFileName)
+		if err = ioutil.WriteFile(path+".tmp", []byte(content), 0600); err == nil {
+			err = os.Rename(path+".tmp", path)
+		}
+	}
+	if err != nil {
+		log.Debugf("failed to write the drain status, %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package coremanager
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	moduleMock "github.com/aws/amazon-ssm-agent/agent/contracts/mocks"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// drainModule is a core module with work in flight
+type drainModule struct {
+	moduleMock.ICoreModule
+	lock     sync.Mutex
+	drained  bool
+	inFlight int
+}
+
+func (m *drainModule) ModuleDrain() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.drained = true
+}
+
+func (m *drainModule) ModuleInFlight() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.inFlight
+}
+
+func (m *drainModule) complete() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.inFlight--
+}
+
+func newDrainModule(name string, inFlight int) *drainModule {
+	module := &drainModule{inFlight: inFlight}
+	module.On("ModuleName").Return(name)
+	return module
+}
+
+// setDrainRoot points the drain files to a new temporary directory, which it returns
+func setDrainRoot(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "drain")
+	assert.NoError(t, err)
+	drainRoot = dir
+	drainProgressInterval = 10 * time.Millisecond
+	return dir
+}
+
+func waitForDrainState(t *testing.T, cm *CoreManager, state contracts.DrainState) contracts.DrainStatus {
+	for i := 0; i < 500; i++ {
+		if status := cm.DrainStatus(); status != nil && status.State == state {
+			return *status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Fail(t, "drain didn't reach the state", "expected %v", state)
+	return contracts.DrainStatus{}
+}
+
+func waitForDrainStatusFile(t *testing.T, state contracts.DrainState) {
+	var status contracts.DrainStatus
+	for i := 0; i < 500; i++ {
+		if jsonutil.UnmarshalFile(filepath.Join(drainRoot, appconfig.DrainStatusFileName), &status) == nil && status.State == state {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Fail(t, "drain status file didn't reach the state", "expected %v", state)
+}
+
+func TestDrainCompletes(t *testing.T) {
+	defer os.RemoveAll(setDrainRoot(t))
+	commands := newDrainModule("MessageDeliveryService", 2)
+	sessions := newDrainModule("MessageGatewayService", 0)
+	cm := newHealthCoreManager(commands, sessions, new(moduleMock.ICoreModule))
+
+	cm.Drain(time.Minute)
+
+	assert.True(t, commands.drained)
+	assert.True(t, sessions.drained)
+	status := waitForDrainState(t, cm, contracts.DrainStateDraining)
+	assert.Equal(t, contracts.DrainStateDraining, cm.Health().Drain.State)
+
+	commands.complete()
+	commands.complete()
+	status = waitForDrainState(t, cm, contracts.DrainStateDrained)
+	assert.Equal(t, 0, status.InFlight)
+	assert.Equal(t, []contracts.ModuleDrainStatus{
+		{Name: "MessageDeliveryService", InFlight: 0},
+		{Name: "MessageGatewayService", InFlight: 0},
+	}, status.Modules)
+
+	// the status file is written before the progress reporting ends
+	waitForDrainStatusFile(t, contracts.DrainStateDrained)
+}
+
+func TestDrainTimesOut(t *testing.T) {
+	defer os.RemoveAll(setDrainRoot(t))
+	commands := newDrainModule("MessageDeliveryService", 1)
+	cm := newHealthCoreManager(commands)
+
+	cm.Drain(50 * time.Millisecond)
+
+	status := waitForDrainState(t, cm, contracts.DrainStateTimedOut)
+	assert.Equal(t, 1, status.InFlight)
+}
+
+func TestDrainTwice(t *testing.T) {
+	defer os.RemoveAll(setDrainRoot(t))
+	commands := newDrainModule("MessageDeliveryService", 1)
+	cm := newHealthCoreManager(commands)
+
+	cm.Drain(time.Minute)
+	deadline := cm.DrainStatus().Deadline
+	cm.Drain(time.Hour)
+
+	assert.Equal(t, deadline, cm.DrainStatus().Deadline)
+	commands.complete()
+	waitForDrainState(t, cm, contracts.DrainStateDrained)
+}
+
+func TestReadDrainRequest(t *testing.T) {
+	defer os.RemoveAll(setDrainRoot(t))
+	path := filepath.Join(drainRoot, appconfig.DrainRequestFileName)
+
+	_, found := readDrainRequest(log.NewMockLog())
+	assert.False(t, found)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"TimeoutSeconds": 30}`), 0600))
+	request, found := readDrainRequest(log.NewMockLog())
+	assert.True(t, found)
+	assert.Equal(t, 30, request.TimeoutSeconds)
+
+	// the request is consumed
+	_, found = readDrainRequest(log.NewMockLog())
+	assert.False(t, found)
+}
+
+func TestDrainTimeout(t *testing.T) {
+	cm := newHealthCoreManager()
+
+	configured := time.Duration(cm.context.AppConfig().Agent.DrainTimeoutSeconds) * time.Second
+	assert.Equal(t, configured, cm.drainTimeout(contracts.DrainRequest{}))
+	assert.Equal(t, 30*time.Second, cm.drainTimeout(contracts.DrainRequest{TimeoutSeconds: 30}))
+}
+
+func TestWriteHealthReadinessWhileDraining(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writeHealth(recorder, contracts.AgentHealth{
+		Status: contracts.AgentStatusActive,
+		Drain:  &contracts.DrainStatus{State: contracts.DrainStateDrained},
+	}, true)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
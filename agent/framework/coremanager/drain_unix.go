@@ -0,0 +1,28 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+
+// +build darwin freebsd linux netbsd openbsd
+
+package coremanager
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyDrain relays SIGUSR1, which requests a drain of the agent
+func notifyDrain(signals chan os.Signal) {
+	signal.Notify(signals, syscall.SIGUSR1)
+}
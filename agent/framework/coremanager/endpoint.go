@@ -0,0 +1,176 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package coremanager encapsulates the logic for configuring, starting and stopping core modules
+package coremanager
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	logger "github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
+)
+
+const (
+	// unixSocketPrefix marks a local endpoint served over a Unix socket
+	unixSocketPrefix = "unix:"
+
+	metricsPath = "/metrics"
+)
+
+// startLocalEndpoints serves the health and the metrics of the agent on the configured local endpoints.
+// Endpoints configured with the same address are served by the same server.
+func (c *CoreManager) startLocalEndpoints() {
+	log := c.context.Log()
+	config := c.context.AppConfig()
+
+	routes := make(map[string]*http.ServeMux)
+	route := func(address string) *http.ServeMux {
+		if _, found := routes[address]; !found {
+			routes[address] = http.NewServeMux()
+		}
+		return routes[address]
+	}
+	if address := config.Agent.HealthEndpoint; address != "" {
+		mux := route(address)
+		mux.HandleFunc(healthPath, func(w http.ResponseWriter, r *http.Request) {
+			writeHealth(w, c.Health(), false)
+		})
+		mux.HandleFunc(readinessPath, func(w http.ResponseWriter, r *http.Request) {
+			writeHealth(w, c.Health(), true)
+		})
+	}
+	if address := config.Agent.MetricsEndpoint; address != "" {
+		route(address).HandleFunc(metricsPath, func(w http.ResponseWriter, r *http.Request) {
+			writeMetrics(w, c.context.Metrics())
+		})
+	}
+
+	c.localServers = serveLocalEndpoints(log, routes)
+}
+
+// stopLocalEndpoints stops serving the health and the metrics of the agent
+func (c *CoreManager) stopLocalEndpoints() {
+	closeLocalEndpoints(c.context.Log(), c.localServers)
+	c.localServers = nil
+}
+
+// StartHibernationEndpoint serves the health of the agent on the configured health endpoint while it hibernates,
+// until the returned function is called. The core manager doesn't exist yet at that point.
+func StartHibernationEndpoint(context context.T, hibernation func() *contracts.HibernationStatus) (stop func()) {
+	log := context.Log()
+	address := context.AppConfig().Agent.HealthEndpoint
+	if address == "" {
+		return func() {}
+	}
+
+	health := func() contracts.AgentHealth {
+		return contracts.AgentHealth{
+			Status:      contracts.AgentStatusHibernating,
+			CheckedAt:   time.Now().UTC(),
+			Modules:     []contracts.ModuleHealth{},
+			Hibernation: hibernation(),
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(healthPath, func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, health(), false)
+	})
+	mux.HandleFunc(readinessPath, func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, health(), true)
+	})
+	servers := serveLocalEndpoints(log, map[string]*http.ServeMux{address: mux})
+	return func() {
+		closeLocalEndpoints(log, servers)
+	}
+}
+
+// serveLocalEndpoints serves each route on its address, in the order of the addresses
+func serveLocalEndpoints(log logger.T, routes map[string]*http.ServeMux) (servers []*http.Server) {
+	addresses := make([]string, 0, len(routes))
+	for address := range routes {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	for _, address := range addresses {
+		listener, err := listenLocalEndpoint(address)
+		if err != nil {
+			log.Errorf("failed to start the local endpoint on %v, %v", address, err)
+			continue
+		}
+		server := &http.Server{Handler: routes[address]}
+		servers = append(servers, server)
+
+		log.Infof("Serving agent health and metrics on %v", address)
+		go func(server *http.Server, address string) {
+			if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+				log.Errorf("local endpoint %v stopped, %v", address, err)
+			}
+		}(server, address)
+	}
+	return servers
+}
+
+// closeLocalEndpoints stops the servers of the local endpoints
+func closeLocalEndpoints(log logger.T, servers []*http.Server) {
+	for _, server := range servers {
+		if err := server.Close(); err != nil {
+			log.Debugf("failed to close the local endpoint, %v", err)
+		}
+	}
+}
+
+// listenLocalEndpoint listens on a Unix socket or a loopback address.
+// The health and the metrics of the agent must not be exposed beyond the instance.
+func listenLocalEndpoint(address string) (net.Listener, error) {
+	if strings.HasPrefix(address, unixSocketPrefix) {
+		socketPath := strings.TrimPrefix(address, unixSocketPrefix)
+		// remove the socket left behind by a previous agent process
+		os.Remove(socketPath)
+		listener, err := net.Listen("unix", socketPath)
+		if err != nil {
+			return nil, err
+		}
+		if err = os.Chmod(socketPath, 0600); err != nil {
+			listener.Close()
+			return nil, err
+		}
+		return listener, nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("%v is not a loopback address", host)
+	}
+	return net.Listen("tcp", address)
+}
+
+// writeMetrics writes the metrics of the agent in the OpenMetrics text format
+func writeMetrics(w http.ResponseWriter, registry *metrics.Registry) {
+	w.Header().Set("Content-Type", metrics.OpenMetricsContentType)
+	if err := registry.WriteOpenMetrics(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package coremanager
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newEndpointContext(healthEndpoint string, metricsEndpoint string) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.SsmagentConfig{}
+	config.Agent.HealthEndpoint = healthEndpoint
+	config.Agent.MetricsEndpoint = metricsEndpoint
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	ctx.On("Metrics").Return(metrics.NewRegistry())
+	return ctx
+}
+
+func TestListenLocalEndpointRefusesRemoteAddress(t *testing.T) {
+	_, err := listenLocalEndpoint("0.0.0.0:8080")
+	assert.Error(t, err)
+}
+
+func TestListenLocalEndpoint(t *testing.T) {
+	listener, err := listenLocalEndpoint("127.0.0.1:0")
+	assert.NoError(t, err)
+	listener.Close()
+
+	dir, err := ioutil.TempDir("", "endpoint")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	listener, err = listenLocalEndpoint(unixSocketPrefix + filepath.Join(dir, "health.sock"))
+	assert.NoError(t, err)
+	listener.Close()
+}
+
+func TestStartLocalEndpointsSharesAddress(t *testing.T) {
+	cm := &CoreManager{context: newEndpointContext("127.0.0.1:0", "127.0.0.1:0")}
+
+	cm.startLocalEndpoints()
+	defer cm.stopLocalEndpoints()
+
+	assert.Len(t, cm.localServers, 1)
+}
+
+func TestStartLocalEndpointsDisabled(t *testing.T) {
+	cm := &CoreManager{context: newEndpointContext("", "")}
+
+	cm.startLocalEndpoints()
+
+	assert.Empty(t, cm.localServers)
+}
+
+func TestStartHibernationEndpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "endpoint")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "health.sock")
+	hibernation := &contracts.HibernationStatus{Reason: contracts.HibernationReasonNoCredentials}
+
+	stop := StartHibernationEndpoint(newEndpointContext(unixSocketPrefix+socketPath, ""), func() *contracts.HibernationStatus {
+		return hibernation
+	})
+	defer stop()
+
+	client := http.Client{Transport: &http.Transport{
+		DialContext: func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", socketPath)
+		},
+	}}
+	response, err := client.Get("http://localhost" + readinessPath)
+	assert.NoError(t, err)
+	defer response.Body.Close()
+
+	var health contracts.AgentHealth
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&health))
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.Equal(t, contracts.AgentStatusHibernating, health.Status)
+	assert.Equal(t, contracts.HibernationReasonNoCredentials, health.Hibernation.Reason)
+}
+
+func TestStartHibernationEndpointDisabled(t *testing.T) {
+	stop := StartHibernationEndpoint(newEndpointContext("", ""), func() *contracts.HibernationStatus {
+		return nil
+	})
+	stop()
+}
+
+func TestWriteMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.Gauge(metrics.Sessions, "Sessions currently open.", nil).Set(2)
+	recorder := httptest.NewRecorder()
+
+	writeMetrics(recorder, registry)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, metrics.OpenMetricsContentType, recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "ssm_agent_sessions 2\n")
+	assert.Contains(t, recorder.Body.String(), "# EOF\n")
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package coremanager encapsulates the logic for configuring, starting and stopping core modules
+package coremanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+const (
+	healthPath    = "/health"
+	readinessPath = "/ready"
+)
+
+// Health aggregates the health reported by the core modules.
+// The agent is Inactive when every reporting module is failing and Degraded when some of them are.
+func (c *CoreManager) Health() contracts.AgentHealth {
+	health := contracts.AgentHealth{
+		Status:    contracts.AgentStatusActive,
+		CheckedAt: time.Now().UTC(),
+		Modules:   []contracts.ModuleHealth{},
+	}
+
+	failing := 0
+	for _, module := range c.coreModules {
+		reporter, ok := module.(contracts.IModuleHealth)
+		if !ok {
+			continue
+		}
+		moduleHealth := reporter.ModuleHealth()
+		if moduleHealth.Name == "" {
+			moduleHealth.Name = module.ModuleName()
+		}
+		if moduleHealth.IsFailing() {
+			failing++
+		}
+		health.Modules = append(health.Modules, moduleHealth)
+	}
+
+	health.Drain = c.DrainStatus()
+
+	if failing > 0 && failing == len(health.Modules) {
+		health.Status = contracts.AgentStatusInactive
+	} else if failing > 0 {
+		health.Status = contracts.AgentStatusDegraded
+	}
+	return health
+}
+
+// provideAgentHealth gives the aggregated health to the core modules which report it
+func (c *CoreManager) provideAgentHealth() {
+	for _, module := range c.coreModules {
+		if consumer, ok := module.(contracts.IAgentHealthAware); ok {
+			consumer.SetAgentHealthProvider(c.Health)
+		}
+	}
+}
+
+// writeHealth writes the health as JSON, a readiness check fails while the agent is Inactive or Hibernating
+func writeHealth(w http.ResponseWriter, health contracts.AgentHealth, readiness bool) {
+	content, err := json.Marshal(health)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	// a draining agent doesn't take new work
+	if readiness && (health.Status == contracts.AgentStatusInactive || health.Status == contracts.AgentStatusHibernating || health.Drain != nil) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(content)
+}
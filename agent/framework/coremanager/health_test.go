@@ -0,0 +1,111 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package coremanager
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	moduleMock "github.com/aws/amazon-ssm-agent/agent/contracts/mocks"
+	"github.com/aws/amazon-ssm-agent/agent/framework/coremodules"
+	"github.com/stretchr/testify/assert"
+)
+
+// healthModule is a core module reporting the health of its tracker
+type healthModule struct {
+	moduleMock.ICoreModule
+	tracker  *contracts.HealthTracker
+	provider func() contracts.AgentHealth
+}
+
+func (m *healthModule) ModuleHealth() contracts.ModuleHealth {
+	return m.tracker.Health()
+}
+
+func (m *healthModule) SetAgentHealthProvider(provider func() contracts.AgentHealth) {
+	m.provider = provider
+}
+
+func newHealthModule(name string, err error) *healthModule {
+	module := &healthModule{tracker: contracts.NewHealthTracker(name)}
+	module.tracker.RecordSuccess()
+	module.tracker.RecordError(err)
+	return module
+}
+
+func newHealthCoreManager(modules ...contracts.ICoreModule) *CoreManager {
+	return &CoreManager{
+		context:     context.NewMockDefault(),
+		coreModules: coremodules.ModuleRegistry(modules),
+	}
+}
+
+func TestHealthActive(t *testing.T) {
+	cm := newHealthCoreManager(newHealthModule("MessageDeliveryService", nil), newHealthModule("MessageGatewayService", nil), new(moduleMock.ICoreModule))
+
+	health := cm.Health()
+
+	assert.Equal(t, contracts.AgentStatusActive, health.Status)
+	assert.Len(t, health.Modules, 2)
+}
+
+func TestHealthDegraded(t *testing.T) {
+	cm := newHealthCoreManager(newHealthModule("MessageDeliveryService", errors.New("throttled")), newHealthModule("MessageGatewayService", nil))
+
+	health := cm.Health()
+
+	assert.Equal(t, contracts.AgentStatusDegraded, health.Status)
+	assert.Equal(t, "throttled", health.Modules[0].LastError)
+}
+
+func TestHealthInactive(t *testing.T) {
+	cm := newHealthCoreManager(newHealthModule("MessageDeliveryService", errors.New("throttled")), newHealthModule("MessageGatewayService", errors.New("closed")))
+
+	assert.Equal(t, contracts.AgentStatusInactive, cm.Health().Status)
+}
+
+func TestHealthRecoveredModuleIsActive(t *testing.T) {
+	module := newHealthModule("MessageDeliveryService", errors.New("throttled"))
+	module.tracker.RecordSuccess()
+
+	assert.Equal(t, contracts.AgentStatusActive, newHealthCoreManager(module).Health().Status)
+}
+
+func TestProvideAgentHealth(t *testing.T) {
+	module := newHealthModule("HealthCheck", nil)
+	cm := newHealthCoreManager(module)
+
+	cm.provideAgentHealth()
+
+	assert.NotNil(t, module.provider)
+	assert.Equal(t, contracts.AgentStatusActive, module.provider().Status)
+}
+
+func TestWriteHealthReadiness(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writeHealth(recorder, contracts.AgentHealth{Status: contracts.AgentStatusInactive}, true)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	writeHealth(recorder, contracts.AgentHealth{Status: contracts.AgentStatusDegraded}, true)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var health contracts.AgentHealth
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &health))
+	assert.Equal(t, contracts.AgentStatusDegraded, health.Status)
+}
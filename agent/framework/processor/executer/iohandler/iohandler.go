@@ -18,8 +18,13 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule"
@@ -78,10 +83,20 @@ type IOHandler interface {
 	AppendError(message string)
 	AppendErrorf(format string, params ...interface{})
 
+	// RegisterSecrets adds secure values to redact from the output of all the plugins of the document
+	RegisterSecrets(secrets ...string)
+
+	// SetCheckpoint persists a progress marker of the plugin, which is handed back to the plugin when it runs again
+	SetCheckpoint(checkpoint string)
+	// GetCheckpoint returns the last progress marker of the plugin, including the one of its previous run
+	GetCheckpoint() string
+
 	// getters/setters
 	GetStatus() contracts.ResultStatus
 	GetStdout() string
 	GetStderr() string
+	GetStdoutSummary() contracts.OutputSummary
+	GetStderrSummary() contracts.OutputSummary
 	GetExitCode() int
 	GetStdoutWriter() multiwriter.DocumentIOMultiWriter
 	GetStderrWriter() multiwriter.DocumentIOMultiWriter
@@ -99,9 +114,12 @@ type DefaultIOHandler struct {
 	ExitCode int
 	Status   contracts.ResultStatus
 	//private members - not exposed directly to plugins because they shouldn't write to these
-	stdout   string
-	stderr   string
-	ioConfig contracts.IOConfiguration
+	stdout string
+	stderr string
+	// summaries of the whole output, of which stdout and stderr only keep the beginning and the end
+	stdoutSummary contracts.OutputSummary
+	stderrSummary contracts.OutputSummary
+	ioConfig      contracts.IOConfiguration
 	//refreshassociation and invoker write a different output rather than merging stdout and stderr
 	output interface{}
 
@@ -115,6 +133,12 @@ func NewDefaultIOHandler(log log.T, ioConfig contracts.IOConfiguration) *Default
 	log.Debugf("IOHandler Initialization with config: %v", ioConfig)
 	out := new(DefaultIOHandler)
 	out.ioConfig = ioConfig
+	if out.ioConfig.Secrets == nil {
+		out.ioConfig.Secrets = contracts.NewDocumentSecrets()
+	}
+	if out.ioConfig.Checkpointer == nil {
+		out.ioConfig.Checkpointer = contracts.NewCheckpointer("", "", nil)
+	}
 
 	return out
 }
@@ -159,14 +183,21 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 	// Initialize console output module
 	stdoutConsole := iomodule.CommandOutput{
 		OutputString:           &out.stdout,
+		Summary:                &out.stdoutSummary,
 		FileName:               pluginConfig.StdoutConsoleFileName,
 		OrchestrationDirectory: fullPath,
+		MaxLength:              pluginConfig.MaxStdoutLength,
+		TruncatedMarker:        "\n" + pluginConfig.OutputTruncatedSuffix + "\n",
 	}
 
 	log.Debug("Initializing the Stdout Multi-writer with file and console listeners")
 	// Get a multi-writer for standard output
-	out.StdoutWriter = multiwriter.NewDocumentIOMultiWriter()
-	out.RegisterOutputSource(log, out.StdoutWriter, stdoutFile, stdoutConsole)
+	patterns := redactionPatterns(log)
+	out.StdoutWriter = multiwriter.NewRedactingDocumentIOMultiWriter(out.ioConfig.Secrets.Values, patterns)
+	sinks := outputSinks(log, out.ioConfig.OutputSinks)
+	source := fileutil.BuildS3Path(filepath.Base(out.ioConfig.OrchestrationDirectory), filePath...)
+	stdoutModules := append([]iomodule.IOModule{stdoutFile, stdoutConsole}, outputSinkModules(log, sinks, source, pluginConfig.StdoutFileName)...)
+	out.RegisterOutputSource(log, out.StdoutWriter, stdoutModules...)
 
 	// Initialize file error module
 	stderrFile := iomodule.File{
@@ -181,14 +212,187 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 	// Initialize console error module
 	stderrConsole := iomodule.CommandOutput{
 		OutputString:           &out.stderr,
+		Summary:                &out.stderrSummary,
 		FileName:               pluginConfig.StderrConsoleFileName,
 		OrchestrationDirectory: fullPath,
+		MaxLength:              pluginConfig.MaxStderrLength,
+		TruncatedMarker:        "\n" + pluginConfig.OutputTruncatedSuffix + "\n",
 	}
 
 	log.Debug("Initializing the Stderr Multi-writer with file and console listeners")
 	// Get a multi-writer for standard error
-	out.StderrWriter = multiwriter.NewDocumentIOMultiWriter()
-	out.RegisterOutputSource(log, out.StderrWriter, stderrFile, stderrConsole)
+	out.StderrWriter = multiwriter.NewRedactingDocumentIOMultiWriter(out.ioConfig.Secrets.Values, patterns)
+	stderrModules := append([]iomodule.IOModule{stderrFile, stderrConsole}, outputSinkModules(log, sinks, source, pluginConfig.StderrFileName)...)
+	out.RegisterOutputSource(log, out.StderrWriter, stderrModules...)
+}
+
+// RegisterSecrets adds secure values to redact from the output of all the plugins of the document
+func (out *DefaultIOHandler) RegisterSecrets(secrets ...string) {
+	if out.ioConfig.Secrets == nil {
+		out.ioConfig.Secrets = contracts.NewDocumentSecrets()
+	}
+	out.ioConfig.Secrets.Add(secrets...)
+}
+
+// SetCheckpoint persists a progress marker of the plugin, which is handed back to the plugin when it runs again
+func (out *DefaultIOHandler) SetCheckpoint(checkpoint string) {
+	if out.ioConfig.Checkpointer == nil {
+		out.ioConfig.Checkpointer = contracts.NewCheckpointer("", "", nil)
+	}
+	out.ioConfig.Checkpointer.Set(checkpoint)
+}
+
+// GetCheckpoint returns the last progress marker of the plugin, including the one of its previous run
+func (out DefaultIOHandler) GetCheckpoint() string {
+	return out.ioConfig.Checkpointer.Get()
+}
+
+// redactionPatterns returns the output redaction patterns of the agent configuration
+var redactionPatterns = func(log log.T) (patterns []*regexp.Regexp) {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		return nil
+	}
+	for _, expression := range config.Ssm.OutputRedactionPatterns {
+		pattern, err := regexp.Compile(expression)
+		if err != nil {
+			log.Warnf("ignoring invalid output redaction pattern %v: %v", expression, err)
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+// agentOutputConfig returns the output settings of the agent configuration
+var agentOutputConfig = func() appconfig.OutputCfg {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		return appconfig.OutputCfg{}
+	}
+	return config.Output
+}
+
+// agentOutputSinks returns the output sinks of the agent configuration
+func agentOutputSinks(output appconfig.OutputCfg) (sinks []contracts.OutputSink) {
+	if output.SyslogEnabled {
+		sinks = append(sinks, contracts.OutputSink{
+			Type:     contracts.OutputSinkSyslog,
+			Network:  output.SyslogNetwork,
+			Address:  output.SyslogAddress,
+			Facility: output.SyslogFacility,
+		})
+	}
+	if output.WebhookURL != "" {
+		sinks = append(sinks, contracts.OutputSink{
+			Type:                 contracts.OutputSinkWebhook,
+			URL:                  output.WebhookURL,
+			BatchSize:            output.WebhookBatchSize,
+			BatchIntervalSeconds: output.WebhookBatchIntervalSeconds,
+			MaxRetries:           output.WebhookMaxRetries,
+			CertificateFile:      output.WebhookCertificateFile,
+			PrivateKeyFile:       output.WebhookPrivateKeyFile,
+			CACertificateFile:    output.WebhookCACertificateFile,
+		})
+	}
+	if output.ArchiveDirectory != "" {
+		sinks = append(sinks, contracts.OutputSink{
+			Type:      contracts.OutputSinkArchive,
+			Directory: output.ArchiveDirectory,
+			MaxSizeMB: output.ArchiveMaxSizeMB,
+			MaxFiles:  output.ArchiveMaxFiles,
+		})
+	}
+	return sinks
+}
+
+// documentOutputSink applies the agent configuration to a sink of a document: the webhooks get the TLS files of the
+// agent configuration and the archives are kept under the archive root
+func documentOutputSink(sink contracts.OutputSink, output appconfig.OutputCfg) (contracts.OutputSink, error) {
+	switch sink.Type {
+	case contracts.OutputSinkWebhook:
+		sink.CertificateFile = output.WebhookCertificateFile
+		sink.PrivateKeyFile = output.WebhookPrivateKeyFile
+		sink.CACertificateFile = output.WebhookCACertificateFile
+	case contracts.OutputSinkArchive:
+		if output.ArchiveRoot == "" {
+			return sink, fmt.Errorf("the archives of documents need Output.ArchiveRoot in the agent configuration")
+		}
+		root := filepath.Clean(output.ArchiveRoot)
+		directory := filepath.Join(root, sink.Directory)
+		if relative, err := filepath.Rel(root, directory); err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return sink, fmt.Errorf("the archive directory %v is outside of %v", sink.Directory, root)
+		}
+		sink.Directory = directory
+	}
+	return sink, nil
+}
+
+// outputSinks returns the sinks of the document and the ones of the agent configuration of the other types
+func outputSinks(log log.T, documentSinks []contracts.OutputSink) []contracts.OutputSink {
+	output := agentOutputConfig()
+	sinks := make([]contracts.OutputSink, 0, len(documentSinks))
+	for _, documentSink := range documentSinks {
+		sink, err := documentOutputSink(documentSink, output)
+		if err != nil {
+			log.Warnf("ignoring the output sink of type %v: %v", documentSink.Type, err)
+			continue
+		}
+		sinks = append(sinks, sink)
+	}
+	for _, sink := range agentOutputSinks(output) {
+		replaced := false
+		for _, documentSink := range documentSinks {
+			replaced = replaced || documentSink.Type == sink.Type
+		}
+		if !replaced {
+			sinks = append(sinks, sink)
+		}
+	}
+	return sinks
+}
+
+// outputSinkModules returns the IO modules writing a stream of output to the sinks
+func outputSinkModules(log log.T, sinks []contracts.OutputSink, source string, streamName string) (modules []iomodule.IOModule) {
+	for _, sink := range sinks {
+		switch sink.Type {
+		case contracts.OutputSinkSyslog:
+			facility := sink.Facility
+			if facility <= 0 {
+				facility = appconfig.DefaultSyslogFacility
+			}
+			modules = append(modules, iomodule.Syslog{
+				Network:    sink.Network,
+				Address:    sink.Address,
+				Facility:   facility,
+				Source:     source,
+				StreamName: streamName,
+			})
+		case contracts.OutputSinkWebhook:
+			modules = append(modules, iomodule.Webhook{
+				URL:               sink.URL,
+				BatchSize:         sink.BatchSize,
+				BatchInterval:     time.Duration(sink.BatchIntervalSeconds) * time.Second,
+				MaxRetries:        sink.MaxRetries,
+				CertificateFile:   sink.CertificateFile,
+				PrivateKeyFile:    sink.PrivateKeyFile,
+				CACertificateFile: sink.CACertificateFile,
+				Source:            source,
+				StreamName:        streamName,
+			})
+		case contracts.OutputSinkArchive:
+			modules = append(modules, iomodule.Archive{
+				Directory:  sink.Directory,
+				MaxSizeMB:  sink.MaxSizeMB,
+				MaxFiles:   sink.MaxFiles,
+				Source:     source,
+				StreamName: streamName,
+			})
+		default:
+			log.Warnf("ignoring the output sink of unknown type %v", sink.Type)
+		}
+	}
+	return modules
 }
 
 // RegisterOutputSource returns a new output source by creating a multiwriter for the output modules.
@@ -259,6 +463,16 @@ func (out DefaultIOHandler) GetStderr() string {
 	return out.stderr
 }
 
+// GetStdoutSummary returns the summary of the whole stdout
+func (out DefaultIOHandler) GetStdoutSummary() contracts.OutputSummary {
+	return out.stdoutSummary
+}
+
+// GetStderrSummary returns the summary of the whole stderr
+func (out DefaultIOHandler) GetStderrSummary() contracts.OutputSummary {
+	return out.stderrSummary
+}
+
 // GetIOConfig returns the io configuration
 func (out DefaultIOHandler) GetIOConfig() contracts.IOConfiguration {
 	return out.ioConfig
@@ -318,12 +532,29 @@ func (out *DefaultIOHandler) Merge(log log.T, mergeOutput *DefaultIOHandler) {
 	stderrBuffer.WriteString(mergeOutput.GetStderr())
 	out.stderr = stderrBuffer.String()
 
+	out.stdoutSummary = mergeSummary(out.stdoutSummary, mergeOutput.GetStdoutSummary())
+	out.stderrSummary = mergeSummary(out.stderrSummary, mergeOutput.GetStderrSummary())
+
 	if out.ExitCode == 0 {
 		out.ExitCode = mergeOutput.GetExitCode()
 	}
 	out.Status = contracts.MergeResultStatus(out.Status, mergeOutput.GetStatus())
 }
 
+// mergeSummary returns the summary of two concatenated outputs, whose hash is unknown when both are non-empty
+func mergeSummary(summary contracts.OutputSummary, other contracts.OutputSummary) contracts.OutputSummary {
+	if other.Bytes == 0 {
+		return summary
+	}
+	if summary.Bytes == 0 {
+		return other
+	}
+	return contracts.OutputSummary{
+		Bytes:     summary.Bytes + other.Bytes,
+		Truncated: summary.Truncated || other.Truncated,
+	}
+}
+
 // MarkAsFailed Failed marks plugin as Failed
 func (out *DefaultIOHandler) MarkAsFailed(err error) {
 	// Update the error exit code
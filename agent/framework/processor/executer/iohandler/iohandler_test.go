@@ -14,13 +14,21 @@
 package iohandler
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 
 	"sync"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule"
 	iomodulemock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule/mock"
 	multiwritermock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -28,6 +36,10 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
+var defaultRedactionPatterns = redactionPatterns
+
+var defaultAgentOutputConfig = agentOutputConfig
+
 type truncateOutputTest struct {
 	stdout   string
 	stderr   string
@@ -148,6 +160,134 @@ func TestAppendInfo(t *testing.T) {
 	assert.Contains(t, output.GetStdout(), "Second entry")
 }
 
+func TestRegisterSecrets(t *testing.T) {
+	redactionPatterns = func(log log.T) []*regexp.Regexp { return nil }
+	defer func() { redactionPatterns = defaultRedactionPatterns }()
+	orchestrationDir, err := ioutil.TempDir("", "iohandler")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	output := NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir})
+	output.Init(log.NewMockLog(), "aws:runShellScript")
+
+	output.RegisterSecrets("s3cret")
+	output.GetStdoutWriter().Write([]byte("the password is s3c"))
+	output.GetStdoutWriter().Write([]byte("ret\n"))
+	output.Close(log.NewMockLog())
+
+	assert.Equal(t, "the password is ****\n", output.GetStdout())
+	stdoutFile, err := ioutil.ReadFile(filepath.Join(orchestrationDir, "awsrunShellScript", "stdout"))
+	assert.NoError(t, err)
+	assert.Equal(t, "the password is ****\n", string(stdoutFile))
+}
+
+func TestCheckpoint(t *testing.T) {
+	var recorded []contracts.PluginCheckpoint
+	recorder := func(checkpoint contracts.PluginCheckpoint) { recorded = append(recorded, checkpoint) }
+	output := NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{
+		Checkpointer: contracts.NewCheckpointer("plugin1", "step-2", recorder),
+	})
+	assert.Equal(t, "step-2", output.GetCheckpoint())
+
+	output.SetCheckpoint("step-3")
+	assert.Equal(t, "step-3", output.GetCheckpoint())
+	assert.Equal(t, []contracts.PluginCheckpoint{{PluginID: "plugin1", Checkpoint: "step-3"}}, recorded)
+
+	// without a checkpointer the checkpoints are kept in memory
+	output = NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+	assert.Equal(t, "", output.GetCheckpoint())
+	output.SetCheckpoint("step-1")
+	assert.Equal(t, "step-1", output.GetCheckpoint())
+}
+
+func TestLargeOutput(t *testing.T) {
+	redactionPatterns = func(log log.T) []*regexp.Regexp { return nil }
+	defer func() { redactionPatterns = defaultRedactionPatterns }()
+	orchestrationDir, err := ioutil.TempDir("", "iohandler")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	output := NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir})
+	output.Init(log.NewMockLog(), "aws:runShellScript")
+
+	chunk := []byte(strings.Repeat("0123456789abcdef", 64))
+	output.GetStdoutWriter().Write([]byte("begin\n"))
+	for i := 0; i < 1024; i++ {
+		output.GetStdoutWriter().Write(chunk)
+	}
+	output.GetStdoutWriter().Write([]byte("\nend"))
+	output.Close(log.NewMockLog())
+
+	pluginConfig := DefaultOutputConfig()
+	stdout := output.GetStdout()
+	assert.True(t, len(stdout) < pluginConfig.MaxStdoutLength)
+	assert.True(t, strings.HasPrefix(stdout, "begin\n0123"))
+	assert.True(t, strings.HasSuffix(stdout, "cdef\nend"))
+	assert.Contains(t, stdout, pluginConfig.OutputTruncatedSuffix)
+
+	summary := output.GetStdoutSummary()
+	assert.Equal(t, int64(6+1024*1024+4), summary.Bytes)
+	assert.True(t, summary.Truncated)
+	consoleFile, err := os.Stat(filepath.Join(orchestrationDir, "awsrunShellScript", "stdoutConsole"))
+	assert.NoError(t, err)
+	assert.Equal(t, summary.Bytes, consoleFile.Size())
+	assert.Equal(t, contracts.OutputSummary{}, output.GetStderrSummary())
+}
+
+func TestMergeSummary(t *testing.T) {
+	output := DefaultIOHandler{stdoutSummary: contracts.OutputSummary{Bytes: 10, SHA256: "aa"}}
+	output.Merge(log.NewMockLog(), &DefaultIOHandler{stdoutSummary: contracts.OutputSummary{Bytes: 30000, SHA256: "bb", Truncated: true}, stderrSummary: contracts.OutputSummary{Bytes: 5, SHA256: "cc"}})
+
+	assert.Equal(t, contracts.OutputSummary{Bytes: 30010, Truncated: true}, output.GetStdoutSummary())
+	assert.Equal(t, contracts.OutputSummary{Bytes: 5, SHA256: "cc"}, output.GetStderrSummary())
+}
+
+func TestOutputSinks(t *testing.T) {
+	agentOutputConfig = func() appconfig.OutputCfg {
+		return appconfig.OutputCfg{
+			SyslogEnabled:          true,
+			SyslogNetwork:          "udp",
+			SyslogAddress:          "syslog:514",
+			SyslogFacility:         16,
+			WebhookURL:             "https://fleet.example.com/output",
+			WebhookCertificateFile: "/etc/amazon/ssm/webhook.crt",
+			WebhookPrivateKeyFile:  "/etc/amazon/ssm/webhook.key",
+			ArchiveRoot:            "/var/log/amazon/ssm/archives",
+		}
+	}
+	defer func() { agentOutputConfig = defaultAgentOutputConfig }()
+
+	sinks := outputSinks(log.NewMockLog(), []contracts.OutputSink{
+		{Type: contracts.OutputSinkWebhook, URL: "https://team.example.com/output", BatchIntervalSeconds: 2},
+		{Type: contracts.OutputSinkArchive, Directory: "team/output"},
+	})
+	modules := outputSinkModules(log.NewMockLog(), sinks, "cmd-1/aws:runShellScript", "stdout")
+
+	assert.Equal(t, []iomodule.IOModule{
+		iomodule.Webhook{URL: "https://team.example.com/output", BatchInterval: 2 * time.Second, CertificateFile: "/etc/amazon/ssm/webhook.crt",
+			PrivateKeyFile: "/etc/amazon/ssm/webhook.key", Source: "cmd-1/aws:runShellScript", StreamName: "stdout"},
+		iomodule.Archive{Directory: "/var/log/amazon/ssm/archives/team/output", Source: "cmd-1/aws:runShellScript", StreamName: "stdout"},
+		iomodule.Syslog{Network: "udp", Address: "syslog:514", Facility: 16, Source: "cmd-1/aws:runShellScript", StreamName: "stdout"},
+	}, modules)
+}
+
+func TestDocumentArchiveOutsideOfRoot(t *testing.T) {
+	output := appconfig.OutputCfg{ArchiveRoot: "/var/log/amazon/ssm/archives"}
+
+	_, err := documentOutputSink(contracts.OutputSink{Type: contracts.OutputSinkArchive, Directory: "../../../../etc"}, output)
+	assert.Error(t, err)
+	sink, err := documentOutputSink(contracts.OutputSink{Type: contracts.OutputSinkArchive, Directory: "/etc"}, output)
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/log/amazon/ssm/archives/etc", sink.Directory)
+	_, err = documentOutputSink(contracts.OutputSink{Type: contracts.OutputSinkArchive, Directory: "output"}, appconfig.OutputCfg{})
+	assert.Error(t, err)
+}
+
+func TestDocumentWebhookCertificates(t *testing.T) {
+	var sink contracts.OutputSink
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "webhook", "url": "https://team.example.com", "certificateFile": "/root/.ssh/id_rsa"}`), &sink))
+
+	assert.Empty(t, sink.CertificateFile)
+}
+
 func TestAppendSpecialChars(t *testing.T) {
 	output := DefaultIOHandler{}
 
@@ -0,0 +1,173 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	archiveFileName         = "output.log"
+	defaultArchiveMaxSizeMB = 10
+	defaultArchiveMaxFiles  = 5
+)
+
+// Archive appends the output to a local file, which is rotated once it reaches its maximum size.
+// Each line is prefixed with its time, source and stream.
+type Archive struct {
+	Directory string
+	MaxSizeMB int
+	MaxFiles  int
+	// Source identifies the command and plugin of the output
+	Source     string
+	StreamName string
+}
+
+// archiveFile is an archive shared by the modules writing to it at the same time
+type archiveFile struct {
+	lock  sync.Mutex
+	path  string
+	file  *os.File
+	size  int64
+	users int
+}
+
+// openArchives are the archives being written, by path
+var openArchives = struct {
+	sync.Mutex
+	files map[string]*archiveFile
+}{files: make(map[string]*archiveFile)}
+
+// Read reads from the stream and appends each line to the archive.
+func (a Archive) Read(log log.T, reader *io.PipeReader) {
+	defer func() { reader.Close() }()
+
+	if err := fileutil.MakeDirs(a.Directory); err != nil {
+		log.Errorf("failed to create the archive directory %v: %v", a.Directory, err)
+		// keep reading so that the other modules get the output
+		io.Copy(ioutil.Discard, reader)
+		return
+	}
+	maxSize := int64(a.MaxSizeMB) * 1024 * 1024
+	if maxSize <= 0 {
+		maxSize = defaultArchiveMaxSizeMB * 1024 * 1024
+	}
+	maxFiles := a.MaxFiles
+	if maxFiles <= 0 {
+		maxFiles = defaultArchiveMaxFiles
+	}
+
+	archive := acquireArchive(filepath.Join(a.Directory, archiveFileName))
+	defer releaseArchive(archive)
+
+	failed := false
+	err := readLines(reader, func(line string) {
+		entry := fmt.Sprintf("%s %s %s: %s\n", time.Now().UTC().Format(time.RFC3339), a.Source, a.StreamName, line)
+		if err := archive.write([]byte(entry), maxSize, maxFiles); err != nil && !failed {
+			// a failure is logged once, the output keeps going to the other modules
+			log.Errorf("failed to write the output to the archive: %v", err)
+			failed = true
+		}
+	})
+	if err != nil {
+		log.Errorf("Error while reading the stream: %v", err)
+	}
+}
+
+func acquireArchive(path string) *archiveFile {
+	openArchives.Lock()
+	defer openArchives.Unlock()
+	archive, ok := openArchives.files[path]
+	if !ok {
+		archive = &archiveFile{path: path}
+		openArchives.files[path] = archive
+	}
+	archive.users++
+	return archive
+}
+
+// releaseArchive closes the archive once no module writes to it
+func releaseArchive(archive *archiveFile) {
+	openArchives.Lock()
+	defer openArchives.Unlock()
+	archive.users--
+	if archive.users > 0 {
+		return
+	}
+	delete(openArchives.files, archive.path)
+	archive.lock.Lock()
+	defer archive.lock.Unlock()
+	if archive.file != nil {
+		archive.file.Close()
+		archive.file = nil
+	}
+}
+
+// write appends the entry, after rotating the archive when the entry would take it past its maximum size
+func (a *archiveFile) write(entry []byte, maxSize int64, maxFiles int) (err error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.file == nil {
+		if err = a.open(); err != nil {
+			return err
+		}
+	}
+	if a.size > 0 && a.size+int64(len(entry)) > maxSize {
+		if err = a.rotate(maxFiles); err != nil {
+			return err
+		}
+	}
+	n, err := a.file.Write(entry)
+	a.size += int64(n)
+	return err
+}
+
+func (a *archiveFile) open() error {
+	file, err := os.OpenFile(a.path, appconfig.FileFlagsCreateOrAppend, appconfig.ReadWriteAccess)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	a.file, a.size = file, info.Size()
+	return nil
+}
+
+// rotate renames output.log to output.log.1, output.log.1 to output.log.2 and so on, the oldest file is removed
+func (a *archiveFile) rotate(maxFiles int) error {
+	a.file.Close()
+	a.file = nil
+	os.Remove(fmt.Sprintf("%s.%d", a.path, maxFiles))
+	for i := maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", a.path, i), fmt.Sprintf("%s.%d", a.path, i+1))
+	}
+	if err := os.Rename(a.path, a.path+".1"); err != nil {
+		return err
+	}
+	return a.open()
+}
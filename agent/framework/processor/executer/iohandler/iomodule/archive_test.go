@@ -0,0 +1,70 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveRead(t *testing.T) {
+	directory, err := ioutil.TempDir("", "archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+	reader, writer := io.Pipe()
+	done := make(chan bool)
+	go func() {
+		Archive{Directory: directory, Source: "cmd-1/aws:runShellScript", StreamName: "stdout"}.Read(log.NewMockLog(), reader)
+		close(done)
+	}()
+	writer.Write([]byte("first line\nsecond line"))
+	writer.Close()
+	<-done
+
+	content, err := ioutil.ReadFile(filepath.Join(directory, archiveFileName))
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.HasSuffix(lines[0], " cmd-1/aws:runShellScript stdout: first line"))
+	assert.True(t, strings.HasSuffix(lines[1], " cmd-1/aws:runShellScript stdout: second line"))
+	assert.Empty(t, openArchives.files)
+}
+
+func TestArchiveRotation(t *testing.T) {
+	directory, err := ioutil.TempDir("", "archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+	path := filepath.Join(directory, archiveFileName)
+	archive := acquireArchive(path)
+
+	for _, entry := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n"} {
+		assert.NoError(t, archive.write([]byte(entry), 8, 2))
+	}
+	releaseArchive(archive)
+
+	for suffix, expected := range map[string]string{"": "dddd\n", ".1": "cccc\n", ".2": "bbbb\n"} {
+		content, err := ioutil.ReadFile(path + suffix)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+	}
+	_, err = ioutil.ReadFile(path + ".3")
+	assert.Error(t, err)
+}
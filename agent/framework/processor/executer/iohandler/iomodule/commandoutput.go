@@ -14,23 +14,28 @@
 package iomodule
 
 import (
-	"bufio"
 	"io"
-
 	"path/filepath"
 
-	"os"
-
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/outputcapture"
 )
 
 // CommandOutput handles writing output to a string.
+// Only the beginning and the end of the output are kept in the string, the whole output is
+// spilled to the console file once it grows past outputcapture.DefaultSpillThreshold.
 type CommandOutput struct {
 	OutputString           *string
+	Summary                *contracts.OutputSummary
 	FileName               string
 	OrchestrationDirectory string
+	// MaxLength is the maximum length of the output string, appconfig.MaxStdoutLength when zero
+	MaxLength int
+	// TruncatedMarker replaces the middle of the output when it is longer than MaxLength
+	TruncatedMarker string
 }
 
 func (c CommandOutput) Read(log log.T, reader *io.PipeReader) {
@@ -41,40 +46,39 @@ func (c CommandOutput) Read(log log.T, reader *io.PipeReader) {
 		return
 	}
 	filePath := filepath.Join(c.OrchestrationDirectory, c.FileName)
-	fileWriter, err := os.OpenFile(filePath, appconfig.FileFlagsCreateOrAppend, appconfig.ReadWriteAccess)
 
-	if err != nil {
-		log.Errorf("Failed to open the file at %v: %v", filePath, err)
-		return
+	maxLength := c.MaxLength
+	if maxLength <= 0 {
+		maxLength = appconfig.MaxStdoutLength
 	}
-
-	defer fileWriter.Close()
-
-	// Read byte by byte and write to file
-	scanner := bufio.NewScanner(reader)
-	scanner.Split(bufio.ScanBytes)
-	for scanner.Scan() {
-		if _, err = fileWriter.Write([]byte(scanner.Text())); err != nil {
-			log.Errorf("Failed to write the message to stdoutConsoleFile: %v", err)
-		}
+	// keep the string strictly shorter than maxLength so that it is not truncated again
+	available := maxLength - len(c.TruncatedMarker) - 1
+	if available < 0 {
+		available = 0
 	}
+	capture := outputcapture.New(outputcapture.Config{
+		HeadSize:       available / 2,
+		TailSize:       available - available/2,
+		Marker:         c.TruncatedMarker,
+		SpillFile:      filePath,
+		SpillThreshold: outputcapture.DefaultSpillThreshold,
+	})
 
-	// Check if scanner exited because of an error
-	if err := scanner.Err(); err != nil {
-		log.Error("Error with the scanner while reading the stream")
+	if _, err := io.Copy(capture, reader); err != nil {
+		log.Errorf("Error reading the stream: %v", err)
+	}
+	if err := capture.Close(); err != nil {
+		log.Errorf("Failed to write the message to %v: %v", filePath, err)
 	}
 
-	fi, err := fileWriter.Stat()
-	if err != nil {
-		log.Errorf("Failed to get file stat: %v", err)
+	if capture.Bytes() == 0 {
 		return
 	}
-
-	// Write output to console
-	if fi.Size() > 0 {
-		*c.OutputString, err = fileutil.ReadAllText(filePath)
-		if err != nil {
-			log.Errorf("Error reading %v at path %v", c.FileName, filePath)
-		}
+	*c.OutputString = capture.String()
+	if c.Summary != nil {
+		*c.Summary = capture.Summary()
+	}
+	if capture.Truncated() {
+		log.Infof("Output of %v bytes truncated to %v bytes, sha256 %v", capture.Bytes(), len(*c.OutputString), capture.Summary().SHA256)
 	}
 }
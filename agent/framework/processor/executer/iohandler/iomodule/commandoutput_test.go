@@ -9,6 +9,12 @@ import (
 
 	"strconv"
 
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/stretchr/testify/assert"
 )
@@ -65,3 +71,49 @@ func testFileCommandOutput(pipeTestCase string, i int) string {
 	return stdout
 
 }
+
+// TestCommandOuputTruncated tests that the CommandOutput module keeps the beginning and the end of long output
+func TestCommandOuputTruncated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commandoutput")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	r, w := io.Pipe()
+	var stdout string
+	var summary contracts.OutputSummary
+	stdoutConsole := CommandOutput{
+		OutputString:           &stdout,
+		Summary:                &summary,
+		FileName:               "stdoutConsole",
+		OrchestrationDirectory: dir,
+		MaxLength:              21,
+		TruncatedMarker:        "[...]",
+	}
+
+	done := make(chan bool)
+	go func() {
+		stdoutConsole.Read(logger, r)
+		close(done)
+	}()
+
+	line := strings.Repeat("x", 1023) + "\n"
+	w.Write([]byte("first line\n"))
+	for i := 0; i < 100; i++ {
+		w.Write([]byte(line))
+	}
+	w.Write([]byte("last line\n"))
+	w.Close()
+	<-done
+
+	assert.Equal(t, "first l[...]st line\n", stdout)
+	assert.True(t, len(stdout) < stdoutConsole.MaxLength)
+	assert.Equal(t, int64(11+100*1024+10), summary.Bytes)
+	assert.True(t, summary.Truncated)
+	assert.Len(t, summary.SHA256, 64)
+
+	// the whole output was spilled to the console file
+	assert.Equal(t, filepath.Join(dir, "stdoutConsole"), summary.File)
+	info, err := os.Stat(summary.File)
+	assert.NoError(t, err)
+	assert.Equal(t, summary.Bytes, info.Size())
+}
@@ -0,0 +1,40 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// maxLineLength is the length after which a line is split
+const maxLineLength = 64 * 1024
+
+// readLines calls handle with each line of the stream, without its end of line, until the stream ends
+func readLines(reader io.Reader, handle func(line string)) error {
+	buffered := bufio.NewReaderSize(reader, maxLineLength)
+	for {
+		line, _, err := buffered.ReadLine()
+		if len(line) > 0 || err == nil {
+			handle(strings.TrimSuffix(string(line), "\r"))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
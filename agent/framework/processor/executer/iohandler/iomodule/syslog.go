@@ -0,0 +1,145 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	syslogAppName     = "amazon-ssm-agent"
+	syslogDialTimeout = 10 * time.Second
+	// syslogMaxMessageLength is the longest message all the receivers must accept over UDP
+	syslogMaxMessageLength = 2048
+
+	syslogSeverityError = 3
+	syslogSeverityInfo  = 6
+)
+
+// localSyslogSockets are the sockets of the local syslog daemon
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// Syslog sends each line of output as an RFC 5424 message
+type Syslog struct {
+	// Network is udp, tcp or tls, the local syslog daemon is used when it's empty
+	Network  string
+	Address  string
+	Facility int
+	// Source identifies the command and plugin of the output, it's added to each message
+	Source string
+	// StreamName is stdout or stderr, it's the MSGID of the messages
+	StreamName string
+}
+
+// Read reads from the stream and sends each line to syslog.
+func (s Syslog) Read(log log.T, reader *io.PipeReader) {
+	defer func() { reader.Close() }()
+
+	conn, err := s.dial()
+	if err != nil {
+		log.Errorf("failed to connect to syslog: %v", err)
+		// keep reading so that the other modules get the output
+		io.Copy(ioutil.Discard, reader)
+		return
+	}
+	defer conn.Close()
+
+	hostname, _ := os.Hostname()
+	severity := syslogSeverityInfo
+	if s.StreamName == "stderr" {
+		severity = syslogSeverityError
+	}
+	failed := false
+	err = readLines(reader, func(line string) {
+		if _, err := conn.Write(s.format(severity, hostname, time.Now(), line)); err != nil && !failed {
+			// a failure is logged once, the output keeps going to the other modules
+			log.Errorf("failed to send the output to syslog: %v", err)
+			failed = true
+		}
+	})
+	if err != nil {
+		log.Errorf("Error while reading the stream: %v", err)
+	}
+}
+
+// format returns the RFC 5424 message of the line, framed with octet counting on the stream networks
+func (s Syslog) format(severity int, hostname string, timestamp time.Time, line string) []byte {
+	if hostname == "" {
+		hostname = "-"
+	}
+	message := fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+		s.Facility*8+severity,
+		timestamp.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		hostname,
+		syslogAppName,
+		os.Getpid(),
+		syslogMsgID(s.StreamName),
+		syslogMessage(s.Source, line))
+
+	switch s.Network {
+	case "tcp", "tls":
+		// RFC 6587 octet counting
+		return []byte(fmt.Sprintf("%d %s", len(message), message))
+	default:
+		if len(message) > syslogMaxMessageLength {
+			message = message[:syslogMaxMessageLength]
+		}
+		return []byte(message)
+	}
+}
+
+func (s Syslog) dial() (net.Conn, error) {
+	switch s.Network {
+	case "":
+		for _, socket := range localSyslogSockets {
+			for _, network := range []string{"unixgram", "unix"} {
+				if conn, err := net.DialTimeout(network, socket, syslogDialTimeout); err == nil {
+					return conn, nil
+				}
+			}
+		}
+		return nil, errors.New("no local syslog daemon")
+	case "udp", "tcp":
+		return net.DialTimeout(s.Network, s.Address, syslogDialTimeout)
+	case "tls":
+		return tls.DialWithDialer(&net.Dialer{Timeout: syslogDialTimeout}, "tcp", s.Address, nil)
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %v, it should be udp, tcp or tls", s.Network)
+	}
+}
+
+// syslogMsgID returns the MSGID field, printable ASCII without spaces
+func syslogMsgID(streamName string) string {
+	if streamName == "" {
+		return "-"
+	}
+	return streamName
+}
+
+// syslogMessage prefixes the line with the source of the output
+func syslogMessage(source string, line string) string {
+	if source == "" {
+		return line
+	}
+	return source + ": " + line
+}
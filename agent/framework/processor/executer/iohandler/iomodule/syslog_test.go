@@ -0,0 +1,82 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyslogFormat(t *testing.T) {
+	timestamp := time.Date(2018, 3, 1, 10, 4, 5, 6000, time.UTC)
+	pid := strconv.Itoa(os.Getpid())
+
+	udp := Syslog{Network: "udp", Facility: 1, Source: "cmd-1/aws:runShellScript", StreamName: "stderr"}
+	assert.Equal(t, "<11>1 2018-03-01T10:04:05.000006Z host amazon-ssm-agent "+pid+" stderr - cmd-1/aws:runShellScript: failed",
+		string(udp.format(syslogSeverityError, "host", timestamp, "failed")))
+
+	tcp := Syslog{Network: "tcp", Facility: 16, StreamName: "stdout"}
+	message := "<134>1 2018-03-01T10:04:05.000006Z - amazon-ssm-agent " + pid + " stdout - hello"
+	assert.Equal(t, strconv.Itoa(len(message))+" "+message, string(tcp.format(syslogSeverityInfo, "", timestamp, "hello")))
+}
+
+func TestSyslogRead(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	reader, writer := io.Pipe()
+	done := make(chan bool)
+	go func() {
+		Syslog{Network: "udp", Address: conn.LocalAddr().String(), Facility: 1, Source: "cmd-1", StreamName: "stdout"}.Read(log.NewMockLog(), reader)
+		close(done)
+	}()
+	writer.Write([]byte("first line\nsecond "))
+	writer.Write([]byte("line\n"))
+	writer.Close()
+	<-done
+
+	expected := regexp.MustCompile(`^<14>1 \S+Z \S+ amazon-ssm-agent \d+ stdout - cmd-1: (first|second) line$`)
+	buffer := make([]byte, syslogMaxMessageLength)
+	for _, line := range []string{"first line", "second line"} {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buffer)
+		assert.NoError(t, err)
+		assert.Regexp(t, expected, string(buffer[:n]))
+		assert.Contains(t, string(buffer[:n]), line)
+	}
+}
+
+func TestSyslogUnreachableKeepsReading(t *testing.T) {
+	reader, writer := io.Pipe()
+	done := make(chan bool)
+	go func() {
+		Syslog{Network: "smtp"}.Read(log.NewMockLog(), reader)
+		close(done)
+	}()
+
+	_, err := writer.Write([]byte("the output still goes to the other modules\n"))
+	writer.Close()
+	<-done
+
+	assert.NoError(t, err)
+}
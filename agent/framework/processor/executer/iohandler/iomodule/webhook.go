@@ -0,0 +1,239 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	defaultWebhookBatchSize     = 100
+	defaultWebhookBatchInterval = 5 * time.Second
+	webhookRequestTimeout       = 30 * time.Second
+	// maxQueuedWebhookBatches are the batches waiting to be posted, the next ones are dropped
+	// so that a slow endpoint doesn't block the command
+	maxQueuedWebhookBatches = 100
+)
+
+var (
+	// webhookRetryDelay is the delay before the first retry, it doubles at each retry
+	webhookRetryDelay = time.Second
+	// webhookFlushTimeout bounds the time the queued batches are posted once the stream ended,
+	// the batches not posted by then are dropped so that a slow endpoint doesn't hold the document
+	webhookFlushTimeout = 10 * time.Second
+)
+
+// Webhook posts batches of lines of output to an HTTP endpoint
+type Webhook struct {
+	URL           string
+	BatchSize     int
+	BatchInterval time.Duration
+	MaxRetries    int
+	// CertificateFile and PrivateKeyFile are the client certificate of mutual TLS
+	CertificateFile string
+	PrivateKeyFile  string
+	// CACertificateFile replaces the system roots to verify the endpoint
+	CACertificateFile string
+	// Source identifies the command and plugin of the output
+	Source     string
+	StreamName string
+}
+
+// WebhookBatch is the JSON body posted to the webhook
+type WebhookBatch struct {
+	Source   string   `json:"source"`
+	Stream   string   `json:"stream"`
+	Hostname string   `json:"hostname"`
+	Sequence int      `json:"sequence"`
+	Lines    []string `json:"lines"`
+}
+
+// Read reads from the stream and posts the lines to the webhook.
+func (w Webhook) Read(log log.T, reader *io.PipeReader) {
+	defer func() { reader.Close() }()
+
+	client, err := w.client()
+	if err != nil {
+		log.Errorf("failed to create the client of the webhook: %v", err)
+		// keep reading so that the other modules get the output
+		io.Copy(ioutil.Discard, reader)
+		return
+	}
+	batchSize := w.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultWebhookBatchSize
+	}
+	batchInterval := w.BatchInterval
+	if batchInterval <= 0 {
+		batchInterval = defaultWebhookBatchInterval
+	}
+
+	lines := make(chan string, batchSize)
+	go func() {
+		defer close(lines)
+		if err := readLines(reader, func(line string) { lines <- line }); err != nil {
+			log.Errorf("Error while reading the stream: %v", err)
+		}
+	}()
+
+	// the context is canceled once the flush at the end of the stream takes too long
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	batches := make(chan WebhookBatch, maxQueuedWebhookBatches)
+	posted := make(chan int)
+	go func() {
+		notPosted := 0
+		for batch := range batches {
+			if ctx.Err() != nil {
+				notPosted += len(batch.Lines)
+				continue
+			}
+			w.post(ctx, log, client, batch)
+		}
+		posted <- notPosted
+	}()
+
+	hostname, _ := os.Hostname()
+	batch := WebhookBatch{Source: w.Source, Stream: w.StreamName, Hostname: hostname}
+	dropped := 0
+	enqueue := func() {
+		select {
+		case batches <- batch:
+		default:
+			dropped += len(batch.Lines)
+		}
+		batch.Sequence++
+		batch.Lines = nil
+	}
+
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				flushTimer := time.AfterFunc(webhookFlushTimeout, cancel)
+				defer flushTimer.Stop()
+				if len(batch.Lines) > 0 {
+					select {
+					case batches <- batch:
+					case <-ctx.Done():
+						dropped += len(batch.Lines)
+					}
+				}
+				close(batches)
+				dropped += <-posted
+				if dropped > 0 {
+					log.Warnf("dropped %v lines of output, the webhook %v is too slow", dropped, w.URL)
+				}
+				return
+			}
+			batch.Lines = append(batch.Lines, line)
+			if len(batch.Lines) >= batchSize {
+				enqueue()
+			}
+		case <-ticker.C:
+			if len(batch.Lines) > 0 {
+				enqueue()
+			}
+		}
+	}
+}
+
+// post posts the batch, retrying the failures which aren't client errors
+func (w Webhook) post(ctx context.Context, log log.T, client *http.Client, batch WebhookBatch) {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		log.Errorf("failed to encode the output for the webhook: %v", err)
+		return
+	}
+	delay := webhookRetryDelay
+	for attempt := 0; ; attempt++ {
+		retry := true
+		err = func() error {
+			request, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+			if err != nil {
+				retry = false
+				return err
+			}
+			request = request.WithContext(ctx)
+			request.Header.Set("Content-Type", "application/json")
+			response, err := client.Do(request)
+			if err != nil {
+				return err
+			}
+			defer response.Body.Close()
+			io.Copy(ioutil.Discard, response.Body)
+			if response.StatusCode >= 200 && response.StatusCode < 300 {
+				return nil
+			}
+			retry = response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+			return fmt.Errorf("the webhook returned %v", response.Status)
+		}()
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= w.MaxRetries || ctx.Err() != nil {
+			log.Errorf("failed to post %v lines of output to the webhook %v: %v", len(batch.Lines), w.URL, err)
+			return
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+		delay *= 2
+	}
+}
+
+// client returns an HTTP client with the client certificate and the roots of the webhook
+func (w Webhook) client() (*http.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if w.CertificateFile != "" || w.PrivateKeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(w.CertificateFile, w.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	if w.CACertificateFile != "" {
+		pem, err := ioutil.ReadFile(w.CACertificateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA certificate: %v", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in %v", w.CACertificateFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	return &http.Client{
+		Timeout: webhookRequestTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}, nil
+}
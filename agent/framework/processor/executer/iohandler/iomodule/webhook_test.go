@@ -0,0 +1,125 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// webhookServer records the batches it receives, the first failures requests fail with the status
+func webhookServer(failures int, status int) (*httptest.Server, func() []WebhookBatch) {
+	var lock sync.Mutex
+	var batches []WebhookBatch
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests++
+		if requests <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		var batch WebhookBatch
+		json.NewDecoder(r.Body).Decode(&batch)
+		batches = append(batches, batch)
+	}))
+	return server, func() []WebhookBatch {
+		lock.Lock()
+		defer lock.Unlock()
+		return batches
+	}
+}
+
+func readWebhook(webhook Webhook, output string) {
+	reader, writer := io.Pipe()
+	done := make(chan bool)
+	go func() {
+		webhook.Read(log.NewMockLog(), reader)
+		close(done)
+	}()
+	writer.Write([]byte(output))
+	writer.Close()
+	<-done
+}
+
+func TestWebhookBatches(t *testing.T) {
+	server, batches := webhookServer(0, 0)
+	defer server.Close()
+
+	readWebhook(Webhook{URL: server.URL, BatchSize: 2, BatchInterval: time.Minute, Source: "cmd-1", StreamName: "stdout"}, "1\n2\n3\n4\n5")
+
+	assert.Len(t, batches(), 3)
+	for i, lines := range [][]string{{"1", "2"}, {"3", "4"}, {"5"}} {
+		assert.Equal(t, "cmd-1", batches()[i].Source)
+		assert.Equal(t, "stdout", batches()[i].Stream)
+		assert.Equal(t, i, batches()[i].Sequence)
+		assert.Equal(t, lines, batches()[i].Lines)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	webhookRetryDelay = time.Millisecond
+	defer func() { webhookRetryDelay = time.Second }()
+
+	for _, test := range []struct {
+		failures   int
+		status     int
+		maxRetries int
+		posted     int
+	}{
+		{failures: 2, status: http.StatusServiceUnavailable, maxRetries: 3, posted: 1},
+		{failures: 2, status: http.StatusTooManyRequests, maxRetries: 1, posted: 0},
+		{failures: 1, status: http.StatusBadRequest, maxRetries: 3, posted: 0},
+	} {
+		server, batches := webhookServer(test.failures, test.status)
+		readWebhook(Webhook{URL: server.URL, MaxRetries: test.maxRetries}, "line\n")
+		server.Close()
+
+		assert.Len(t, batches(), test.posted, fmt.Sprintf("%+v", test))
+	}
+}
+
+func TestWebhookFlushTimeout(t *testing.T) {
+	webhookFlushTimeout = 100 * time.Millisecond
+	defer func() { webhookFlushTimeout = 10 * time.Second }()
+	release := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	readWebhook(Webhook{URL: server.URL, BatchSize: 1, BatchInterval: time.Minute}, "1\n2\n3\n4\n5\n")
+
+	// the batches still queued when the flush times out are dropped
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestWebhookInvalidClientCertificate(t *testing.T) {
+	readWebhook(Webhook{URL: "https://localhost", CertificateFile: "missing.pem", PrivateKeyFile: "missing.key"}, "line\n")
+}
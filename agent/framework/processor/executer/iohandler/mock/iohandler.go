@@ -98,6 +98,22 @@ func (m *MockIOHandler) AppendErrorf(format string, params ...interface{}) {
 	m.Called(format, params)
 }
 
+// RegisterSecrets is a mocked method that just returns what mock tells it to.
+func (m *MockIOHandler) RegisterSecrets(secrets ...string) {
+	m.Called(secrets)
+}
+
+// SetCheckpoint is a mocked method that just returns what mock tells it to.
+func (m *MockIOHandler) SetCheckpoint(checkpoint string) {
+	m.Called(checkpoint)
+}
+
+// GetCheckpoint is a mocked method that just returns what mock tells it to.
+func (m *MockIOHandler) GetCheckpoint() string {
+	args := m.Called()
+	return args.String(0)
+}
+
 // GetStatus is a mocked method that just returns what mock tells it to.
 func (m *MockIOHandler) GetStatus() contracts.ResultStatus {
 	args := m.Called()
@@ -116,6 +132,18 @@ func (m *MockIOHandler) GetStderr() string {
 	return args.String(0)
 }
 
+// GetStdoutSummary is a mocked method that just returns what mock tells it to.
+func (m *MockIOHandler) GetStdoutSummary() contracts.OutputSummary {
+	args := m.Called()
+	return args.Get(0).(contracts.OutputSummary)
+}
+
+// GetStderrSummary is a mocked method that just returns what mock tells it to.
+func (m *MockIOHandler) GetStderrSummary() contracts.OutputSummary {
+	args := m.Called()
+	return args.Get(0).(contracts.OutputSummary)
+}
+
 // GetExitCode is a mocked method that just returns what mock tells it to.
 func (m *MockIOHandler) GetExitCode() int {
 	args := m.Called()
@@ -17,6 +17,7 @@ package multiwriter
 import (
 	"fmt"
 	"io"
+	"regexp"
 	"sync"
 )
 
@@ -35,13 +36,23 @@ type DocumentIOMultiWriter interface {
 type DefaultDocumentIOMultiWriter struct {
 	writers []*io.PipeWriter
 	wg      *sync.WaitGroup
+	// redactor redacts the data before any writer sees it
+	redactor *redactor
+	lock     sync.Mutex
 }
 
 // NewDocumentIOMultiWriter creates a new document multi-writer
 func NewDocumentIOMultiWriter() (b *DefaultDocumentIOMultiWriter) {
-	var w []*io.PipeWriter
-	b = &DefaultDocumentIOMultiWriter{w, new(sync.WaitGroup)}
-	return
+	return NewRedactingDocumentIOMultiWriter(nil, nil)
+}
+
+// NewRedactingDocumentIOMultiWriter creates a new document multi-writer which replaces the secrets
+// and the matches of the patterns with RedactedValue. The secrets are read at every write.
+func NewRedactingDocumentIOMultiWriter(secrets func() []string, patterns []*regexp.Regexp) (b *DefaultDocumentIOMultiWriter) {
+	return &DefaultDocumentIOMultiWriter{
+		wg:       new(sync.WaitGroup),
+		redactor: &redactor{secrets: secrets, patterns: patterns},
+	}
 }
 
 // AddWriter adds a new writer to an existing multi-writer
@@ -61,8 +72,19 @@ func (b *DefaultDocumentIOMultiWriter) Write(p []byte) (n int, err error) {
 		return 0, fmt.Errorf("No writers present.")
 	}
 
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.writeToAll(b.redactor.write(p))
+	return len(p), nil
+}
+
+// writeToAll writes the redacted data to all the attached pipes.
+func (b *DefaultDocumentIOMultiWriter) writeToAll(p []byte) {
+	if len(p) == 0 {
+		return
+	}
 	for i := 0; i < len(b.writers); i++ {
-		n, err = b.writers[i].Write(p)
+		n, err := b.writers[i].Write(p)
 		// TODO: Handler other error types and close the writers after a fixed number of retries
 		if err == io.ErrClosedPipe {
 			// remove the writer as the reader is closed
@@ -73,7 +95,6 @@ func (b *DefaultDocumentIOMultiWriter) Write(p []byte) (n int, err error) {
 			err = io.ErrShortWrite
 		}
 	}
-	return len(p), nil
 }
 
 // WriteString is responsible for writing a string to all the attached pipes.
@@ -82,15 +103,18 @@ func (b *DefaultDocumentIOMultiWriter) WriteString(message string) (n int, err e
 		return 0, fmt.Errorf("No writers present.")
 	}
 
-	for _, w := range b.writers {
-		p := []byte(message)
-		n, err = w.Write(p)
-	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.writeToAll(b.redactor.write([]byte(message)))
 	return len(message), nil
 }
 
-// Close waits for all the writers to be closed.
+// Close writes the data kept for redaction and waits for all the writers to be closed.
 func (b *DefaultDocumentIOMultiWriter) Close() (err error) {
+	b.lock.Lock()
+	b.writeToAll(b.redactor.flush())
+	b.lock.Unlock()
+
 	for i := 0; i < len(b.writers); i++ {
 		err = b.writers[i].Close()
 	}
@@ -0,0 +1,166 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package multiwriter implements a multi-writer
+package multiwriter
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+)
+
+const (
+	// RedactedValue replaces the secrets and the matches of the redaction patterns
+	RedactedValue = "****"
+
+	// maxPendingLineLength is the length after which a line without end is written even though
+	// a redaction pattern could match across the cut
+	maxPendingLineLength = 64 * 1024
+)
+
+// redactor removes the secrets and the matches of the patterns from a stream. The end of a write which could be the
+// start of a secret is kept until the next write, so that the secrets split across writes are redacted too.
+// The patterns match within a line, the last line is kept until it ends when patterns are set.
+type redactor struct {
+	secrets  func() []string
+	patterns []*regexp.Regexp
+	pending  []byte
+
+	// secretList is the last list of secrets read, secretBytes holds them sorted and converted once
+	secretList  []string
+	secretBytes [][]byte
+}
+
+// write returns the part of the stream which can be written once redacted
+func (r *redactor) write(p []byte) []byte {
+	secrets := r.sortedSecrets()
+	if len(secrets) == 0 && len(r.patterns) == 0 {
+		return p
+	}
+
+	r.pending = append(r.pending, p...)
+	cut := len(r.pending) - partialSecretLength(r.pending, secrets)
+	if len(r.patterns) > 0 {
+		if end := bytes.LastIndexByte(r.pending[:cut], '\n'); end >= 0 {
+			cut = end + 1
+		} else if cut < maxPendingLineLength {
+			cut = 0
+		}
+	}
+
+	ready, end := redactSecrets(r.pending, secrets, cut)
+	r.pending = append([]byte(nil), r.pending[end:]...)
+	return r.redactPatterns(ready)
+}
+
+// flush returns the redacted rest of the stream
+func (r *redactor) flush() []byte {
+	if len(r.pending) == 0 {
+		return nil
+	}
+	ready, _ := redactSecrets(r.pending, r.sortedSecrets(), len(r.pending))
+	r.pending = nil
+	return r.redactPatterns(ready)
+}
+
+// sortedSecrets returns the secrets longest first, so that a secret containing another one is redacted as a whole.
+// They are only sorted and converted again when the list of secrets changed.
+func (r *redactor) sortedSecrets() [][]byte {
+	if r.secrets == nil {
+		return nil
+	}
+	secrets := r.secrets()
+	if equalSecrets(secrets, r.secretList) {
+		return r.secretBytes
+	}
+	r.secretList = append([]string(nil), secrets...)
+	sorted := append([]string(nil), secrets...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	r.secretBytes = make([][]byte, 0, len(sorted))
+	for _, secret := range sorted {
+		if secret != "" {
+			r.secretBytes = append(r.secretBytes, []byte(secret))
+		}
+	}
+	return r.secretBytes
+}
+
+func equalSecrets(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *redactor) redactPatterns(p []byte) []byte {
+	for _, pattern := range r.patterns {
+		p = pattern.ReplaceAllLiteral(p, []byte(RedactedValue))
+	}
+	return p
+}
+
+// redactSecrets redacts p up to the cut, it returns the redacted data and where it ended,
+// which is after the cut when a secret starts before the cut and ends after it
+func redactSecrets(p []byte, secrets [][]byte, cut int) (redacted []byte, end int) {
+	if len(secrets) == 0 {
+		return p[:cut], cut
+	}
+	// next holds where each secret is found next, it's only searched again once the redaction went past it
+	next := make([]int, len(secrets))
+	for i := range next {
+		next[i] = -1
+	}
+	redacted = make([]byte, 0, cut)
+	for end < cut {
+		match := -1
+		for i, secret := range secrets {
+			if next[i] < end {
+				next[i] = len(p)
+				if index := bytes.Index(p[end:], secret); index >= 0 {
+					next[i] = end + index
+				}
+			}
+			// the secrets are sorted longest first, the first one found at a position is the longest
+			if next[i] < cut && (match < 0 || next[i] < next[match]) {
+				match = i
+			}
+		}
+		if match < 0 {
+			redacted = append(redacted, p[end:cut]...)
+			return redacted, cut
+		}
+		redacted = append(redacted, p[end:next[match]]...)
+		redacted = append(redacted, RedactedValue...)
+		end = next[match] + len(secrets[match])
+	}
+	return redacted, end
+}
+
+// partialSecretLength returns the length of the longest end of p which is the start of a secret
+func partialSecretLength(p []byte, secrets [][]byte) (length int) {
+	for _, secret := range secrets {
+		for n := len(secret) - 1; n > length; n-- {
+			if n <= len(p) && bytes.HasSuffix(p, secret[:n]) {
+				length = n
+				break
+			}
+		}
+	}
+	return length
+}
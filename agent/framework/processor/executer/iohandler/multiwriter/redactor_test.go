@@ -0,0 +1,105 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package multiwriter
+
+import (
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// redact writes the chunks to a redactor and returns what it lets through
+func redact(r *redactor, chunks ...string) string {
+	var output []byte
+	for _, chunk := range chunks {
+		output = append(output, r.write([]byte(chunk))...)
+	}
+	return string(append(output, r.flush()...))
+}
+
+func TestRedactSecrets(t *testing.T) {
+	secrets := func() []string { return []string{"s3cret", "s3cret-key"} }
+
+	assert.Equal(t, "password ****, key ****\n", redact(&redactor{secrets: secrets}, "password s3cret, key s3cret-key\n"))
+	// the secrets split across writes
+	assert.Equal(t, "password ****, key ****\n", redact(&redactor{secrets: secrets}, "password s3", "cret, key s", "3", "cret-", "key\n"))
+	// the start of a secret at the end of the stream
+	assert.Equal(t, "password s3cr", redact(&redactor{secrets: secrets}, "password s3", "cr"))
+}
+
+func TestRedactSecretsAddedLater(t *testing.T) {
+	secrets := []string{"s3cret"}
+	r := &redactor{secrets: func() []string { return secrets }}
+
+	assert.Equal(t, "password ****\n", string(r.write([]byte("password s3cret\n"))))
+	secrets = append(secrets, "t0ken")
+	assert.Equal(t, "token ****, password ****\n", string(r.write([]byte("token t0ken, password s3cret\n"))))
+}
+
+func TestRedactSecretsInLongOutput(t *testing.T) {
+	secrets := func() []string { return []string{"abc", "abcdef", "xyz"} }
+	output := strings.Repeat("abcdef xyz ab ", 1000)
+
+	assert.Equal(t, strings.Repeat("**** **** ab ", 1000), redact(&redactor{secrets: secrets}, output))
+}
+
+func TestRedactKeepsOnlyPartialSecrets(t *testing.T) {
+	r := &redactor{secrets: func() []string { return []string{"s3cret"} }}
+
+	assert.Equal(t, "password ", string(r.write([]byte("password s3c"))))
+	assert.Equal(t, "s3crest is written\n", string(r.write([]byte("rest is written\n"))))
+}
+
+func TestRedactSecretAcrossTheCut(t *testing.T) {
+	r := &redactor{secrets: func() []string { return []string{"s3cret", "tab123"} }}
+
+	// "tab" could start the second secret but its "t" ends the first one
+	assert.Equal(t, "x****", string(r.write([]byte("xs3cretab"))))
+	assert.Equal(t, "ab", string(r.flush()))
+}
+
+func TestRedactPatterns(t *testing.T) {
+	patterns := []*regexp.Regexp{regexp.MustCompile(`AKIA[0-9A-Z]{16}`)}
+	r := &redactor{patterns: patterns}
+
+	// the line is kept until it ends
+	assert.Equal(t, "", string(r.write([]byte("key AKIAIOSFODNN"))))
+	assert.Equal(t, "key ****\n", string(r.write([]byte("7EXAMPLE\nnext"))))
+	assert.Equal(t, "next", string(r.flush()))
+}
+
+func TestRedactingMultiWriter(t *testing.T) {
+	secrets := []string{}
+	mw := NewRedactingDocumentIOMultiWriter(func() []string { return secrets }, nil)
+	r, w := io.Pipe()
+	mw.AddWriter(w)
+	output := make(chan string)
+	go func() {
+		data, _ := ioutil.ReadAll(r)
+		mw.wg.Done()
+		output <- string(data)
+	}()
+
+	secrets = append(secrets, "s3cret")
+	mw.Write([]byte("the secret is s3c"))
+	mw.WriteString("ret")
+	mw.Close()
+
+	assert.Equal(t, "the secret is ****", <-output)
+	assert.False(t, strings.Contains(RedactedValue, "s3cret"))
+}
@@ -33,7 +33,7 @@ type Channel interface {
 }
 
 //find the folder named as "documentID" under the default root dir
-//if not found, create a new filechannel under the default root dir
+//if not found, create a new channel under the default root dir
 //return the channel and the found flag
 func CreateFileChannel(log log.T, mode Mode, filename string) (Channel, error, bool) {
 	instanceID, err := platform.InstanceID()
@@ -41,20 +41,35 @@ func CreateFileChannel(log log.T, mode Mode, filename string) (Channel, error, b
 		log.Errorf("failed to load instance ID: %v", err)
 		return nil, err, false
 	}
+	channelPath := path.Join(appconfig.DefaultDataStorePath, instanceID, defaultFileChannelPath, filename)
 	list, err := fileutil.ReadDir(path.Join(appconfig.DefaultDataStorePath, instanceID, defaultFileChannelPath))
 	if err != nil {
 		log.Infof("failed to read the default channel root directory: %v, creating a new Channel", err)
-		f, err := NewFileWatcherChannel(log, mode, path.Join(appconfig.DefaultDataStorePath, instanceID, defaultFileChannelPath, filename))
+		f, err := newChannel(log, mode, channelPath, false)
 		return f, err, false
 	}
 	for _, val := range list {
 		if val.Name() == filename {
 			log.Infof("channel: %v found", filename)
-			f, err := NewFileWatcherChannel(log, mode, path.Join(appconfig.DefaultDataStorePath, instanceID, defaultFileChannelPath, filename))
+			f, err := newChannel(log, mode, channelPath, true)
 			return f, err, true
 		}
 	}
-	log.Infof("channel: %v not found, creating a new file channel...", filename)
-	f, err := NewFileWatcherChannel(log, mode, path.Join(appconfig.DefaultDataStorePath, instanceID, defaultFileChannelPath, filename))
+	log.Infof("channel: %v not found, creating a new channel...", filename)
+	f, err := newChannel(log, mode, channelPath, false)
 	return f, err, false
 }
+
+//newChannel creates a socket channel where supported, unless the other end already uses a file channel,
+//and falls back to the file channel
+func newChannel(log log.T, mode Mode, channelPath string, found bool) (Channel, error) {
+	useSocket := socketExists(channelPath) || (mode == ModeMaster && !found)
+	if socketChannelSupported && useSocket {
+		ch, err := NewSocketChannel(log, mode, channelPath)
+		if err == nil {
+			return ch, nil
+		}
+		log.Warnf("failed to create socket channel %v: %v, falling back to file channel", channelPath, err)
+	}
+	return NewFileWatcherChannel(log, mode, channelPath)
+}
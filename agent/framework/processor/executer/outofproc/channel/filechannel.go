@@ -213,11 +213,16 @@ func (ch *fileWatcherChannel) consumeAll() {
 
 //TODO add unittest
 func (ch *fileWatcherChannel) isReadable(filename string) bool {
+	return isReadable(ch.mode, filename)
+}
+
+//isReadable returns true when the file is a datagram sent by the other end of a channel in the given mode
+func isReadable(mode Mode, filename string) bool {
 	matched, err := regexp.MatchString("[a-zA-Z]+-[0-9]+-[0-9]+", filename)
 	if !matched || err != nil {
 		return false
 	}
-	return !strings.Contains(filename, string(ch.mode)) && !strings.Contains(filename, "tmp")
+	return !strings.Contains(filename, string(mode)) && !strings.Contains(filename, "tmp")
 }
 
 //read and remove a given file
@@ -0,0 +1,458 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package channel
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// socketFileName is the name of the Unix domain socket in the channel directory
+	socketFileName = "ipc.sock"
+	// maxSocketPathLength is the longest socket path accepted by all the supported platforms
+	maxSocketPathLength = 103
+
+	frameHello byte = 1
+	frameData  byte = 2
+	frameAck   byte = 3
+
+	// frameHeaderSize is the size of the type, the sequence number and the length of a frame
+	frameHeaderSize = 1 + 8 + 4
+	// maxFrameSize is the size of the largest frame accepted
+	maxFrameSize = 64 * 1024 * 1024
+
+	socketWriteTimeout          = 10 * time.Second
+	minReconnectInterval        = 100 * time.Millisecond
+	maxReconnectInterval        = time.Second
+	closeFlushTimeout           = 10 * time.Second
+	closeFlushPollingInterval   = 10 * time.Millisecond
+	defaultSocketFileCreateMode = 0600
+)
+
+// frame is a message of the socket protocol.
+// A hello frame carries the last sequence number received and a payload of "<own session>/<peer session>",
+// a data frame carries a datagram and an ack frame the sequence number of the last datagram received.
+type frame struct {
+	kind     byte
+	sequence uint64
+	payload  []byte
+}
+
+// socketChannel is a Channel over a Unix domain socket in the channel directory, the master listens on it and
+// the worker connects to it. Datagrams are numbered and kept until the peer acknowledges them, so that they are
+// sent again when the worker reconnects after a master restart. The datagrams not acknowledged when the channel
+// is closed are written to the channel directory like the ones of the file channel, for the next channel to read.
+type socketChannel struct {
+	logger        log.T
+	mode          Mode
+	path          string
+	socketPath    string
+	session       string
+	listener      *net.UnixListener
+	onMessageChan chan string
+	done          chan struct{}
+
+	// mu protects the connection and the sequence state
+	mu          sync.Mutex
+	conn        *net.UnixConn
+	sent        uint64
+	pending     []frame
+	peerSession string
+	received    uint64
+	resync      bool
+	closed      bool
+
+	// deliverMu prevents closing onMessageChan while a datagram is delivered
+	deliverMu sync.RWMutex
+}
+
+// NewSocketChannel creates a socket channel in the directory name
+func NewSocketChannel(logger log.T, mode Mode, name string) (*socketChannel, error) {
+	socketPath := path.Join(name, socketFileName)
+	if len(socketPath) > maxSocketPathLength {
+		return nil, fmt.Errorf("socket path %v is longer than %v characters", socketPath, maxSocketPathLength)
+	}
+	if err := createIfNotExist(name); err != nil {
+		return nil, err
+	}
+	session, err := newSession()
+	if err != nil {
+		return nil, err
+	}
+	ch := &socketChannel{
+		logger:        logger,
+		mode:          mode,
+		path:          name,
+		socketPath:    socketPath,
+		session:       session,
+		onMessageChan: make(chan string, defaultChannelBufferSize),
+		done:          make(chan struct{}),
+	}
+	if mode == ModeMaster {
+		// a socket left by a previous master is replaced, its worker reconnects to the new one
+		os.Remove(socketPath)
+		address := &net.UnixAddr{Name: socketPath, Net: "unix"}
+		if ch.listener, err = net.ListenUnix("unix", address); err != nil {
+			return nil, err
+		}
+		// keep the socket when the master closes the channel, so that the next master knows its worker uses it
+		ch.listener.SetUnlinkOnClose(false)
+		if err = os.Chmod(socketPath, defaultSocketFileCreateMode); err != nil {
+			ch.listener.Close()
+			return nil, err
+		}
+	}
+	go func() {
+		// the datagrams spooled by a closed channel come before the ones sent over the socket
+		ch.consumeSpool()
+		if mode == ModeMaster {
+			ch.accept()
+		} else {
+			ch.connect()
+		}
+	}()
+	return ch, nil
+}
+
+// newSession returns a random identifier of the channel
+func newSession() (string, error) {
+	session := make([]byte, 8)
+	if _, err := rand.Read(session); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(session), nil
+}
+
+// socketExists returns true when the channel directory name holds the socket of a socket channel
+func socketExists(name string) bool {
+	info, err := os.Stat(path.Join(name, socketFileName))
+	return err == nil && info.Mode()&os.ModeSocket != 0
+}
+
+// Send queues a datagram until the peer acknowledges it, and sends it if the peer is connected
+func (ch *socketChannel) Send(rawJson string) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.closed {
+		return errors.New("channel already closed")
+	}
+	ch.sent++
+	data := frame{kind: frameData, sequence: ch.sent, payload: []byte(rawJson)}
+	ch.pending = append(ch.pending, data)
+	if ch.conn != nil {
+		ch.write(ch.conn, data)
+	}
+	return nil
+}
+
+func (ch *socketChannel) GetMessage() <-chan string {
+	return ch.onMessageChan
+}
+
+// Close waits for the peer to acknowledge the datagrams sent, spools the others to the channel directory,
+// and closes the socket
+func (ch *socketChannel) Close() {
+	ch.mu.Lock()
+	if ch.closed {
+		ch.mu.Unlock()
+		return
+	}
+	log := ch.logger
+	log.Infof("channel %v requested close", ch.path)
+	ch.closed = true
+	// the worker also waits for a restarting master to reconnect
+	deadline := time.Now().Add(closeFlushTimeout)
+	for len(ch.pending) > 0 && (ch.conn != nil || ch.mode == ModeWorker) && time.Now().Before(deadline) {
+		ch.mu.Unlock()
+		time.Sleep(closeFlushPollingInterval)
+		ch.mu.Lock()
+	}
+	ch.spool(ch.pending)
+	ch.pending = nil
+	if ch.conn != nil {
+		ch.conn.Close()
+		ch.conn = nil
+	}
+	ch.mu.Unlock()
+
+	close(ch.done)
+	if ch.listener != nil {
+		ch.listener.Close()
+	}
+	ch.deliverMu.Lock()
+	close(ch.onMessageChan)
+	ch.deliverMu.Unlock()
+	log.Infof("channel %v closed", ch.path)
+}
+
+func (ch *socketChannel) Destroy() {
+	ch.Close()
+	//only master can remove the dir at close
+	if ch.mode == ModeMaster {
+		ch.logger.Debug("master removing directory...")
+		if err := os.RemoveAll(ch.path); err != nil {
+			ch.logger.Errorf("failed to remove directory %v : %v", ch.path, err)
+		}
+	}
+}
+
+// accept serves the connections of the worker, a new connection replaces the previous one
+func (ch *socketChannel) accept() {
+	for {
+		conn, err := ch.listener.AcceptUnix()
+		if err != nil {
+			select {
+			case <-ch.done:
+			default:
+				ch.logger.Errorf("channel %v stopped accepting connections: %v", ch.path, err)
+			}
+			return
+		}
+		if err = verifyPeer(conn); err != nil {
+			ch.logger.Errorf("rejecting connection to channel %v: %v", ch.path, err)
+			conn.Close()
+			continue
+		}
+		go ch.serve(conn)
+	}
+}
+
+// connect connects to the master, and reconnects when the connection breaks until the channel is closed
+func (ch *socketChannel) connect() {
+	interval := minReconnectInterval
+	for {
+		select {
+		case <-ch.done:
+			return
+		default:
+		}
+		conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: ch.socketPath, Net: "unix"})
+		if err == nil {
+			if err = verifyPeer(conn); err == nil {
+				interval = minReconnectInterval
+				ch.serve(conn)
+				continue
+			}
+			ch.logger.Errorf("rejecting the master of channel %v: %v", ch.path, err)
+			conn.Close()
+		}
+		select {
+		case <-ch.done:
+			return
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > maxReconnectInterval {
+			interval = maxReconnectInterval
+		}
+	}
+}
+
+// serve uses conn until it breaks
+func (ch *socketChannel) serve(conn *net.UnixConn) {
+	ch.mu.Lock()
+	if ch.closed {
+		ch.mu.Unlock()
+		conn.Close()
+		return
+	}
+	if ch.conn != nil {
+		ch.conn.Close()
+	}
+	ch.conn = conn
+	ch.write(conn, frame{kind: frameHello, sequence: ch.received, payload: []byte(ch.session + "/" + ch.peerSession)})
+	ch.mu.Unlock()
+	ch.logger.Debugf("channel %v connected", ch.path)
+
+	reader := bufio.NewReader(conn)
+	for {
+		received, err := readFrame(reader)
+		if err != nil {
+			if err != io.EOF {
+				ch.logger.Debugf("channel %v connection broken: %v", ch.path, err)
+			}
+			break
+		}
+		ch.handle(conn, received)
+	}
+
+	ch.mu.Lock()
+	if ch.conn == conn {
+		ch.conn = nil
+	}
+	ch.mu.Unlock()
+	conn.Close()
+}
+
+// handle processes a frame received on conn
+func (ch *socketChannel) handle(conn *net.UnixConn, received frame) {
+	switch received.kind {
+	case frameHello:
+		sessions := strings.SplitN(string(received.payload), "/", 2)
+		peerSession, echoedSession := sessions[0], ""
+		if len(sessions) == 2 {
+			echoedSession = sessions[1]
+		}
+		ch.mu.Lock()
+		defer ch.mu.Unlock()
+		if peerSession != ch.peerSession {
+			// the datagrams of a new peer are numbered from the first one it sends again
+			ch.peerSession = peerSession
+			ch.resync = true
+		}
+		if echoedSession == ch.session {
+			ch.acknowledge(received.sequence)
+		}
+		for _, data := range ch.pending {
+			if ch.write(conn, data) != nil {
+				return
+			}
+		}
+	case frameData:
+		ch.mu.Lock()
+		if ch.resync {
+			ch.received = received.sequence - 1
+			ch.resync = false
+		}
+		if received.sequence != ch.received+1 {
+			// already delivered before the peer reconnected
+			ch.mu.Unlock()
+			return
+		}
+		ch.received = received.sequence
+		ch.write(conn, frame{kind: frameAck, sequence: received.sequence})
+		ch.mu.Unlock()
+		ch.deliver(string(received.payload))
+	case frameAck:
+		ch.mu.Lock()
+		ch.acknowledge(received.sequence)
+		ch.mu.Unlock()
+	}
+}
+
+// acknowledge drops the pending datagrams up to sequence, which the peer received
+func (ch *socketChannel) acknowledge(sequence uint64) {
+	i := 0
+	for i < len(ch.pending) && ch.pending[i].sequence <= sequence {
+		i++
+	}
+	ch.pending = ch.pending[i:]
+}
+
+// deliver pushes a datagram to the receiver, unless the channel is closed
+func (ch *socketChannel) deliver(datagram string) {
+	ch.deliverMu.RLock()
+	defer ch.deliverMu.RUnlock()
+	select {
+	case <-ch.done:
+		ch.logger.Errorf("channel %v closed, dropping datagram", ch.path)
+	case ch.onMessageChan <- datagram:
+	}
+}
+
+// write sends a frame on conn, and stops using the connection if it fails; the caller holds mu
+func (ch *socketChannel) write(conn *net.UnixConn, f frame) error {
+	buffer := make([]byte, frameHeaderSize+len(f.payload))
+	buffer[0] = f.kind
+	binary.BigEndian.PutUint64(buffer[1:9], f.sequence)
+	binary.BigEndian.PutUint32(buffer[9:13], uint32(len(f.payload)))
+	copy(buffer[frameHeaderSize:], f.payload)
+	conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+	if _, err := conn.Write(buffer); err != nil {
+		ch.logger.Debugf("channel %v failed to write: %v", ch.path, err)
+		// stop writing, but let the reader consume the acknowledgements already received before it closes conn
+		conn.SetReadDeadline(time.Now().Add(socketWriteTimeout))
+		if ch.conn == conn {
+			ch.conn = nil
+		}
+		return err
+	}
+	return nil
+}
+
+// readFrame reads the next frame
+func readFrame(reader io.Reader) (f frame, err error) {
+	header := make([]byte, frameHeaderSize)
+	if _, err = io.ReadFull(reader, header); err != nil {
+		return
+	}
+	f.kind = header[0]
+	f.sequence = binary.BigEndian.Uint64(header[1:9])
+	length := binary.BigEndian.Uint32(header[9:13])
+	if length > maxFrameSize {
+		return f, fmt.Errorf("frame of %v bytes exceeds the limit of %v bytes", length, maxFrameSize)
+	}
+	f.payload = make([]byte, length)
+	_, err = io.ReadFull(reader, f.payload)
+	return
+}
+
+// spool writes the datagrams to the channel directory, named like the ones of the file channel
+func (ch *socketChannel) spool(datagrams []frame) {
+	if len(datagrams) == 0 {
+		return
+	}
+	ch.logger.Infof("channel %v spooling %v datagrams not acknowledged", ch.path, len(datagrams))
+	tmpPath := path.Join(ch.path, "tmp")
+	if err := createIfNotExist(tmpPath); err != nil {
+		ch.logger.Errorf("failed to create directory: %v", err)
+		return
+	}
+	curTime := time.Now()
+	startTime := fmt.Sprintf("%04d%02d%02d%02d%02d%02d", curTime.Year(), curTime.Month(), curTime.Day(), curTime.Hour(), curTime.Minute(), curTime.Second())
+	for counter, data := range datagrams {
+		sequenceID := fmt.Sprintf("%v-%s-%03d", ch.mode, startTime, counter)
+		tmpFilePath := path.Join(tmpPath, sequenceID)
+		if err := ioutil.WriteFile(tmpFilePath, data.payload, defaultFileWriteMode); err != nil {
+			ch.logger.Errorf("write file %v encountered error: %v", tmpFilePath, err)
+			return
+		}
+		if err := os.Rename(tmpFilePath, path.Join(ch.path, sequenceID)); err != nil {
+			ch.logger.Errorf("spool renaming file encountered error: %v", err)
+			return
+		}
+	}
+}
+
+// consumeSpool delivers and removes the datagrams spooled by the peer, in order
+func (ch *socketChannel) consumeSpool() {
+	fileInfos, _ := ioutil.ReadDir(ch.path)
+	for _, info := range fileInfos {
+		if !isReadable(ch.mode, info.Name()) {
+			continue
+		}
+		filePath := path.Join(ch.path, info.Name())
+		buf, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			ch.logger.Errorf("message %v failed to read: %v", filePath, err)
+			continue
+		}
+		os.Remove(filePath)
+		ch.deliver(string(buf))
+	}
+}
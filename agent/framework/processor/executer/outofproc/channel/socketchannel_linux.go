@@ -0,0 +1,48 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package channel
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// socketChannelSupported is true on the platforms checking the credentials of the peer of a socket
+const socketChannelSupported = true
+
+// verifyPeer checks that the process at the other end of conn runs as root or as the agent's user
+func verifyPeer(conn *net.UnixConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var credentials *syscall.Ucred
+	var credentialsErr error
+	if err = raw.Control(func(fd uintptr) {
+		credentials, credentialsErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return err
+	}
+	if credentialsErr != nil {
+		return credentialsErr
+	}
+	if credentials.Uid != 0 && int(credentials.Uid) != os.Geteuid() {
+		return fmt.Errorf("peer process %v runs as uid %v", credentials.Pid, credentials.Uid)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package channel
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const socketReceiveTimeout = 5 * time.Second
+
+// newChannelDir creates the temporary directory of a socket channel, the caller removes it
+func newChannelDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "channel")
+	assert.NoError(t, err)
+	return dir
+}
+
+func receiveAll(t *testing.T, ch Channel, count int) []string {
+	var received []string
+	for len(received) < count {
+		select {
+		case datagram, more := <-ch.GetMessage():
+			if !more {
+				return received
+			}
+			received = append(received, datagram)
+		case <-time.After(socketReceiveTimeout):
+			t.Fatalf("received %v datagrams out of %v", len(received), count)
+		}
+	}
+	return received
+}
+
+func TestSocketChannelDuplexTransmission(t *testing.T) {
+	dir := newChannelDir(t)
+	defer os.RemoveAll(dir)
+	master, err := NewSocketChannel(log.NewMockLog(), ModeMaster, dir)
+	assert.NoError(t, err)
+	assert.True(t, socketExists(dir))
+	worker, err := NewSocketChannel(log.NewMockLog(), ModeWorker, dir)
+	assert.NoError(t, err)
+
+	for _, datagram := range []string{"m000", "m001", "m002"} {
+		assert.NoError(t, master.Send(datagram))
+	}
+	for _, datagram := range []string{"w000", "w001"} {
+		assert.NoError(t, worker.Send(datagram))
+	}
+	assert.Equal(t, []string{"m000", "m001", "m002"}, receiveAll(t, worker, 3))
+	assert.Equal(t, []string{"w000", "w001"}, receiveAll(t, master, 2))
+
+	worker.Close()
+	master.Destroy()
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSocketChannelMasterRestart(t *testing.T) {
+	dir := newChannelDir(t)
+	defer os.RemoveAll(dir)
+	master, err := NewSocketChannel(log.NewMockLog(), ModeMaster, dir)
+	assert.NoError(t, err)
+	worker, err := NewSocketChannel(log.NewMockLog(), ModeWorker, dir)
+	assert.NoError(t, err)
+
+	assert.NoError(t, worker.Send("w000"))
+	assert.Equal(t, []string{"w000"}, receiveAll(t, master, 1))
+	master.Close()
+	assert.True(t, socketExists(dir))
+
+	// sent while no master listens
+	assert.NoError(t, worker.Send("w001"))
+	assert.NoError(t, worker.Send("w002"))
+
+	restarted, err := NewSocketChannel(log.NewMockLog(), ModeMaster, dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"w001", "w002"}, receiveAll(t, restarted, 2))
+	assert.NoError(t, restarted.Send("m000"))
+	assert.Equal(t, []string{"m000"}, receiveAll(t, worker, 1))
+
+	worker.Close()
+	restarted.Destroy()
+}
+
+func TestSocketChannelSpoolsUnacknowledgedDatagrams(t *testing.T) {
+	dir := newChannelDir(t)
+	defer os.RemoveAll(dir)
+	master, err := NewSocketChannel(log.NewMockLog(), ModeMaster, dir)
+	assert.NoError(t, err)
+	assert.NoError(t, master.Send("m000"))
+	assert.NoError(t, master.Send("m001"))
+	master.Close()
+
+	worker, err := NewSocketChannel(log.NewMockLog(), ModeWorker, dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m000", "m001"}, receiveAll(t, worker, 2))
+	files, _ := ioutil.ReadDir(dir)
+	for _, file := range files {
+		assert.False(t, isReadable(ModeWorker, file.Name()), file.Name())
+	}
+	worker.Close()
+	assert.Error(t, worker.Send("w000"))
+}
+
+func TestSocketChannelPathTooLong(t *testing.T) {
+	parent := newChannelDir(t)
+	defer os.RemoveAll(parent)
+	dir := path.Join(parent, string(bytes.Repeat([]byte("a"), maxSocketPathLength)))
+	_, err := NewSocketChannel(log.NewMockLog(), ModeMaster, dir)
+	assert.Error(t, err)
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	dir := newChannelDir(t)
+	defer os.RemoveAll(dir)
+	master, err := NewSocketChannel(log.NewMockLog(), ModeMaster, dir)
+	assert.NoError(t, err)
+	defer master.Close()
+
+	var buffer bytes.Buffer
+	expected := frame{kind: frameData, sequence: 42, payload: []byte("payload")}
+	header := make([]byte, frameHeaderSize)
+	header[0] = expected.kind
+	header[8] = 42
+	header[12] = byte(len(expected.payload))
+	buffer.Write(header)
+	buffer.Write(expected.payload)
+	received, err := readFrame(&buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, received)
+
+	header[9] = 0xff
+	_, err = readFrame(bytes.NewReader(header))
+	assert.Error(t, err)
+}
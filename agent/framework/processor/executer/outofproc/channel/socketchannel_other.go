@@ -0,0 +1,29 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build !linux
+
+package channel
+
+import (
+	"errors"
+	"net"
+)
+
+// socketChannelSupported is false on the platforms where the credentials of the peer of a socket aren't checked
+const socketChannelSupported = false
+
+// verifyPeer rejects all the peers on the platforms without socket channel
+func verifyPeer(conn *net.UnixConn) error {
+	return errors.New("socket channel is not supported on this platform")
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/channel"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/messaging"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
+	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
@@ -25,6 +26,8 @@ const (
 	defaultZombieProcessTimeout = 3 * time.Second
 	//command maximum timeout
 	defaultOrphanProcessTimeout = 172800 * time.Second
+	//number of times a document worker that exited before completing the document is restarted
+	defaultWorkerRestartLimit = 3
 )
 
 type OutOfProcExecuter struct {
@@ -32,6 +35,9 @@ type OutOfProcExecuter struct {
 	docState   *contracts.DocumentState
 	ctx        context.T
 	cancelFlag task.CancelFlag
+	//workerExited is closed when the document worker is known to have exited
+	workerExited   chan struct{}
+	workerRestarts int
 }
 
 var channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
@@ -52,6 +58,13 @@ var processCreator = func(name string, argv []string) (proc.OSProcess, error) {
 	return proc.StartProcess(name, argv)
 }
 
+//processGroupKiller kills the processes left by an exited document worker, e.g. the commands its plugin was running
+var processGroupKiller = func(log log.T, procinfo contracts.OSProcInfo) {
+	if err := proc.KillProcessGroup(procinfo.Pid); err != nil {
+		log.Warnf("failed to kill process group of document worker %v: %v", procinfo.Pid, err)
+	}
+}
+
 func NewOutOfProcExecuter(ctx context.T) *OutOfProcExecuter {
 	return &OutOfProcExecuter{
 		BasicExecuter: *basicexecuter.NewBasicExecuter(ctx),
@@ -76,7 +89,10 @@ func (e *OutOfProcExecuter) Run(
 	stopTimer := make(chan bool, 1)
 	//start prepare messaging
 	//if anything fails during the prep stage, use in-proc Runner
+	span := e.ctx.Tracer().Start("startWorker", docState.DocumentInformation.TraceParent)
 	ipc, err := e.initialize(stopTimer)
+	span.RecordError(err)
+	span.End()
 	//save doc store immediately in case agent restarts.
 	docStore.Save(*e.docState)
 
@@ -97,7 +113,7 @@ func (e *OutOfProcExecuter) Run(
 				log.Info("Executer closed")
 				close(resChan)
 			}()
-			e.messaging(log, ipc, resChan, cancelFlag, stopTimer)
+			e.messaging(log, ipc, resChan, cancelFlag, stopTimer, store)
 		}(docStore)
 
 		return resChan
@@ -107,24 +123,73 @@ func (e *OutOfProcExecuter) Run(
 //Executer spins up an ipc transmission worker, it creates a Data processing backend and hands off the backend to the ipc worker
 //ipc worker and data backend act as 2 threads exchange raw json messages, and messaging protocol happened in data backend, data backend is self-contained and exit when command finishes accordingly
 //Executer however does hold a timer to the worker to forcefully termniate both of them
-func (e *OutOfProcExecuter) messaging(log log.T, ipc channel.Channel, resChan chan contracts.DocumentResult, cancelFlag task.CancelFlag, stopTimer chan bool) {
-
-	//handoff reply functionalities to data backend.
-	backend := messaging.NewExecuterBackend(resChan, e.docState, cancelFlag)
-	//handoff the data backend to messaging worker
-	if err := messaging.Messaging(log, ipc, backend, stopTimer); err != nil {
+//If the worker exits before completing the document, it's restarted with the document state, so that the plugins resume from their checkpoints
+func (e *OutOfProcExecuter) messaging(log log.T, ipc channel.Channel, resChan chan contracts.DocumentResult, cancelFlag task.CancelFlag, stopTimer chan bool, store executer.DocumentStore) {
+	for {
+		//handoff reply functionalities to data backend.
+		backend := messaging.NewExecuterBackend(resChan, e.docState, cancelFlag, store)
+		//handoff the data backend to messaging worker
+		err := messaging.Messaging(log, ipc, backend, stopTimer)
+		if err == nil {
+			return
+		}
 		//the messaging worker encountered error, either ipc run into error or data backend throws error
 		log.Errorf("messaging worker encountered error: %v", err)
-		if e.docState.DocumentInformation.DocumentStatus == contracts.ResultStatusInProgress ||
-			e.docState.DocumentInformation.DocumentStatus == "" ||
-			e.docState.DocumentInformation.DocumentStatus == contracts.ResultStatusNotStarted {
+		//destroy the channel
+		ipc.Destroy()
+		if e.canRestartWorker() {
+			e.workerRestarts++
+			log.Infof("document worker exited before completing the document, restarting it (attempt %v of %v)...", e.workerRestarts, defaultWorkerRestartLimit)
+			//the commands the exited worker started must not run along the ones of the new worker
+			processGroupKiller(log, e.docState.DocumentInformation.ProcInfo)
+			var restartErr error
+			if ipc, restartErr = e.initialize(stopTimer); restartErr == nil {
+				//save the process info of the new worker in case agent restarts
+				store.Save(*e.docState)
+				continue
+			}
+			log.Errorf("failed to restart document worker: %v", restartErr)
+		}
+		if e.isInProgress() {
 			e.docState.DocumentInformation.DocumentStatus = contracts.ResultStatusFailed
 			log.Info("document failed half way, sending fail message...")
 			resChan <- e.generateUnexpectedFailResult(fmt.Sprintf("document process failed unexpectedly: %s , check [ssm-document-worker]/[ssm-session-worker] log for crash reason", err))
 		}
-		//destroy the channel
-		ipc.Destroy()
+		return
+	}
+}
+
+func (e *OutOfProcExecuter) isInProgress() bool {
+	return e.docState.DocumentInformation.DocumentStatus == contracts.ResultStatusInProgress ||
+		e.docState.DocumentInformation.DocumentStatus == "" ||
+		e.docState.DocumentInformation.DocumentStatus == contracts.ResultStatusNotStarted
+}
+
+//canRestartWorker returns true when the document worker exited before completing the document, and the plugin it was running
+//can resume. sessions are not restarted since their plugins cannot resume
+func (e *OutOfProcExecuter) canRestartWorker() bool {
+	select {
+	case <-e.workerExited:
+	default:
+		return false
+	}
+	return e.isInProgress() &&
+		e.docState.DocumentType != contracts.StartSession &&
+		e.canResumeRunningPlugin() &&
+		e.workerRestarts < defaultWorkerRestartLimit &&
+		!e.cancelFlag.Canceled() &&
+		!e.cancelFlag.ShutDown()
+}
+
+//canResumeRunningPlugin returns true when the first plugin which hasn't completed recorded a checkpoint, or can run again from its start
+func (e *OutOfProcExecuter) canResumeRunningPlugin() bool {
+	for _, plugin := range e.docState.InstancePluginsInformation {
+		switch plugin.Result.Status {
+		case "", contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress, contracts.ResultStatusSuccessAndReboot:
+			return plugin.Checkpoint != "" || runpluginutil.IsPluginResumable(plugin.Name)
+		}
 	}
+	return false
 }
 
 func (e *OutOfProcExecuter) generateUnexpectedFailResult(errMsg string) contracts.DocumentResult {
@@ -150,6 +215,7 @@ func (e *OutOfProcExecuter) initialize(stopTimer chan bool) (ipc channel.Channel
 	var found bool
 	documentID := e.docState.DocumentInformation.DocumentID
 	instanceID := e.docState.DocumentInformation.InstanceID
+	e.workerExited = make(chan struct{})
 	ipc, err, found = channelCreator(log, channel.ModeMaster, documentID)
 
 	if err != nil {
@@ -165,6 +231,7 @@ func (e *OutOfProcExecuter) initialize(stopTimer chan bool) (ipc channel.Channel
 			stopTime = defaultOrphanProcessTimeout
 		} else {
 			log.Infof("process: %v not found, treat as exited", procInfo.Pid)
+			close(e.workerExited)
 			stopTime = defaultZombieProcessTimeout
 		}
 		go timeout(stopTimer, stopTime, e.cancelFlag)
@@ -217,6 +284,7 @@ func (e *OutOfProcExecuter) WaitForProcess(stopTimer chan bool, process proc.OSP
 		log.Debugf("process: %v exited successfully, trying to stop messaging worker", process.Pid())
 	}
 	//waitReturned = true
+	close(e.workerExited)
 	timeout(stopTimer, defaultZombieProcessTimeout, e.cancelFlag)
 }
 
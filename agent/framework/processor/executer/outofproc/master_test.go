@@ -1,23 +1,30 @@
 package outofproc
 
 import (
+	"io/ioutil"
+	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	executermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/mock"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/channel"
 	channelmock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/channel/mock"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/messaging"
 	procmock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc/mock"
 
 	"errors"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
+	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 type TestCase struct {
@@ -42,6 +49,8 @@ var testPid = 100
 
 var logger = log.NewMockLog()
 
+var defaultProcessGroupKiller = processGroupKiller
+
 func CreateTestCase() *TestCase {
 	contextMock := context.NewMockDefaultWithContext([]string{"MASTER"})
 	docStore := new(executermocks.MockDocumentStore)
@@ -328,6 +337,227 @@ func TestInitializeConnectOldOrphan(t *testing.T) {
 
 //TODO add Run() unittest
 
+func TestMessagingRestartsExitedWorker(t *testing.T) {
+	testCase := CreateTestCase()
+	testCase.docState.InstancePluginsInformation[0].Checkpoint = "step-2"
+	testCase.docState.DocumentInformation.ProcInfo = contracts.OSProcInfo{Pid: testPid - 1}
+	var killedProcInfo contracts.OSProcInfo
+	processGroupKiller = func(log log.T, procinfo contracts.OSProcInfo) { killedProcInfo = procinfo }
+	defer func() { processGroupKiller = defaultProcessGroupKiller }()
+	cancel := task.NewChanneledCancelFlag()
+	defer cancel.Set(task.Completed)
+	exe := &OutOfProcExecuter{
+		ctx:          testCase.context,
+		docState:     &testCase.docState,
+		cancelFlag:   cancel,
+		workerExited: make(chan struct{}),
+	}
+	//the first worker exited before completing the document
+	close(exe.workerExited)
+	stopTimer := make(chan bool, 1)
+	stopTimer <- true
+	exitedChannel := new(channelmock.MockedChannel)
+	exitedMessages := make(chan string)
+	exitedChannel.On("Send", mock.Anything).Return(nil)
+	exitedChannel.On("GetMessage").Return(exitedMessages)
+	exitedChannel.On("Close").Run(func(mock.Arguments) { close(exitedMessages) }).Return()
+	exitedChannel.On("Destroy").Return()
+
+	//the restarted worker receives the document state along with the checkpoints, and completes the document
+	restartedChannel := new(channelmock.MockedChannel)
+	restartedMessages := make(chan string, 1)
+	restartedChannel.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+		assert.Contains(t, args.String(0), "step-2")
+		completeMessage, _ := messaging.CreateDatagram(messaging.MessageTypeComplete, contracts.DocumentResult{
+			Status:        contracts.ResultStatusSuccess,
+			PluginResults: testCase.results,
+		})
+		restartedMessages <- completeMessage
+	}).Return(nil).Once()
+	restartedChannel.On("GetMessage").Return(restartedMessages)
+	restartedChannel.On("Destroy").Return()
+	channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
+		return restartedChannel, nil, false
+	}
+	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
+		return testCase.processMock, nil
+	}
+	testCase.processMock.On("Wait").Run(func(mock.Arguments) { cancel.Wait() }).Return(nil)
+	testCase.processMock.On("Pid").Return(testPid)
+	testCase.processMock.On("StartTime").Return(testStartDateTime)
+	testCase.docStore.On("Save", mock.Anything).Return()
+
+	resChan := make(chan contracts.DocumentResult, 3)
+	exe.messaging(logger, exitedChannel, resChan, cancel, stopTimer, testCase.docStore)
+
+	assert.Equal(t, 1, exe.workerRestarts)
+	//the processes of the exited worker were killed before starting the new one
+	assert.Equal(t, testPid-1, killedProcInfo.Pid)
+	assert.Equal(t, testPid, exe.docState.DocumentInformation.ProcInfo.Pid)
+	res := <-resChan
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Status)
+	exitedChannel.AssertCalled(t, "Destroy")
+	restartedChannel.AssertExpectations(t)
+	testCase.docStore.AssertExpectations(t)
+}
+
+func TestCanRestartWorker(t *testing.T) {
+	testCase := CreateTestCase()
+	cancel := task.NewChanneledCancelFlag()
+	exe := &OutOfProcExecuter{
+		ctx:          testCase.context,
+		docState:     &testCase.docState,
+		cancelFlag:   cancel,
+		workerExited: make(chan struct{}),
+	}
+	testCase.docState.InstancePluginsInformation[0].Checkpoint = "step-2"
+	//the worker is still running
+	assert.False(t, exe.canRestartWorker())
+	close(exe.workerExited)
+	assert.True(t, exe.canRestartWorker())
+	exe.workerRestarts = defaultWorkerRestartLimit
+	assert.False(t, exe.canRestartWorker())
+	exe.workerRestarts = 0
+	testCase.docState.DocumentInformation.DocumentStatus = contracts.ResultStatusFailed
+	assert.False(t, exe.canRestartWorker())
+	testCase.docState.DocumentInformation.DocumentStatus = contracts.ResultStatusInProgress
+	cancel.Set(task.Canceled)
+	assert.False(t, exe.canRestartWorker())
+}
+
+func TestCanResumeRunningPlugin(t *testing.T) {
+	testCase := CreateTestCase()
+	exe := &OutOfProcExecuter{
+		ctx:      testCase.context,
+		docState: &testCase.docState,
+	}
+	//the running plugin neither recorded a checkpoint nor can run again from its start
+	assert.False(t, exe.canResumeRunningPlugin())
+	testCase.docState.InstancePluginsInformation[0].Checkpoint = "step-2"
+	assert.True(t, exe.canResumeRunningPlugin())
+	//the checkpoint of a completed plugin doesn't matter
+	testCase.docState.InstancePluginsInformation[0].Result.Status = contracts.ResultStatusSuccess
+	assert.False(t, exe.canResumeRunningPlugin())
+	testCase.docState.InstancePluginsInformation[1].Name = appconfig.PluginNameAwsSoftwareInventory
+	assert.True(t, exe.canResumeRunningPlugin())
+}
+
+//TestRunResumesPluginAfterWorkerRestart runs a document whose worker exits after its plugin recorded a checkpoint,
+//the restarted worker runs the plugin again from the checkpoint
+func TestRunResumesPluginAfterWorkerRestart(t *testing.T) {
+	testCase := CreateTestCase()
+	testCase.docState.InstancePluginsInformation = []contracts.PluginState{{Name: "acme:deployService", Id: "plugin1"}}
+	orchestrationDirectory, err := ioutil.TempDir("", "outofproc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDirectory)
+	testCase.docState.IOConfig.OrchestrationDirectory = orchestrationDirectory
+	plugin := &checkpointPlugin{crashed: make(chan struct{})}
+	defer close(plugin.crashed)
+	registry := runpluginutil.PluginRegistry{"acme:deployService": checkpointPluginFactory{plugin: plugin}}
+	channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
+		return channelmock.NewFakeChannel(logger, mode, documentID), nil, false
+	}
+	var workers []*fakeWorker
+	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
+		worker := startFakeWorker(argv[0], registry)
+		workers = append(workers, worker)
+		return worker, nil
+	}
+	processGroupKiller = func(log log.T, procinfo contracts.OSProcInfo) {}
+	defer func() { processGroupKiller = defaultProcessGroupKiller }()
+	checkpointSaved := make(chan struct{})
+	var once sync.Once
+	testCase.docStore.On("Load").Return(testCase.docState)
+	testCase.docStore.On("Save", mock.Anything).Run(func(args mock.Arguments) {
+		if args.Get(0).(contracts.DocumentState).InstancePluginsInformation[0].Checkpoint == "step-2" {
+			once.Do(func() { close(checkpointSaved) })
+		}
+	}).Return()
+	cancelFlag := task.NewChanneledCancelFlag()
+	defer cancelFlag.Set(task.Completed)
+
+	resChan := NewOutOfProcExecuter(testCase.context).Run(cancelFlag, testCase.docStore)
+	//the first worker exits once the checkpoint of its plugin is persisted
+	<-checkpointSaved
+	workers[0].ipc.Close()
+
+	var res contracts.DocumentResult
+	for res = range resChan {
+	}
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, res.PluginResults["plugin1"].Status)
+	assert.Equal(t, "step-2", res.PluginResults["plugin1"].ResumedFromCheckpoint)
+	assert.Equal(t, "step-2", plugin.resumedFrom)
+	assert.Len(t, workers, 2)
+}
+
+//checkpointPlugin records a checkpoint and hangs until its worker exits, it completes when it runs again from the checkpoint
+type checkpointPlugin struct {
+	crashed     chan struct{}
+	resumedFrom string
+}
+
+func (p *checkpointPlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	if checkpoint := output.GetCheckpoint(); checkpoint != "" {
+		p.resumedFrom = checkpoint
+		output.MarkAsSucceeded()
+		return
+	}
+	output.SetCheckpoint("step-2")
+	<-p.crashed
+}
+
+type checkpointPluginFactory struct {
+	plugin *checkpointPlugin
+}
+
+func (f checkpointPluginFactory) Create(context context.T) (runpluginutil.T, error) {
+	return f.plugin, nil
+}
+
+func (f checkpointPluginFactory) IsExternal() bool {
+	return true
+}
+
+//fakeWorker runs the plugins of the document in the same process as the document worker would, it exits when its channel is closed
+type fakeWorker struct {
+	ipc    channel.Channel
+	exited chan struct{}
+}
+
+func startFakeWorker(documentID string, registry runpluginutil.PluginRegistry) *fakeWorker {
+	worker := &fakeWorker{
+		ipc:    channelmock.NewFakeChannel(logger, channel.ModeWorker, documentID),
+		exited: make(chan struct{}),
+	}
+	backend := messaging.NewWorkerBackend(context.NewMockDefault(), func(context context.T, docState contracts.DocumentState, resChan chan contracts.PluginResult, cancelFlag task.CancelFlag) {
+		runpluginutil.RunPlugins(context, docState.InstancePluginsInformation, docState.IOConfig, registry, resChan, cancelFlag)
+		close(resChan)
+	})
+	go func() {
+		messaging.Messaging(logger, worker.ipc, backend, make(chan bool))
+		close(worker.exited)
+	}()
+	return worker
+}
+
+func (w *fakeWorker) Pid() int {
+	return testPid
+}
+
+func (w *fakeWorker) StartTime() time.Time {
+	return testStartDateTime
+}
+
+func (w *fakeWorker) Kill() error {
+	return nil
+}
+
+func (w *fakeWorker) Wait() error {
+	<-w.exited
+	return nil
+}
+
 //this is needed, since after marshal-unmarshalling thru the data channel, the pointer value changed
 func assertValueEqual(t *testing.T, a map[string]*contracts.PluginResult, b map[string]*contracts.PluginResult) {
 	assert.Equal(t, len(a), len(b))
@@ -2,13 +2,16 @@ package messaging
 
 import (
 	"errors"
+	"fmt"
 
 	"sync"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/tracing"
 )
 
 const (
@@ -41,9 +44,11 @@ type ExecuterBackend struct {
 	cancelFlag task.CancelFlag
 	output     chan contracts.DocumentResult
 	stopChan   chan int
+	//docStore persists the checkpoints of the plugins as soon as they're received
+	docStore executer.DocumentStore
 }
 
-func NewExecuterBackend(output chan contracts.DocumentResult, docState *contracts.DocumentState, cancelFlag task.CancelFlag) *ExecuterBackend {
+func NewExecuterBackend(output chan contracts.DocumentResult, docState *contracts.DocumentState, cancelFlag task.CancelFlag, docStore executer.DocumentStore) *ExecuterBackend {
 	stopChan := make(chan int, defaultBackendChannelSize)
 	inputChan := make(chan string, defaultBackendChannelSize)
 	p := ExecuterBackend{
@@ -52,13 +57,14 @@ func NewExecuterBackend(output chan contracts.DocumentResult, docState *contract
 		input:      inputChan,
 		cancelFlag: cancelFlag,
 		stopChan:   stopChan,
+		docStore:   docStore,
 	}
 	go p.start(*docState)
 	return &p
 }
 
 func (p *ExecuterBackend) start(docState contracts.DocumentState) {
-	startDatagram, _ := CreateDatagram(MessageTypePluginConfig, docState)
+	startDatagram, _ := CreateTracedDatagram(MessageTypePluginConfig, docState, docState.DocumentInformation.TraceParent)
 	p.input <- startDatagram
 	p.cancelFlag.Wait()
 	if p.cancelFlag.Canceled() {
@@ -97,6 +103,18 @@ func (p *ExecuterBackend) Process(datagram string) error {
 			//get document result, force termniate messaging worker
 			p.stopChan <- stopTypeTerminate
 		}
+	case MessageTypeCheckpoint:
+		var checkpoint contracts.PluginCheckpoint
+		if err := jsonutil.Unmarshal(content, &checkpoint); err != nil {
+			return err
+		}
+		if !contracts.UpdateCheckpoint(checkpoint, p.docState) {
+			return fmt.Errorf("received checkpoint of unknown plugin %v", checkpoint.PluginID)
+		}
+		//persist the checkpoint right away, so that the plugin resumes from it if the worker or the agent restarts
+		if p.docStore != nil {
+			p.docStore.Save(*p.docState)
+		}
 	default:
 		return errors.New("unsupported message type")
 	}
@@ -126,7 +144,8 @@ func NewWorkerBackend(ctx context.T, runner PluginRunner) *WorkerBackend {
 }
 
 func (p *WorkerBackend) Process(datagram string) error {
-	t, content := ParseDatagram(datagram)
+	message := parseMessage(datagram)
+	t, content := message.Type, message.Content
 	log := p.ctx.Log()
 	switch t {
 	case MessageTypePluginConfig:
@@ -139,9 +158,18 @@ func (p *WorkerBackend) Process(datagram string) error {
 			return err
 		}
 		p.once.Do(func() {
+			// the plugins run under the span of the worker, which joins the trace of the master
+			span := p.ctx.Tracer().Start("runPlugins", message.TraceParent)
+			span.SetAttribute("document.id", docState.DocumentInformation.DocumentID)
+			if traceParent := span.TraceParent(); traceParent != "" {
+				for i := range docState.InstancePluginsInformation {
+					docState.InstancePluginsInformation[i].Configuration.TraceParent = traceParent
+				}
+			}
+			docState.IOConfig.CheckpointRecorder = p.sendCheckpoint
 			statusChan := make(chan contracts.PluginResult)
 			go p.runner(p.ctx, docState, statusChan, p.cancelFlag)
-			go p.pluginListener(statusChan)
+			go p.pluginListener(statusChan, span)
 		})
 
 	case MessageTypeCancel:
@@ -154,7 +182,19 @@ func (p *WorkerBackend) Process(datagram string) error {
 	return nil
 }
 
-func (p *WorkerBackend) pluginListener(statusChan chan contracts.PluginResult) {
+//sendCheckpoint forwards the checkpoint of a plugin to the master, which persists it in the document state
+func (p *WorkerBackend) sendCheckpoint(checkpoint contracts.PluginCheckpoint) {
+	log := p.ctx.Log()
+	checkpointMessage, err := CreateDatagram(MessageTypeCheckpoint, checkpoint)
+	if err != nil {
+		log.Errorf("failed to create checkpoint message: %v", err)
+		return
+	}
+	log.Debugf("plugin: %v reached checkpoint, sending checkpoint message...", checkpoint.PluginID)
+	p.input <- checkpointMessage
+}
+
+func (p *WorkerBackend) pluginListener(statusChan chan contracts.PluginResult, span *tracing.Span) {
 	log := p.ctx.Log()
 	results := make(map[string]*contracts.PluginResult)
 	var finalStatus contracts.ResultStatus
@@ -170,6 +210,8 @@ func (p *WorkerBackend) pluginListener(statusChan chan contracts.PluginResult) {
 			PluginResults: results,
 			LastPlugin:    "",
 		}
+		span.SetAttribute("document.status", string(finalStatus))
+		span.End()
 		log.Info("sending document complete response...")
 		completeMessage, _ := CreateDatagram(MessageTypeComplete, docResult)
 		p.input <- completeMessage
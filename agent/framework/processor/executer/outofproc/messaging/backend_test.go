@@ -5,11 +5,14 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	executermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/mock"
 
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 var contextMock = context.NewMockDefault()
@@ -189,6 +192,51 @@ func TestExecuterBackend_ProcessUnsupportedVersion(t *testing.T) {
 	logger.Info(err)
 }
 
+func TestExecuterBackend_ProcessCheckpoint(t *testing.T) {
+	testCase := CreateTestCase()
+	docStore := new(executermocks.MockDocumentStore)
+	docStore.On("Save", mock.Anything).Return()
+	backend := ExecuterBackend{
+		cancelFlag: task.NewMockDefault(),
+		docState:   &testCase.docState,
+		docStore:   docStore,
+	}
+	checkpointDatagram, _ := CreateDatagram(MessageTypeCheckpoint, contracts.PluginCheckpoint{PluginID: "plugin2", Checkpoint: "step-3"})
+	assert.NoError(t, backend.Process(checkpointDatagram))
+	assert.Equal(t, "", testCase.docState.InstancePluginsInformation[0].Checkpoint)
+	assert.Equal(t, "step-3", testCase.docState.InstancePluginsInformation[1].Checkpoint)
+	docStore.AssertCalled(t, "Save", testCase.docState)
+
+	unknownDatagram, _ := CreateDatagram(MessageTypeCheckpoint, contracts.PluginCheckpoint{PluginID: "plugin3", Checkpoint: "step-1"})
+	assert.Error(t, backend.Process(unknownDatagram))
+	docStore.AssertNumberOfCalls(t, "Save", 1)
+}
+
+func TestWorkerBackend_SendCheckpoint(t *testing.T) {
+	inputChan := make(chan string)
+	pluginRunner := func(
+		context context.T,
+		docState contracts.DocumentState,
+		resChan chan contracts.PluginResult,
+		cancelFlag task.CancelFlag,
+	) {
+		docState.IOConfig.CheckpointRecorder(contracts.PluginCheckpoint{PluginID: "aws:runScript", Checkpoint: "step-1"})
+	}
+	backend := WorkerBackend{
+		ctx:        contextMock,
+		input:      inputChan,
+		cancelFlag: task.NewChanneledCancelFlag(),
+		runner:     pluginRunner,
+		stopChan:   make(chan int, 1),
+	}
+	backend.Process(testPluginsRawJSON)
+	messageType, content := ParseDatagram(<-inputChan)
+	assert.EqualValues(t, MessageTypeCheckpoint, messageType)
+	var checkpoint contracts.PluginCheckpoint
+	assert.NoError(t, jsonutil.Unmarshal(content, &checkpoint))
+	assert.Equal(t, contracts.PluginCheckpoint{PluginID: "aws:runScript", Checkpoint: "step-1"}, checkpoint)
+}
+
 func TestWorkerBackend_ProcessCancelV1(t *testing.T) {
 	_ = CreateTestCase()
 	inputChan := make(chan string, 10)
@@ -237,7 +285,7 @@ func TestWorkerBackendPluginListener(t *testing.T) {
 		input:    inputChan,
 		stopChan: stopChan,
 	}
-	go backend.pluginListener(statusChan)
+	go backend.pluginListener(statusChan, nil)
 	statusChan <- *testCase.results["plugin1"]
 	data := <-inputChan
 	//cannot assume string equal, unmarshal sometimes switch map's order
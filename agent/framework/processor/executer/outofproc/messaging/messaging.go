@@ -21,6 +21,7 @@ const (
 	MessageTypeComplete     = "complete"
 	MessageTypeReply        = "reply"
 	MessageTypeCancel       = "cancel"
+	MessageTypeCheckpoint   = "checkpoint"
 )
 
 var versions = []string{"1.0"}
@@ -29,6 +30,8 @@ type Message struct {
 	Version string      `json:"version"`
 	Type    MessageType `json:"type"`
 	Content string      `json:"content"`
+	// TraceParent propagates the trace context of the master to the worker, in the W3C traceparent format
+	TraceParent string `json:"traceparent,omitempty"`
 }
 
 //MessagingBackend defines an asycn message in/out processing pipeline
@@ -50,6 +53,11 @@ func GetLatestVersion() string {
 //Message schema is determined by the current version, content struct is indicated by type field
 //TODO add version handling
 func CreateDatagram(t MessageType, content interface{}) (string, error) {
+	return CreateTracedDatagram(t, content, "")
+}
+
+//CreateTracedDatagram marshals a given arbitrary object to raw json string, along with the trace context of the sender
+func CreateTracedDatagram(t MessageType, content interface{}, traceParent string) (string, error) {
 	contentStr, err := jsonutil.Marshal(content)
 	if err != nil {
 		return "", err
@@ -57,7 +65,8 @@ func CreateDatagram(t MessageType, content interface{}) (string, error) {
 	message := Message{
 		Version: GetLatestVersion(),
 		Type:    t,
-		Content: contentStr,
+		Content:     contentStr,
+		TraceParent: traceParent,
 	}
 	datagram, err := jsonutil.Marshal(message)
 	if err != nil {
@@ -68,9 +77,14 @@ func CreateDatagram(t MessageType, content interface{}) (string, error) {
 
 //TODO add version and error handling
 func ParseDatagram(datagram string) (MessageType, string) {
+	message := parseMessage(datagram)
+	return message.Type, message.Content
+}
+
+func parseMessage(datagram string) Message {
 	message := Message{}
 	jsonutil.Unmarshal(datagram, &message)
-	return message.Type, message.Content
+	return message
 }
 
 // Messaging implements the duplex transmission between master and worker, it send datagram it received to data backend,
@@ -5,6 +5,7 @@ import (
 
 	channelmock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/channel/mock"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -72,6 +73,20 @@ func TestMessagingShutdown(t *testing.T) {
 	backendMock.AssertExpectations(t)
 }
 
+func TestCreateTracedDatagram(t *testing.T) {
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	datagram, err := CreateTracedDatagram(MessageTypePluginConfig, "content", traceParent)
+	assert.NoError(t, err)
+
+	message := parseMessage(datagram)
+	assert.Equal(t, MessageType(MessageTypePluginConfig), message.Type)
+	assert.Equal(t, traceParent, message.TraceParent)
+
+	datagram, err = CreateDatagram(MessageTypeCancel, "cancel")
+	assert.NoError(t, err)
+	assert.NotContains(t, datagram, "traceparent")
+}
+
 type BackendMock struct {
 	mock.Mock
 }
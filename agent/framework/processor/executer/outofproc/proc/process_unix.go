@@ -17,6 +17,7 @@
 package proc
 
 import (
+	"fmt"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -36,6 +37,19 @@ func prepareProcess(command *exec.Cmd) {
 	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 }
 
+//KillProcessGroup kills the process group led by the document worker with the given pid, which holds the processes its plugins started
+//the group is gone already when none of its processes is running anymore
+func KillProcessGroup(pid int) error {
+	//-0 and -1 would kill the group of the agent and every process it can signal
+	if pid <= 1 {
+		return fmt.Errorf("invalid process group: %v", pid)
+	}
+	if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	return nil
+}
+
 //given the pid and the unix process startTime format string, return whether the process is still alive
 func find_process(pid int, startTime time.Time) (bool, error) {
 	output, err := ps()
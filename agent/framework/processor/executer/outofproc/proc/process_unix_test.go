@@ -21,6 +21,7 @@ import (
 
 	"time"
 
+	"io/ioutil"
 	"os/exec"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -73,3 +74,22 @@ func TestCompareTime(t *testing.T) {
 	testTime := time.Date(2017, 8, 4, 11, 39, 23, 10000, time.UTC)
 	assert.True(t, compareTimes(testTime, testInput))
 }
+
+func TestKillProcessGroup(t *testing.T) {
+	//the child the worker started holds the standard output of the worker until it's killed too
+	worker := exec.Command("sh", "-c", "sleep 30 & wait")
+	prepareProcess(worker)
+	stdout, err := worker.StdoutPipe()
+	assert.NoError(t, err)
+	assert.NoError(t, worker.Start())
+	start := time.Now()
+
+	assert.NoError(t, KillProcessGroup(worker.Process.Pid))
+
+	_, err = ioutil.ReadAll(stdout)
+	assert.NoError(t, err)
+	assert.Error(t, worker.Wait())
+	assert.True(t, time.Since(start) < 10*time.Second)
+	assert.Error(t, KillProcessGroup(0))
+	assert.Error(t, KillProcessGroup(1))
+}
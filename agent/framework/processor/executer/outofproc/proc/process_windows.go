@@ -33,6 +33,12 @@ func prepareProcess(command *exec.Cmd) {
 	// nothing to do on windows
 }
 
+//KillProcessGroup does nothing on windows, which doesn't group the processes the document worker started,
+//and may have reused the pid of the exited worker already
+func KillProcessGroup(pid int) error {
+	return nil
+}
+
 //given the pid and the high order filetime, look up the process
 func find_process(pid int, startTime time.Time) (bool, error) {
 	const da = syscall.STANDARD_RIGHTS_READ |
@@ -58,6 +58,10 @@ func initialize(args []string) (context.T, string, error) {
 
 	// initialize appconfig, use default config
 	config := appconfig.DefaultConfig()
+	// the spans of the worker are exported the same way as the spans of the agent
+	if agentConfig, err := appconfig.Config(false); err == nil {
+		config.Tracing = agentConfig.Tracing
+	}
 
 	logger.Debugf("Session worker parse args: %v", args)
 	channelName, _, err := proc.ParseArgv(args)
@@ -85,6 +89,8 @@ func main() {
 	}
 
 	createFileChannelAndExecutePlugin(context, channelName)
+	//ensure spans are flushed
+	context.Tracer().Shutdown()
 	log.Info("Session worker closed")
 }
 
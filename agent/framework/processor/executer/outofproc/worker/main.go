@@ -42,6 +42,10 @@ func initialize(args []string) (context.T, string, error) {
 	logger := ssmlog.SSMLogger(false)
 	// initialize appconfig, use default config
 	config := appconfig.DefaultConfig()
+	// the spans of the worker are exported the same way as the spans of the agent
+	if agentConfig, err := appconfig.Config(false); err == nil {
+		config.Tracing = agentConfig.Tracing
+	}
 	logger.Infof("parsing args: %v", args)
 	channelName, instanceID, err := proc.ParseArgv(args)
 	logger.Infof("using channelName %v, instanceID: %v", channelName, instanceID)
@@ -87,7 +91,8 @@ func main() {
 		return
 	}
 	logger.Info("document worker closed")
-	//ensure logs are flushed
+	//ensure spans and logs are flushed
+	ctx.Tracer().Shutdown()
 	logger.Close()
 	//TODO figure out s3 aync problem
 	//TODO figure out why defer main doesnt work on windows
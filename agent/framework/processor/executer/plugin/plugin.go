@@ -26,6 +26,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/dockercontainer"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/externalplugin"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/lrpminvoker"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/refreshassociation"
@@ -135,6 +136,22 @@ func (r RunDocumentFactory) Create(context context.T) (runpluginutil.T, error) {
 	return rundocument.NewPlugin()
 }
 
+// ExternalPluginFactory creates the plugins running the executables of the external plugin directory
+type ExternalPluginFactory struct {
+	name       string
+	directory  string
+	publicKeys []string
+}
+
+func (f ExternalPluginFactory) Create(context context.T) (runpluginutil.T, error) {
+	return externalplugin.NewPlugin(f.name, f.directory, f.publicKeys)
+}
+
+// IsExternal returns true, external plugins are known to the agent without being in allPlugins
+func (f ExternalPluginFactory) IsExternal() bool {
+	return true
+}
+
 type SessionPluginFactory struct {
 	newPluginFunc sessionplugin.NewPluginFunc
 }
@@ -186,9 +203,33 @@ func loadWorkers(context context.T) {
 		context.Log().Infof("Successfully loaded platform dependent plugin %v", key)
 	}
 
+	for key, value := range loadExternalPlugins(context) {
+		if _, found := plugins[key]; found {
+			context.Log().Warnf("Ignoring external plugin %v, which has the name of a built-in plugin", key)
+			continue
+		}
+		plugins[key] = value
+		context.Log().Infof("Successfully loaded external plugin %v", key)
+	}
+
 	registeredPlugins = &plugins
 }
 
+// loadExternalPlugins registers the out-of-tree plugins installed in the external plugin directory
+func loadExternalPlugins(context context.T) runpluginutil.PluginRegistry {
+	var externalPlugins = runpluginutil.PluginRegistry{}
+
+	config := context.AppConfig().Ssm
+	for _, name := range externalplugin.Discover(context.Log(), config.ExternalPluginDirectory) {
+		externalPlugins[name] = ExternalPluginFactory{
+			name:       name,
+			directory:  config.ExternalPluginDirectory,
+			publicKeys: config.ExternalPluginPublicKeys,
+		}
+	}
+	return externalPlugins
+}
+
 // loadSessionPlugins loads all session plugins
 func loadSessionPlugins() {
 	var sessionPlugins = runpluginutil.PluginRegistry{}
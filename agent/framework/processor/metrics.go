@@ -0,0 +1,74 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor defines the document processing unit interface
+package processor
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
+)
+
+const (
+	sendCommandPoolName   = "send"
+	cancelCommandPoolName = "cancel"
+)
+
+// poolJobs returns the gauge of the jobs queued or running in the named pool for the document type
+func poolJobs(context context.T, pool string, documentType contracts.DocumentType) *metrics.Gauge {
+	return context.Metrics().Gauge(metrics.TaskPoolJobs,
+		"Jobs queued or running in the task pools of the document processors.",
+		metrics.Labels{"pool": pool, "document_type": string(documentType)})
+}
+
+// openSessions returns the gauge of the sessions open on the instance if the document starts a session, nil otherwise.
+// Sessions run in the session worker, so they are counted here for the duration of the document instead.
+func openSessions(context context.T, docState *contracts.DocumentState) *metrics.Gauge {
+	if docState.DocumentType != contracts.StartSession {
+		return nil
+	}
+	var sessionType string
+	if len(docState.InstancePluginsInformation) > 0 {
+		sessionType = docState.InstancePluginsInformation[0].Name
+	}
+	return context.Metrics().Gauge(metrics.Sessions, "Sessions currently open.", metrics.Labels{"session_type": sessionType})
+}
+
+// recordSubmitted counts a document submitted to the processor
+func recordSubmitted(context context.T, documentType contracts.DocumentType) {
+	context.Metrics().Counter(metrics.DocumentsSubmitted,
+		"Documents submitted to the document processors.",
+		metrics.Labels{"document_type": string(documentType)}).Inc()
+}
+
+// recordResult records the duration of the plugin reported by an intermediate result,
+// or counts the document when the result is final
+func recordResult(context context.T, documentType contracts.DocumentType, res contracts.DocumentResult) {
+	registry := context.Metrics()
+	if res.LastPlugin == "" {
+		registry.Counter(metrics.DocumentsCompleted,
+			"Documents completed by the document processors.",
+			metrics.Labels{"document_type": string(documentType), "status": string(res.Status)}).Inc()
+		return
+	}
+
+	pluginRes, found := res.PluginResults[res.LastPlugin]
+	if !found || pluginRes == nil || pluginRes.StartDateTime.IsZero() || pluginRes.EndDateTime.Before(pluginRes.StartDateTime) {
+		return
+	}
+	registry.Histogram(metrics.PluginDuration,
+		"Execution time of the plugins.",
+		metrics.DefaultDurationBuckets,
+		metrics.Labels{"plugin": pluginRes.PluginName, "status": string(pluginRes.Status)}).Observe(pluginRes.EndDateTime.Sub(pluginRes.StartDateTime).Seconds())
+}
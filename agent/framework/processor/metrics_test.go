@@ -0,0 +1,89 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor defines the document processing unit interface
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func exportedMetrics(t *testing.T, ctx context.T) string {
+	var buffer bytes.Buffer
+	assert.NoError(t, ctx.Metrics().WriteOpenMetrics(&buffer))
+	return buffer.String()
+}
+
+func TestSubmitRecordsPoolJobs(t *testing.T) {
+	ctx := context.NewMockDefault()
+	sendCommandPoolMock := new(task.MockedPool)
+	sendCommandPoolMock.On("Submit", ctx.Log(), "messageID", mock.Anything).Return(nil)
+	sendCommandPoolMock.On("Submit", ctx.Log(), "duplicateID", mock.Anything).Return(errors.New("job already exists"))
+	processor := EngineProcessor{
+		sendCommandPool: sendCommandPoolMock,
+		context:         ctx,
+	}
+
+	docState := contracts.DocumentState{DocumentType: contracts.SendCommand}
+	docState.DocumentInformation.MessageID = "messageID"
+	assert.NoError(t, processor.submit(&docState))
+	docState.DocumentInformation.MessageID = "duplicateID"
+	assert.Error(t, processor.submit(&docState))
+
+	exported := exportedMetrics(t, ctx)
+	assert.Contains(t, exported, `ssm_agent_task_pool_jobs{document_type="SendCommand",pool="send"} 1`)
+	assert.Contains(t, exported, `ssm_agent_documents_submitted_total{document_type="SendCommand"} 1`)
+}
+
+func TestRecordResult(t *testing.T) {
+	ctx := context.NewMockDefault()
+	start := time.Now()
+	pluginRes := &contracts.PluginResult{
+		PluginName:    "aws:runShellScript",
+		Status:        contracts.ResultStatusSuccess,
+		StartDateTime: start,
+		EndDateTime:   start.Add(2 * time.Second),
+	}
+
+	recordResult(ctx, contracts.SendCommand, contracts.DocumentResult{
+		LastPlugin:    "plugin0",
+		PluginResults: map[string]*contracts.PluginResult{"plugin0": pluginRes},
+	})
+	recordResult(ctx, contracts.SendCommand, contracts.DocumentResult{Status: contracts.ResultStatusSuccess})
+
+	exported := exportedMetrics(t, ctx)
+	assert.Contains(t, exported, `ssm_agent_plugin_duration_seconds_sum{plugin="aws:runShellScript",status="Success"} 2`)
+	assert.Contains(t, exported, `ssm_agent_documents_completed_total{document_type="SendCommand",status="Success"} 1`)
+}
+
+func TestOpenSessions(t *testing.T) {
+	ctx := context.NewMockDefault()
+	assert.Nil(t, openSessions(ctx, &contracts.DocumentState{DocumentType: contracts.SendCommand}))
+
+	docState := &contracts.DocumentState{
+		DocumentType:               contracts.StartSession,
+		InstancePluginsInformation: []contracts.PluginState{{Name: "Standard_Stream"}},
+	}
+	openSessions(ctx, docState).Inc()
+
+	assert.Contains(t, exportedMetrics(t, ctx), `ssm_agent_sessions{session_type="Standard_Stream"} 1`)
+}
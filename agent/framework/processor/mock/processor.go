@@ -33,3 +33,18 @@ func (m *MockedProcessor) Cancel(docState contracts.DocumentState) {
 	m.Called(docState)
 	return
 }
+
+func (m *MockedProcessor) SetWorkersLimit(commandWorkerLimit int) {
+	m.Called(commandWorkerLimit)
+	return
+}
+
+func (m *MockedProcessor) Drain() {
+	m.Called()
+	return
+}
+
+func (m *MockedProcessor) InFlight() int {
+	args := m.Called()
+	return args.Int(0)
+}
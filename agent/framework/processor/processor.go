@@ -30,6 +30,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/longrunning/manager"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/rebooter"
@@ -56,6 +57,12 @@ type Processor interface {
 	Submit(docState contracts.DocumentState)
 	//cancel process the cancel document, with no return value since the command is already tracked in a different thread
 	Cancel(docState contracts.DocumentState)
+	//SetWorkersLimit changes the number of documents processed in parallel
+	SetWorkersLimit(commandWorkerLimit int)
+	//Drain stops accepting new documents, documents already submitted and cancel documents are still processed
+	Drain()
+	//InFlight returns the number of documents submitted which haven't completed yet
+	InFlight() int
 	//TODO do we need to implement CancelAll?
 	//CancelAll()
 }
@@ -69,6 +76,8 @@ type EngineProcessor struct {
 	supportedDocTypes []contracts.DocumentType
 	resChan           chan contracts.DocumentResult
 	documentMgr       docmanager.DocumentMgr
+	drainLock         sync.RWMutex
+	draining          bool
 }
 
 //TODO worker pool should be triggered in the Start() function
@@ -134,6 +143,11 @@ func (p *EngineProcessor) InitialProcessing() (err error) {
 //Submit() is the public interface for sending run document request to processor
 func (p *EngineProcessor) Submit(docState contracts.DocumentState) {
 	log := p.context.Log()
+	if p.isDraining() {
+		log.Warnf("Draining, document %v is not accepted", docState.DocumentInformation.MessageID)
+		p.reject(&docState, "the agent is draining and does not accept new documents")
+		return
+	}
 	//queue up the pending document
 	p.documentMgr.PersistDocumentState(log, docState.DocumentInformation.DocumentID, docState.DocumentInformation.InstanceID, appconfig.DefaultLocationOfPending, docState)
 	err := p.submit(&docState)
@@ -156,7 +170,12 @@ func (p *EngineProcessor) submit(docState *contracts.DocumentState) error {
 	} else {
 		jobID = docState.DocumentInformation.MessageID
 	}
-	return p.sendCommandPool.Submit(log, jobID, func(cancelFlag task.CancelFlag) {
+	span := startDocumentSpan(p.context, "submit", docState)
+	defer span.End()
+	jobs := poolJobs(p.context, sendCommandPoolName, docState.DocumentType)
+	jobs.Inc()
+	err := p.sendCommandPool.Submit(log, jobID, func(cancelFlag task.CancelFlag) {
+		defer jobs.Dec()
 		processCommand(
 			p.context,
 			p.executerCreator,
@@ -165,7 +184,76 @@ func (p *EngineProcessor) submit(docState *contracts.DocumentState) error {
 			docState,
 			p.documentMgr)
 	})
+	if err != nil {
+		jobs.Dec()
+		span.RecordError(err)
+		return err
+	}
+	recordSubmitted(p.context, docState.DocumentType)
+	return nil
+}
+
+// SetWorkersLimit changes the number of documents processed in parallel, documents in progress are not interrupted
+func (p *EngineProcessor) SetWorkersLimit(commandWorkerLimit int) {
+	p.context.Log().Infof("Processing up to %v documents in parallel", commandWorkerLimit)
+	p.sendCommandPool.Resize(commandWorkerLimit)
+}
+
+// reject fails a document without running it, the failed result is sent from the task pool
+// so that it is never sent after the result channel is closed
+func (p *EngineProcessor) reject(docState *contracts.DocumentState, reason string) {
+	log := p.context.Log()
+	now := time.Now()
+	results := make(map[string]*contracts.PluginResult)
+	for _, pluginState := range docState.InstancePluginsInformation {
+		results[pluginState.Id] = &contracts.PluginResult{
+			PluginID:      pluginState.Id,
+			PluginName:    pluginState.Name,
+			Status:        contracts.ResultStatusFailed,
+			Code:          1,
+			Error:         reason,
+			StandardError: reason,
+			StartDateTime: now,
+			EndDateTime:   now,
+		}
+	}
+	info := docState.DocumentInformation
+	result := contracts.DocumentResult{
+		Status:          contracts.ResultStatusFailed,
+		PluginResults:   results,
+		MessageID:       info.MessageID,
+		AssociationID:   info.AssociationID,
+		NPlugins:        len(results),
+		DocumentName:    info.DocumentName,
+		DocumentVersion: info.DocumentVersion,
+	}
+	err := p.sendCommandPool.Submit(log, info.MessageID, func(cancelFlag task.CancelFlag) {
+		p.resChan <- result
+	})
+	if err != nil {
+		log.Errorf("failed to reject document %v: %v", info.MessageID, err)
+	}
+}
+
+// Drain stops accepting new documents, the documents in progress run to completion
+func (p *EngineProcessor) Drain() {
+	p.drainLock.Lock()
+	defer p.drainLock.Unlock()
+	if !p.draining {
+		p.context.Log().Infof("Draining, %v documents in progress", p.sendCommandPool.JobCount())
+		p.draining = true
+	}
+}
 
+func (p *EngineProcessor) isDraining() bool {
+	p.drainLock.RLock()
+	defer p.drainLock.RUnlock()
+	return p.draining
+}
+
+// InFlight returns the number of documents queued or in progress
+func (p *EngineProcessor) InFlight() int {
+	return p.sendCommandPool.JobCount()
 }
 
 func (p *EngineProcessor) Cancel(docState contracts.DocumentState) {
@@ -179,10 +267,14 @@ func (p *EngineProcessor) Cancel(docState contracts.DocumentState) {
 	}
 	//queue up the pending document
 	p.documentMgr.PersistDocumentState(log, docState.DocumentInformation.DocumentID, docState.DocumentInformation.InstanceID, appconfig.DefaultLocationOfPending, docState)
+	jobs := poolJobs(p.context, cancelCommandPoolName, docState.DocumentType)
+	jobs.Inc()
 	err := p.cancelCommandPool.Submit(log, jobID, func(cancelFlag task.CancelFlag) {
+		defer jobs.Dec()
 		processCancelCommand(p.context, p.sendCommandPool, &docState, p.documentMgr)
 	})
 	if err != nil {
+		jobs.Dec()
 		log.Error("CancelCommand failed", err)
 		return
 	}
@@ -313,7 +405,11 @@ func (p *EngineProcessor) isSupportedDocumentType(documentType contracts.Documen
 }
 
 func processCommand(context context.T, executerCreator ExecuterCreator, cancelFlag task.CancelFlag, resChan chan contracts.DocumentResult, docState *contracts.DocumentState, docMgr docmanager.DocumentMgr) {
+	context = context.WithFields(documentFields(docState))
 	log := context.Log()
+	span := startDocumentSpan(context, "processCommand", docState)
+	defer span.End()
+	propagateTraceParent(span, docState)
 	//persist the current running document
 	docMgr.MoveDocumentState(log,
 		docState.DocumentInformation.DocumentID,
@@ -330,6 +426,10 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 		cancelFlag,
 		&docStore,
 	)
+	if sessions := openSessions(context, docState); sessions != nil {
+		sessions.Inc()
+		defer sessions.Dec()
+	}
 	// Listen for reboot
 	var final *contracts.DocumentResult
 	for res := range statusChan {
@@ -340,12 +440,17 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 
 		}
 		handleCloudwatchPlugin(context, res.PluginResults, documentID)
+		recordResult(context, docState.DocumentType, res)
+		res.TraceParent = span.TraceParent()
 		//hand off the message to Service
 		resChan <- res
 		final = &res
 	}
 	//TODO add shutdown as API call, move cancelFlag out of task pool; cancelFlag to contracts, nobody else above runplugins needs to create cancelFlag.
 	// Shutdown/reboot detection
+	if final != nil {
+		span.SetAttribute("DocumentStatus", string(final.Status))
+	}
 	if final == nil || final.LastPlugin != "" {
 		log.Infof("document %v still in progress, shutting down...", messageID)
 		return
@@ -367,7 +472,7 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 
 //TODO CancelCommand is currently treated as a special type of Command by the Processor, but in general Cancel operation should be seen as a probe to existing commands
 func processCancelCommand(context context.T, sendCommandPool task.Pool, docState *contracts.DocumentState, docMgr docmanager.DocumentMgr) {
-
+	context = context.WithFields(documentFields(docState))
 	log := context.Log()
 	//persist the final status of cancel-message in current folder
 	docMgr.MoveDocumentState(log,
@@ -395,6 +500,29 @@ func processCancelCommand(context context.T, sendCommandPool task.Pool, docState
 
 }
 
+// documentFields returns the log fields identifying the document and the command, association or session running it
+func documentFields(docState *contracts.DocumentState) log.Fields {
+	info := docState.DocumentInformation
+	fields := log.Fields{
+		log.FieldMessageID:    info.MessageID,
+		log.FieldDocumentName: info.DocumentName,
+	}
+	switch docState.DocumentType {
+	case contracts.Association:
+		fields[log.FieldAssociationID] = info.AssociationID
+	case contracts.StartSession, contracts.TerminateSession:
+		fields[log.FieldSessionID] = info.DocumentID
+	default:
+		fields[log.FieldCommandID] = info.CommandID
+	}
+	for key, value := range fields {
+		if value == "" {
+			delete(fields, key)
+		}
+	}
+	return fields
+}
+
 //TODO remove this once CloudWatch plugin is reworked
 //temporary solution on plugins with shared responsibility with agent
 func handleCloudwatchPlugin(context context.T, pluginResults map[string]*contracts.PluginResult, documentID string) {
@@ -76,6 +76,53 @@ func TestEngineProcessor_Cancel(t *testing.T) {
 	cancelCommandPoolMock.AssertExpectations(t)
 }
 
+func TestEngineProcessor_SetWorkersLimit(t *testing.T) {
+	sendCommandPoolMock := new(task.MockedPool)
+	sendCommandPoolMock.On("Resize", 10).Return()
+	processor := EngineProcessor{
+		sendCommandPool: sendCommandPoolMock,
+		context:         context.NewMockDefault(),
+	}
+
+	processor.SetWorkersLimit(10)
+
+	sendCommandPoolMock.AssertExpectations(t)
+}
+
+func TestEngineProcessor_SubmitWhileDraining(t *testing.T) {
+	sendCommandPoolMock := new(task.MockedPool)
+	sendCommandPoolMock.On("JobCount").Return(1)
+	var job task.Job
+	sendCommandPoolMock.On("Submit", mock.Anything, "messageID", mock.Anything).Run(func(args mock.Arguments) {
+		job = args.Get(2).(task.Job)
+	}).Return(nil)
+	docMock := new(DocumentMgrMock)
+	resChan := make(chan contracts.DocumentResult, 1)
+	processor := EngineProcessor{
+		sendCommandPool: sendCommandPoolMock,
+		context:         context.NewMockDefault(),
+		documentMgr:     docMock,
+		resChan:         resChan,
+	}
+
+	processor.Drain()
+	docState := contracts.DocumentState{
+		InstancePluginsInformation: []contracts.PluginState{{Id: "plugin1", Name: "aws:runShellScript"}},
+	}
+	docState.DocumentInformation.MessageID = "messageID"
+	processor.Submit(docState)
+
+	assert.Equal(t, 1, processor.InFlight())
+	docMock.AssertNotCalled(t, "PersistDocumentState", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	// the document is failed from the task pool instead of being dropped
+	job(task.NewChanneledCancelFlag())
+	res := <-resChan
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Equal(t, "messageID", res.MessageID)
+	assert.Empty(t, res.LastPlugin)
+	assert.Equal(t, contracts.ResultStatusFailed, res.PluginResults["plugin1"].Status)
+}
+
 func TestEngineProcessor_Stop(t *testing.T) {
 	sendCommandPoolMock := new(task.MockedPool)
 	cancelCommandPoolMock := new(task.MockedPool)
@@ -214,3 +261,19 @@ func (m *DocumentMgrMock) RemoveDocumentState(log log.T, documentID, instanceID,
 	m.Called(log, documentID, instanceID, location)
 	return
 }
+
+func TestDocumentFields(t *testing.T) {
+	docState := contracts.DocumentState{DocumentType: contracts.SendCommand}
+	docState.DocumentInformation.MessageID = "messageID"
+	docState.DocumentInformation.CommandID = "commandID"
+	assert.Equal(t, log.Fields{log.FieldMessageID: "messageID", log.FieldCommandID: "commandID"}, documentFields(&docState))
+
+	docState = contracts.DocumentState{DocumentType: contracts.Association}
+	docState.DocumentInformation.AssociationID = "associationID"
+	docState.DocumentInformation.DocumentName = "AWS-RunShellScript"
+	assert.Equal(t, log.Fields{log.FieldAssociationID: "associationID", log.FieldDocumentName: "AWS-RunShellScript"}, documentFields(&docState))
+
+	docState = contracts.DocumentState{DocumentType: contracts.StartSession}
+	docState.DocumentInformation.DocumentID = "sessionID"
+	assert.Equal(t, log.Fields{log.FieldSessionID: "sessionID"}, documentFields(&docState))
+}
@@ -0,0 +1,45 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor defines the document processing unit interface
+package processor
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/tracing"
+)
+
+// startDocumentSpan starts a span joining the trace of the document, attributed with the fields identifying the document
+func startDocumentSpan(context context.T, name string, docState *contracts.DocumentState) *tracing.Span {
+	span := context.Tracer().Start(name, docState.DocumentInformation.TraceParent)
+	for key, value := range documentFields(docState) {
+		span.SetAttribute(key, fmt.Sprint(value))
+	}
+	span.SetAttribute("DocumentType", string(docState.DocumentType))
+	return span
+}
+
+// propagateTraceParent makes the span the parent of the work done for the document and its plugins
+func propagateTraceParent(span *tracing.Span, docState *contracts.DocumentState) {
+	traceParent := span.TraceParent()
+	if traceParent == "" {
+		return
+	}
+	docState.DocumentInformation.TraceParent = traceParent
+	for i := range docState.InstancePluginsInformation {
+		docState.InstancePluginsInformation[i].Configuration.TraceParent = traceParent
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor defines the document processing unit interface
+package processor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/tracing"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTracedDocument() *contracts.DocumentState {
+	return &contracts.DocumentState{
+		DocumentInformation: contracts.DocumentInfo{
+			MessageID:   "aws.ssm.commandID.instanceID",
+			CommandID:   "commandID",
+			TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+		DocumentType:               contracts.SendCommand,
+		InstancePluginsInformation: []contracts.PluginState{{Id: "plugin1"}, {Id: "plugin2"}},
+	}
+}
+
+func TestPropagateTraceParent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "traces")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tracer := tracing.NewTracer(log.NewMockLog(), appconfig.TracingCfg{
+		Exporter: appconfig.TracingExporterFile,
+		FilePath: filepath.Join(dir, "traces.json"),
+	})
+	defer tracer.Shutdown()
+	docState := newTracedDocument()
+
+	span := tracer.Start("processCommand", docState.DocumentInformation.TraceParent)
+	propagateTraceParent(span, docState)
+
+	traceParent := docState.DocumentInformation.TraceParent
+	assert.Equal(t, span.TraceParent(), traceParent)
+	assert.True(t, strings.HasPrefix(traceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-"))
+	for _, plugin := range docState.InstancePluginsInformation {
+		assert.Equal(t, traceParent, plugin.Configuration.TraceParent)
+	}
+}
+
+func TestPropagateTraceParentWithoutTracing(t *testing.T) {
+	docState := newTracedDocument()
+	var tracer *tracing.Tracer
+
+	propagateTraceParent(tracer.Start("processCommand", docState.DocumentInformation.TraceParent), docState)
+
+	assert.Equal(t, newTracedDocument(), docState)
+}
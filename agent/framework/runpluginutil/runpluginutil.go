@@ -46,6 +46,13 @@ type PluginFactory interface {
 	Create(context context.T) (T, error)
 }
 
+// ExternalPluginFactory is implemented by the factories of the out-of-tree plugins,
+// which are known to the agent without being in allPlugins.
+type ExternalPluginFactory interface {
+	PluginFactory
+	IsExternal() bool
+}
+
 // PluginRegistry stores a set of plugins (both worker and long running plugins), indexed by ID.
 type PluginRegistry map[string]PluginFactory
 
@@ -80,6 +87,28 @@ var allSessionPlugins = map[string]struct{}{
 	appconfig.PluginNamePort:                {},
 }
 
+// runAsUserPlugins is the list of the plugins which run their commands as the runAsUser of the document.
+// The other plugins would run as the agent's user, so their steps fail when the document sets a runAsUser.
+var runAsUserPlugins = map[string]struct{}{
+	appconfig.PluginNameAwsRunPowerShellScript: {},
+	appconfig.PluginNameAwsRunShellScript:      {},
+}
+
+// resumablePlugins is the list of the plugins whose steps can run again from their start when the document worker
+// running them exits, since running them twice leaves the instance in the same state.
+// The steps of the other plugins only run again once they recorded a checkpoint to resume from.
+var resumablePlugins = map[string]struct{}{
+	appconfig.PluginDownloadContent:          {},
+	appconfig.PluginNameAwsSoftwareInventory: {},
+	appconfig.PluginNameRefreshAssociation:   {},
+}
+
+// IsPluginResumable returns true when the steps of the plugin can run again from their start
+func IsPluginResumable(pluginName string) bool {
+	_, resumable := resumablePlugins[pluginName]
+	return resumable
+}
+
 // Assign method to global variables to allow unittest to override
 var isSupportedPlugin = IsPluginSupportedForCurrentPlatform
 
@@ -98,6 +127,11 @@ func RunPlugins(
 
 	pluginOutputs = make(map[string]*contracts.PluginResult)
 
+	// the secrets resolved by a plugin are redacted from the output of the next ones too
+	if ioConfig.Secrets == nil {
+		ioConfig.Secrets = contracts.NewDocumentSecrets()
+	}
+
 	//Contains the logStreamPrefix without the pluginID
 	logStreamPrefix := ioConfig.CloudWatchConfig.LogStreamPrefix
 
@@ -162,6 +196,9 @@ func RunPlugins(
 
 		pluginFactory, pluginHandlerFound = registry[pluginName]
 		isKnown, isSupported, _ = isSupportedPlugin(context.Log(), pluginName)
+		if externalFactory, ok := pluginFactory.(ExternalPluginFactory); ok && externalFactory.IsExternal() {
+			isKnown, isSupported = true, true
+		}
 		operation, logMessage := getStepExecutionOperation(
 			context.Log(),
 			pluginName,
@@ -171,10 +208,25 @@ func RunPlugins(
 			pluginHandlerFound,
 			configuration.IsPreconditionEnabled,
 			configuration.Preconditions)
+		if _, honorsRunAsUser := runAsUserPlugins[pluginName]; operation == executeStep && configuration.RunAsUser != "" && !honorsRunAsUser {
+			operation, logMessage = failStep, fmt.Sprintf(
+				"Plugin with name %s cannot run as %s, only %s and %s steps support runAsUser. Step name: %s",
+				pluginName,
+				configuration.RunAsUser,
+				appconfig.PluginNameAwsRunShellScript,
+				appconfig.PluginNameAwsRunPowerShellScript,
+				pluginID)
+		}
 
 		switch operation {
 		case executeStep:
 			context.Log().Infof("Running plugin %s", pluginName)
+			// the plugin resumes from the checkpoint it persisted before the document worker was restarted
+			ioConfig.Checkpointer = contracts.NewCheckpointer(pluginID, pluginState.Checkpoint, ioConfig.CheckpointRecorder)
+			if pluginState.Checkpoint != "" {
+				context.Log().Infof("plugin %v resuming from checkpoint %v", pluginID, pluginState.Checkpoint)
+				pluginOutputs[pluginID].ResumedFromCheckpoint = pluginState.Checkpoint
+			}
 			r = runPlugin(context, pluginFactory, pluginName, configuration, cancelFlag, ioConfig)
 			pluginOutputs[pluginID].Code = r.Code
 			pluginOutputs[pluginID].Status = r.Status
@@ -182,6 +234,8 @@ func RunPlugins(
 			pluginOutputs[pluginID].Output = r.Output
 			pluginOutputs[pluginID].StandardOutput = r.StandardOutput
 			pluginOutputs[pluginID].StandardError = r.StandardError
+			pluginOutputs[pluginID].StandardOutputSummary = r.StandardOutputSummary
+			pluginOutputs[pluginID].StandardErrorSummary = r.StandardErrorSummary
 			pluginOutputs[pluginID].StepName = r.StepName
 
 		case skipStep:
@@ -206,6 +260,7 @@ func RunPlugins(
 		context.Log().Infof("Sending plugin %v completion message", pluginID)
 
 		// truncate the result and send it back to buffer channel.
+		// the output captured by the plugin is already bounded, this only applies to output set directly by the plugin.
 		result := *pluginOutputs[pluginID]
 		pluginConfig := iohandler.DefaultOutputConfig()
 		result.StandardOutput = pluginutil.StringPrefix(result.StandardOutput, pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
@@ -231,11 +286,22 @@ func runPlugin(
 	cancelFlag task.CancelFlag,
 	ioConfig contracts.IOConfiguration) (res contracts.PluginResult) {
 	// create a new context that includes plugin ID
-	context = context.With("[pluginName=" + pluginName + "]")
+	context = context.With("[pluginName=" + pluginName + "]").WithFields(log.Fields{log.FieldPluginName: pluginName})
 
 	log := context.Log()
 	var stepName string
 
+	// the span ends after the recovery below, so that it records the final result of the plugin
+	span := context.Tracer().Start(pluginName, config.TraceParent)
+	span.SetAttribute("PluginId", config.PluginID)
+	defer func() {
+		span.SetAttribute("Status", string(res.Status))
+		if res.Status == contracts.ResultStatusFailed || res.Status == contracts.ResultStatusTimedOut {
+			span.RecordError(fmt.Errorf("plugin %v: %v", res.Status, res.Error))
+		}
+		span.End()
+	}()
+
 	defer func() {
 		// recover in case the plugin panics
 		// this should handle some kind of seg fault errors.
@@ -308,10 +374,20 @@ func runPlugin(
 	res.Output = output.GetOutput()
 	res.StandardOutput = output.GetStdout()
 	res.StandardError = output.GetStderr()
+	res.StandardOutputSummary = outputSummary(output.GetStdoutSummary())
+	res.StandardErrorSummary = outputSummary(output.GetStderrSummary())
 
 	return
 }
 
+// outputSummary returns the summary to report for an output stream, nil when the plugin did not write to it
+func outputSummary(summary contracts.OutputSummary) *contracts.OutputSummary {
+	if summary.Bytes == 0 {
+		return nil
+	}
+	return &summary
+}
+
 // executePlugin executes the plugin that's passed in and initializes the necessary writers
 func executePlugin(context context.T,
 	plugin T,
@@ -19,8 +19,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
@@ -1428,6 +1430,109 @@ func TestRunPluginsWithUnknownPlugin(t *testing.T) {
 	assert.Equal(t, pluginResults, outputs)
 }
 
+// externalPluginFactoryMock is the factory of an out-of-tree plugin
+type externalPluginFactoryMock struct {
+	PluginFactoryMock
+}
+
+func (m *externalPluginFactoryMock) IsExternal() bool {
+	return true
+}
+
+// TestRunPluginsWithExternalPlugin tests that plugins of external factories run although they are not known
+func TestRunPluginsWithExternalPlugin(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
+	ctx := context.NewMockDefault()
+	config := contracts.Configuration{
+		PluginID:   testUnknownPlugin,
+		PluginName: testUnknownPlugin,
+	}
+	pluginStates := []contracts.PluginState{{Name: testUnknownPlugin, Id: testUnknownPlugin, Configuration: config}}
+
+	plugin := new(PluginMock)
+	plugin.On("Execute", ctx, config, cancelFlag, mock.Anything).Return()
+	pluginFactory := new(externalPluginFactoryMock)
+	pluginFactory.On("Create", mock.Anything).Return(plugin, nil)
+	pluginRegistry := PluginRegistry{testUnknownPlugin: pluginFactory}
+
+	ch := make(chan contracts.PluginResult, 1)
+	outputs := RunPlugins(ctx, pluginStates, contracts.IOConfiguration{}, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	plugin.AssertExpectations(t)
+	assert.Empty(t, outputs[testUnknownPlugin].Error)
+}
+
+// TestRunPluginsWithRunAsUser tests that only the plugins running their commands as the runAsUser execute when it is set
+func TestRunPluginsWithRunAsUser(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
+	ctx := context.NewMockDefault()
+	scriptConfig := contracts.Configuration{PluginID: "script", PluginName: appconfig.PluginNameAwsRunShellScript, RunAsUser: "ec2-user"}
+	downloadConfig := contracts.Configuration{PluginID: "download", PluginName: appconfig.PluginDownloadContent, RunAsUser: "ec2-user"}
+	pluginStates := []contracts.PluginState{
+		{Name: appconfig.PluginNameAwsRunShellScript, Id: "script", Configuration: scriptConfig},
+		{Name: appconfig.PluginDownloadContent, Id: "download", Configuration: downloadConfig},
+	}
+
+	scriptPlugin := new(PluginMock)
+	scriptPlugin.On("Execute", ctx, scriptConfig, cancelFlag, mock.Anything).Return()
+	scriptFactory := new(PluginFactoryMock)
+	scriptFactory.On("Create", mock.Anything).Return(scriptPlugin, nil)
+	downloadFactory := new(PluginFactoryMock)
+	pluginRegistry := PluginRegistry{
+		appconfig.PluginNameAwsRunShellScript: scriptFactory,
+		appconfig.PluginDownloadContent:       downloadFactory,
+	}
+
+	ch := make(chan contracts.PluginResult, 2)
+	outputs := RunPlugins(ctx, pluginStates, contracts.IOConfiguration{}, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	scriptPlugin.AssertExpectations(t)
+	downloadFactory.AssertNotCalled(t, "Create", mock.Anything)
+	assert.Equal(t, contracts.ResultStatusFailed, outputs["download"].Status)
+	assert.Contains(t, outputs["download"].Error, "cannot run as ec2-user")
+}
+
+// TestRunPluginsResumesFromCheckpoint tests that a plugin gets the checkpoint of its previous run and records new ones
+func TestRunPluginsResumesFromCheckpoint(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
+	ctx := context.NewMockDefault()
+	config := contracts.Configuration{
+		PluginID:   testPlugin1,
+		PluginName: testPlugin1,
+	}
+	pluginStates := []contracts.PluginState{{Name: testPlugin1, Id: testPlugin1, Configuration: config, Checkpoint: "step-2"}}
+
+	plugin := new(PluginMock)
+	plugin.On("Execute", ctx, config, cancelFlag, mock.Anything).Run(func(args mock.Arguments) {
+		output := args.Get(3).(iohandler.IOHandler)
+		assert.Equal(t, "step-2", output.GetCheckpoint())
+		output.SetCheckpoint("step-3")
+	}).Return()
+	pluginFactory := new(PluginFactoryMock)
+	pluginFactory.On("Create", mock.Anything).Return(plugin, nil)
+	pluginRegistry := PluginRegistry{testPlugin1: pluginFactory}
+
+	var recorded []contracts.PluginCheckpoint
+	ioConfig := contracts.IOConfiguration{
+		CheckpointRecorder: func(checkpoint contracts.PluginCheckpoint) { recorded = append(recorded, checkpoint) },
+	}
+	ch := make(chan contracts.PluginResult, 1)
+	outputs := RunPlugins(ctx, pluginStates, ioConfig, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	plugin.AssertExpectations(t)
+	assert.Equal(t, "step-2", outputs[testPlugin1].ResumedFromCheckpoint)
+	assert.Equal(t, []contracts.PluginCheckpoint{{PluginID: testPlugin1, Checkpoint: "step-3"}}, recorded)
+}
+
 func TestRunPluginSuccessWithNonTruncatedResult(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
@@ -16,12 +16,15 @@ package health
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig/configwatcher"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/amazon-ssm-agent/agent/ssm"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 	"github.com/carlescere/scheduler"
 )
@@ -38,7 +41,11 @@ type HealthCheck struct {
 	context               context.T
 	healthCheckStopPolicy *sdkutil.StopPolicy
 	healthJob             *scheduler.Job
+	healthJobMutex        sync.Mutex
+	stopped               bool
 	service               ssm.Service
+	health                *contracts.HealthTracker
+	agentHealth           func() contracts.AgentHealth
 }
 
 const (
@@ -49,6 +56,11 @@ const (
 
 var healthModule *HealthCheck
 
+// scheduleHealthJob runs the job now and then every given number of minutes
+var scheduleHealthJob = func(minutes int, job func()) (*scheduler.Job, error) {
+	return scheduler.Every(minutes).Minutes().Run(job)
+}
+
 // AgentState enumerates active and passive agentMode
 type AgentState int32
 
@@ -73,17 +85,40 @@ func NewHealthCheck(context context.T, svc ssm.Service) *HealthCheck {
 		context:               healthContext,
 		healthCheckStopPolicy: healthCheckStopPolicy,
 		service:               svc,
+		health:                contracts.NewHealthTracker(name),
 	}
+	healthContext.ConfigWatcher().Subscribe(name, []string{"Ssm.HealthFrequencyMinutes"}, healthModule.rescheduleUpdateHealth)
 	return healthModule
 }
 
 // schedules recurrent updateHealth calls
 func (h *HealthCheck) scheduleUpdateHealth() {
+	h.healthJobMutex.Lock()
+	defer h.healthJobMutex.Unlock()
+	if h.stopped {
+		return
+	}
+	h.startHealthJob()
+}
+
+// reschedules the recurrent updateHealth calls at the frequency of the reloaded configuration
+func (h *HealthCheck) rescheduleUpdateHealth(change configwatcher.Change) {
+	h.healthJobMutex.Lock()
+	defer h.healthJobMutex.Unlock()
+	if h.stopped || h.healthJob == nil {
+		// the first schedule is still pending, it reads the frequency from the reloaded configuration
+		return
+	}
+	h.healthJob.Quit <- true
+	h.startHealthJob()
+}
+
+// startHealthJob schedules the health job, the caller must hold healthJobMutex
+func (h *HealthCheck) startHealthJob() {
 	var err error
-	if h.healthJob, err = scheduler.Every(h.scheduleInMinutes()).Minutes().Run(h.updateHealth); err != nil {
+	if h.healthJob, err = scheduleHealthJob(h.scheduleInMinutes(), h.updateHealth); err != nil {
 		h.context.Log().Errorf("unable to schedule health update. %v", err)
 	}
-	return
 }
 
 // updates SSM with the instance health information
@@ -92,10 +127,13 @@ func (h *HealthCheck) updateHealth() {
 	log.Infof("%s reporting agent health.", name)
 
 	var err error
-	//TODO when will status become inactive?
-	// If both ssm config and command is inactive => agent is inactive.
-	if _, err = h.service.UpdateInstanceInformation(log, version.Version, "Active", AgentName); err != nil {
+	status := h.agentStatus()
+	if _, err = h.service.UpdateInstanceInformation(log, version.Version, string(status), AgentName); err != nil {
 		sdkutil.HandleAwsError(log, err, h.healthCheckStopPolicy)
+		h.health.RecordError(err)
+	} else {
+		updateutil.RecordHealthMarker(log, updateutil.HealthMarkerHealthPing, version.Version)
+		h.health.RecordSuccess()
 	}
 
 	if !h.healthCheckStopPolicy.IsHealthy() {
@@ -106,6 +144,32 @@ func (h *HealthCheck) updateHealth() {
 	return
 }
 
+// agentStatus returns the aggregated status of the core modules, Active until the core manager provides it
+func (h *HealthCheck) agentStatus() contracts.AgentStatus {
+	if h.agentHealth == nil {
+		return contracts.AgentStatusActive
+	}
+	agentHealth := h.agentHealth()
+	if agentHealth.Status != contracts.AgentStatusActive {
+		for _, module := range agentHealth.Modules {
+			if module.IsFailing() {
+				h.context.Log().Warnf("%v is failing: %v", module.Name, module.LastError)
+			}
+		}
+	}
+	return agentHealth.Status
+}
+
+// SetAgentHealthProvider sets the source of the aggregated health reported to SSM
+func (h *HealthCheck) SetAgentHealthProvider(provider func() contracts.AgentHealth) {
+	h.agentHealth = provider
+}
+
+// ModuleHealth returns the outcome of the last health reports
+func (h *HealthCheck) ModuleHealth() contracts.ModuleHealth {
+	return h.health.Health()
+}
+
 // scheduleInMinutes Run Schedule In Minutes
 func (h *HealthCheck) scheduleInMinutes() int {
 	updateHealthFrequencyMins := 5
@@ -155,9 +219,13 @@ func (h *HealthCheck) ModuleExecute(context context.T) (err error) {
 
 // ModuleRequestStop handles the termination of the health check module job
 func (h *HealthCheck) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	h.healthJobMutex.Lock()
+	defer h.healthJobMutex.Unlock()
+	h.stopped = true
 	if h.healthJob != nil {
 		h.context.Log().Info("stopping update instance health job.")
 		h.healthJob.Quit <- true
+		h.healthJob = nil
 	}
 	return nil
 }
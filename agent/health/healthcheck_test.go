@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig/configwatcher"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -32,6 +33,8 @@ import (
 	"github.com/stretchr/testify/suite"
 )
 
+var defaultScheduleHealthJob = scheduleHealthJob
+
 // HealthCheck Test suite. Define the testsuite object.
 // Add logMock, contextMock, serviceMock, healthJobMock struct into test suite.
 // Suite is the testify framework struct
@@ -93,6 +96,25 @@ func (suite *HealthCheckTestSuite) TestModuleExecute() {
 	suite.serviceMock.AssertCalled(suite.T(), "UpdateInstanceInformation", mock.Anything, version.Version, "Active", AgentName)
 }
 
+// Testing the updateHealth reports the aggregated agent status and records its outcome
+func (suite *HealthCheckTestSuite) TestUpdateHealthReportsAgentStatus() {
+	healthCheck := &HealthCheck{
+		healthCheckStopPolicy: suite.stopPolicy,
+		context:               suite.contextMock,
+		service:               suite.serviceMock,
+		health:                contracts.NewHealthTracker(name),
+	}
+	healthCheck.SetAgentHealthProvider(func() contracts.AgentHealth {
+		return contracts.AgentHealth{Status: contracts.AgentStatusDegraded}
+	})
+	suite.serviceMock.On("UpdateInstanceInformation", mock.Anything, version.Version, "Degraded", AgentName).Return(nil, nil)
+
+	healthCheck.updateHealth()
+
+	suite.serviceMock.AssertCalled(suite.T(), "UpdateInstanceInformation", mock.Anything, version.Version, "Degraded", AgentName)
+	assert.NotNil(suite.T(), healthCheck.ModuleHealth().LastSuccess)
+}
+
 //Testing the ModuleRequestStop method with healthjob define
 func (suite *HealthCheckTestSuite) TestModuleRequestStopWithHealthJob() {
 	suite.healthCheck = &HealthCheck{
@@ -127,6 +149,57 @@ func (suite *HealthCheckTestSuite) TestModuleRequestStopWithoutHealthJob() {
 	}(wg)
 }
 
+// stubScheduleHealthJob replaces the scheduler with one returning the given jobs without running them
+func stubScheduleHealthJob(jobs ...*scheduler.Job) (scheduled *int) {
+	scheduled = new(int)
+	scheduleHealthJob = func(minutes int, job func()) (*scheduler.Job, error) {
+		next := jobs[*scheduled]
+		*scheduled++
+		return next, nil
+	}
+	return scheduled
+}
+
+// Testing the health job is rescheduled when the health frequency changes
+func (suite *HealthCheckTestSuite) TestRescheduleUpdateHealth() {
+	defer func() { scheduleHealthJob = defaultScheduleHealthJob }()
+	healthJob := &scheduler.Job{Quit: make(chan bool, 1)}
+	newHealthJob := &scheduler.Job{Quit: make(chan bool, 1)}
+	scheduled := stubScheduleHealthJob(newHealthJob)
+	healthCheck := &HealthCheck{
+		context:   suite.contextMock,
+		healthJob: healthJob,
+		service:   suite.serviceMock,
+	}
+
+	healthCheck.rescheduleUpdateHealth(configwatcher.Change{Applied: []string{"Ssm.HealthFrequencyMinutes"}})
+
+	assert.True(suite.T(), <-healthJob.Quit, "the previous health job should be stopped")
+	assert.Equal(suite.T(), 1, *scheduled)
+	assert.Equal(suite.T(), newHealthJob, healthCheck.healthJob, "a new health job should be scheduled")
+}
+
+// Testing the health job is neither rescheduled nor scheduled once the module stopped
+func (suite *HealthCheckTestSuite) TestRescheduleUpdateHealthAfterStop() {
+	defer func() { scheduleHealthJob = defaultScheduleHealthJob }()
+	healthJob := &scheduler.Job{Quit: make(chan bool, 1)}
+	scheduled := stubScheduleHealthJob()
+	healthCheck := &HealthCheck{
+		context:   suite.contextMock,
+		healthJob: healthJob,
+		service:   suite.serviceMock,
+	}
+
+	healthCheck.ModuleRequestStop(contracts.StopTypeSoftStop)
+	assert.True(suite.T(), <-healthJob.Quit, "the health job should be stopped")
+
+	healthCheck.rescheduleUpdateHealth(configwatcher.Change{Applied: []string{"Ssm.HealthFrequencyMinutes"}})
+	healthCheck.scheduleUpdateHealth()
+
+	assert.Equal(suite.T(), 0, *scheduled)
+	assert.Nil(suite.T(), healthCheck.healthJob)
+}
+
 // Testing the GetAgentState method which should return Active status
 func (suite *HealthCheckTestSuite) TestGetAgentStateActive() {
 	// UpdateEmptyInstanceInformation will return active in the h.ping() function.
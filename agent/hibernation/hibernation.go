@@ -17,9 +17,12 @@
 package hibernation
 
 import (
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/health"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/carlescere/scheduler"
@@ -28,7 +31,7 @@ import (
 
 // Hibernate holds information about the current agent state
 type IHibernate interface {
-	ExecuteHibernation() health.AgentState
+	ExecuteHibernation(cause error) health.AgentState
 }
 
 type Hibernate struct {
@@ -43,12 +46,20 @@ type Hibernate struct {
 
 	seelogger seelog.LoggerInterface
 	isLogged  bool
+
+	statusLock sync.RWMutex
+	status     contracts.HibernationStatus
+	watchers   []wakeWatcher
+	stop       chan struct{}
 }
 
 // modeChan is a channel that tracks the status of the agent
 var modeChan = make(chan health.AgentState, 10)
 var backOffRate = 3
 
+// wakeCheckInterval is how often the watched changes which can wake the agent early are checked
+var wakeCheckInterval = 30 * time.Second
+
 const (
 	hibernateMode      = "AgentHibernate"
 	maxBackOffInterval = 60 * 60 //Minute conversion
@@ -71,38 +82,136 @@ func NewHibernateMode(healthModule health.IHealthCheck, context context.T) *Hibe
 		maxInterval:         maxBackOffInterval,
 		scheduleBackOff:     scheduleBackOffStrategy,
 		schedulePing:        scheduleEmptyHealthPing,
+		watchers:            defaultWakeWatchers(),
+		stop:                make(chan struct{}),
 	}
 }
 
-// ExecuteHibernation Starts the hibernate mode by blocking agent start and by scheduling health pings
-func (m *Hibernate) ExecuteHibernation() health.AgentState {
+// ExecuteHibernation Starts the hibernate mode by blocking agent start and by scheduling health pings.
+// The cause is the error of the health ping which failed, the agent also wakes up early to ping again
+// when the instance role, the network or the config files change.
+func (m *Hibernate) ExecuteHibernation(cause error) health.AgentState {
 	next := time.Duration(initialPingRate) * time.Second
 	m.seelogger.Info("Agent is in hibernate mode. Reducing logging. Logging will be reduced to one log per backoff period")
+	m.recordCheck(cause, next)
+	go m.watchForWake()
+	defer close(m.stop)
+
 	// Wait backoff time and then schedule health pings
-	<-time.After(next)
-	m.scheduleBackOff(m)
+	initialWait := time.After(next)
 
 loop:
 	// using an infinite loop to block the agent from starting
 	for {
 		// block and wait for health mode to be active
-		status := <-modeChan
-		switch status {
-		case health.Active:
-			//Agent mode is now active. Agent can start. Exit loop
-			m.stopEmptyPing()
-			m.seelogger.Flush()
-			return status //returning status for testing purposes.
-		case health.Passive:
-			continue loop
-		default:
-			continue loop
+		select {
+		case <-initialWait:
+			m.scheduleBackOff(m)
+		case status := <-modeChan:
+			switch status {
+			case health.Active:
+				//Agent mode is now active. Agent can start. Exit loop
+				m.stopEmptyPing()
+				m.seelogger.Infof("Health ping succeeded, leaving hibernate mode after %v", time.Since(m.Status().Since).Round(time.Second))
+				m.seelogger.Flush()
+				return status //returning status for testing purposes.
+			case health.Passive:
+				continue loop
+			default:
+				continue loop
+			}
 		}
 	}
 }
 
+// Status returns the reason of the hibernation and when the agent checks again whether it can reach the service
+func (m *Hibernate) Status() *contracts.HibernationStatus {
+	m.statusLock.RLock()
+	defer m.statusLock.RUnlock()
+	status := m.status
+	return &status
+}
+
+// recordCheck records the outcome of a health ping, the reason is logged when it changes.
+// The next scheduled ping is unchanged when next is zero.
+func (m *Hibernate) recordCheck(err error, next time.Duration) {
+	now := time.Now().UTC()
+	m.statusLock.Lock()
+	defer m.statusLock.Unlock()
+	if m.status.Since.IsZero() {
+		m.status.Since = now
+	}
+	m.status.LastCheck = now
+	if next > 0 {
+		m.status.NextCheck = now.Add(next)
+	}
+	if err == nil {
+		return
+	}
+	m.status.LastError = err.Error()
+	if reason := reasonOf(err); reason != m.status.Reason {
+		m.status.Reason = reason
+		m.seelogger.Infof("Agent is hibernating because of %v, next health check in %v", reason, m.status.NextCheck.Sub(now).Round(time.Second))
+	}
+}
+
+// watchForWake pings the service as soon as a watched change occurs rather than at the next scheduled ping
+func (m *Hibernate) watchForWake() {
+	fingerprints := make(map[string]string)
+	for _, watcher := range m.watchers {
+		if fingerprint, err := watcher.fingerprint(); err == nil {
+			fingerprints[watcher.name] = fingerprint
+		}
+	}
+
+	ticker := time.NewTicker(wakeCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			changed := []string{}
+			for _, watcher := range m.watchers {
+				fingerprint, err := watcher.fingerprint()
+				if err != nil {
+					continue
+				}
+				if previous, found := fingerprints[watcher.name]; found && previous != fingerprint {
+					changed = append(changed, watcher.name)
+					if watcher.changed != nil {
+						watcher.changed()
+					}
+				}
+				fingerprints[watcher.name] = fingerprint
+			}
+			if len(changed) > 0 {
+				m.wake(strings.Join(changed, ", "))
+			}
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// wake pings the service after a change which can let the agent reach it
+func (m *Hibernate) wake(trigger string) {
+	now := time.Now().UTC()
+	m.statusLock.Lock()
+	m.status.LastWake = trigger
+	m.status.LastWakeTime = &now
+	m.statusLock.Unlock()
+
+	m.seelogger.Infof("The %v changed, checking whether the agent can reach the service", trigger)
+	m.isLogged = false
+	m.checkHealth(0)
+}
+
 func (m *Hibernate) healthCheck() {
+	m.checkHealth(time.Duration(m.currentPingInterval) * time.Second)
+}
+
+func (m *Hibernate) checkHealth(next time.Duration) {
 	status, err := m.healthModule.GetAgentState()
+	m.recordCheck(err, next)
 	if err != nil && !m.isLogged {
 		m.seelogger.Errorf("Health ping failed with error - %v", err.Error())
 		m.isLogged = true
@@ -141,13 +250,16 @@ func scheduleBackOffStrategy(m *Hibernate) {
 
 	next := time.Duration(backoffInterval) * time.Second
 	go func(m *Hibernate) {
-		m.seelogger.Infof("Backing off health check to every %v seconds for %v seconds.", m.currentPingInterval, backoffInterval)
+		m.seelogger.Infof("Backing off health check to every %v seconds for %v seconds. Hibernation reason: %v",
+			m.currentPingInterval, backoffInterval, m.Status().Reason)
 		select {
 		case <-time.After(next):
 			// recall scheduleEmptyHealthPing to form a timed loop.
 			// loop is broken when currentPingInterval reaches maxInterval
 			m.isLogged = false
 			go m.scheduleBackOff(m)
+		case <-m.stop:
+			// the agent left hibernate mode
 		}
 	}(m)
 	return
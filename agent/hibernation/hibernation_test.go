@@ -17,12 +17,17 @@
 package hibernation
 
 import (
+	"errors"
+	"net"
 	"testing"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/health"
+	healthmock "github.com/aws/amazon-ssm-agent/agent/health/mocks"
 	"github.com/aws/amazon-ssm-agent/agent/ssm"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -35,12 +40,82 @@ func TestHibernation_ExecuteHibernation_AgentTurnsActive(t *testing.T) {
 	for i := 0; i < 4; i++ {
 		modeChan <- health.Passive
 	}
-	var status health.AgentState
+	done := make(chan health.AgentState)
 	go func(h *Hibernate) {
-		status = h.ExecuteHibernation()
-		assert.Equal(t, health.Active, status)
+		done <- h.ExecuteHibernation(errors.New("RequestError: send request failed"))
 	}(hibernate)
 	modeChan <- health.Active
+	assert.Equal(t, health.Active, <-done)
+}
+
+func TestHibernation_ExecuteHibernation_WakesOnChange(t *testing.T) {
+	ctx := context.NewMockDefault()
+	healthMock := new(healthmock.IHealthCheck)
+	healthMock.On("GetAgentState").Return(health.Active, nil)
+
+	hibernate := NewHibernateMode(healthMock, ctx)
+	hibernate.scheduleBackOff = fakeScheduler
+	wakeCheckInterval = 10 * time.Millisecond
+	role := ""
+	reloaded := false
+	hibernate.watchers = []wakeWatcher{
+		{name: "instance role", fingerprint: func() (string, error) { return role, nil }, changed: func() { reloaded = true }},
+		{name: "network configuration", fingerprint: func() (string, error) { return "", errors.New("unknown") }},
+	}
+
+	done := make(chan health.AgentState)
+	go func(h *Hibernate) {
+		done <- h.ExecuteHibernation(awserr.New("NoCredentialProviders", "no valid providers in chain", nil))
+	}(hibernate)
+	time.Sleep(50 * time.Millisecond)
+	healthMock.AssertNotCalled(t, "GetAgentState")
+	assert.Equal(t, contracts.HibernationReasonNoCredentials, hibernate.Status().Reason)
+
+	role = "SSMInstanceRole"
+	select {
+	case status := <-done:
+		assert.Equal(t, health.Active, status)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "agent didn't wake up when the instance role changed")
+	}
+	assert.True(t, reloaded)
+	assert.Equal(t, "instance role", hibernate.Status().LastWake)
+}
+
+func TestHibernation_healthCheckRecordsReason(t *testing.T) {
+	ctx := context.NewMockDefault()
+	healthMock := new(healthmock.IHealthCheck)
+	healthMock.On("GetAgentState").Return(health.Passive, awserr.New("AccessDeniedException", "not authorized", nil))
+
+	hibernate := NewHibernateMode(healthMock, ctx)
+	hibernate.currentPingInterval = 600
+	hibernate.healthCheck()
+
+	assert.Equal(t, health.Passive, <-modeChan)
+	status := hibernate.Status()
+	assert.Equal(t, contracts.HibernationReasonAccessDenied, status.Reason)
+	assert.Equal(t, "AccessDeniedException: not authorized", status.LastError)
+	assert.Equal(t, 10*time.Minute, status.NextCheck.Sub(status.LastCheck))
+}
+
+func TestReasonOf(t *testing.T) {
+	testCases := []struct {
+		err    error
+		reason contracts.HibernationReason
+	}{
+		{awserr.New("NoCredentialProviders", "no valid providers in chain", nil), contracts.HibernationReasonNoCredentials},
+		{awserr.New("EC2RoleRequestError", "no EC2 instance role found", nil), contracts.HibernationReasonNoCredentials},
+		{awserr.New("UnrecognizedClientException", "invalid token", nil), contracts.HibernationReasonAccessDenied},
+		{awserr.NewRequestFailure(awserr.New("Forbidden", "forbidden", nil), 403, "id"), contracts.HibernationReasonAccessDenied},
+		{awserr.New("RequestError", "send request failed", nil), contracts.HibernationReasonEndpointUnreachable},
+		{awserr.New("Unknown", "failed", &net.DNSError{Err: "no such host", Name: "ssm.example.com"}), contracts.HibernationReasonEndpointUnreachable},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, contracts.HibernationReasonEndpointUnreachable},
+		{awserr.NewRequestFailure(awserr.New("InternalServerError", "failed", nil), 500, "id"), contracts.HibernationReasonUnknown},
+		{errors.New("failed"), contracts.HibernationReasonUnknown},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.reason, reasonOf(testCase.err), testCase.err.Error())
+	}
 }
 
 func TestHibernation_scheduleBackOffStrategy(t *testing.T) {
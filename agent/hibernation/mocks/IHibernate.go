@@ -24,13 +24,13 @@ type IHibernate struct {
 	mock.Mock
 }
 
-// ExecuteHibernation provides a mock function with given fields:
-func (_m *IHibernate) ExecuteHibernation() health.AgentState {
-	ret := _m.Called()
+// ExecuteHibernation provides a mock function with given fields: cause
+func (_m *IHibernate) ExecuteHibernation(cause error) health.AgentState {
+	ret := _m.Called(cause)
 
 	var r0 health.AgentState
-	if rf, ok := ret.Get(0).(func() health.AgentState); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(error) health.AgentState); ok {
+		r0 = rf(cause)
 	} else {
 		r0 = ret.Get(0).(health.AgentState)
 	}
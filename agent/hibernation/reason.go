@@ -0,0 +1,77 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package hibernation is responsible for the agent in hibernate mode.
+package hibernation
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// noCredentialsErrorCodes are returned by the credential providers when they have no credentials
+var noCredentialsErrorCodes = map[string]bool{
+	"NoCredentialProviders": true,
+	"EC2RoleRequestError":   true,
+	"EnvAccessKeyNotFound":  true,
+	"SharedCredsLoad":       true,
+}
+
+// accessDeniedErrorCodes are returned by the service when it rejects the credentials
+var accessDeniedErrorCodes = map[string]bool{
+	"AccessDeniedException":       true,
+	"UnrecognizedClientException": true,
+	"InvalidClientTokenId":        true,
+	"ExpiredTokenException":       true,
+	"InvalidSignatureException":   true,
+	"IncompleteSignature":         true,
+	"MissingAuthenticationToken":  true,
+}
+
+// unreachableErrorCodes are returned by the SDK when the request didn't get a response
+var unreachableErrorCodes = map[string]bool{
+	"RequestError":    true,
+	"RequestCanceled": true,
+	"ResponseTimeout": true,
+}
+
+// reasonOf classifies the error of a failed health ping
+func reasonOf(err error) contracts.HibernationReason {
+	aErr, ok := err.(awserr.Error)
+	if !ok {
+		if _, isNetError := err.(net.Error); isNetError {
+			return contracts.HibernationReasonEndpointUnreachable
+		}
+		return contracts.HibernationReasonUnknown
+	}
+
+	switch {
+	case noCredentialsErrorCodes[aErr.Code()]:
+		return contracts.HibernationReasonNoCredentials
+	case accessDeniedErrorCodes[aErr.Code()]:
+		return contracts.HibernationReasonAccessDenied
+	case unreachableErrorCodes[aErr.Code()]:
+		return contracts.HibernationReasonEndpointUnreachable
+	}
+	if reqErr, ok := err.(awserr.RequestFailure); ok &&
+		(reqErr.StatusCode() == http.StatusUnauthorized || reqErr.StatusCode() == http.StatusForbidden) {
+		return contracts.HibernationReasonAccessDenied
+	}
+	if _, isNetError := aErr.OrigErr().(net.Error); isNetError {
+		return contracts.HibernationReasonEndpointUnreachable
+	}
+	return contracts.HibernationReasonUnknown
+}
@@ -0,0 +1,103 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package hibernation is responsible for the agent in hibernate mode.
+package hibernation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+)
+
+// wakeWatcher watches a part of the environment which can let the agent reach the service when it changes
+type wakeWatcher struct {
+	// name describes the change in the logs and the health of the agent
+	name string
+	// fingerprint summarizes the watched state, an error means the state is unknown and is ignored
+	fingerprint func() (string, error)
+	// changed runs before the health ping which follows a change, if set
+	changed func()
+}
+
+// defaultWakeWatchers watches the instance role, the network and the config files
+func defaultWakeWatchers() []wakeWatcher {
+	watchers := []wakeWatcher{
+		{name: "network configuration", fingerprint: networkFingerprint},
+		{name: "agent configuration", fingerprint: configFingerprint, changed: reloadConfig},
+	}
+	// managed instances don't get credentials from the instance metadata
+	if isManaged, err := registration.HasManagedInstancesCredentials(); err == nil && !isManaged {
+		watchers = append(watchers, wakeWatcher{name: "instance role", fingerprint: instanceRoleFingerprint})
+	}
+	return watchers
+}
+
+// instanceRoleFingerprint returns the name of the role attached to the instance, empty when there is none
+func instanceRoleFingerprint() (string, error) {
+	role, err := platform.NewEC2MetadataClient().ReadResource(platform.SecurityCredentialsResource)
+	if err != nil && strings.Contains(err.Error(), "404 Not Found") {
+		// the instance metadata has no credentials when no role is attached
+		return "", nil
+	}
+	return strings.TrimSpace(string(role)), err
+}
+
+// networkFingerprint summarizes the addresses of the network interfaces, the routes and the DNS configuration
+func networkFingerprint() (string, error) {
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	values := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		values = append(values, address.String())
+	}
+	sort.Strings(values)
+	return hashFiles(strings.Join(values, ","), networkConfigFiles), nil
+}
+
+// configFingerprint summarizes the config file and its drop-in files
+func configFingerprint() (string, error) {
+	dropIns, err := filepath.Glob(filepath.Join(appconfig.DropInDir(), "*.json"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(dropIns)
+	return hashFiles(strings.Join(dropIns, ","), append([]string{appconfig.AppConfigPath}, dropIns...)), nil
+}
+
+// reloadConfig makes the next health ping use the changed configuration
+func reloadConfig() {
+	appconfig.Config(true)
+}
+
+// hashFiles hashes the prefix and the content of the files, a file which can't be read counts as empty
+func hashFiles(prefix string, paths []string) string {
+	hash := sha256.New()
+	hash.Write([]byte(prefix))
+	for _, path := range paths {
+		content, _ := ioutil.ReadFile(path)
+		hash.Write([]byte{0})
+		hash.Write(content)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,25 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+// Package hibernation is responsible for the agent in hibernate mode.
+package hibernation
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// networkConfigFiles hold the DNS configuration which isn't reflected in the interface addresses
+var networkConfigFiles = []string{filepath.Join(os.Getenv("SystemRoot"), "System32", "drivers", "etc", "hosts")}
@@ -49,6 +49,7 @@ func LoadLog(defaultLogDir string, logFile string) []byte {
         <format id="fmterror" format="%Date %Time %LEVEL [%FuncShort @ %File.%Line] %Msg%n"/>
         <format id="fmtdebug" format="%Date %Time %LEVEL [%FuncShort @ %File.%Line] %Msg%n"/>
         <format id="fmtinfo" format="%Date %Time %LEVEL %Msg%n"/>
+        <format id="fmtjson" format="%StructuredJSON%n"/>
     </formats>
 </seelog>
 `
@@ -0,0 +1,81 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cihub/seelog"
+)
+
+// Fields are key/value pairs attached to every message logged by a logger
+type Fields map[string]interface{}
+
+// Names of the fields identifying the work done by the agent
+const (
+	FieldInstanceID    = "InstanceId"
+	FieldMessageID     = "MessageId"
+	FieldCommandID     = "CommandId"
+	FieldAssociationID = "AssociationId"
+	FieldSessionID     = "SessionId"
+	FieldDocumentName  = "DocumentName"
+	FieldPluginName    = "PluginName"
+)
+
+// JSONFormatterName is the seelog format verb writing each message as a JSON object with its fields,
+// e.g. <format id="fmtjson" format="%StructuredJSON%n"/>
+const JSONFormatterName = "StructuredJSON"
+
+func init() {
+	seelog.RegisterCustomFormatter(JSONFormatterName, func(param string) seelog.FormatterFunc {
+		return formatJSON
+	})
+}
+
+// merge returns the union of the fields, the values of other take precedence
+func (f Fields) merge(other Fields) Fields {
+	merged := make(Fields, len(f)+len(other))
+	for key, value := range f {
+		merged[key] = value
+	}
+	for key, value := range other {
+		merged[key] = value
+	}
+	return merged
+}
+
+// formatJSON formats a message and the fields of the logger which logged it as a JSON object
+func formatJSON(message string, level seelog.LogLevel, context seelog.LogContextInterface) interface{} {
+	entry := make(map[string]interface{})
+	if fields, ok := context.CustomContext().(Fields); ok {
+		for key, value := range fields {
+			entry[key] = value
+		}
+	}
+	entry["Time"] = context.CallTime().UTC().Format(time.RFC3339Nano)
+	entry["Level"] = strings.ToUpper(level.String())
+	entry["Message"] = message
+	if context.IsValid() {
+		entry["Caller"] = fmt.Sprintf("%v:%v", context.FileName(), context.Line())
+	}
+
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return message
+	}
+	return string(content)
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cihub/seelog"
+	"github.com/stretchr/testify/assert"
+)
+
+// newJSONLogger creates a wrapper logging JSON messages to the returned buffer
+func newJSONLogger(t *testing.T) (T, *bytes.Buffer) {
+	var buffer bytes.Buffer
+	seelogger, err := seelog.LoggerFromWriterWithMinLevelAndFormat(&buffer, seelog.TraceLvl, "%"+JSONFormatterName+"%n")
+	assert.NoError(t, err)
+	seelogger.SetAdditionalStackDepth(1)
+	logger := &Wrapper{Format: &ContextFormatFilter{Context: []string{}}, M: new(sync.Mutex), Delegate: &DelegateLogger{BaseLoggerInstance: seelogger}}
+	return logger, &buffer
+}
+
+// entries parses the JSON messages in the buffer
+func entries(t *testing.T, buffer *bytes.Buffer) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		entry := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		result = append(result, entry)
+	}
+	return result
+}
+
+func TestJSONFormatterWritesFields(t *testing.T) {
+	logger, buffer := newJSONLogger(t)
+
+	commandLogger := logger.WithFields(Fields{FieldCommandID: "command-1"}).WithContext("[messageID=1]")
+	pluginLogger := commandLogger.WithFields(Fields{FieldPluginName: "aws:runShellScript"})
+	pluginLogger.Infof("running %v", "plugin")
+	commandLogger.Error("command failed")
+	logger.Debug("no fields")
+	logger.Flush()
+
+	logged := entries(t, buffer)
+	assert.Len(t, logged, 3)
+
+	assert.Equal(t, "command-1", logged[0][FieldCommandID])
+	assert.Equal(t, "aws:runShellScript", logged[0][FieldPluginName])
+	assert.Equal(t, "[messageID=1] running plugin", logged[0]["Message"])
+	assert.Equal(t, "INFO", logged[0]["Level"])
+	assert.Contains(t, logged[0]["Caller"], "fields_test.go")
+
+	assert.Equal(t, "command-1", logged[1][FieldCommandID])
+	assert.NotContains(t, logged[1], FieldPluginName)
+	assert.Equal(t, "ERROR", logged[1]["Level"])
+
+	assert.NotContains(t, logged[2], FieldCommandID)
+}
+
+func TestWithFieldsDoesNotModifyParent(t *testing.T) {
+	parent := Fields{FieldCommandID: "command-1"}
+
+	merged := parent.merge(Fields{FieldCommandID: "command-2", FieldPluginName: "aws:runPowerShellScript"})
+
+	assert.Equal(t, Fields{FieldCommandID: "command-1"}, parent)
+	assert.Equal(t, "command-2", merged[FieldCommandID])
+	assert.Equal(t, "aws:runPowerShellScript", merged[FieldPluginName])
+}
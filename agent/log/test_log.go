@@ -40,6 +40,7 @@ func NewMockLog() *Mock {
 	log.On("Warnf", mock.AnythingOfType("string"), mock.Anything).Return(mock.AnythingOfType("error"))
 	log.On("Tracef", mock.Anything, mock.Anything).Return()
 	log.On("Infof", mock.Anything, mock.Anything).Return()
+	log.On("WithFields", mock.Anything).Return(log)
 	return log
 }
 
@@ -56,6 +57,7 @@ func NewMockLogWithContext(ctx string) *Mock {
 	log.On("Errorf", mock.AnythingOfType("string"), mock.Anything).Return(mock.AnythingOfType("error"))
 	log.On("Tracef", mock.Anything, mock.Anything).Return()
 	log.On("Infof", mock.Anything, mock.Anything).Return()
+	log.On("WithFields", mock.Anything).Return(log)
 	return log
 }
 
@@ -66,6 +68,12 @@ func (_m *Mock) WithContext(context ...string) (contextLogger T) {
 	return ret.Get(0).(T)
 }
 
+// WithFields mocks the WithFields function.
+func (_m *Mock) WithFields(fields Fields) (contextLogger T) {
+	ret := _m.Called(fields)
+	return ret.Get(0).(T)
+}
+
 // Tracef mocks the Tracef function.
 func (_m *Mock) Tracef(format string, params ...interface{}) {
 	fmt.Print(_m.context)
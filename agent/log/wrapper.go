@@ -27,6 +27,12 @@ type Wrapper struct {
 	Format   FormatFilter
 	M        *sync.Mutex
 	Delegate *DelegateLogger
+	Fields   Fields
+}
+
+// contextSetter is implemented by the seelog loggers, which hand the context over to the formatters
+type contextSetter interface {
+	SetContext(context interface{})
 }
 
 // FormatFilter can modify the format and or parameters to be passed to a logger.
@@ -42,10 +48,23 @@ type FormatFilter interface {
 // WithContext creates a wrapper logger with context
 func (w *Wrapper) WithContext(context ...string) (contextLogger T) {
 	formatFilter := &ContextFormatFilter{Context: context}
-	contextLogger = &Wrapper{Format: formatFilter, M: w.M, Delegate: w.Delegate}
+	contextLogger = &Wrapper{Format: formatFilter, M: w.M, Delegate: w.Delegate, Fields: w.Fields}
 	return contextLogger
 }
 
+// WithFields creates a wrapper logger which attaches the fields to every message
+func (w *Wrapper) WithFields(fields Fields) (contextLogger T) {
+	contextLogger = &Wrapper{Format: w.Format, M: w.M, Delegate: w.Delegate, Fields: w.Fields.merge(fields)}
+	return contextLogger
+}
+
+// setFields hands the fields of the wrapper over to the delegate logger, it must be called holding the lock
+func (w *Wrapper) setFields() {
+	if setter, ok := w.Delegate.BaseLoggerInstance.(contextSetter); ok {
+		setter.SetContext(w.Fields)
+	}
+}
+
 // Tracef formats message according to format specifier
 // and writes to log with level = Trace.
 func (w *Wrapper) Tracef(format string, params ...interface{}) {
@@ -53,6 +72,7 @@ func (w *Wrapper) Tracef(format string, params ...interface{}) {
 
 	w.M.Lock()
 	defer w.M.Unlock()
+	w.setFields()
 	w.Delegate.BaseLoggerInstance.Tracef(format, params...)
 }
 
@@ -63,6 +83,7 @@ func (w *Wrapper) Debugf(format string, params ...interface{}) {
 
 	w.M.Lock()
 	defer w.M.Unlock()
+	w.setFields()
 	w.Delegate.BaseLoggerInstance.Debugf(format, params...)
 }
 
@@ -73,6 +94,7 @@ func (w *Wrapper) Infof(format string, params ...interface{}) {
 
 	w.M.Lock()
 	defer w.M.Unlock()
+	w.setFields()
 	w.Delegate.BaseLoggerInstance.Infof(format, params...)
 }
 
@@ -83,6 +105,7 @@ func (w *Wrapper) Warnf(format string, params ...interface{}) error {
 
 	w.M.Lock()
 	defer w.M.Unlock()
+	w.setFields()
 	return w.Delegate.BaseLoggerInstance.Warnf(format, params...)
 }
 
@@ -93,6 +116,7 @@ func (w *Wrapper) Errorf(format string, params ...interface{}) error {
 
 	w.M.Lock()
 	defer w.M.Unlock()
+	w.setFields()
 	return w.Delegate.BaseLoggerInstance.Errorf(format, params...)
 }
 
@@ -103,6 +127,7 @@ func (w *Wrapper) Criticalf(format string, params ...interface{}) error {
 
 	w.M.Lock()
 	defer w.M.Unlock()
+	w.setFields()
 	return w.Delegate.BaseLoggerInstance.Criticalf(format, params...)
 }
 
@@ -112,6 +137,7 @@ func (w *Wrapper) Trace(v ...interface{}) {
 	v = w.Format.Filter(v...)
 	w.M.Lock()
 	defer w.M.Unlock()
+	w.setFields()
 	w.Delegate.BaseLoggerInstance.Trace(v...)
 }
 
@@ -122,6 +148,7 @@ func (w *Wrapper) Debug(v ...interface{}) {
 
 	w.M.Lock()
 	defer w.M.Unlock()
+	w.setFields()
 	w.Delegate.BaseLoggerInstance.Debug(v...)
 }
 
@@ -132,6 +159,7 @@ func (w *Wrapper) Info(v ...interface{}) {
 
 	w.M.Lock()
 	defer w.M.Unlock()
+	w.setFields()
 	w.Delegate.BaseLoggerInstance.Info(v...)
 }
 
@@ -142,6 +170,7 @@ func (w *Wrapper) Warn(v ...interface{}) error {
 
 	w.M.Lock()
 	defer w.M.Unlock()
+	w.setFields()
 	return w.Delegate.BaseLoggerInstance.Warn(v...)
 }
 
@@ -152,6 +181,7 @@ func (w *Wrapper) Error(v ...interface{}) error {
 
 	w.M.Lock()
 	defer w.M.Unlock()
+	w.setFields()
 	return w.Delegate.BaseLoggerInstance.Error(v...)
 }
 
@@ -162,6 +192,7 @@ func (w *Wrapper) Critical(v ...interface{}) error {
 
 	w.M.Lock()
 	defer w.M.Unlock()
+	w.setFields()
 	return w.Delegate.BaseLoggerInstance.Critical(v...)
 }
 
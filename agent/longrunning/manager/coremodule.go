@@ -95,6 +95,9 @@ type Manager struct {
 
 	//ec2config's configuration xml parser
 	ec2ConfigXmlParser cloudwatch.Ec2ConfigXmlParser
+
+	//health records the outcome of the periodic checks of the long running plugins
+	health *contracts.HealthTracker
 }
 
 var singletonInstance *Manager
@@ -138,6 +141,7 @@ func EnsureInitialization(context context.T) {
 			registeredPlugins:  regPlugins,
 			fileSysUtil:        fileSysUtil,
 			ec2ConfigXmlParser: ec2ConfigXmlParser,
+			health:             contracts.NewHealthTracker(Name),
 		}
 	})
 
@@ -179,6 +183,7 @@ func (m *Manager) ModuleExecute(context context.T) (err error) {
 
 	if err != nil {
 		log.Errorf("%s is exiting - unable to read from data store", m.ModuleName())
+		m.health.RecordError(err)
 		return
 	}
 
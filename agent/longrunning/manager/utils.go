@@ -15,6 +15,7 @@
 package manager
 
 import (
+	"fmt"
 	"sync"
 
 	"path/filepath"
@@ -41,10 +42,22 @@ func (m *Manager) ensurePluginsAreRunning() {
 	lock.RLock()
 	defer lock.RUnlock()
 
+	// plugins which had to be restarted are the backlog of the manager
+	var stopped []string
+	defer func() {
+		m.health.SetBacklog(len(stopped))
+		if len(stopped) > 0 {
+			m.health.RecordError(fmt.Errorf("long running plugins %v were not running", stopped))
+		} else {
+			m.health.RecordSuccess()
+		}
+	}()
+
 	if len(m.runningPlugins) > 0 {
 		for n := range m.runningPlugins {
 			p, isRegistered := m.registeredPlugins[n]
 			if isRegistered && !p.Handler.IsRunning(m.context) {
+				stopped = append(stopped, n)
 				log.Infof("Starting %s since it wasn't running before")
 				//todo: we arent using task pools anymore -> change the following implementation
 				m.startPlugin.Submit(m.context.Log(), n, func(cancelFlag task.CancelFlag) {
@@ -74,6 +87,11 @@ func (m *Manager) ensurePluginsAreRunning() {
 	}
 }
 
+// ModuleHealth returns the outcome of the last check of the long running plugins
+func (m *Manager) ModuleHealth() contracts.ModuleHealth {
+	return m.health.Health()
+}
+
 // stopLifeCycleManagementJob stops periodic health checks of long running plugins
 func (m *Manager) stopLifeCycleManagementJob() {
 	if m.managingLifeCycleJob != nil {
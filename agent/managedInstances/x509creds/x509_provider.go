@@ -0,0 +1,286 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package x509creds contains the credential provider which exchanges an X.509 client certificate for the
+// credentials of a role through IAM Roles Anywhere
+package x509creds
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+)
+
+const (
+	// ProviderName provides a name of the X.509 role provider
+	ProviderName = "x509RoleProvider"
+
+	serviceName     = "rolesanywhere"
+	sessionsPath    = "/sessions"
+	amzDateFormat   = "20060102T150405Z"
+	shortDateFormat = "20060102"
+	requestTimeout  = 30 * time.Second
+)
+
+// Config holds the certificate and the Roles Anywhere resources the credentials are requested with
+type Config struct {
+	CertificateFile string
+	PrivateKeyFile  string
+	TrustAnchorArn  string
+	ProfileArn      string
+	RoleArn         string
+	// Region defaults to the region of the trust anchor
+	Region string
+	// Endpoint defaults to the regional Roles Anywhere endpoint
+	Endpoint        string
+	DurationSeconds int
+}
+
+// x509RoleProvider implements the AWS SDK credential provider. It signs a CreateSession request with the
+// private key of the certificate, and keeps track if the credentials it got are expired.
+type x509RoleProvider struct {
+	credentials.Expiry
+
+	config Config
+	client *http.Client
+}
+
+type createSessionRequest struct {
+	DurationSeconds int    `json:"durationSeconds"`
+	ProfileArn      string `json:"profileArn"`
+	RoleArn         string `json:"roleArn"`
+	TrustAnchorArn  string `json:"trustAnchorArn"`
+}
+
+type createSessionResponse struct {
+	CredentialSet []struct {
+		Credentials struct {
+			AccessKeyID     string `json:"accessKeyId"`
+			SecretAccessKey string `json:"secretAccessKey"`
+			SessionToken    string `json:"sessionToken"`
+			Expiration      string `json:"expiration"`
+		} `json:"credentials"`
+	} `json:"credentialSet"`
+}
+
+var (
+	emptyCredential = credentials.Value{ProviderName: ProviderName}
+	timeNow         = time.Now
+)
+
+// NewProvider returns a provider of the credentials of the role of the config
+func NewProvider(config Config) (credentials.Provider, error) {
+	if config.CertificateFile == "" || config.PrivateKeyFile == "" {
+		return nil, errors.New("the certificate and private key files are required")
+	}
+	if config.TrustAnchorArn == "" || config.ProfileArn == "" || config.RoleArn == "" {
+		return nil, errors.New("the trust anchor, profile and role ARNs are required")
+	}
+	if config.Region == "" {
+		anchor, err := arn.Parse(config.TrustAnchorArn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trust anchor ARN %v: %v", config.TrustAnchorArn, err)
+		}
+		config.Region = anchor.Region
+	}
+	if config.Endpoint == "" {
+		resolved, err := endpoints.DefaultResolver().EndpointFor(serviceName, config.Region, endpoints.ResolveUnknownServiceOption)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the %v endpoint in %v: %v", serviceName, config.Region, err)
+		}
+		config.Endpoint = resolved.URL
+	} else if !strings.Contains(config.Endpoint, "://") {
+		config.Endpoint = "https://" + config.Endpoint
+	}
+	return &x509RoleProvider{
+		config: config,
+		client: &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// Retrieve requests a session from Roles Anywhere. The certificate and key are read on every request,
+// so a renewed certificate is picked up on the next refresh.
+func (p *x509RoleProvider) Retrieve() (credentials.Value, error) {
+	certificate, signer, err := loadCertificate(p.config.CertificateFile, p.config.PrivateKeyFile)
+	if err != nil {
+		return emptyCredential, err
+	}
+
+	body, err := json.Marshal(createSessionRequest{
+		DurationSeconds: p.config.DurationSeconds,
+		ProfileArn:      p.config.ProfileArn,
+		RoleArn:         p.config.RoleArn,
+		TrustAnchorArn:  p.config.TrustAnchorArn,
+	})
+	if err != nil {
+		return emptyCredential, err
+	}
+	request, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(p.config.Endpoint, "/")+sessionsPath, bytes.NewReader(body))
+	if err != nil {
+		return emptyCredential, err
+	}
+	if err = signRequest(request, body, certificate, signer, p.config.Region, timeNow().UTC()); err != nil {
+		return emptyCredential, err
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return emptyCredential, fmt.Errorf("error occurred in CreateSession: %v", err)
+	}
+	defer response.Body.Close()
+	content, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return emptyCredential, fmt.Errorf("error reading the CreateSession response: %v", err)
+	}
+	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusOK {
+		return emptyCredential, fmt.Errorf("CreateSession failed with status %v: %v", response.StatusCode, strings.TrimSpace(string(content)))
+	}
+
+	var session createSessionResponse
+	if err = json.Unmarshal(content, &session); err != nil {
+		return emptyCredential, fmt.Errorf("invalid CreateSession response: %v", err)
+	}
+	if len(session.CredentialSet) == 0 {
+		return emptyCredential, errors.New("CreateSession returned no credentials")
+	}
+	creds := session.CredentialSet[0].Credentials
+	expiration, err := time.Parse(time.RFC3339, creds.Expiration)
+	if err != nil {
+		return emptyCredential, fmt.Errorf("invalid expiration of the credentials %v: %v", creds.Expiration, err)
+	}
+
+	// refresh at half the lifetime of the credentials, the same as the managed instance credentials
+	p.SetExpiration(expiration, time.Until(expiration)/2)
+
+	return credentials.Value{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		ProviderName:    ProviderName,
+	}, nil
+}
+
+// signRequest signs the request with the AWS4-X509 variant of signature version 4, the credential
+// is the serial number of the certificate and the signature is made with its private key
+func signRequest(request *http.Request, body []byte, certificate *x509.Certificate, signer crypto.Signer, region string, now time.Time) error {
+	var algorithm string
+	switch signer.Public().(type) {
+	case *rsa.PublicKey:
+		algorithm = "AWS4-X509-RSA-SHA256"
+	case *ecdsa.PublicKey:
+		algorithm = "AWS4-X509-ECDSA-SHA256"
+	default:
+		return errors.New("the private key should be an RSA or EC key")
+	}
+
+	amzDate := now.Format(amzDateFormat)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-X509", base64.StdEncoding.EncodeToString(certificate.Raw))
+
+	signedHeaders := "content-type;host;x-amz-date;x-amz-x509"
+	canonicalHeaders := fmt.Sprintf("content-type:%v\nhost:%v\nx-amz-date:%v\nx-amz-x509:%v\n",
+		request.Header.Get("Content-Type"), request.URL.Host, amzDate, request.Header.Get("X-Amz-X509"))
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		canonicalPath(request.URL),
+		request.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		hexDigest(body),
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format(shortDateFormat), region, serviceName, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{algorithm, amzDate, scope, hexDigest([]byte(canonicalRequest))}, "\n")
+
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return fmt.Errorf("failed to sign the CreateSession request: %v", err)
+	}
+
+	request.Header.Set("Authorization", fmt.Sprintf("%v Credential=%v/%v, SignedHeaders=%v, Signature=%v",
+		algorithm, certificate.SerialNumber.String(), scope, signedHeaders, hex.EncodeToString(signature)))
+	return nil
+}
+
+func canonicalPath(u *url.URL) string {
+	if path := u.EscapedPath(); path != "" {
+		return path
+	}
+	return "/"
+}
+
+func hexDigest(content []byte) string {
+	digest := sha256.Sum256(content)
+	return hex.EncodeToString(digest[:])
+}
+
+// loadCertificate reads the PEM encoded certificate and its PKCS#1, PKCS#8 or SEC 1 private key
+func loadCertificate(certificateFile, privateKeyFile string) (*x509.Certificate, crypto.Signer, error) {
+	content, err := ioutil.ReadFile(certificateFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading the certificate: %v", err)
+	}
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, nil, fmt.Errorf("%v is not a PEM encoded certificate", certificateFile)
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid certificate %v: %v", certificateFile, err)
+	}
+
+	if content, err = ioutil.ReadFile(privateKeyFile); err != nil {
+		return nil, nil, fmt.Errorf("error reading the private key: %v", err)
+	}
+	if block, _ = pem.Decode(content); block == nil {
+		return nil, nil, fmt.Errorf("%v is not a PEM encoded private key", privateKeyFile)
+	}
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid private key %v: %v", privateKeyFile, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported private key %v", privateKeyFile)
+	}
+	return certificate, signer, nil
+}
@@ -0,0 +1,232 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package x509creds
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testTrustAnchorArn = "arn:aws:rolesanywhere:us-west-2:123456789012:trust-anchor/4579702c-9abb-47c2-88b2-c734e0b29539"
+	testProfileArn     = "arn:aws:rolesanywhere:us-west-2:123456789012:profile/6f4943fb-13d4-4242-89c4-be367595c560"
+	testRoleArn        = "arn:aws:iam::123456789012:role/ssm-hybrid"
+)
+
+// writeCertificate writes a self-signed certificate and its private key in PEM files of a new temporary directory
+func writeCertificate(t *testing.T, key crypto.Signer, keyBlock *pem.Block) (certificateFile, keyFile string) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(4242),
+		Subject:      pkix.Name{CommonName: "mi-0123456789abcdef0"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "x509creds")
+	assert.NoError(t, err)
+	certificateFile = filepath.Join(dir, "certificate.pem")
+	keyFile = filepath.Join(dir, "private-key.pem")
+	assert.NoError(t, ioutil.WriteFile(certificateFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(keyBlock), 0600))
+	return
+}
+
+func newRSACertificate(t *testing.T) (string, string, crypto.PublicKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	certificateFile, keyFile := writeCertificate(t, key, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certificateFile, keyFile, key.Public()
+}
+
+func newECCertificate(t *testing.T) (string, string, crypto.PublicKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	certificateFile, keyFile := writeCertificate(t, key, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return certificateFile, keyFile, key.Public()
+}
+
+var authorizationPattern = regexp.MustCompile(`^(AWS4-X509-\w+-SHA256) Credential=4242/(\d{8})/us-west-2/rolesanywhere/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-x509, Signature=([0-9a-f]+)$`)
+
+// rolesAnywhereServer verifies the signature of the CreateSession requests and returns credentials
+func rolesAnywhereServer(t *testing.T, publicKey crypto.PublicKey, expiration time.Time) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/sessions", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		var request createSessionRequest
+		assert.NoError(t, json.Unmarshal(body, &request))
+		assert.Equal(t, createSessionRequest{3600, testProfileArn, testRoleArn, testTrustAnchorArn}, request)
+
+		match := authorizationPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+		if !assert.NotNil(t, match, r.Header.Get("Authorization")) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		bodyDigest := sha256.Sum256(body)
+		canonicalRequest := "POST\n/sessions\n\n" +
+			"content-type:application/json\nhost:" + r.Host + "\nx-amz-date:" + r.Header.Get("X-Amz-Date") + "\nx-amz-x509:" + r.Header.Get("X-Amz-X509") + "\n\n" +
+			"content-type;host;x-amz-date;x-amz-x509\n" + hex.EncodeToString(bodyDigest[:])
+		requestDigest := sha256.Sum256([]byte(canonicalRequest))
+		stringToSign := match[1] + "\n" + r.Header.Get("X-Amz-Date") + "\n" + match[2] + "/us-west-2/rolesanywhere/aws4_request\n" + hex.EncodeToString(requestDigest[:])
+		digest := sha256.Sum256([]byte(stringToSign))
+		signature, _ := hex.DecodeString(match[3])
+
+		switch key := publicKey.(type) {
+		case *rsa.PublicKey:
+			assert.Equal(t, "AWS4-X509-RSA-SHA256", match[1])
+			assert.NoError(t, rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature))
+		case *ecdsa.PublicKey:
+			assert.Equal(t, "AWS4-X509-ECDSA-SHA256", match[1])
+			var ecdsaSignature struct{ R, S *big.Int }
+			_, err := asn1.Unmarshal(signature, &ecdsaSignature)
+			assert.NoError(t, err)
+			assert.True(t, ecdsa.Verify(key, digest[:], ecdsaSignature.R, ecdsaSignature.S))
+		}
+		der, _ := base64.StdEncoding.DecodeString(r.Header.Get("X-Amz-X509"))
+		certificate, err := x509.ParseCertificate(der)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4242), certificate.SerialNumber.Int64())
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"credentialSet":[{"credentials":{"accessKeyId":"ASIAEXAMPLE","secretAccessKey":"secret","sessionToken":"token","expiration":"` +
+			expiration.Format(time.RFC3339) + `"}}]}`))
+	}))
+}
+
+func newTestProvider(t *testing.T, certificateFile, keyFile, endpoint string) *x509RoleProvider {
+	provider, err := NewProvider(Config{
+		CertificateFile: certificateFile,
+		PrivateKeyFile:  keyFile,
+		TrustAnchorArn:  testTrustAnchorArn,
+		ProfileArn:      testProfileArn,
+		RoleArn:         testRoleArn,
+		Endpoint:        endpoint,
+		DurationSeconds: 3600,
+	})
+	assert.NoError(t, err)
+	return provider.(*x509RoleProvider)
+}
+
+func TestRetrieveWithRSACertificate(t *testing.T) {
+	certificateFile, keyFile, publicKey := newRSACertificate(t)
+	defer os.RemoveAll(filepath.Dir(certificateFile))
+	server := rolesAnywhereServer(t, publicKey, time.Now().Add(time.Hour))
+	defer server.Close()
+	provider := newTestProvider(t, certificateFile, keyFile, server.URL)
+
+	value, err := provider.Retrieve()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ASIAEXAMPLE", value.AccessKeyID)
+	assert.Equal(t, "secret", value.SecretAccessKey)
+	assert.Equal(t, "token", value.SessionToken)
+	assert.Equal(t, ProviderName, value.ProviderName)
+	assert.False(t, provider.IsExpired())
+}
+
+func TestRetrieveWithECCertificate(t *testing.T) {
+	certificateFile, keyFile, publicKey := newECCertificate(t)
+	defer os.RemoveAll(filepath.Dir(certificateFile))
+	server := rolesAnywhereServer(t, publicKey, time.Now().Add(time.Hour))
+	defer server.Close()
+	provider := newTestProvider(t, certificateFile, keyFile, server.URL)
+
+	value, err := provider.Retrieve()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ASIAEXAMPLE", value.AccessKeyID)
+}
+
+func TestRetrieveExpiresAtHalfLifetime(t *testing.T) {
+	certificateFile, keyFile, publicKey := newRSACertificate(t)
+	defer os.RemoveAll(filepath.Dir(certificateFile))
+	server := rolesAnywhereServer(t, publicKey, time.Now().Add(time.Minute))
+	defer server.Close()
+	provider := newTestProvider(t, certificateFile, keyFile, server.URL)
+
+	_, err := provider.Retrieve()
+	assert.NoError(t, err)
+	assert.False(t, provider.IsExpired())
+
+	provider.CurrentTime = func() time.Time { return time.Now().Add(31 * time.Second) }
+	assert.True(t, provider.IsExpired())
+}
+
+func TestRetrieveAccessDenied(t *testing.T) {
+	certificateFile, keyFile, _ := newRSACertificate(t)
+	defer os.RemoveAll(filepath.Dir(certificateFile))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"Untrusted certificate."}`))
+	}))
+	defer server.Close()
+	provider := newTestProvider(t, certificateFile, keyFile, server.URL)
+
+	value, err := provider.Retrieve()
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+	assert.Equal(t, ProviderName, value.ProviderName)
+	assert.True(t, provider.IsExpired())
+}
+
+func TestRetrieveMissingCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "x509creds")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	provider := newTestProvider(t, filepath.Join(dir, "missing.pem"), "key.pem", "https://rolesanywhere.example.com")
+
+	_, err = provider.Retrieve()
+
+	assert.Error(t, err)
+}
+
+func TestNewProvider(t *testing.T) {
+	_, err := NewProvider(Config{TrustAnchorArn: testTrustAnchorArn, ProfileArn: testProfileArn, RoleArn: testRoleArn})
+	assert.Error(t, err)
+
+	_, err = NewProvider(Config{CertificateFile: "certificate.pem", PrivateKeyFile: "key.pem", TrustAnchorArn: "trust-anchor", ProfileArn: testProfileArn, RoleArn: testRoleArn})
+	assert.Error(t, err)
+
+	provider, err := NewProvider(Config{CertificateFile: "certificate.pem", PrivateKeyFile: "key.pem", TrustAnchorArn: testTrustAnchorArn, ProfileArn: testProfileArn, RoleArn: testRoleArn})
+	assert.NoError(t, err)
+	assert.Equal(t, "us-west-2", provider.(*x509RoleProvider).config.Region)
+	assert.Equal(t, "https://rolesanywhere.us-west-2.amazonaws.com", provider.(*x509RoleProvider).config.Endpoint)
+}
@@ -0,0 +1,220 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package metrics implements a registry of counters, gauges and histograms
+// describing the internals of the agent, and exports them in the OpenMetrics text format.
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	counterType   = "counter"
+	gaugeType     = "gauge"
+	histogramType = "histogram"
+)
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the histograms measuring durations
+var DefaultDurationBuckets = []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600}
+
+// DefaultSizeBuckets are the upper bounds, in bytes, of the histograms measuring payload sizes
+var DefaultSizeBuckets = []float64{1 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20}
+
+// Labels identify a series within a metric
+type Labels map[string]string
+
+// Registry holds the metrics of the agent.
+// It is safe for concurrent use, and a nil registry hands out metrics which record nothing.
+type Registry struct {
+	lock     sync.Mutex
+	families map[string]*family
+}
+
+// family is a named metric and all its labelled series
+type family struct {
+	name       string
+	help       string
+	metricType string
+	buckets    []float64
+	series     map[string]*series
+}
+
+// series is a single time series of a family
+type series struct {
+	lock   sync.Mutex
+	labels string
+	value  float64
+	counts []uint64
+	count  uint64
+}
+
+// Counter is a monotonically increasing value
+type Counter struct{ s *series }
+
+// Gauge is a value that can go up and down
+type Gauge struct{ s *series }
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	s       *series
+	buckets []float64
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Counter returns the counter with the given name and labels, creating it if needed
+func (r *Registry) Counter(name string, help string, labels Labels) *Counter {
+	if s, _ := r.series(name, help, counterType, nil, labels); s != nil {
+		return &Counter{s: s}
+	}
+	return nil
+}
+
+// Gauge returns the gauge with the given name and labels, creating it if needed
+func (r *Registry) Gauge(name string, help string, labels Labels) *Gauge {
+	if s, _ := r.series(name, help, gaugeType, nil, labels); s != nil {
+		return &Gauge{s: s}
+	}
+	return nil
+}
+
+// Histogram returns the histogram with the given name and labels, creating it if needed.
+// The buckets of a histogram are set by its first registration.
+func (r *Registry) Histogram(name string, help string, buckets []float64, labels Labels) *Histogram {
+	if s, bounds := r.series(name, help, histogramType, buckets, labels); s != nil {
+		return &Histogram{s: s, buckets: bounds}
+	}
+	return nil
+}
+
+// series returns the series of the named family and the buckets of the family.
+// The series is nil if the name is registered with another type.
+func (r *Registry) series(name string, help string, metricType string, buckets []float64, labels Labels) (*series, []float64) {
+	if r == nil {
+		return nil, nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	f, found := r.families[name]
+	if !found {
+		sorted := append([]float64{}, buckets...)
+		sort.Float64s(sorted)
+		f = &family{name: name, help: help, metricType: metricType, buckets: sorted, series: make(map[string]*series)}
+		r.families[name] = f
+	}
+	if f.metricType != metricType {
+		return nil, nil
+	}
+
+	key := formatLabels(labels)
+	s, found := f.series[key]
+	if !found {
+		s = &series{labels: key, counts: make([]uint64, len(f.buckets))}
+		f.series[key] = s
+	}
+	return s, f.buckets
+}
+
+// Inc increments the counter by one
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increases the counter, negative values are ignored
+func (c *Counter) Add(value float64) {
+	if c == nil || value < 0 {
+		return
+	}
+	c.s.lock.Lock()
+	defer c.s.lock.Unlock()
+	c.s.value += value
+}
+
+// Set sets the gauge to the given value
+func (g *Gauge) Set(value float64) {
+	if g == nil {
+		return
+	}
+	g.s.lock.Lock()
+	defer g.s.lock.Unlock()
+	g.s.value = value
+}
+
+// Add adds the given value to the gauge
+func (g *Gauge) Add(value float64) {
+	if g == nil {
+		return
+	}
+	g.s.lock.Lock()
+	defer g.s.lock.Unlock()
+	g.s.value += value
+}
+
+// Inc increments the gauge by one
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec decrements the gauge by one
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Observe records a value in the histogram
+func (h *Histogram) Observe(value float64) {
+	if h == nil {
+		return
+	}
+	h.s.lock.Lock()
+	defer h.s.lock.Unlock()
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.s.counts[i]++
+		}
+	}
+	h.s.count++
+	h.s.value += value
+}
+
+// ObserveSince records the seconds elapsed since start in the histogram
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// formatLabels formats the labels in the exposition format, sorted by name
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+`="`+labelValueReplacer.Replace(labels[name])+`"`)
+	}
+	return strings.Join(pairs, ",")
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,98 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeMetrics(t *testing.T, registry *Registry) string {
+	var buffer bytes.Buffer
+	assert.NoError(t, registry.WriteOpenMetrics(&buffer))
+	return buffer.String()
+}
+
+func TestCounter(t *testing.T) {
+	registry := NewRegistry()
+	registry.Counter("documents", "Documents processed.", Labels{"status": "Success"}).Inc()
+	registry.Counter("documents", "Documents processed.", Labels{"status": "Success"}).Add(2)
+	registry.Counter("documents", "Documents processed.", Labels{"status": "Failed"}).Add(-1)
+
+	assert.Equal(t,
+		"# TYPE documents counter\n"+
+			"# HELP documents Documents processed.\n"+
+			"documents_total{status=\"Failed\"} 0\n"+
+			"documents_total{status=\"Success\"} 3\n"+
+			"# EOF\n",
+		writeMetrics(t, registry))
+}
+
+func TestGauge(t *testing.T) {
+	registry := NewRegistry()
+	gauge := registry.Gauge("sessions", "", nil)
+	gauge.Set(5)
+	gauge.Inc()
+	gauge.Dec()
+	gauge.Dec()
+
+	assert.Equal(t, "# TYPE sessions gauge\nsessions 4\n# EOF\n", writeMetrics(t, registry))
+}
+
+func TestHistogram(t *testing.T) {
+	registry := NewRegistry()
+	histogram := registry.Histogram("poll_seconds", "", []float64{10, 1}, Labels{"service": "MessageDeliveryService"})
+	histogram.Observe(0.5)
+	histogram.Observe(2)
+	histogram.Observe(20)
+
+	assert.Equal(t,
+		"# TYPE poll_seconds histogram\n"+
+			"poll_seconds_bucket{service=\"MessageDeliveryService\",le=\"1\"} 1\n"+
+			"poll_seconds_bucket{service=\"MessageDeliveryService\",le=\"10\"} 2\n"+
+			"poll_seconds_bucket{service=\"MessageDeliveryService\",le=\"+Inf\"} 3\n"+
+			"poll_seconds_sum{service=\"MessageDeliveryService\"} 22.5\n"+
+			"poll_seconds_count{service=\"MessageDeliveryService\"} 3\n"+
+			"# EOF\n",
+		writeMetrics(t, registry))
+}
+
+func TestTypeConflictIsIgnored(t *testing.T) {
+	registry := NewRegistry()
+	registry.Counter("sessions", "", nil).Inc()
+
+	gauge := registry.Gauge("sessions", "", nil)
+	gauge.Set(10)
+
+	assert.Nil(t, gauge)
+	assert.Contains(t, writeMetrics(t, registry), "sessions_total 1\n")
+}
+
+func TestLabelValuesAreEscaped(t *testing.T) {
+	registry := NewRegistry()
+	registry.Gauge("plugins", "", Labels{"name": "a\"b\\c\nd"}).Set(1)
+
+	assert.Contains(t, writeMetrics(t, registry), `plugins{name="a\"b\\c\nd"} 1`)
+}
+
+func TestNilRegistry(t *testing.T) {
+	var registry *Registry
+	registry.Counter("documents", "", nil).Inc()
+	registry.Gauge("sessions", "", nil).Set(1)
+	registry.Histogram("poll_seconds", "", DefaultDurationBuckets, nil).Observe(1)
+
+	assert.Equal(t, "# EOF\n", writeMetrics(t, registry))
+}
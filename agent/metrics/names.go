@@ -0,0 +1,42 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+// Names of the metrics reported by the agent
+const (
+	// MessagesReceived counts the messages received by a message service
+	MessagesReceived = "ssm_agent_messages_received"
+	// MessagePollDuration measures the latency of polling a message service for messages
+	MessagePollDuration = "ssm_agent_message_poll_duration_seconds"
+	// StopPolicyErrors is the number of consecutive errors counted by a stop policy
+	StopPolicyErrors = "ssm_agent_stop_policy_errors"
+
+	// DocumentsSubmitted counts the documents submitted to a processor
+	DocumentsSubmitted = "ssm_agent_documents_submitted"
+	// DocumentsCompleted counts the documents which completed, by final status
+	DocumentsCompleted = "ssm_agent_documents_completed"
+	// TaskPoolJobs is the number of jobs queued or running in a task pool
+	TaskPoolJobs = "ssm_agent_task_pool_jobs"
+	// PluginDuration measures the execution time of plugins
+	PluginDuration = "ssm_agent_plugin_duration_seconds"
+
+	// Sessions is the number of sessions currently open
+	Sessions = "ssm_agent_sessions"
+
+	// AssociationsProcessed counts the association runs, by outcome
+	AssociationsProcessed = "ssm_agent_associations_processed"
+
+	// InventoryUploadBytes measures the size of the inventory uploaded to SSM
+	InventoryUploadBytes = "ssm_agent_inventory_upload_bytes"
+)
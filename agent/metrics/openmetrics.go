@@ -0,0 +1,123 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package metrics implements a registry of counters, gauges and histograms
+// describing the internals of the agent, and exports them in the OpenMetrics text format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenMetricsContentType is the content type of the OpenMetrics text exposition format
+const OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// WriteOpenMetrics writes all the metrics of the registry in the OpenMetrics text format
+func (r *Registry) WriteOpenMetrics(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	for _, f := range r.sortedFamilies() {
+		fmt.Fprintf(out, "# TYPE %v %v\n", f.name, f.metricType)
+		if f.help != "" {
+			fmt.Fprintf(out, "# HELP %v %v\n", f.name, helpReplacer.Replace(f.help))
+		}
+		for _, s := range f.sortedSeries() {
+			s.lock.Lock()
+			switch f.metricType {
+			case counterType:
+				writeSample(out, f.name+"_total", s.labels, "", s.value)
+			case gaugeType:
+				writeSample(out, f.name, s.labels, "", s.value)
+			case histogramType:
+				for i, bound := range f.buckets {
+					writeSample(out, f.name+"_bucket", s.labels, `le="`+formatValue(bound)+`"`, float64(s.counts[i]))
+				}
+				writeSample(out, f.name+"_bucket", s.labels, `le="+Inf"`, float64(s.count))
+				writeSample(out, f.name+"_sum", s.labels, "", s.value)
+				writeSample(out, f.name+"_count", s.labels, "", float64(s.count))
+			}
+			s.lock.Unlock()
+		}
+	}
+	fmt.Fprint(out, "# EOF\n")
+	return out.Flush()
+}
+
+// sortedFamilies returns the families of the registry sorted by name
+func (r *Registry) sortedFamilies() []*family {
+	if r == nil {
+		return nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, &family{name: f.name, help: f.help, metricType: f.metricType, buckets: f.buckets, series: f.copySeries()})
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+	return families
+}
+
+// copySeries copies the series map so the family can be written without holding the registry lock
+func (f *family) copySeries() map[string]*series {
+	series := make(map[string]*series, len(f.series))
+	for key, s := range f.series {
+		series[key] = s
+	}
+	return series
+}
+
+// sortedSeries returns the series of the family sorted by labels
+func (f *family) sortedSeries() []*series {
+	series := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		series = append(series, s)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].labels < series[j].labels })
+	return series
+}
+
+// writeSample writes a single sample line
+func writeSample(out io.Writer, name string, labels string, extraLabel string, value float64) {
+	if extraLabel != "" {
+		if labels != "" {
+			labels += ","
+		}
+		labels += extraLabel
+	}
+	if labels != "" {
+		fmt.Fprintf(out, "%v{%v} %v\n", name, labels, formatValue(value))
+		return
+	}
+	fmt.Fprintf(out, "%v %v\n", name, formatValue(value))
+}
+
+// formatValue formats a sample value as required by the exposition format
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
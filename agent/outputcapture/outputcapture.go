@@ -0,0 +1,207 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package outputcapture keeps a bounded view of a stream of command output.
+//
+// The beginning and the end of the output are kept in memory for the reply, while the whole
+// output is hashed, counted and, past a threshold, spilled to disk instead of being buffered.
+package outputcapture
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"os"
+	"unicode/utf8"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+// DefaultSpillThreshold is the number of bytes of output kept in memory before it is spilled to disk
+const DefaultSpillThreshold = 64 * 1024
+
+// Config holds the limits of a capture
+type Config struct {
+	// HeadSize is the number of bytes kept from the beginning of the output
+	HeadSize int
+	// TailSize is the number of bytes kept from the end of the output
+	TailSize int
+	// Marker replaces the middle of the output when it does not fit in the head and the tail
+	Marker string
+	// SpillFile is the file the whole output is written to once it exceeds SpillThreshold, it's truncated when the
+	// capture starts. The output that does not fit in the head and the tail is dropped when empty.
+	SpillFile string
+	// SpillThreshold is the number of bytes of output kept in memory before it is spilled to SpillFile
+	SpillThreshold int
+}
+
+// Capture is an io.Writer keeping the head and the tail of the output written to it
+type Capture struct {
+	config Config
+	head   []byte
+	// tail holds the end of the output past the head, it is compacted when it reaches twice TailSize
+	tail    []byte
+	pending []byte
+	spill   *os.File
+	spilled bool
+	bytes   int64
+	hash    hash.Hash
+	err     error
+}
+
+// New returns a capture with the given limits
+func New(config Config) *Capture {
+	if config.HeadSize < 0 {
+		config.HeadSize = 0
+	}
+	if config.TailSize < 0 {
+		config.TailSize = 0
+	}
+	capture := &Capture{
+		config: config,
+		hash:   sha256.New(),
+	}
+	// the spill file only holds the output of this capture, the one of an earlier run of the plugin is removed
+	if config.SpillFile != "" {
+		if err := os.Truncate(config.SpillFile, 0); err != nil && !os.IsNotExist(err) {
+			capture.err = err
+		}
+	}
+	return capture
+}
+
+// Write captures p, it never fails so that the stream is always drained; spill errors are returned by Close
+func (c *Capture) Write(p []byte) (int, error) {
+	c.bytes += int64(len(p))
+	c.hash.Write(p)
+
+	rest := p
+	if room := c.config.HeadSize - len(c.head); room > 0 {
+		if room > len(rest) {
+			room = len(rest)
+		}
+		c.head = append(c.head, rest[:room]...)
+		rest = rest[room:]
+	}
+	c.appendTail(rest)
+	c.store(p)
+	return len(p), nil
+}
+
+// appendTail keeps the last TailSize bytes of the output past the head
+func (c *Capture) appendTail(p []byte) {
+	if c.config.TailSize == 0 || len(p) == 0 {
+		return
+	}
+	if len(p) >= c.config.TailSize {
+		c.tail = append(c.tail[:0], p[len(p)-c.config.TailSize:]...)
+		return
+	}
+	if len(c.tail)+len(p) > 2*c.config.TailSize {
+		keep := c.config.TailSize - len(p)
+		c.tail = append(c.tail[:0], c.tail[len(c.tail)-keep:]...)
+	}
+	c.tail = append(c.tail, p...)
+}
+
+// store keeps the whole output in memory until it exceeds the spill threshold, and appends it to the spill file after that
+func (c *Capture) store(p []byte) {
+	if c.config.SpillFile == "" || c.err != nil {
+		return
+	}
+	if !c.spilled && len(c.pending)+len(p) <= c.config.SpillThreshold {
+		c.pending = append(c.pending, p...)
+		return
+	}
+	if !c.spilled {
+		c.spilled = true
+		if c.spill, c.err = os.OpenFile(c.config.SpillFile, appconfig.FileFlagsCreateOrTruncate, appconfig.ReadWriteAccess); c.err != nil {
+			c.pending = nil
+			return
+		}
+		p = append(c.pending, p...)
+		c.pending = nil
+	}
+	_, c.err = c.spill.Write(p)
+}
+
+// Close closes the spill file and returns the first error met while spilling the output
+func (c *Capture) Close() error {
+	if c.spill != nil {
+		if err := c.spill.Close(); c.err == nil {
+			c.err = err
+		}
+		c.spill = nil
+	}
+	return c.err
+}
+
+// Truncated returns true when the output does not fit in the head and the tail
+func (c *Capture) Truncated() bool {
+	return c.bytes > int64(len(c.head)+c.config.TailSize)
+}
+
+// Bytes returns the size of the whole output
+func (c *Capture) Bytes() int64 {
+	return c.bytes
+}
+
+// String returns the head and the tail of the output, separated by the marker when the middle was dropped.
+// Characters cut by the windows are dropped as well.
+func (c *Capture) String() string {
+	tail := c.tail
+	if len(tail) > c.config.TailSize {
+		tail = tail[len(tail)-c.config.TailSize:]
+	}
+	if !c.Truncated() {
+		return string(c.head) + string(tail)
+	}
+	return string(trimIncompleteRune(c.head)) + c.config.Marker + string(trimLeadingContinuation(tail))
+}
+
+// Summary describes the whole output
+func (c *Capture) Summary() contracts.OutputSummary {
+	summary := contracts.OutputSummary{
+		Bytes:     c.bytes,
+		SHA256:    hex.EncodeToString(c.hash.Sum(nil)),
+		Truncated: c.Truncated(),
+	}
+	if c.spilled && c.err == nil {
+		summary.File = c.config.SpillFile
+	}
+	return summary
+}
+
+// trimIncompleteRune removes a multi-byte character cut at the end of p
+func trimIncompleteRune(p []byte) []byte {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if !utf8.FullRune(p[i:]) {
+				return p[:i]
+			}
+			break
+		}
+	}
+	return p
+}
+
+// trimLeadingContinuation removes a multi-byte character cut at the beginning of p
+func trimLeadingContinuation(p []byte) []byte {
+	for i := 0; i < len(p) && i < utf8.UTFMax; i++ {
+		if utf8.RuneStart(p[i]) {
+			return p[i:]
+		}
+	}
+	return p
+}
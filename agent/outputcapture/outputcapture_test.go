@@ -0,0 +1,121 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package outputcapture
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptureKeepsShortOutput(t *testing.T) {
+	capture := New(Config{HeadSize: 10, TailSize: 10, Marker: "..."})
+	capture.Write([]byte("short "))
+	capture.Write([]byte("output"))
+
+	assert.NoError(t, capture.Close())
+	assert.Equal(t, "short output", capture.String())
+	assert.False(t, capture.Truncated())
+	summary := capture.Summary()
+	assert.Equal(t, int64(12), summary.Bytes)
+	assert.Equal(t, sha256Hex("short output"), summary.SHA256)
+	assert.Empty(t, summary.File)
+}
+
+func TestCaptureKeepsHeadAndTail(t *testing.T) {
+	capture := New(Config{HeadSize: 5, TailSize: 5, Marker: "..."})
+	output := ""
+	for i := 0; i < 1000; i++ {
+		chunk := strings.Repeat(string('a'+byte(i%26)), i%7+1)
+		output += chunk
+		capture.Write([]byte(chunk))
+	}
+
+	assert.Equal(t, output[:5]+"..."+output[len(output)-5:], capture.String())
+	assert.True(t, capture.Truncated())
+	assert.True(t, cap(capture.tail) <= 20)
+	summary := capture.Summary()
+	assert.Equal(t, int64(len(output)), summary.Bytes)
+	assert.Equal(t, sha256Hex(output), summary.SHA256)
+	assert.True(t, summary.Truncated)
+}
+
+func TestCaptureDropsCutCharacters(t *testing.T) {
+	capture := New(Config{HeadSize: 4, TailSize: 3, Marker: "|"})
+	capture.Write([]byte("abc℃defghijkl℃x"))
+
+	assert.Equal(t, "abc|x", capture.String())
+}
+
+func TestCaptureSpillsPastThreshold(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outputcapture")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	spillFile := filepath.Join(dir, "stdoutConsole")
+
+	capture := New(Config{HeadSize: 2, TailSize: 2, Marker: "..", SpillFile: spillFile, SpillThreshold: 8})
+	capture.Write([]byte("12345"))
+	_, err = os.Stat(spillFile)
+	assert.True(t, os.IsNotExist(err))
+
+	capture.Write([]byte("67890"))
+	capture.Write([]byte("abc"))
+	assert.NoError(t, capture.Close())
+
+	content, err := ioutil.ReadFile(spillFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567890abc", string(content))
+	assert.Equal(t, "12..bc", capture.String())
+	assert.Equal(t, spillFile, capture.Summary().File)
+}
+
+func TestCaptureTruncatesSpillFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outputcapture")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	spillFile := filepath.Join(dir, "stdoutConsole")
+	assert.NoError(t, ioutil.WriteFile(spillFile, []byte("output of an earlier run\n"), 0600))
+
+	capture := New(Config{HeadSize: 2, TailSize: 2, SpillFile: spillFile, SpillThreshold: 4})
+	content, err := ioutil.ReadFile(spillFile)
+	assert.NoError(t, err)
+	assert.Empty(t, content)
+
+	capture.Write([]byte("123456"))
+	assert.NoError(t, capture.Close())
+	content, err = ioutil.ReadFile(spillFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "123456", string(content))
+}
+
+func TestCaptureReportsSpillError(t *testing.T) {
+	capture := New(Config{HeadSize: 2, TailSize: 2, SpillFile: filepath.Join("missing", "directory", "file"), SpillThreshold: 1})
+	n, err := capture.Write([]byte("output"))
+
+	assert.Equal(t, 6, n)
+	assert.NoError(t, err)
+	assert.Error(t, capture.Close())
+	assert.Empty(t, capture.Summary().File)
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -48,6 +48,11 @@ type IPackageArchive interface {
 	WriteManifestToCache(packageArn string, version string, manifest []byte) error
 }
 
+// IVersionLister is implemented by archives that can list the published versions of a package
+type IVersionLister interface {
+	ListVersions(tracer trace.Tracer, packageName string) ([]string, error)
+}
+
 func ParseManifest(data *[]byte) (*birdwatcher.Manifest, error) {
 	var manifest birdwatcher.Manifest
 
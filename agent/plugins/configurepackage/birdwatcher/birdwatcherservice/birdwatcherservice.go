@@ -120,6 +120,15 @@ func (ds *PackageService) DownloadArtifact(tracer trace.Tracer, packageName stri
 	return downloadFile(ds, tracer, file, packageName, version)
 }
 
+// ListVersions returns the published versions of a package, or none if the archive cannot list them
+func (ds *PackageService) ListVersions(tracer trace.Tracer, packageName string) ([]string, error) {
+	lister, ok := ds.packageArchive.(archive.IVersionLister)
+	if !ok {
+		return nil, nil
+	}
+	return lister.ListVersions(tracer, packageName)
+}
+
 // ReportResult sents back the result of the install/upgrade/uninstall run back to Birdwatcher
 func (ds *PackageService) ReportResult(tracer trace.Tracer, result packageservice.PackageResult) error {
 	log := tracer.CurrentTrace().Logger
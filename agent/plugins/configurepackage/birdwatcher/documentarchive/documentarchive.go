@@ -201,6 +201,27 @@ func (da *PackageArchive) DownloadArchiveInfo(tracer trace.Tracer, packageName s
 	return da.manifest, nil
 }
 
+// ListVersions returns the version names of the active versions of the package document
+func (da *PackageArchive) ListVersions(tracer trace.Tracer, packageName string) ([]string, error) {
+	versions := []string{}
+	input := &ssm.ListDocumentVersionsInput{Name: &packageName}
+	for {
+		resp, err := da.facadeClient.ListDocumentVersions(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list package document versions: %v", err)
+		}
+		for _, info := range resp.DocumentVersions {
+			if info.VersionName != nil && info.Status != nil && *info.Status == ssm.DocumentStatusActive {
+				versions = append(versions, *info.VersionName)
+			}
+		}
+		if resp.NextToken == nil || *resp.NextToken == "" {
+			return versions, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
 // ReadManifestFromCache reads the manifest that was stored in manifestCache, if present
 // Document packages store the manifest with the document version
 func (da *PackageArchive) ReadManifestFromCache(packageArn string, version string) (*birdwatcher.Manifest, error) {
@@ -97,6 +97,30 @@ func TestGetResourceVersion(t *testing.T) {
 	}
 }
 
+func TestListVersions(t *testing.T) {
+	active := ssm.DocumentStatusActive
+	failed := ssm.DocumentStatusFailed
+	v1, v2, v3 := "1.0.0", "1.1.0", "2.0.0"
+	facadeSession := facade.FacadeStub{
+		ListDocumentVersionsOutput: &ssm.ListDocumentVersionsOutput{
+			DocumentVersions: []*ssm.DocumentVersionInfo{
+				{VersionName: &v1, Status: &active},
+				{VersionName: &v2, Status: &failed},
+				{VersionName: &v3, Status: &active},
+				{Status: &active},
+			},
+		},
+	}
+	tracer := trace.NewTracer(log.NewMockLog())
+	testArchive := New(&facadeSession).(archive.IVersionLister)
+
+	versions, err := testArchive.ListVersions(tracer, "packageName")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "2.0.0"}, versions)
+	assert.Equal(t, "packageName", *facadeSession.ListDocumentVersionsInput.Name)
+}
+
 func TestSetAndGetResources(t *testing.T) {
 	manifest := birdwatcher.Manifest{}
 	packageName := "packagename"
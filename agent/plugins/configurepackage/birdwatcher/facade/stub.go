@@ -37,6 +37,10 @@ type FacadeStub struct {
 	DescribeDocumentInput  *ssm.DescribeDocumentInput
 	DescribeDocumentOutput *ssm.DescribeDocumentOutput
 	DescribeDocumentError  error
+
+	ListDocumentVersionsInput  *ssm.ListDocumentVersionsInput
+	ListDocumentVersionsOutput *ssm.ListDocumentVersionsOutput
+	ListDocumentVersionsError  error
 }
 
 func (m *FacadeStub) GetManifestRequest(*ssm.GetManifestInput) (*request.Request, *ssm.GetManifestOutput) {
@@ -74,3 +78,12 @@ func (m *FacadeStub) DescribeDocument(input *ssm.DescribeDocumentInput) (*ssm.De
 	m.DescribeDocumentInput = input
 	return m.DescribeDocumentOutput, m.DescribeDocumentError
 }
+
+func (m *FacadeStub) ListDocumentVersionsRequest(*ssm.ListDocumentVersionsInput) (*request.Request, *ssm.ListDocumentVersionsOutput) {
+	panic("not implemented")
+}
+
+func (m *FacadeStub) ListDocumentVersions(input *ssm.ListDocumentVersionsInput) (*ssm.ListDocumentVersionsOutput, error) {
+	m.ListDocumentVersionsInput = input
+	return m.ListDocumentVersionsOutput, m.ListDocumentVersionsError
+}
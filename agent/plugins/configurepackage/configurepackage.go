@@ -44,6 +44,14 @@ const (
 	InstallAction = "Install"
 	// UninstallAction represents the json command to uninstall package
 	UninstallAction = "Uninstall"
+	// PinAction represents the json command to pin a package to a version
+	PinAction = "Pin"
+	// UnpinAction represents the json command to remove the version pin of a package
+	UnpinAction = "Unpin"
+	// HoldAction represents the json command to prevent changes to the installed version of a package
+	HoldAction = "Hold"
+	// UnholdAction represents the json command to release the hold of a package
+	UnholdAction = "Unhold"
 	// InstallationType specifies whether the
 	InstallationTypeLegacy  = "Uninstall and reinstall"
 	InstallationTypeInPlace = "In-place update"
@@ -362,7 +370,9 @@ func checkAlreadyInstalled(
 			if validateOutput.GetStatus() == contracts.ResultStatusSuccess {
 				if installState == localpackages.Installing || installState == localpackages.Updating {
 					validateTrace.AppendInfof("Successfully installed %v %v", packageName, targetVersion)
-					if uninst != nil {
+					if uninst != nil && installState == localpackages.Updating {
+						retainPreviousVersion(tracer, repository, inst, uninst)
+					} else if uninst != nil {
 						cleanupAfterUninstall(tracer, repository, uninst, output)
 					}
 					output.MarkAsSucceeded()
@@ -482,6 +492,9 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 	defer tracer.BeginSection("configurePackage").End()
 
 	out := trace.PluginOutputTrace{Tracer: tracer}
+	if checkpoint := output.GetCheckpoint(); checkpoint != "" {
+		log.Infof("resuming after the document worker restarted, the last install stage was %v", checkpoint)
+	}
 
 	if cancelFlag.ShutDown() {
 		out.MarkAsShutdown()
@@ -526,21 +539,41 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 			} else {
 				defer p.localRepository.UnlockPackage(tracer, packageArn)
 
-				log.Debugf("Prepare for %v %v %v", input.Action, input.Name, input.Version)
-				inst, uninst, isUpdateInPlace, installState, installedVersion := prepareConfigurePackage(
-					tracer,
-					config,
-					p.localRepository,
-					packageService,
-					input,
-					packageArn,
-					manifestVersion,
-					isSameAsCache,
-					&out)
+				if isVersionControlAction(input.Action) {
+					setVersionControl(tracer, p.localRepository, input, packageArn, manifestVersion, &out)
+				} else {
+					manifestVersion, isSameAsCache = applyVersionControl(tracer, p.localRepository, packageService, input, packageArn, manifestVersion, isSameAsCache, &out)
+				}
+
+				var inst, uninst installer.Installer
+				var isUpdateInPlace bool
+				var installState localpackages.InstallState
+				var installedVersion string
+				if out.GetStatus() != contracts.ResultStatusFailed && out.GetStatus() != contracts.ResultStatusSuccess {
+					log.Debugf("Prepare for %v %v %v", input.Action, input.Name, input.Version)
+					inst, uninst, isUpdateInPlace, installState, installedVersion = prepareConfigurePackage(
+						tracer,
+						config,
+						p.localRepository,
+						packageService,
+						input,
+						packageArn,
+						manifestVersion,
+						isSameAsCache,
+						&out)
+				}
 				log.Debugf("HasInst %v, HasUninst %v, IsInplaceUpdate %v, InstallState %v, PackageName %v, InstalledVersion %v", inst != nil, uninst != nil, isUpdateInPlace, installState, packageArn, installedVersion)
 
+				// install the packages this package depends on first, holding their locks until the package is installed
+				var changedDependencies []*changedDependency
+				if input.Action == InstallAction && out.GetStatus() != contracts.ResultStatusFailed && out.GetStatus() != contracts.ResultStatusSuccess {
+					var lockedDependencies []string
+					changedDependencies, lockedDependencies = p.configureDependencies(tracer, context, config, packageService, packageArn, manifestVersion, &out)
+					defer unlockDependencies(tracer, p.localRepository, lockedDependencies)
+				}
+
 				//if the status is already decided as failed or succeeded, do not execute anything
-				if out.GetStatus() != contracts.ResultStatusFailed && out.GetStatus() != contracts.ResultStatusSuccess {
+				if out.GetStatus() != contracts.ResultStatusFailed && out.GetStatus() != contracts.ResultStatusSuccess && !out.GetStatus().IsReboot() {
 					alreadyInstalled := checkAlreadyInstalled(tracer, context, p.localRepository, installedVersion, installState, inst, uninst, &out)
 					// if package is not installed, set isUpdateInPlace to false so as to execute install script to install
 					if installedVersion == "" || installState == localpackages.None {
@@ -553,13 +586,16 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 						executeConfigurePackage(
 							tracer,
 							context,
-							p.localRepository,
+							checkpointedRepository{Repository: p.localRepository, setCheckpoint: output.SetCheckpoint},
 							inst,
 							uninst,
 							isUpdateInPlace,
 							installState,
 							&out)
 					}
+					if out.GetStatus() == contracts.ResultStatusFailed {
+						rollbackDependencies(tracer, context, config, p.localRepository, packageService, changedDependencies)
+					}
 				}
 				if err := p.localRepository.LoadTraces(tracer, packageArn); err != nil {
 					log.Errorf("Error loading prior traces: %v", err.Error())
@@ -569,7 +605,7 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 					if err != nil {
 						log.Errorf("Error persisting traces: %v", err.Error())
 					}
-				} else {
+				} else if !isVersionControlAction(input.Action) {
 					version := manifestVersion
 					if out.GetStatus() != contracts.ResultStatusFailed && out.GetStatus() != contracts.ResultStatusSuccess {
 						if input.Action == InstallAction {
@@ -614,6 +650,41 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 	return
 }
 
+// configureDependencies resolves, locks and installs the dependencies of a package version before it is installed.
+// It returns the dependencies changed by this execution and the packages whose locks must be released.
+func (p *Plugin) configureDependencies(
+	tracer trace.Tracer,
+	context context.T,
+	config contracts.Configuration,
+	packageService packageservice.PackageService,
+	packageArn string,
+	version string,
+	output contracts.PluginOutputter) (changed []*changedDependency, locked []string) {
+
+	manifest, err := p.localRepository.GetPackageManifest(tracer, packageArn, version)
+	if err != nil {
+		tracer.CurrentTrace().WithError(err)
+		output.MarkAsFailed(nil, nil)
+		return nil, nil
+	}
+	if len(manifest.Dependencies) == 0 {
+		return nil, nil
+	}
+
+	plan, locked, err := resolveDependencies(tracer, config, p.localRepository, packageService, packageArn, manifest)
+	if err != nil {
+		output.MarkAsFailed(nil, nil)
+		return nil, locked
+	}
+
+	changed = installDependencies(tracer, context, config, p.localRepository, packageService, plan, output)
+	if output.GetStatus() == contracts.ResultStatusFailed {
+		rollbackDependencies(tracer, context, config, p.localRepository, packageService, changed)
+		return nil, locked
+	}
+	return changed, locked
+}
+
 // Name returns the name of the plugin.
 func Name() string {
 	return appconfig.PluginNameAwsConfigurePackage
@@ -0,0 +1,305 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package configurepackage implements the ConfigurePackage plugin.
+package configurepackage
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/installer"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/aws/amazon-ssm-agent/agent/versionutil"
+)
+
+// dependency is one package of the install plan computed by resolveDependencies
+type dependency struct {
+	name          string
+	packageArn    string
+	version       string
+	isSameAsCache bool
+}
+
+// changedDependency records a dependency installed by this execution so that it can be rolled back
+type changedDependency struct {
+	dependency      *dependency
+	inst            installer.Installer
+	previousVersion string
+}
+
+// dependencyResolver computes the order in which the dependencies of a package must be installed
+type dependencyResolver struct {
+	tracer         trace.Tracer
+	config         contracts.Configuration
+	repository     localpackages.Repository
+	packageService packageservice.PackageService
+
+	plan      []*dependency
+	locked    []string
+	satisfied map[string]string // dependency name to chosen (or already installed) version
+	visiting  map[string]bool
+}
+
+// resolveDependencies returns the dependencies of the package that are not installed in a satisfying version yet,
+// ordered so that every package comes after the packages it depends on.
+// Packages in the plan are locked, then downloaded and validated so that their own dependencies can be read from their manifest.
+// The locked packages are returned even on error and must be released with unlockDependencies.
+func resolveDependencies(
+	tracer trace.Tracer,
+	config contracts.Configuration,
+	repository localpackages.Repository,
+	packageService packageservice.PackageService,
+	packageName string,
+	manifest *localpackages.PackageManifest) (plan []*dependency, locked []string, err error) {
+
+	resolveTrace := tracer.BeginSection(fmt.Sprintf("resolve dependencies of %v", packageName))
+	defer resolveTrace.EndWithError(&err)
+
+	resolver := &dependencyResolver{
+		tracer:         tracer,
+		config:         config,
+		repository:     repository,
+		packageService: packageService,
+		satisfied:      make(map[string]string),
+		visiting:       map[string]bool{packageName: true},
+	}
+	for _, required := range manifest.Dependencies {
+		if err = resolver.resolve(required, packageName); err != nil {
+			return nil, resolver.locked, err
+		}
+	}
+
+	resolveTrace.AppendInfof("%v dependencies to install", len(resolver.plan))
+	return resolver.plan, resolver.locked, nil
+}
+
+// resolve adds a dependency and, depth first, its own dependencies to the plan
+func (resolver *dependencyResolver) resolve(required localpackages.PackageDependency, requiredBy string) error {
+	tracer := resolver.tracer
+
+	if resolver.visiting[required.Name] {
+		return fmt.Errorf("dependency cycle: %v depends on %v", requiredBy, required.Name)
+	}
+	if version, ok := resolver.satisfied[required.Name]; ok {
+		// Shared dependency - the version chosen for another package must also work for this one
+		if matches, err := versionutil.SatisfiesConstraint(version, required.Version); err != nil || !matches {
+			return fmt.Errorf("conflicting dependency: %v requires %v %v but version %v was selected", requiredBy, required.Name, required.Version, version)
+		}
+		return nil
+	}
+
+	requestedVersion, err := resolver.selectVersion(required, requiredBy)
+	if err != nil {
+		return err
+	}
+	name, version := resolver.packageService.GetPackageArnAndVersion(required.Name, requestedVersion)
+	packageArn, manifestVersion, isSameAsCache, err := resolver.packageService.DownloadManifest(tracer, name, version)
+	if err != nil {
+		return fmt.Errorf("failed to get manifest of dependency %v: %v", required.Name, err)
+	}
+
+	installedVersion := resolver.repository.GetInstalledVersion(tracer, packageArn)
+	installState, _ := resolver.repository.GetInstallState(tracer, packageArn)
+	pinnedVersion, hold := resolver.repository.GetVersionControl(tracer, packageArn)
+	if installedVersion != "" && installState == localpackages.Installed {
+		if matches, _ := versionutil.SatisfiesConstraint(installedVersion, required.Version); matches {
+			tracer.CurrentTrace().AppendInfof("dependency %v is satisfied by installed version %v", required.Name, installedVersion)
+			resolver.satisfied[required.Name] = installedVersion
+			return nil
+		}
+		if hold {
+			return fmt.Errorf("dependency %v is on hold at version %v which does not satisfy %v required by %v", required.Name, installedVersion, required.Version, requiredBy)
+		}
+	}
+
+	if pinnedVersion != "" && pinnedVersion != manifestVersion {
+		if matches, err := versionutil.SatisfiesConstraint(pinnedVersion, required.Version); err != nil || !matches {
+			return fmt.Errorf("dependency %v is pinned to version %v which does not satisfy %v required by %v", required.Name, pinnedVersion, required.Version, requiredBy)
+		}
+		name, version = resolver.packageService.GetPackageArnAndVersion(required.Name, pinnedVersion)
+		if packageArn, manifestVersion, isSameAsCache, err = resolver.packageService.DownloadManifest(tracer, name, version); err != nil {
+			return fmt.Errorf("failed to get manifest of pinned dependency %v %v: %v", required.Name, pinnedVersion, err)
+		}
+	}
+
+	if matches, err := versionutil.SatisfiesConstraint(manifestVersion, required.Version); err != nil || !matches {
+		return fmt.Errorf("no version of dependency %v satisfies %v required by %v (available: %v)", required.Name, required.Version, requiredBy, manifestVersion)
+	}
+
+	// hold the lock before the package is downloaded so that no other execution changes it until it is installed
+	if err = resolver.repository.LockPackage(tracer, packageArn, InstallAction); err != nil {
+		return fmt.Errorf("failed to lock dependency %v: %v", required.Name, err)
+	}
+	resolver.locked = append(resolver.locked, packageArn)
+
+	if _, err = ensurePackage(tracer, resolver.repository, resolver.packageService, packageArn, manifestVersion, isSameAsCache, resolver.config); err != nil {
+		return fmt.Errorf("failed to download dependency %v %v: %v", required.Name, manifestVersion, err)
+	}
+	manifest, err := resolver.repository.GetPackageManifest(tracer, packageArn, manifestVersion)
+	if err != nil {
+		return fmt.Errorf("invalid manifest for dependency %v %v: %v", required.Name, manifestVersion, err)
+	}
+
+	resolver.visiting[required.Name] = true
+	for _, transitive := range manifest.Dependencies {
+		if err = resolver.resolve(transitive, required.Name); err != nil {
+			return err
+		}
+	}
+	delete(resolver.visiting, required.Name)
+
+	resolver.satisfied[required.Name] = manifestVersion
+	resolver.plan = append(resolver.plan, &dependency{
+		name:          required.Name,
+		packageArn:    packageArn,
+		version:       manifestVersion,
+		isSameAsCache: isSameAsCache,
+	})
+	return nil
+}
+
+// selectVersion returns the highest published version of a dependency that satisfies its constraint.
+// Services that cannot list versions fall back to the latest version, which is checked against the constraint later.
+func (resolver *dependencyResolver) selectVersion(required localpackages.PackageDependency, requiredBy string) (string, error) {
+	if exact, ok := versionutil.ExactVersion(required.Version); ok {
+		return exact, nil
+	}
+	lister, ok := resolver.packageService.(packageservice.VersionLister)
+	if !ok {
+		return packageservice.Latest, nil
+	}
+	versions, err := lister.ListVersions(resolver.tracer, required.Name)
+	if err != nil {
+		return "", fmt.Errorf("failed to list versions of dependency %v: %v", required.Name, err)
+	}
+	if len(versions) == 0 {
+		return packageservice.Latest, nil
+	}
+
+	sort.Sort(sort.Reverse(versionutil.ByVersion(versions)))
+	for _, version := range versions {
+		if matches, err := versionutil.SatisfiesConstraint(version, required.Version); err == nil && matches {
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("no version of dependency %v satisfies %v required by %v (available: %v)", required.Name, required.Version, requiredBy, versions)
+}
+
+// unlockDependencies releases the locks taken by resolveDependencies
+func unlockDependencies(tracer trace.Tracer, repository localpackages.Repository, locked []string) {
+	for i := len(locked) - 1; i >= 0; i-- {
+		repository.UnlockPackage(tracer, locked[i])
+	}
+}
+
+// installDependencies installs the planned dependencies in order, stopping at the first failure or reboot.
+// It returns the dependencies that were changed by this execution.
+func installDependencies(
+	tracer trace.Tracer,
+	context context.T,
+	config contracts.Configuration,
+	repository localpackages.Repository,
+	packageService packageservice.PackageService,
+	plan []*dependency,
+	output contracts.PluginOutputter) (changed []*changedDependency) {
+
+	for _, dep := range plan {
+		installTrace := tracer.BeginSection(fmt.Sprintf("install dependency %v/%v", dep.name, dep.version))
+		depOutput := &trace.PluginOutputTrace{Tracer: tracer}
+		input := &ConfigurePackagePluginInput{Name: dep.name, Version: dep.version, Action: InstallAction}
+
+		inst, uninst, _, installState, installedVersion := prepareConfigurePackage(
+			tracer,
+			dependencyConfiguration(config, dep),
+			repository,
+			packageService,
+			input,
+			dep.packageArn,
+			dep.version,
+			dep.isSameAsCache,
+			depOutput)
+
+		if depOutput.GetStatus() != contracts.ResultStatusFailed && depOutput.GetStatus() != contracts.ResultStatusSuccess {
+			alreadyInstalled := checkAlreadyInstalled(tracer, context, repository, installedVersion, installState, inst, uninst, depOutput)
+			if !alreadyInstalled || !dep.isSameAsCache {
+				executeConfigurePackage(tracer, context, repository, inst, uninst, false, installState, depOutput)
+				if depOutput.GetStatus() == contracts.ResultStatusSuccess {
+					if uninst == nil {
+						changed = append(changed, &changedDependency{dependency: dep, inst: inst})
+					} else if uninst.Version() != dep.version {
+						changed = append(changed, &changedDependency{dependency: dep, inst: inst, previousVersion: uninst.Version()})
+					}
+				}
+			}
+		}
+
+		installTrace.WithExitcode(int64(depOutput.GetExitCode()))
+		if depOutput.GetStatus().IsReboot() {
+			installTrace.AppendInfof("rebooting to finish installation of dependency %v", dep.name).End()
+			output.MarkAsSuccessWithReboot()
+			return changed
+		}
+		if !depOutput.GetStatus().IsSuccess() {
+			installTrace.AppendErrorf("failed to install dependency %v %v", dep.name, dep.version).End()
+			output.MarkAsFailed(nil, nil)
+			return changed
+		}
+		installTrace.End()
+	}
+	return changed
+}
+
+// rollbackDependencies restores the dependencies changed by installDependencies in reverse dependency order.
+// Dependencies that replaced an older version get the older version reinstalled, new dependencies are uninstalled.
+func rollbackDependencies(
+	tracer trace.Tracer,
+	context context.T,
+	config contracts.Configuration,
+	repository localpackages.Repository,
+	packageService packageservice.PackageService,
+	changed []*changedDependency) {
+
+	for i := len(changed) - 1; i >= 0; i-- {
+		dep := changed[i].dependency
+		rollbackTrace := tracer.BeginSection(fmt.Sprintf("rollback dependency %v/%v", dep.name, dep.version))
+		rollbackOutput := &trace.PluginOutputTrace{Tracer: tracer}
+
+		if changed[i].previousVersion == "" {
+			executeUninstall(tracer, context, repository, nil, changed[i].inst, false, false, rollbackOutput)
+		} else {
+			previous, err := ensurePackage(tracer, repository, packageService, dep.packageArn, changed[i].previousVersion, true, dependencyConfiguration(config, dep))
+			if err != nil {
+				rollbackTrace.WithError(err).End()
+				continue
+			}
+			executeUninstall(tracer, context, repository, previous, changed[i].inst, false, true, rollbackOutput)
+		}
+		rollbackTrace.WithExitcode(int64(rollbackOutput.GetExitCode())).End()
+	}
+}
+
+// dependencyConfiguration gives each dependency its own orchestration directory and output prefix
+func dependencyConfiguration(config contracts.Configuration, dep *dependency) contracts.Configuration {
+	config.OrchestrationDirectory = fileutil.BuildPath(config.OrchestrationDirectory, "dependencies", dep.name)
+	if config.OutputS3KeyPrefix != "" {
+		config.OutputS3KeyPrefix = fileutil.BuildS3Path(config.OutputS3KeyPrefix, "dependencies", dep.name)
+	}
+	return config
+}
@@ -0,0 +1,278 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package configurepackage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
+	repoMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages/mock"
+	serviceMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice/mock"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// availablePackage describes a package offered by the mocked package service
+type availablePackage struct {
+	version      string
+	dependencies []localpackages.PackageDependency
+}
+
+func dependencyServiceMock(available map[string]availablePackage) *serviceMock.Mock {
+	mockService := serviceMock.Mock{}
+	for name, pkg := range available {
+		mockService.On("GetPackageArnAndVersion", name, mock.Anything).Return(name, pkg.version)
+		mockService.On("ListVersions", mock.Anything, name).Return([]string{pkg.version}, nil)
+		mockService.On("DownloadManifest", mock.Anything, name, pkg.version).Return(name, pkg.version, true, nil)
+	}
+	return &mockService
+}
+
+func dependencyRepoMock(available map[string]availablePackage, installed map[string]string) *repoMock.MockedRepository {
+	mockRepo := dependencyRepoStateMock(available, installed)
+	for name, pkg := range available {
+		mockRepo.On("LockPackage", mock.Anything, name, InstallAction).Return(nil)
+		mockRepo.On("ValidatePackage", mock.Anything, name, pkg.version).Return(nil)
+	}
+	return mockRepo
+}
+
+// dependencyRepoStateMock mocks the repository without locking and downloading, which tests can then record or fail
+func dependencyRepoStateMock(available map[string]availablePackage, installed map[string]string) *repoMock.MockedRepository {
+	mockRepo := repoMock.MockedRepository{}
+	for name, pkg := range available {
+		if version, ok := installed[name]; ok {
+			mockRepo.On("GetInstalledVersion", mock.Anything, name).Return(version)
+			mockRepo.On("GetInstallState", mock.Anything, name).Return(localpackages.Installed, version)
+		} else {
+			mockRepo.On("GetInstalledVersion", mock.Anything, name).Return("")
+			mockRepo.On("GetInstallState", mock.Anything, name).Return(localpackages.None, "")
+		}
+		mockRepo.On("GetVersionControl", mock.Anything, name).Return("", false)
+		mockRepo.On("GetInstaller", mock.Anything, mock.Anything, name, pkg.version).Return(installerNameVersionOnlyMock(name, pkg.version))
+		mockRepo.On("GetPackageManifest", mock.Anything, name, pkg.version).Return(&localpackages.PackageManifest{Name: name, Version: pkg.version, Dependencies: pkg.dependencies}, nil)
+	}
+	return &mockRepo
+}
+
+func planNames(plan []*dependency) []string {
+	names := []string{}
+	for _, dep := range plan {
+		names = append(names, dep.name)
+	}
+	return names
+}
+
+func newDependencyTracer() trace.Tracer {
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+	return tracer
+}
+
+func TestResolveDependenciesOrder(t *testing.T) {
+	available := map[string]availablePackage{
+		"runtime": {version: "1.4.0"},
+		"library": {version: "2.0.0", dependencies: []localpackages.PackageDependency{{Name: "runtime", Version: ">=1.2"}}},
+	}
+	manifest := &localpackages.PackageManifest{Dependencies: []localpackages.PackageDependency{
+		{Name: "library", Version: ">=2.0, <3.0"},
+		{Name: "runtime"},
+	}}
+
+	plan, _, err := resolveDependencies(newDependencyTracer(), contracts.Configuration{}, dependencyRepoMock(available, nil), dependencyServiceMock(available), "agent", manifest)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"runtime", "library"}, planNames(plan))
+	assert.Equal(t, "1.4.0", plan[0].version)
+}
+
+func TestResolveDependenciesAlreadyInstalled(t *testing.T) {
+	available := map[string]availablePackage{
+		"runtime": {version: "1.4.0"},
+	}
+	manifest := &localpackages.PackageManifest{Dependencies: []localpackages.PackageDependency{{Name: "runtime", Version: ">=1.0"}}}
+
+	plan, _, err := resolveDependencies(newDependencyTracer(), contracts.Configuration{}, dependencyRepoMock(available, map[string]string{"runtime": "1.1.0"}), dependencyServiceMock(available), "agent", manifest)
+
+	assert.NoError(t, err)
+	assert.Empty(t, plan)
+}
+
+func TestResolveDependenciesInstalledVersionTooOld(t *testing.T) {
+	available := map[string]availablePackage{
+		"runtime": {version: "1.4.0"},
+	}
+	manifest := &localpackages.PackageManifest{Dependencies: []localpackages.PackageDependency{{Name: "runtime", Version: ">=1.2"}}}
+
+	plan, _, err := resolveDependencies(newDependencyTracer(), contracts.Configuration{}, dependencyRepoMock(available, map[string]string{"runtime": "1.1.0"}), dependencyServiceMock(available), "agent", manifest)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"runtime"}, planNames(plan))
+}
+
+func TestResolveDependenciesUnsatisfiable(t *testing.T) {
+	available := map[string]availablePackage{
+		"runtime": {version: "1.4.0"},
+	}
+	manifest := &localpackages.PackageManifest{Dependencies: []localpackages.PackageDependency{{Name: "runtime", Version: ">=2.0"}}}
+
+	_, _, err := resolveDependencies(newDependencyTracer(), contracts.Configuration{}, dependencyRepoMock(available, nil), dependencyServiceMock(available), "agent", manifest)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no version of dependency runtime satisfies >=2.0")
+}
+
+func TestResolveDependenciesConflict(t *testing.T) {
+	available := map[string]availablePackage{
+		"runtime": {version: "1.4.0"},
+		"library": {version: "2.0.0", dependencies: []localpackages.PackageDependency{{Name: "runtime", Version: "<1.3"}}},
+	}
+	manifest := &localpackages.PackageManifest{Dependencies: []localpackages.PackageDependency{
+		{Name: "runtime"},
+		{Name: "library"},
+	}}
+
+	_, _, err := resolveDependencies(newDependencyTracer(), contracts.Configuration{}, dependencyRepoMock(available, nil), dependencyServiceMock(available), "agent", manifest)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "conflicting dependency")
+}
+
+func TestResolveDependenciesCycle(t *testing.T) {
+	available := map[string]availablePackage{
+		"first":  {version: "1.0", dependencies: []localpackages.PackageDependency{{Name: "second"}}},
+		"second": {version: "1.0", dependencies: []localpackages.PackageDependency{{Name: "first"}}},
+	}
+	manifest := &localpackages.PackageManifest{Dependencies: []localpackages.PackageDependency{{Name: "first"}}}
+
+	_, _, err := resolveDependencies(newDependencyTracer(), contracts.Configuration{}, dependencyRepoMock(available, nil), dependencyServiceMock(available), "agent", manifest)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency cycle")
+}
+
+func TestResolveDependenciesVersionRange(t *testing.T) {
+	mockService := &serviceMock.Mock{}
+	mockService.On("ListVersions", mock.Anything, "runtime").Return([]string{"1.0.0", "2.1.0", "1.4.0", "1.10.0"}, nil)
+	mockService.On("GetPackageArnAndVersion", "runtime", "1.10.0").Return("runtime", "1.10.0")
+	mockService.On("DownloadManifest", mock.Anything, "runtime", "1.10.0").Return("runtime", "1.10.0", true, nil)
+	available := map[string]availablePackage{
+		"runtime": {version: "1.10.0"},
+	}
+	manifest := &localpackages.PackageManifest{Dependencies: []localpackages.PackageDependency{{Name: "runtime", Version: ">=1.2, <2"}}}
+
+	plan, locked, err := resolveDependencies(newDependencyTracer(), contracts.Configuration{}, dependencyRepoMock(available, nil), mockService, "agent", manifest)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"runtime"}, planNames(plan))
+	assert.Equal(t, "1.10.0", plan[0].version)
+	assert.Equal(t, []string{"runtime"}, locked)
+	mockService.AssertNotCalled(t, "GetPackageArnAndVersion", "runtime", "latest")
+}
+
+func TestResolveDependenciesLocksBeforeDownload(t *testing.T) {
+	available := map[string]availablePackage{
+		"runtime": {version: "1.4.0"},
+		"library": {version: "2.0.0", dependencies: []localpackages.PackageDependency{{Name: "runtime"}}},
+	}
+	mockRepo := dependencyRepoStateMock(available, nil)
+	var calls []string
+	for name, pkg := range available {
+		name := name
+		mockRepo.On("ValidatePackage", mock.Anything, name, pkg.version).Run(func(mock.Arguments) { calls = append(calls, "download "+name) }).Return(nil)
+		mockRepo.On("LockPackage", mock.Anything, name, InstallAction).Run(func(mock.Arguments) { calls = append(calls, "lock "+name) }).Return(nil)
+	}
+	manifest := &localpackages.PackageManifest{Dependencies: []localpackages.PackageDependency{{Name: "library"}}}
+
+	_, locked, err := resolveDependencies(newDependencyTracer(), contracts.Configuration{}, mockRepo, dependencyServiceMock(available), "agent", manifest)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"library", "runtime"}, locked)
+	assert.Equal(t, []string{"lock library", "download library", "lock runtime", "download runtime"}, calls)
+}
+
+func TestResolveDependenciesLockFailure(t *testing.T) {
+	available := map[string]availablePackage{
+		"runtime": {version: "1.4.0"},
+		"library": {version: "2.0.0"},
+	}
+	mockRepo := dependencyRepoStateMock(available, nil)
+	mockRepo.On("LockPackage", mock.Anything, "runtime", InstallAction).Return(nil)
+	mockRepo.On("ValidatePackage", mock.Anything, "runtime", "1.4.0").Return(nil)
+	mockRepo.On("LockPackage", mock.Anything, "library", InstallAction).Return(errors.New("package is locked"))
+	manifest := &localpackages.PackageManifest{Dependencies: []localpackages.PackageDependency{{Name: "runtime"}, {Name: "library"}}}
+
+	_, locked, err := resolveDependencies(newDependencyTracer(), contracts.Configuration{}, mockRepo, dependencyServiceMock(available), "agent", manifest)
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{"runtime"}, locked)
+	mockRepo.AssertNotCalled(t, "ValidatePackage", mock.Anything, "library", "2.0.0")
+}
+
+func TestInstallDependenciesStopsAtFailure(t *testing.T) {
+	runtimeInstaller := installerSuccessMock("runtime", "1.0")
+	libraryInstaller := installerFailedMock("library", "2.0")
+	mockRepo := &repoMock.MockedRepository{}
+	mockRepo.On("GetInstalledVersion", mock.Anything, mock.Anything).Return("")
+	mockRepo.On("GetInstallState", mock.Anything, mock.Anything).Return(localpackages.None, "")
+	mockRepo.On("ValidatePackage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, "runtime", "1.0").Return(runtimeInstaller)
+	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, "library", "2.0").Return(libraryInstaller)
+	mockRepo.On("SetInstallState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	plan := []*dependency{
+		{name: "runtime", packageArn: "runtime", version: "1.0", isSameAsCache: true},
+		{name: "library", packageArn: "library", version: "2.0", isSameAsCache: true},
+	}
+	tracer := newDependencyTracer()
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+
+	changed := installDependencies(tracer, contextMock, contracts.Configuration{}, mockRepo, &serviceMock.Mock{}, plan, output)
+
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Len(t, changed, 1)
+	assert.Equal(t, "runtime", changed[0].dependency.name)
+	runtimeInstaller.AssertExpectations(t)
+	libraryInstaller.AssertExpectations(t)
+	mockRepo.AssertCalled(t, "SetInstallState", mock.Anything, "runtime", "1.0", localpackages.Installed)
+	mockRepo.AssertCalled(t, "SetInstallState", mock.Anything, "library", "2.0", localpackages.Failed)
+}
+
+func TestRollbackDependencies(t *testing.T) {
+	newInstaller := uninstallerSuccessMock("runtime", "1.0")
+	upgradedInstaller := uninstallerSuccessMock("library", "2.0")
+	previousInstaller := installerSuccessMock("library", "1.0")
+	mockRepo := &repoMock.MockedRepository{}
+	mockRepo.On("GetInstallState", mock.Anything, "library").Return(localpackages.Installed, "2.0")
+	mockRepo.On("ValidatePackage", mock.Anything, "library", "1.0").Return(nil)
+	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, "library", "1.0").Return(previousInstaller)
+	mockRepo.On("SetInstallState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("RemovePackage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	changed := []*changedDependency{
+		{dependency: &dependency{name: "runtime", packageArn: "runtime", version: "1.0"}, inst: newInstaller},
+		{dependency: &dependency{name: "library", packageArn: "library", version: "2.0"}, inst: upgradedInstaller, previousVersion: "1.0"},
+	}
+
+	rollbackDependencies(newDependencyTracer(), contextMock, contracts.Configuration{}, mockRepo, &serviceMock.Mock{}, changed)
+
+	newInstaller.AssertExpectations(t)
+	upgradedInstaller.AssertExpectations(t)
+	previousInstaller.AssertExpectations(t)
+	mockRepo.AssertCalled(t, "SetInstallState", mock.Anything, "runtime", "1.0", localpackages.None)
+	mockRepo.AssertCalled(t, "SetInstallState", mock.Anything, "library", "1.0", localpackages.Installed)
+}
@@ -58,6 +58,22 @@ func executeConfigurePackage(
 	}
 }
 
+// checkpointedRepository records each install state a package goes through as the checkpoint of the plugin,
+// the install resumes from the state kept by the repository when the document worker is restarted
+type checkpointedRepository struct {
+	localpackages.Repository
+	setCheckpoint func(checkpoint string)
+}
+
+// SetInstallState sets the install state of the package and records it once it's persisted
+func (repository checkpointedRepository) SetInstallState(tracer trace.Tracer, packageArn string, version string, state localpackages.InstallState) error {
+	if err := repository.Repository.SetInstallState(tracer, packageArn, version, state); err != nil {
+		return err
+	}
+	repository.setCheckpoint(fmt.Sprintf("%v/%v %v", packageArn, version, state))
+	return nil
+}
+
 // set package install state and log any error
 func setNewInstallState(tracer trace.Tracer, repository localpackages.Repository, inst installer.Installer, newInstallState localpackages.InstallState) {
 	trace := tracer.BeginSection(fmt.Sprintf("set install state install %s/%s - state: %v", inst.PackageName(), inst.Version(), newInstallState))
@@ -98,7 +114,9 @@ func executeInstall(
 
 	installtrace.WithExitcode(int64(result.GetExitCode()))
 
+	updatedInPlace := false
 	if result.GetStatus() == contracts.ResultStatusSuccess {
+		updatedInPlace = isUpdateInPlace && !isRollback
 		validatetrace := tracer.BeginSection(fmt.Sprintf("validate %s/%s - rollback: %t", inst.PackageName(), inst.Version(), isRollback))
 		result = inst.Validate(tracer, context)
 		validatetrace.WithExitcode(int64(result.GetExitCode()))
@@ -127,11 +145,22 @@ func executeInstall(
 			setNewInstallState(tracer, repository, inst, localpackages.Failed)
 			return
 		}
+		if updatedInPlace {
+			// The update replaced the previous version in place, so restore it by installing it over the update
+			rollbacktrace := tracer.BeginSection(fmt.Sprintf("rollback in-place update of %s/%s to %s", inst.PackageName(), inst.Version(), uninst.Version()))
+			rollbacktrace.AppendInfof("Failed to validate %v %v, rolling back to %v in place", inst.PackageName(), inst.Version(), uninst.Version())
+			executeInstall(tracer, context, repository, uninst, inst, isUpdateInPlace, true, output)
+			rollbacktrace.End()
+			return
+		}
 		// Execute rollback
 		executeUninstall(tracer, context, repository, uninst, inst, isUpdateInPlace, true, output)
 		return
 	}
-	if uninst != nil {
+	if updatedInPlace && uninst != nil {
+		// Keep the replaced version so that a later in-place update can be rolled back to it
+		retainPreviousVersion(tracer, repository, inst, uninst)
+	} else if uninst != nil {
 		// Cleanup after uninstall
 		cleanupAfterUninstall(tracer, repository, uninst, output)
 	}
@@ -213,3 +242,18 @@ func cleanupAfterUninstall(tracer trace.Tracer, repository localpackages.Reposit
 
 	trace.End()
 }
+
+// retainPreviousVersion keeps the version replaced by an in-place update in the repository and
+// removes the version that was kept by the update before it
+func retainPreviousVersion(tracer trace.Tracer, repository localpackages.Repository, inst installer.Installer, uninst installer.Installer) {
+	trace := tracer.BeginSection(fmt.Sprintf("retain %s/%s for rollback", uninst.PackageName(), uninst.Version()))
+
+	previousVersion := repository.GetPreviousVersion(tracer, inst.PackageName())
+	if previousVersion != "" && previousVersion != inst.Version() && previousVersion != uninst.Version() {
+		if err := repository.RemovePackage(tracer, inst.PackageName(), previousVersion); err != nil {
+			trace.WithError(err)
+		}
+	}
+
+	trace.End()
+}
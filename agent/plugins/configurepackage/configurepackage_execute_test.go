@@ -17,10 +17,12 @@ package configurepackage
 import (
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
 	repository_mock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages/mock"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -39,6 +41,26 @@ func TestInstallNew(t *testing.T) {
 	repoMock.AssertExpectations(t)
 }
 
+func TestInstallRecordsCheckpoints(t *testing.T) {
+	installerMock := installerSuccessMock("SsmTest", "0.0.1")
+	repoMock := &repository_mock.MockedRepository{}
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Installing).Return(nil)
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Installed).Return(nil)
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+	var checkpoints []string
+	repository := checkpointedRepository{
+		Repository:    repoMock,
+		setCheckpoint: func(checkpoint string) { checkpoints = append(checkpoints, checkpoint) },
+	}
+
+	executeConfigurePackage(tracer, contextMock, repository, installerMock, nil, false, localpackages.New, output)
+
+	assert.Equal(t, []string{"SsmTest/0.0.1 Installing", "SsmTest/0.0.1 Installed"}, checkpoints)
+	repoMock.AssertExpectations(t)
+}
+
 func TestUpgrade(t *testing.T) {
 	uninstallerMock := uninstallerSuccessMock("SsmTest", "0.0.1")
 	installerMock := installerSuccessMock("SsmTest", "0.0.2")
@@ -317,7 +339,8 @@ func TestUpdate(t *testing.T) {
 	uninstallerMock := installerNameVersionOnlyMock("SsmTest", "0.0.1")
 	repoMock := &repository_mock.MockedRepository{}
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Updating).Return(nil)
-	repoMock.On("RemovePackage", mock.Anything, "SsmTest", mock.Anything).Return(nil)
+	repoMock.On("GetPreviousVersion", mock.Anything, "SsmTest").Return("0.0.0")
+	repoMock.On("RemovePackage", mock.Anything, "SsmTest", "0.0.0").Return(nil)
 	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Installed).Return(nil)
 	tracer := trace.NewTracer(log.NewMockLog())
 	tracer.BeginSection("test segment root")
@@ -328,6 +351,51 @@ func TestUpdate(t *testing.T) {
 	uninstallerMock.AssertExpectations(t)
 	installerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
+	// the replaced version is kept for rollback
+	repoMock.AssertNotCalled(t, "RemovePackage", mock.Anything, "SsmTest", "0.0.1")
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+}
+
+func TestUpdate_ValidateFailed_RollbackInPlace(t *testing.T) {
+	installerMock := trueUpdateInstallerMockValidateFails("SsmTest", "0.0.2")
+	uninstallerMock := installerSuccessMock("SsmTest", "0.0.1")
+	repoMock := &repository_mock.MockedRepository{}
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Updating).Return(nil)
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.RollbackInstall).Return(nil)
+	repoMock.On("RemovePackage", mock.Anything, "SsmTest", "0.0.2").Return(nil)
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Installed).Return(nil)
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, true, localpackages.Updating, output)
+
+	uninstallerMock.AssertExpectations(t)
+	installerMock.AssertExpectations(t)
+	repoMock.AssertExpectations(t)
+	// the new version is not uninstalled, the previous version is installed over it
+	installerMock.AssertNotCalled(t, "Uninstall", mock.Anything)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStdout(), "Failed to validate SsmTest 0.0.2, rolling back to 0.0.1 in place")
+}
+
+func TestUpdate_ValidateFailed_RollbackInPlaceFailed(t *testing.T) {
+	installerMock := trueUpdateInstallerMockValidateFails("SsmTest", "0.0.2")
+	uninstallerMock := installerFailedMock("SsmTest", "0.0.1")
+	repoMock := &repository_mock.MockedRepository{}
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.2", localpackages.Updating).Return(nil)
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.RollbackInstall).Return(nil)
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Failed).Return(nil)
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+
+	executeConfigurePackage(tracer, contextMock, repoMock, installerMock, uninstallerMock, true, localpackages.Updating, output)
+
+	uninstallerMock.AssertExpectations(t)
+	installerMock.AssertExpectations(t)
+	repoMock.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
 }
 
 func TestUpdate_UpdateFailed_RollbackSucceeds(t *testing.T) {
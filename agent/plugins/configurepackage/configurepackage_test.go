@@ -633,9 +633,9 @@ func TestInstallingValid(t *testing.T) {
 func TestUpdatingValid(t *testing.T) {
 	pluginInformation := createStubPluginInputInstall()
 	installerMock := trueUpdateInstallerMock(pluginInformation.Name, pluginInformation.Version)
-	uninstallerMock := installerNameVersionOnlyMock(pluginInformation.Name, pluginInformation.Version)
+	uninstallerMock := installerNameVersionOnlyMock(pluginInformation.Name, "0.0.1")
 	repoMock := repoInstallMock(pluginInformation, installerMock)
-	repoMock.On("RemovePackage", mock.Anything, pluginInformation.Name, pluginInformation.Version).Return(nil)
+	repoMock.On("GetPreviousVersion", mock.Anything, pluginInformation.Name).Return("")
 	tracer := trace.NewTracer(log.NewMockLog())
 	output := &trace.PluginOutputTrace{Tracer: tracer}
 
@@ -649,6 +649,7 @@ func TestUpdatingValid(t *testing.T) {
 		uninstallerMock,
 		output)
 	assert.True(t, alreadyInstalled)
+	repoMock.AssertNotCalled(t, "RemovePackage", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestRollbackValid(t *testing.T) {
@@ -1166,6 +1167,10 @@ func TestExecuteConfigurePackagePlugin_DocumentService(t *testing.T) {
 			repoMock := repoInstallMock_ReadWriteManifestHash(pluginInformation, installerMock, manifestVersion, docVersion, getDocument_DocVersion, testdata.action)
 			bwFacade := facadeMock.BirdwatcherFacade{}
 			mockIOHandler := createMockIOHandlerStruct(testdata.errorResponse)
+			if testdata.action == InstallAction && !testdata.getDocumentReturnsError {
+				// each install stage of the package is recorded as a checkpoint
+				mockIOHandler.On("SetCheckpoint", mock.Anything).Return()
+			}
 			getManifestInput := &ssm.GetManifestInput{
 				PackageName:    &pluginInformation.Name,
 				PackageVersion: &version,
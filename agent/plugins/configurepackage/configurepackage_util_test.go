@@ -44,6 +44,8 @@ func repoInstallMock(pluginInformation *ConfigurePackagePluginInput, installerMo
 	mockRepo.On("ValidatePackage", mock.Anything, mock.Anything, pluginInformation.Version).Return(nil)
 	mockRepo.On("SetInstallState", mock.Anything, mock.Anything, pluginInformation.Version, mock.Anything).Return(nil)
 	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, mock.Anything, pluginInformation.Version).Return(installerMock)
+	mockRepo.On("GetPackageManifest", mock.Anything, mock.Anything, pluginInformation.Version).Return(&localpackages.PackageManifest{}, nil)
+	mockRepo.On("GetVersionControl", mock.Anything, mock.Anything).Return("", false)
 	mockRepo.On("LockPackage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockRepo.On("UnlockPackage", mock.Anything, mock.Anything).Return()
 	mockRepo.On("LoadTraces", mock.Anything, mock.Anything).Return(nil)
@@ -57,6 +59,7 @@ func repoInstallMock_WithValidatePackageError(pluginInformation *ConfigurePackag
 	mockRepo.On("ValidatePackage", mock.Anything, mock.Anything, pluginInformation.Version).Return(errors.New("There's an error"))
 	mockRepo.On("SetInstallState", mock.Anything, mock.Anything, pluginInformation.Version, mock.Anything).Return(nil)
 	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, mock.Anything, pluginInformation.Version).Return(installerMock)
+	mockRepo.On("GetVersionControl", mock.Anything, mock.Anything).Return("", false)
 	mockRepo.On("LockPackage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockRepo.On("UnlockPackage", mock.Anything, mock.Anything).Return()
 	mockRepo.On("LoadTraces", mock.Anything, mock.Anything).Return(nil)
@@ -70,6 +73,7 @@ func repoAlreadyInstalledMock(pluginInformation *ConfigurePackagePluginInput, in
 	mockRepo.On("ValidatePackage", mock.Anything, mock.Anything, pluginInformation.Version).Return(nil)
 	mockRepo.On("SetInstallState", mock.Anything, mock.Anything, pluginInformation.Version, mock.Anything).Return(nil)
 	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, mock.Anything, pluginInformation.Version).Return(installerMock)
+	mockRepo.On("GetVersionControl", mock.Anything, mock.Anything).Return("", false)
 	mockRepo.On("LockPackage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockRepo.On("UnlockPackage", mock.Anything, mock.Anything).Return()
 	return &mockRepo
@@ -84,6 +88,7 @@ func repoUpgradeMock(installerMock installer.Installer) *repoMock.MockedReposito
 	mockRepo.On("SetInstallState", mock.Anything, mock.Anything, "0.0.2", mock.Anything).Return(nil)
 	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, mock.Anything, "0.0.1").Return(installerMock)
 	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, mock.Anything, "0.0.2").Return(installerMock)
+	mockRepo.On("GetVersionControl", mock.Anything, mock.Anything).Return("", false)
 	mockRepo.On("LockPackage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockRepo.On("UnlockPackage", mock.Anything, mock.Anything).Return()
 	return &mockRepo
@@ -95,6 +100,7 @@ func repoUninstallMock(pluginInformation *ConfigurePackagePluginInput, installer
 	mockRepo.On("GetInstallState", mock.Anything, mock.Anything).Return(localpackages.Installed, "")
 	mockRepo.On("ValidatePackage", mock.Anything, mock.Anything, "0.0.1").Return(nil).Once()
 	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, mock.Anything, "0.0.1").Return(installerMock)
+	mockRepo.On("GetVersionControl", mock.Anything, mock.Anything).Return("", false)
 	mockRepo.On("LockPackage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockRepo.On("UnlockPackage", mock.Anything, mock.Anything).Return()
 	return &mockRepo
@@ -109,6 +115,7 @@ func repoUpdateMock(installerMock installer.Installer) *repoMock.MockedRepositor
 	mockRepo.On("SetInstallState", mock.Anything, mock.Anything, "0.0.2", mock.Anything).Return(nil)
 	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, mock.Anything, "0.0.1").Return(installerMock)
 	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, mock.Anything, "0.0.2").Return(installerMock)
+	mockRepo.On("GetVersionControl", mock.Anything, mock.Anything).Return("", false)
 	mockRepo.On("LockPackage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockRepo.On("UnlockPackage", mock.Anything, mock.Anything).Return()
 	return &mockRepo
@@ -121,6 +128,8 @@ func repoUpdateMock_PackageNotInstalled(pluginInformation *ConfigurePackagePlugi
 	mockRepo.On("ValidatePackage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockRepo.On("SetInstallState", mock.Anything, mock.Anything, pluginInformation.Version, mock.Anything).Return(nil)
 	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, mock.Anything, pluginInformation.Version).Return(installerMock)
+	mockRepo.On("GetPackageManifest", mock.Anything, mock.Anything, pluginInformation.Version).Return(&localpackages.PackageManifest{}, nil)
+	mockRepo.On("GetVersionControl", mock.Anything, mock.Anything).Return("", false)
 	mockRepo.On("LockPackage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockRepo.On("UnlockPackage", mock.Anything, mock.Anything).Return()
 	mockRepo.On("LoadTraces", mock.Anything, mock.Anything).Return(nil)
@@ -129,6 +138,7 @@ func repoUpdateMock_PackageNotInstalled(pluginInformation *ConfigurePackagePlugi
 
 func repoUpdateMock_BirdwatcherNotAllowed() *repoMock.MockedRepository {
 	mockRepo := repoMock.MockedRepository{}
+	mockRepo.On("GetVersionControl", mock.Anything, mock.Anything).Return("", false)
 	mockRepo.On("LockPackage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockRepo.On("UnlockPackage", mock.Anything, mock.Anything).Return()
 	mockRepo.On("LoadTraces", mock.Anything, mock.Anything).Return(nil)
@@ -142,6 +152,7 @@ func repoUpdateMock_VersionAlreadyInstalledWithDifferentManifest(input *Configur
 	mockRepo.On("ValidatePackage", mock.Anything, mock.Anything, "0.0.2").Return(nil)
 	mockRepo.On("SetInstallState", mock.Anything, mock.Anything, "0.0.2", mock.Anything).Return(nil)
 	mockRepo.On("GetInstaller", mock.Anything, mock.Anything, mock.Anything, "0.0.2").Return(installerMock)
+	mockRepo.On("GetVersionControl", mock.Anything, mock.Anything).Return("", false)
 	mockRepo.On("LockPackage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockRepo.On("UnlockPackage", mock.Anything, mock.Anything).Return()
 	return &mockRepo
@@ -153,6 +164,7 @@ func repoInstallMock_ReadWriteManifest(pluginInformation *ConfigurePackagePlugin
 	mockRepo.On("WriteManifest", pluginInformation.Name, version, mock.Anything).Return(nil)
 	mockRepo.On("GetInstalledVersion", mock.Anything, pluginInformation.Name).Return("")
 	mockRepo.On("GetInstallState", mock.Anything, pluginInformation.Name).Return(localpackages.None, "")
+	mockRepo.On("GetVersionControl", mock.Anything, pluginInformation.Name).Return("", false)
 	mockRepo.On("UnlockPackage", mock.Anything, mock.Anything).Return().Once()
 	mockRepo.On("LoadTraces", mock.Anything, mock.Anything).Return(nil)
 
@@ -160,6 +172,7 @@ func repoInstallMock_ReadWriteManifest(pluginInformation *ConfigurePackagePlugin
 		mockRepo.On("LockPackage", mock.Anything, pluginInformation.Name, "Install").Return(nil).Once()
 		mockRepo.On("ValidatePackage", mock.Anything, pluginInformation.Name, version).Return(nil)
 		mockRepo.On("GetInstaller", mock.Anything, mock.Anything, pluginInformation.Name, version).Return(installerMock)
+		mockRepo.On("GetPackageManifest", mock.Anything, pluginInformation.Name, version).Return(&localpackages.PackageManifest{}, nil)
 		mockRepo.On("SetInstallState", mock.Anything, pluginInformation.Name, version, mock.Anything).Return(nil)
 	} else {
 		mockRepo.On("LockPackage", mock.Anything, pluginInformation.Name, "Uninstall").Return(nil).Once()
@@ -175,6 +188,7 @@ func repoInstallMock_ReadWriteManifestHash(pluginInformation *ConfigurePackagePl
 	mockRepo.On("WriteManifestHash", pluginInformation.Name, newpkgDocVersion, mock.Anything).Return(nil)
 	mockRepo.On("ReadManifest", pluginInformation.Name, newpkgDocVersion).Return([]byte(""), nil)
 	mockRepo.On("WriteManifest", pluginInformation.Name, newpkgDocVersion, mock.Anything).Return(nil)
+	mockRepo.On("GetVersionControl", mock.Anything, pluginInformation.Name).Return("", false)
 	mockRepo.On("UnlockPackage", mock.Anything, mock.Anything).Return().Once()
 	mockRepo.On("LoadTraces", mock.Anything, mock.Anything).Return(nil)
 
@@ -184,6 +198,7 @@ func repoInstallMock_ReadWriteManifestHash(pluginInformation *ConfigurePackagePl
 		mockRepo.On("LockPackage", mock.Anything, pluginInformation.Name, "Install").Return(nil).Once()
 		mockRepo.On("ValidatePackage", mock.Anything, pluginInformation.Name, version).Return(nil)
 		mockRepo.On("GetInstaller", mock.Anything, mock.Anything, pluginInformation.Name, version).Return(installerMock)
+		mockRepo.On("GetPackageManifest", mock.Anything, pluginInformation.Name, version).Return(&localpackages.PackageManifest{}, nil)
 		mockRepo.On("SetInstallState", mock.Anything, pluginInformation.Name, version, mock.Anything).Return(nil)
 	} else {
 		mockRepo.On("GetInstalledVersion", mock.Anything, pluginInformation.Name).Return("")
@@ -276,6 +291,15 @@ func trueUpdateInstallerMock(packageName string, version string) *installerMock.
 	return &mockInst
 }
 
+func trueUpdateInstallerMockValidateFails(packageName string, version string) *installerMock.Mock {
+	mockInst := installerMock.Mock{}
+	mockInst.On("Update", mock.Anything).Return(pluginOutputWithStatus(contracts.ResultStatusSuccess)).Once()
+	mockInst.On("Validate", mock.Anything).Return(pluginOutputWithStatus(contracts.ResultStatusFailed)).Once()
+	mockInst.On("PackageName").Return(packageName)
+	mockInst.On("Version").Return(version)
+	return &mockInst
+}
+
 func trueUpdateInstallerMockRollbackSucceeds(packageName string, version string) *installerMock.Mock {
 	mockInst := installerMock.Mock{}
 	mockInst.On("Update", mock.Anything).Return(pluginOutputWithStatus(contracts.ResultStatusFailed)).Once()
@@ -412,6 +436,8 @@ func createMockCancelFlag() task.CancelFlag {
 func createMockIOHandler() iohandler.IOHandler {
 	mockIOHandler := new(iohandlermocks.MockIOHandler)
 
+	mockIOHandler.On("GetCheckpoint").Return("")
+	mockIOHandler.On("SetCheckpoint", mock.Anything).Return()
 	mockIOHandler.On("SetExitCode", mock.Anything).Return()
 	mockIOHandler.On("SetStatus", mock.Anything).Return()
 	mockIOHandler.On("AppendInfo", mock.Anything).Return()
@@ -423,6 +449,7 @@ func createMockIOHandler() iohandler.IOHandler {
 func createMockIOHandlerStruct(errorResponse string) *iohandlermocks.MockIOHandler {
 	mockIOHandler := iohandlermocks.MockIOHandler{}
 
+	mockIOHandler.On("GetCheckpoint").Return("")
 	mockIOHandler.On("SetExitCode", mock.Anything).Return()
 	mockIOHandler.On("SetStatus", mock.Anything).Return()
 	mockIOHandler.On("AppendInfo", mock.Anything).Return()
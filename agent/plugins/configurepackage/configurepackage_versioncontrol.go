@@ -0,0 +1,125 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package configurepackage implements the ConfigurePackage plugin.
+package configurepackage
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+)
+
+// isVersionControlAction returns true for the actions that change the pin or hold of a package instead of installing it
+func isVersionControlAction(action string) bool {
+	return action == PinAction || action == UnpinAction || action == HoldAction || action == UnholdAction
+}
+
+// setVersionControl performs the Pin, Unpin, Hold and Unhold actions.
+// Pin without a version (or with latest) pins the installed version, otherwise the requested version is pinned.
+func setVersionControl(
+	tracer trace.Tracer,
+	repository localpackages.Repository,
+	input *ConfigurePackagePluginInput,
+	packageArn string,
+	manifestVersion string,
+	output contracts.PluginOutputter) {
+
+	trace := tracer.BeginSection(fmt.Sprintf("%s %s", input.Action, input.Name))
+	defer trace.End()
+
+	pinnedVersion, hold := repository.GetVersionControl(tracer, packageArn)
+	switch input.Action {
+	case PinAction:
+		pinnedVersion = manifestVersion
+		if input.Version == "" || packageservice.IsLatest(input.Version) {
+			if pinnedVersion = repository.GetInstalledVersion(tracer, packageArn); pinnedVersion == "" {
+				trace.AppendErrorf("%v is not installed, specify the version to pin", input.Name)
+				output.MarkAsFailed(nil, nil)
+				return
+			}
+		}
+		trace.AppendInfof("pinning %v to version %v", input.Name, pinnedVersion)
+	case UnpinAction:
+		pinnedVersion = ""
+	case HoldAction:
+		hold = true
+	case UnholdAction:
+		hold = false
+	}
+
+	if err := repository.SetVersionControl(tracer, packageArn, pinnedVersion, hold); err != nil {
+		trace.WithError(err)
+		output.MarkAsFailed(nil, nil)
+		return
+	}
+	output.MarkAsSucceeded()
+}
+
+// applyVersionControl enforces the pin and hold of a package for the Install and Uninstall actions.
+// A held package is neither changed nor removed. A pinned package resolves latest to the pinned version and
+// refuses any other explicit version. It returns the manifest version and cache flag to continue with.
+func applyVersionControl(
+	tracer trace.Tracer,
+	repository localpackages.Repository,
+	packageService packageservice.PackageService,
+	input *ConfigurePackagePluginInput,
+	packageArn string,
+	manifestVersion string,
+	isSameAsCache bool,
+	output contracts.PluginOutputter) (string, bool) {
+
+	pinnedVersion, hold := repository.GetVersionControl(tracer, packageArn)
+	if pinnedVersion == "" && !hold {
+		return manifestVersion, isSameAsCache
+	}
+
+	trace := tracer.BeginSection(fmt.Sprintf("apply version control - pinned: %v hold: %v", pinnedVersion, hold))
+	defer trace.End()
+
+	if hold {
+		if installedVersion := repository.GetInstalledVersion(tracer, packageArn); installedVersion != "" {
+			if input.Action == UninstallAction {
+				trace.AppendErrorf("%v is on hold, unhold the package before uninstalling it", input.Name)
+				output.MarkAsFailed(nil, nil)
+				return manifestVersion, isSameAsCache
+			}
+			trace.AppendInfof("%v is on hold at version %v, skipping install of %v", input.Name, installedVersion, manifestVersion)
+			output.MarkAsSucceeded()
+			return installedVersion, isSameAsCache
+		}
+	}
+
+	if input.Action != InstallAction || pinnedVersion == "" || pinnedVersion == manifestVersion {
+		return manifestVersion, isSameAsCache
+	}
+	if input.Version != "" && !packageservice.IsLatest(input.Version) {
+		trace.AppendErrorf("%v is pinned to version %v, unpin the package to install version %v", input.Name, pinnedVersion, manifestVersion)
+		output.MarkAsFailed(nil, nil)
+		return manifestVersion, isSameAsCache
+	}
+
+	trace.AppendInfof("%v is pinned, installing version %v instead of %v", input.Name, pinnedVersion, manifestVersion)
+	packageName, packageVersion := packageService.GetPackageArnAndVersion(input.Name, pinnedVersion)
+	_, pinnedManifestVersion, pinnedIsSameAsCache, err := packageService.DownloadManifest(tracer, packageName, packageVersion)
+	if err != nil {
+		trace.WithError(err)
+		output.MarkAsFailed(nil, nil)
+		return manifestVersion, isSameAsCache
+	}
+	return pinnedManifestVersion, pinnedIsSameAsCache
+}
@@ -0,0 +1,156 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package configurepackage
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
+	repoMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages/mock"
+	serviceMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice/mock"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func versionControlRepoMock(pinnedVersion string, hold bool, installedVersion string) *repoMock.MockedRepository {
+	mockRepo := repoMock.MockedRepository{}
+	mockRepo.On("GetVersionControl", mock.Anything, "SsmTest").Return(pinnedVersion, hold)
+	mockRepo.On("GetInstalledVersion", mock.Anything, "SsmTest").Return(installedVersion)
+	return &mockRepo
+}
+
+func newVersionControlOutput() (trace.Tracer, *trace.PluginOutputTrace) {
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+	return tracer, &trace.PluginOutputTrace{Tracer: tracer}
+}
+
+func TestSetVersionControlPinInstalledVersion(t *testing.T) {
+	mockRepo := versionControlRepoMock("", false, "0.0.1")
+	mockRepo.On("SetVersionControl", mock.Anything, "SsmTest", "0.0.1", false).Return(nil)
+	tracer, output := newVersionControlOutput()
+
+	setVersionControl(tracer, mockRepo, &ConfigurePackagePluginInput{Name: "SsmTest", Action: PinAction}, "SsmTest", "0.0.2", output)
+
+	mockRepo.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+}
+
+func TestSetVersionControlPinRequestedVersion(t *testing.T) {
+	mockRepo := versionControlRepoMock("", true, "0.0.1")
+	mockRepo.On("SetVersionControl", mock.Anything, "SsmTest", "0.0.2", true).Return(nil)
+	tracer, output := newVersionControlOutput()
+
+	setVersionControl(tracer, mockRepo, &ConfigurePackagePluginInput{Name: "SsmTest", Version: "0.0.2", Action: PinAction}, "SsmTest", "0.0.2", output)
+
+	mockRepo.AssertCalled(t, "SetVersionControl", mock.Anything, "SsmTest", "0.0.2", true)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+}
+
+func TestSetVersionControlPinNotInstalled(t *testing.T) {
+	mockRepo := versionControlRepoMock("", false, "")
+	tracer, output := newVersionControlOutput()
+
+	setVersionControl(tracer, mockRepo, &ConfigurePackagePluginInput{Name: "SsmTest", Action: PinAction}, "SsmTest", "0.0.2", output)
+
+	mockRepo.AssertNotCalled(t, "SetVersionControl", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+}
+
+func TestSetVersionControlHoldAndUnpin(t *testing.T) {
+	mockRepo := versionControlRepoMock("0.0.1", false, "0.0.1")
+	mockRepo.On("SetVersionControl", mock.Anything, "SsmTest", "0.0.1", true).Return(nil).Once()
+	mockRepo.On("SetVersionControl", mock.Anything, "SsmTest", "", false).Return(nil).Once()
+	tracer, output := newVersionControlOutput()
+
+	setVersionControl(tracer, mockRepo, &ConfigurePackagePluginInput{Name: "SsmTest", Action: HoldAction}, "SsmTest", "0.0.1", output)
+	setVersionControl(tracer, mockRepo, &ConfigurePackagePluginInput{Name: "SsmTest", Action: UnpinAction}, "SsmTest", "0.0.1", output)
+
+	mockRepo.AssertNumberOfCalls(t, "SetVersionControl", 2)
+}
+
+func TestApplyVersionControlNone(t *testing.T) {
+	mockRepo := versionControlRepoMock("", false, "0.0.1")
+	mockService := &serviceMock.Mock{}
+	tracer, output := newVersionControlOutput()
+
+	version, isSameAsCache := applyVersionControl(tracer, mockRepo, mockService, &ConfigurePackagePluginInput{Name: "SsmTest", Action: InstallAction}, "SsmTest", "0.0.2", true, output)
+
+	assert.Equal(t, "0.0.2", version)
+	assert.True(t, isSameAsCache)
+	assert.Equal(t, contracts.ResultStatus(""), output.GetStatus())
+}
+
+func TestApplyVersionControlHoldSkipsInstall(t *testing.T) {
+	mockRepo := versionControlRepoMock("", true, "0.0.1")
+	tracer, output := newVersionControlOutput()
+
+	version, _ := applyVersionControl(tracer, mockRepo, &serviceMock.Mock{}, &ConfigurePackagePluginInput{Name: "SsmTest", Action: InstallAction}, "SsmTest", "0.0.2", true, output)
+
+	assert.Equal(t, "0.0.1", version)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+}
+
+func TestApplyVersionControlHoldFailsUninstall(t *testing.T) {
+	mockRepo := versionControlRepoMock("", true, "0.0.1")
+	tracer, output := newVersionControlOutput()
+
+	applyVersionControl(tracer, mockRepo, &serviceMock.Mock{}, &ConfigurePackagePluginInput{Name: "SsmTest", Action: UninstallAction}, "SsmTest", "0.0.1", true, output)
+
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+}
+
+func TestApplyVersionControlPinResolvesLatest(t *testing.T) {
+	mockRepo := versionControlRepoMock("0.0.1", false, "0.0.1")
+	mockService := &serviceMock.Mock{}
+	mockService.On("GetPackageArnAndVersion", "SsmTest", "0.0.1").Return("SsmTest", "0.0.1")
+	mockService.On("DownloadManifest", mock.Anything, "SsmTest", "0.0.1").Return("SsmTest", "0.0.1", false, nil)
+	tracer, output := newVersionControlOutput()
+
+	version, isSameAsCache := applyVersionControl(tracer, mockRepo, mockService, &ConfigurePackagePluginInput{Name: "SsmTest", Version: "latest", Action: InstallAction}, "SsmTest", "0.0.2", true, output)
+
+	mockService.AssertExpectations(t)
+	assert.Equal(t, "0.0.1", version)
+	assert.False(t, isSameAsCache)
+	assert.Equal(t, contracts.ResultStatus(""), output.GetStatus())
+}
+
+func TestApplyVersionControlPinRefusesOtherVersion(t *testing.T) {
+	mockRepo := versionControlRepoMock("0.0.1", false, "0.0.1")
+	tracer, output := newVersionControlOutput()
+
+	applyVersionControl(tracer, mockRepo, &serviceMock.Mock{}, &ConfigurePackagePluginInput{Name: "SsmTest", Version: "0.0.2", Action: InstallAction}, "SsmTest", "0.0.2", true, output)
+
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+}
+
+func TestResolveDependenciesHoldNotSatisfied(t *testing.T) {
+	available := map[string]availablePackage{
+		"runtime": {version: "1.4.0"},
+	}
+	mockRepo := &repoMock.MockedRepository{}
+	mockRepo.On("GetInstalledVersion", mock.Anything, "runtime").Return("1.1.0")
+	mockRepo.On("GetInstallState", mock.Anything, "runtime").Return(localpackages.Installed, "1.1.0")
+	mockRepo.On("GetVersionControl", mock.Anything, "runtime").Return("", true)
+	manifest := &localpackages.PackageManifest{Dependencies: []localpackages.PackageDependency{{Name: "runtime", Version: ">=1.2"}}}
+
+	_, _, err := resolveDependencies(newDependencyTracer(), contracts.Configuration{}, mockRepo, dependencyServiceMock(available), "agent", manifest)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency runtime is on hold at version 1.1.0")
+}
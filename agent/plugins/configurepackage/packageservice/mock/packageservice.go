@@ -44,6 +44,11 @@ func (ds *Mock) DownloadArtifact(tracer trace.Tracer, packageName string, versio
 	return args.String(0), args.Error(1)
 }
 
+func (ds *Mock) ListVersions(tracer trace.Tracer, packageName string) ([]string, error) {
+	args := ds.Called(tracer, packageName)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 func (ds *Mock) ReportResult(tracer trace.Tracer, result packageservice.PackageResult) error {
 	args := ds.Called(tracer, result)
 	return args.Error(0)
@@ -49,6 +49,11 @@ type PackageService interface {
 	ReportResult(tracer trace.Tracer, result PackageResult) error
 }
 
+// VersionLister is implemented by package services that can list the published versions of a package
+type VersionLister interface {
+	ListVersions(tracer trace.Tracer, packageName string) ([]string, error)
+}
+
 const (
 	PackageServiceName_ssms3       = "ssms3"
 	PackageServiceName_birdwatcher = "birdwatcherUsingBirdwatcherArchive"
@@ -150,6 +150,24 @@ func (ds *PackageService) DownloadArtifact(tracer trace.Tracer, packageName stri
 	return downloadPackageFromS3(tracer, s3Location)
 }
 
+// ListVersions returns the versions of a package published in S3 for this platform/arch
+func (ds *PackageService) ListVersions(tracer trace.Tracer, packageName string) ([]string, error) {
+	logger := tracer.CurrentTrace().Logger
+	amazonS3URL := s3util.ParseAmazonS3URL(logger, getS3Url(ds.packageURL, packageName))
+
+	folders, err := networkdep.ListS3Folders(logger, amazonS3URL)
+	if err != nil {
+		return nil, err
+	}
+	versions := []string{}
+	for _, folder := range folders {
+		if _, _, _, err := parseVersion(folder); err == nil {
+			versions = append(versions, folder)
+		}
+	}
+	return versions, nil
+}
+
 func (*PackageService) ReportResult(tracer trace.Tracer, result packageservice.PackageResult) error {
 	// NOP
 	return nil
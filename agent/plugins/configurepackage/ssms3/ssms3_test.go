@@ -173,6 +173,22 @@ func TestDownloadManifestWithError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestListVersions(t *testing.T) {
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+
+	mockObj := new(SSMS3Mock)
+	mockObj.On("ListS3Folders", mock.Anything, mock.Anything).Return([]string{"1.0.0", "latest", "2.0.0"}, nil)
+
+	networkdep = mockObj
+
+	ds := &PackageService{packageURL: "https://abc.s3.mock-region.amazonaws.com/"}
+	versions, err := ds.ListVersions(tracer, "packageName")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "2.0.0"}, versions)
+}
+
 func TestSuccessfulDownloadArtifact(t *testing.T) {
 	tracer := trace.NewTracer(log.NewMockLog())
 	tracer.BeginSection("test segment root")
@@ -40,6 +40,8 @@ type TokenInfoImpl struct {
 		resolverOptions ssmparameterresolver.ResolveOptions) (info map[string]ssmparameterresolver.SsmParameterInfo, err error)
 	paramAccess    ssmparameterresolver.SsmParameterService
 	gitoauthclient githubclient.IOAuthClient
+	// registerSecrets adds the token to the values redacted from the output of the document
+	registerSecrets func(secrets ...string)
 }
 
 // GetOAuthClient is the only method from privategithub package that is accessible to gitresource
@@ -68,6 +70,7 @@ func (t TokenInfoImpl) GetOAuthClient(log log.T, tokenInfo string) (client *http
 
 	resolverOptions := ssmparameterresolver.ResolveOptions{
 		IgnoreSecureParameters: false,
+		RegisterSecrets:        t.registerSecrets,
 	}
 
 	// Get the parameter value from parameter store.
@@ -112,12 +115,13 @@ func validateTokenParameter(tokenInfo string) (valid bool, err error) {
 		"Please specify parameter as '{{ ssm-secure:parameter-name }}'")
 }
 
-// NewTokenInfoImpl returns an object of type TokenInfoImpl
-func NewTokenInfoImpl() TokenInfoImpl {
+// NewTokenInfoImpl returns an object of type TokenInfoImpl, the token is passed to registerSecrets once resolved
+func NewTokenInfoImpl(registerSecrets func(secrets ...string)) TokenInfoImpl {
 	parameterService := ssmparameterresolver.NewService()
 	return TokenInfoImpl{
-		SsmParameter:   getSSMParameter,
-		paramAccess:    parameterService,
-		gitoauthclient: githubclient.OAuthClient{},
+		SsmParameter:    getSSMParameter,
+		paramAccess:     parameterService,
+		gitoauthclient:  githubclient.OAuthClient{},
+		registerSecrets: registerSecrets,
 	}
 }
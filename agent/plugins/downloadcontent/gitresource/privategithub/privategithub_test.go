@@ -40,15 +40,18 @@ func TestTokenInfoImpl_GetOAuthClient_Success(t *testing.T) {
 
 	var clientVal *http.Client
 	oauthclientmock.On("GetGithubOauthClient", tokenValue).Return(clientVal)
+	var registered []string
 	tokenInfo := TokenInfoImpl{
-		SsmParameter:   getMockedSecureParam,
-		gitoauthclient: oauthclientmock,
+		SsmParameter:    getMockedSecureParam,
+		gitoauthclient:  oauthclientmock,
+		registerSecrets: func(secrets ...string) { registered = append(registered, secrets...) },
 	}
 
 	httpout, err := tokenInfo.GetOAuthClient(logMock, `{{ ssm-secure:dummysecureparam }}`)
 
 	assert.NoError(t, err)
 	assert.Equal(t, clientVal, httpout)
+	assert.Equal(t, []string{tokenValue}, registered)
 	oauthclientmock.AssertExpectations(t)
 }
 
@@ -117,6 +120,9 @@ func getMockedParam(log log.T, paramService ssmparameterresolver.ISsmParameterSe
 	}
 	info = make(map[string]ssmparameterresolver.SsmParameterInfo)
 	info["ssm-secure:dummysecureparam"] = secureParamOut
+	if resolverOptions.RegisterSecrets != nil && secureParamOut.Type == parameterstore.ParamTypeSecureString {
+		resolverOptions.RegisterSecrets(tokenValue)
+	}
 
 	return info, nil
 }
@@ -132,6 +138,9 @@ func getMockedSecureParam(log log.T, paramService ssmparameterresolver.ISsmParam
 	}
 	info = make(map[string]ssmparameterresolver.SsmParameterInfo)
 	info["ssm-secure:dummysecureparam"] = secureParamOut
+	if resolverOptions.RegisterSecrets != nil && secureParamOut.Type == parameterstore.ParamTypeSecureString {
+		resolverOptions.RegisterSecrets(tokenValue)
+	}
 
 	return info, nil
 }
@@ -0,0 +1,380 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package externalplugin implements the out-of-tree plugins, executables of the external plugin directory
+// running the steps whose action has a custom prefix, such as acme:deployService.
+package externalplugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/pluginsdk"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"golang.org/x/crypto/ed25519"
+)
+
+const (
+	// reservedPrefix is the prefix of the actions of the built-in plugins
+	reservedPrefix = "aws"
+
+	// cancelGracePeriod is how long a plugin has to stop after it is asked to cancel, before it is killed
+	cancelGracePeriod = 10 * time.Second
+)
+
+// stepTimeout returns the timeoutSeconds of the step, or the default timeout of the plugins
+var stepTimeout = func(log log.T, config contracts.Configuration) time.Duration {
+	var properties struct {
+		TimeoutSeconds interface{} `json:"timeoutSeconds"`
+	}
+	jsonutil.Remarshal(config.Properties, &properties)
+	return time.Duration(pluginutil.ValidateExecutionTimeout(log, properties.TimeoutSeconds)) * time.Second
+}
+
+// namePattern matches the prefixes and the names of the actions of external plugins
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ParseName splits the action name of an external plugin into its prefix and its name,
+// ok is false for the names of the built-in plugins and names that can't be a file name.
+func ParseName(pluginName string) (prefix string, action string, ok bool) {
+	parts := strings.SplitN(pluginName, ":", 2)
+	if len(parts) != 2 || parts[0] == reservedPrefix || !namePattern.MatchString(parts[0]) || !namePattern.MatchString(parts[1]) {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// Discover returns the names of the external plugins installed in directory
+func Discover(log log.T, directory string) (pluginNames []string) {
+	prefixes, err := ioutil.ReadDir(directory)
+	if err != nil {
+		log.Debugf("no external plugin loaded from %v: %v", directory, err)
+		return nil
+	}
+	for _, prefix := range prefixes {
+		if !prefix.IsDir() {
+			continue
+		}
+		executables, err := ioutil.ReadDir(filepath.Join(directory, prefix.Name()))
+		if err != nil {
+			log.Warnf("failed to list the external plugins in %v: %v", prefix.Name(), err)
+			continue
+		}
+		for _, executable := range executables {
+			if !executable.Mode().IsRegular() || !strings.HasSuffix(executable.Name(), executableExtension()) ||
+				strings.HasSuffix(executable.Name(), pluginsdk.SignatureExtension) {
+				continue
+			}
+			pluginName := prefix.Name() + ":" + strings.TrimSuffix(executable.Name(), executableExtension())
+			if _, _, ok := ParseName(pluginName); ok {
+				pluginNames = append(pluginNames, pluginName)
+			}
+		}
+	}
+	return pluginNames
+}
+
+// executableExtension returns the extension of the executables of the plugins
+func executableExtension() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+// Plugin runs the executable of an external plugin
+type Plugin struct {
+	Name string
+	Path string
+}
+
+// NewPlugin returns the plugin running the executable of pluginName in directory, after checking that
+// it can only be modified by the agent's user and, when publicKeys are given, that it is signed by one of them
+func NewPlugin(pluginName string, directory string, publicKeys []string) (*Plugin, error) {
+	prefix, action, ok := ParseName(pluginName)
+	if !ok {
+		return nil, fmt.Errorf("%v is not the name of an external plugin", pluginName)
+	}
+	path := filepath.Join(directory, prefix, action+executableExtension())
+	if err := verifyOwnership(path, directory); err != nil {
+		return nil, fmt.Errorf("refusing to run external plugin %v: %v", pluginName, err)
+	}
+	if len(publicKeys) > 0 {
+		if err := verifySignature(path, publicKeys); err != nil {
+			return nil, fmt.Errorf("refusing to run external plugin %v: %v", pluginName, err)
+		}
+	}
+	return &Plugin{Name: pluginName, Path: path}, nil
+}
+
+// verifySignature checks that the executable at path is signed by one of publicKeys
+func verifySignature(path string, publicKeys []string) error {
+	var keys []ed25519.PublicKey
+	for _, encoded := range publicKeys {
+		key, err := pluginsdk.ParsePublicKey(encoded)
+		if err != nil {
+			return fmt.Errorf("invalid external plugin public key %v: %v", encoded, err)
+		}
+		keys = append(keys, key)
+	}
+	return pluginsdk.VerifyFile(path, keys)
+}
+
+// Execute runs the step with the executable of the plugin
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", p.Name, config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	result, err := p.run(log, config, cancelFlag, output.GetStdoutWriter(), output.GetStderrWriter(), output)
+	switch {
+	case result != nil:
+		setResult(output, *result)
+	case cancelFlag.ShutDown():
+		output.MarkAsShutdown()
+	case cancelFlag.Canceled():
+		output.MarkAsCancelled()
+	default:
+		output.MarkAsFailed(err)
+	}
+}
+
+// checkpointer holds the checkpoint of the step, it hands the plugin the checkpoint of its previous run and records the new ones
+type checkpointer interface {
+	GetCheckpoint() string
+	SetCheckpoint(checkpoint string)
+}
+
+// run starts the executable, sends it the step and copies its output and its checkpoints until it returns the result of the step
+func (p *Plugin) run(log log.T, config contracts.Configuration, cancelFlag task.CancelFlag, stdout io.Writer, stderr io.Writer, checkpoints checkpointer) (result *contracts.PluginResult, err error) {
+	command := exec.Command(p.Path)
+	if config.OrchestrationDirectory != "" {
+		if err = fileutil.MakeDirs(config.OrchestrationDirectory); err != nil {
+			return nil, fmt.Errorf("failed to create the orchestration directory %v: %v", config.OrchestrationDirectory, err)
+		}
+		command.Dir = config.OrchestrationDirectory
+	}
+	command.Stderr = stderr
+	stdin, err := command.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	messages, err := command.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = command.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start external plugin %v: %v", p.Name, err)
+	}
+
+	done := make(chan bool)
+	defer close(done)
+	timedOut := make(chan bool)
+	encoder := &messageEncoder{encoder: json.NewEncoder(stdin)}
+	scanner := bufio.NewScanner(messages)
+	scanner.Buffer(make([]byte, 64*1024), pluginsdk.MaxMessageSize)
+
+	if err = p.handshake(scanner, encoder, config, checkpoints.GetCheckpoint()); err != nil {
+		command.Process.Kill()
+		command.Wait()
+		return nil, err
+	}
+	timeout := stepTimeout(log, config)
+	go p.stopOnRequest(log, command, encoder, cancelFlag, timeout, timedOut, done)
+
+	for scanner.Scan() {
+		var message pluginsdk.Message
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			log.Warnf("ignoring invalid message of external plugin %v: %v", p.Name, err)
+			continue
+		}
+		switch message.Type {
+		case pluginsdk.MessageTypeOutput:
+			if message.Stream == pluginsdk.StreamStderr {
+				io.WriteString(stderr, message.Data)
+			} else {
+				io.WriteString(stdout, message.Data)
+			}
+		case pluginsdk.MessageTypeCheckpoint:
+			checkpoints.SetCheckpoint(message.Checkpoint)
+		case pluginsdk.MessageTypeResult:
+			result = message.Result
+		default:
+			log.Warnf("ignoring message of unknown type %v from external plugin %v", message.Type, p.Name)
+		}
+	}
+	var readErr error
+	if readErr = scanner.Err(); readErr != nil {
+		// a plugin which can't be read anymore, e.g. after a message longer than MaxMessageSize, is killed
+		// so that it doesn't block writing its next messages and Wait returns
+		log.Warnf("failed to read the messages of external plugin %v, killing it: %v", p.Name, readErr)
+		command.Process.Kill()
+	}
+	stdin.Close()
+
+	waitErr := command.Wait()
+	select {
+	case <-timedOut:
+		return timedOutResult(result, fmt.Sprintf("external plugin %v timed out after %v", p.Name, timeout)), nil
+	default:
+	}
+	switch {
+	case readErr != nil:
+		err = fmt.Errorf("failed to read the messages of external plugin %v: %v", p.Name, readErr)
+		result = nil
+	case waitErr != nil:
+		err = fmt.Errorf("external plugin %v exited without a result: %v", p.Name, waitErr)
+	case result == nil:
+		err = fmt.Errorf("external plugin %v exited without a result", p.Name)
+	}
+	return result, err
+}
+
+// timedOutResult returns the result of a step which timed out, with the output the plugin returned if any
+func timedOutResult(result *contracts.PluginResult, reason string) *contracts.PluginResult {
+	timedOut := contracts.PluginResult{Code: appconfig.CommandStoppedPreemptivelyExitCode, Error: reason}
+	if result != nil {
+		timedOut.Output = result.Output
+		if result.Error != "" {
+			timedOut.Error = result.Error
+		}
+	}
+	timedOut.Status = contracts.ResultStatusTimedOut
+	return &timedOut
+}
+
+// handshake checks the protocol version of the plugin and sends it the step along with the checkpoint of its previous run
+func (p *Plugin) handshake(scanner *bufio.Scanner, encoder *messageEncoder, config contracts.Configuration, checkpoint string) error {
+	if !scanner.Scan() {
+		return fmt.Errorf("external plugin %v exited before saying hello: %v", p.Name, scanner.Err())
+	}
+	var hello pluginsdk.Message
+	if err := json.Unmarshal(scanner.Bytes(), &hello); err != nil || hello.Type != pluginsdk.MessageTypeHello {
+		return fmt.Errorf("external plugin %v did not say hello", p.Name)
+	}
+	if !pluginsdk.IsCompatibleVersion(hello.ProtocolVersion) {
+		return fmt.Errorf("external plugin %v speaks protocol version %v, the agent speaks version %v", p.Name, hello.ProtocolVersion, pluginsdk.ProtocolVersion)
+	}
+	return encoder.send(pluginsdk.Message{Type: pluginsdk.MessageTypeExecute, ProtocolVersion: pluginsdk.ProtocolVersion, Configuration: &config, Checkpoint: checkpoint})
+}
+
+// stopOnRequest asks the plugin to stop when the step is cancelled or times out, and kills it if it's still running
+// after cancelGracePeriod. timedOut is closed when the step timed out.
+func (p *Plugin) stopOnRequest(log log.T, command *exec.Cmd, encoder *messageEncoder, cancelFlag task.CancelFlag, timeout time.Duration, timedOut chan bool, done chan bool) {
+	cancelled := make(chan bool)
+	go func() {
+		cancelFlag.Wait()
+		if cancelFlag.Canceled() || cancelFlag.ShutDown() {
+			close(cancelled)
+		}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return
+	case <-cancelled:
+		log.Infof("Asking external plugin %v to cancel", p.Name)
+	case <-timer.C:
+		log.Infof("External plugin %v timed out after %v, asking it to cancel", p.Name, timeout)
+		close(timedOut)
+	}
+
+	if err := encoder.send(pluginsdk.Message{Type: pluginsdk.MessageTypeCancel}); err != nil {
+		log.Debugf("failed to send the cancel message to external plugin %v: %v", p.Name, err)
+	}
+	select {
+	case <-done:
+	case <-time.After(cancelGracePeriod):
+		log.Infof("Killing external plugin %v", p.Name)
+		if err := command.Process.Kill(); err != nil {
+			log.Debugf("failed to kill external plugin %v: %v", p.Name, err)
+		}
+	}
+}
+
+// setResult reports the result returned by the plugin
+func setResult(output iohandler.IOHandler, result contracts.PluginResult) {
+	switch value := result.Output.(type) {
+	case nil:
+	case string:
+		output.AppendInfo(value)
+	default:
+		if indented, err := jsonutil.MarshalIndent(value); err == nil {
+			output.AppendInfo(indented)
+		} else {
+			output.AppendInfo(fmt.Sprint(value))
+		}
+	}
+	if result.Error != "" {
+		output.AppendError(result.Error)
+	}
+
+	status := result.Status
+	switch status {
+	case "":
+		status = contracts.ResultStatusSuccess
+		if result.Code != 0 {
+			status = contracts.ResultStatusFailed
+		}
+	case contracts.ResultStatusSuccess, contracts.ResultStatusSuccessAndReboot:
+	case contracts.ResultStatusFailed, contracts.ResultStatusCancelled, contracts.ResultStatusTimedOut:
+		if result.Code == 0 {
+			// the step did not succeed, so its exit code can't be 0
+			result.Code = 1
+		}
+	default:
+		output.MarkAsFailed(fmt.Errorf("external plugin returned the unknown status %v", result.Status))
+		return
+	}
+	output.SetStatus(status)
+	output.SetExitCode(result.Code)
+}
+
+// messageEncoder writes the messages to the plugin from several goroutines
+type messageEncoder struct {
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+// send writes message on a line
+func (e *messageEncoder) send(message pluginsdk.Message) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.encoder.Encode(message)
+}
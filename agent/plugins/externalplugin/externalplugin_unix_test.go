@@ -0,0 +1,322 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package externalplugin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/pluginsdk"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
+)
+
+var defaultStepTimeout = stepTimeout
+
+const (
+	hello         = `echo '{"type":"hello","protocolVersion":"1.0"}'`
+	successResult = `echo '{"type":"result","result":{"status":"Success","code":0}}'`
+)
+
+// TestMain runs the test binary as an external plugin built with pluginsdk when asked to
+func TestMain(m *testing.M) {
+	if os.Getenv("EXTERNAL_PLUGIN_TEST") == "1" {
+		pluginsdk.Run(func(step *pluginsdk.Step) contracts.PluginResult {
+			var input struct{ Service string }
+			if err := step.Properties(&input); err != nil {
+				return pluginsdk.Failure(1, err)
+			}
+			fmt.Fprintf(step.Stdout, "deployed %v", input.Service)
+			return pluginsdk.Success()
+		})
+	}
+	os.Exit(m.Run())
+}
+
+func TestParseName(t *testing.T) {
+	prefix, action, ok := ParseName("acme:deployService")
+	assert.True(t, ok)
+	assert.Equal(t, "acme", prefix)
+	assert.Equal(t, "deployService", action)
+
+	for _, name := range []string{"aws:runShellScript", "deployService", "acme:", "acme:../../bin/sh", "../acme:deploy", "acme:.hidden"} {
+		_, _, ok := ParseName(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	directory := newTempDir(t)
+	defer os.RemoveAll(directory)
+	writePlugin(t, directory, "acme", "deployService", successResult)
+	writePlugin(t, directory, "acme", "rollback", successResult)
+	writePlugin(t, directory, "aws", "runShellScript", successResult)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "acme", "deployService.sig"), []byte("c2lnbmF0dXJl"), 0600))
+
+	assert.Equal(t, []string{"acme:deployService", "acme:rollback"}, Discover(log.NewMockLog(), directory))
+	assert.Empty(t, Discover(log.NewMockLog(), filepath.Join(directory, "missing")))
+}
+
+func TestNewPluginChecksOwnership(t *testing.T) {
+	directory := newTempDir(t)
+	defer os.RemoveAll(directory)
+	writePlugin(t, directory, "acme", "deployService", successResult)
+
+	plugin, err := NewPlugin("acme:deployService", directory, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(directory, "acme", "deployService"), plugin.Path)
+
+	assert.NoError(t, os.Chmod(filepath.Join(directory, "acme"), 0777))
+	_, err = NewPlugin("acme:deployService", directory, nil)
+	assert.Error(t, err)
+
+	_, err = NewPlugin("aws:runShellScript", directory, nil)
+	assert.Error(t, err)
+}
+
+func TestNewPluginChecksSignature(t *testing.T) {
+	directory := newTempDir(t)
+	defer os.RemoveAll(directory)
+	path := writePlugin(t, directory, "acme", "deployService", successResult)
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	publicKeys := []string{base64.StdEncoding.EncodeToString(publicKey)}
+
+	_, err = NewPlugin("acme:deployService", directory, publicKeys)
+	assert.Error(t, err)
+
+	signature, err := pluginsdk.SignFile(path, privateKey)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(path+pluginsdk.SignatureExtension, []byte(signature), 0600))
+	_, err = NewPlugin("acme:deployService", directory, publicKeys)
+	assert.NoError(t, err)
+
+	_, err = NewPlugin("acme:deployService", directory, []string{"not a key"})
+	assert.Error(t, err)
+}
+
+func TestRun(t *testing.T) {
+	directory := newTempDir(t)
+	defer os.RemoveAll(directory)
+	orchestrationDirectory := filepath.Join(directory, "orchestration")
+	writePlugin(t, directory, "acme", "deployService", hello+`
+read execute
+echo "$execute" > execute.json
+printf '%s\n' '{"type":"output","stream":"stdout","data":"deploying\n"}'
+echo "slow rollout" >&2
+`+successResult)
+	plugin, err := NewPlugin("acme:deployService", directory, nil)
+	assert.NoError(t, err)
+	var stdout, stderr bytes.Buffer
+
+	result, err := plugin.run(log.NewMockLog(), contracts.Configuration{PluginID: "deploy", OrchestrationDirectory: orchestrationDirectory}, task.NewChanneledCancelFlag(), &stdout, &stderr, newIOHandler(t, directory))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &contracts.PluginResult{Status: contracts.ResultStatusSuccess}, result)
+	assert.Equal(t, "deploying\n", stdout.String())
+	assert.Equal(t, "slow rollout\n", stderr.String())
+	execute, err := ioutil.ReadFile(filepath.Join(orchestrationDirectory, "execute.json"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(execute), `"type":"execute","protocolVersion":"1.1"`)
+	assert.Contains(t, string(execute), `"PluginID":"deploy"`)
+}
+
+func TestRunCheckpoints(t *testing.T) {
+	directory := newTempDir(t)
+	defer os.RemoveAll(directory)
+	orchestrationDirectory := filepath.Join(directory, "orchestration")
+	writePlugin(t, directory, "acme", "deployService", hello+`
+read execute
+echo "$execute" > execute.json
+echo '{"type":"checkpoint","checkpoint":"host-3"}'
+`+successResult)
+	plugin, err := NewPlugin("acme:deployService", directory, nil)
+	assert.NoError(t, err)
+	var stdout, stderr bytes.Buffer
+	output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{Checkpointer: contracts.NewCheckpointer("deploy", "host-2", nil)})
+
+	result, err := plugin.run(log.NewMockLog(), contracts.Configuration{OrchestrationDirectory: orchestrationDirectory}, task.NewChanneledCancelFlag(), &stdout, &stderr, output)
+
+	assert.NoError(t, err)
+	assert.Equal(t, contracts.ResultStatusSuccess, result.Status)
+	assert.Equal(t, "host-3", output.GetCheckpoint())
+	execute, err := ioutil.ReadFile(filepath.Join(orchestrationDirectory, "execute.json"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(execute), `"checkpoint":"host-2"`)
+}
+
+func TestRunWithoutResult(t *testing.T) {
+	directory := newTempDir(t)
+	defer os.RemoveAll(directory)
+	writePlugin(t, directory, "acme", "deployService", hello+"\nread execute\nexit 3")
+	plugin, err := NewPlugin("acme:deployService", directory, nil)
+	assert.NoError(t, err)
+	var stdout, stderr bytes.Buffer
+
+	result, err := plugin.run(log.NewMockLog(), contracts.Configuration{}, task.NewChanneledCancelFlag(), &stdout, &stderr, newIOHandler(t, directory))
+
+	assert.Nil(t, result)
+	assert.EqualError(t, err, "external plugin acme:deployService exited without a result: exit status 3")
+}
+
+func TestRunRejectsIncompatibleVersion(t *testing.T) {
+	directory := newTempDir(t)
+	defer os.RemoveAll(directory)
+	writePlugin(t, directory, "acme", "deployService", `echo '{"type":"hello","protocolVersion":"2.0"}'`+"\nread execute\n"+successResult)
+	plugin, err := NewPlugin("acme:deployService", directory, nil)
+	assert.NoError(t, err)
+	var stdout, stderr bytes.Buffer
+
+	result, err := plugin.run(log.NewMockLog(), contracts.Configuration{}, task.NewChanneledCancelFlag(), &stdout, &stderr, newIOHandler(t, directory))
+
+	assert.Nil(t, result)
+	assert.Error(t, err)
+}
+
+func TestExecuteCancel(t *testing.T) {
+	directory := newTempDir(t)
+	defer os.RemoveAll(directory)
+	writePlugin(t, directory, "acme", "deployService", hello+`
+read execute
+read cancel
+echo '{"type":"result","result":{"status":"Cancelled","code":2}}'`)
+	plugin, err := NewPlugin("acme:deployService", directory, nil)
+	assert.NoError(t, err)
+	output := newIOHandler(t, directory)
+	cancelFlag := task.NewChanneledCancelFlag()
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancelFlag.Set(task.Canceled)
+	}()
+
+	plugin.Execute(context.NewMockDefault(), contracts.Configuration{}, cancelFlag, output)
+	output.Close(log.NewMockLog())
+
+	assert.Equal(t, contracts.ResultStatusCancelled, output.GetStatus())
+	assert.Equal(t, 2, output.GetExitCode())
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	stepTimeout = func(log log.T, config contracts.Configuration) time.Duration { return 100 * time.Millisecond }
+	defer func() { stepTimeout = defaultStepTimeout }()
+	directory := newTempDir(t)
+	defer os.RemoveAll(directory)
+	writePlugin(t, directory, "acme", "deployService", hello+`
+read execute
+read cancel
+echo '{"type":"result","result":{"status":"Cancelled","code":2,"output":"stopped the rollout"}}'`)
+	plugin, err := NewPlugin("acme:deployService", directory, nil)
+	assert.NoError(t, err)
+	output := newIOHandler(t, directory)
+
+	plugin.Execute(context.NewMockDefault(), contracts.Configuration{}, task.NewChanneledCancelFlag(), output)
+	output.Close(log.NewMockLog())
+
+	assert.Equal(t, contracts.ResultStatusTimedOut, output.GetStatus())
+	assert.Equal(t, appconfig.CommandStoppedPreemptivelyExitCode, output.GetExitCode())
+	assert.Equal(t, "stopped the rollout", output.GetStdout())
+}
+
+func TestStepTimeout(t *testing.T) {
+	timeout := stepTimeout(log.NewMockLog(), contracts.Configuration{Properties: map[string]interface{}{"timeoutSeconds": "120"}})
+	assert.Equal(t, 120*time.Second, timeout)
+}
+
+func TestRunKillsPluginAfterTooLongMessage(t *testing.T) {
+	directory := newTempDir(t)
+	defer os.RemoveAll(directory)
+	writePlugin(t, directory, "acme", "deployService", hello+fmt.Sprintf(`
+read execute
+head -c %v /dev/zero | tr '\0' a
+echo
+exec sleep 30`, pluginsdk.MaxMessageSize+1))
+	plugin, err := NewPlugin("acme:deployService", directory, nil)
+	assert.NoError(t, err)
+	var stdout, stderr bytes.Buffer
+	start := time.Now()
+
+	result, err := plugin.run(log.NewMockLog(), contracts.Configuration{}, task.NewChanneledCancelFlag(), &stdout, &stderr, newIOHandler(t, directory))
+
+	assert.Nil(t, result)
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < 10*time.Second)
+}
+
+func TestExecuteWithPluginSDK(t *testing.T) {
+	directory := newTempDir(t)
+	defer os.RemoveAll(directory)
+	executable, err := os.Executable()
+	assert.NoError(t, err)
+	writePlugin(t, directory, "acme", "deployService", fmt.Sprintf("EXTERNAL_PLUGIN_TEST=1 exec '%v'", executable))
+	plugin, err := NewPlugin("acme:deployService", directory, nil)
+	assert.NoError(t, err)
+	output := newIOHandler(t, directory)
+
+	plugin.Execute(context.NewMockDefault(), contracts.Configuration{Properties: map[string]interface{}{"Service": "billing"}}, task.NewChanneledCancelFlag(), output)
+	output.Close(log.NewMockLog())
+
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Equal(t, 0, output.GetExitCode())
+	assert.Equal(t, "deployed billing", output.GetStdout())
+}
+
+func TestSetResult(t *testing.T) {
+	output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+	setResult(output, contracts.PluginResult{Status: contracts.ResultStatusFailed, Error: "service not found", Output: "checked 3 hosts"})
+
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Equal(t, 1, output.GetExitCode())
+	assert.Equal(t, "checked 3 hosts", output.GetStdout())
+	assert.Equal(t, "service not found", output.GetStderr())
+
+	output = iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+	setResult(output, contracts.PluginResult{Status: "Exploded"})
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+}
+
+// writePlugin writes a shell script plugin and returns its path
+func writePlugin(t *testing.T, directory string, prefix string, action string, script string) string {
+	assert.NoError(t, os.MkdirAll(filepath.Join(directory, prefix), 0755))
+	path := filepath.Join(directory, prefix, action)
+	assert.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755))
+	return path
+}
+
+// newTempDir creates a temporary directory, the caller removes it
+func newTempDir(t *testing.T) string {
+	directory, err := ioutil.TempDir("", "externalplugin")
+	assert.NoError(t, err)
+	return directory
+}
+
+// newIOHandler returns an initialized IOHandler writing to the output directory of the test directory
+func newIOHandler(t *testing.T, directory string) *iohandler.DefaultIOHandler {
+	output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{OrchestrationDirectory: filepath.Join(directory, "output")})
+	output.Init(log.NewMockLog(), "acme:deployService")
+	return output
+}
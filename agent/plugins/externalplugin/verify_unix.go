@@ -0,0 +1,51 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package externalplugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// verifyOwnership checks that the executable at path, and the directories between it and directory,
+// are owned by root or the agent's user and are not writable by anybody else
+func verifyOwnership(path string, directory string) error {
+	path = filepath.Clean(path)
+	directory = filepath.Clean(directory)
+	for current := path; ; current = filepath.Dir(current) {
+		info, err := os.Lstat(current)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%v is a symbolic link", current)
+		}
+		if current == path && !info.Mode().IsRegular() {
+			return fmt.Errorf("%v is not a regular file", current)
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Uid != 0 && int(stat.Uid) != os.Geteuid() {
+			return fmt.Errorf("%v is owned by uid %v, neither root nor the agent's user", current, stat.Uid)
+		}
+		if info.Mode().Perm()&0022 != 0 {
+			return fmt.Errorf("%v is writable by its group or others", current)
+		}
+		if current == directory || current == filepath.Dir(current) {
+			return nil
+		}
+	}
+}
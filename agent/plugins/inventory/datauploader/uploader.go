@@ -26,6 +26,7 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
@@ -134,6 +135,12 @@ func (u *InventoryUploader) SendDataToSSM(context context.T, items []*ssm.Invent
 		} else {
 			log.Debugf("PutInventory was called successfully with response - %v", resp)
 			u.updateContentHash(context, items)
+			if data, marshalErr := json.Marshal(params); marshalErr == nil {
+				context.Metrics().Histogram(metrics.InventoryUploadBytes,
+					"Size of the inventory data uploaded to SSM.",
+					metrics.DefaultSizeBuckets,
+					nil).Observe(float64(len(data)))
+			}
 		}
 	}
 
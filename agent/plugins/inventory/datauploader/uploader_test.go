@@ -15,6 +15,7 @@
 package datauploader
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"testing"
@@ -235,4 +236,12 @@ func testSendData(t *testing.T, putInventorySucceeds bool) {
 	// assert that the expectations were met
 	mockSSM.AssertExpectations(t)
 	mockOptimizer.AssertExpectations(t)
+
+	var exported bytes.Buffer
+	c.Metrics().WriteOpenMetrics(&exported)
+	if putInventorySucceeds {
+		assert.Contains(t, exported.String(), "ssm_agent_inventory_upload_bytes_count 1")
+	} else {
+		assert.NotContains(t, exported.String(), "ssm_agent_inventory_upload_bytes")
+	}
 }
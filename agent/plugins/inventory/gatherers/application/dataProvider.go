@@ -26,6 +26,10 @@ const (
 
 var selectAwsApps map[string]string
 
+// packageRepository is the repository of the packages installed by aws:configurePackage.
+// The platform tests replace it with a mock.
+var packageRepository = localpackages.NewRepository()
+
 func init() {
 	//NOTE:
 	// For V1 - to filter out aws components from aws applications - we are using a list of all aws components that
@@ -17,9 +17,11 @@ package runscript
 import (
 	"fmt"
 	"path/filepath"
-
+	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/executers"
@@ -28,11 +30,17 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/ssmparameterresolver"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
 const (
 	downloadsDir = "downloads" //Directory under the orchestration directory where the downloaded resource resides
+
+	secureParameterPrefix = "ssm-secure:"
+
+	// completedCheckpointPrefix starts the checkpoint recorded once the commands exited, it's followed by their exit code
+	completedCheckpointPrefix = "completed:"
 )
 
 // Plugin is the type for the runscript plugin.
@@ -54,6 +62,15 @@ type RunScriptPluginInput struct {
 	ID               string
 	WorkingDirectory string
 	TimeoutSeconds   interface{}
+	// RunAsUser is the local user the commands run as, it overrides the runAsUser of the document
+	RunAsUser string
+	// ResourceLimits override the Execution limits of the agent configuration
+	ResourceLimits executers.ResourceLimits
+	// Sandbox runs the commands in Linux namespaces with read-only system paths, a seccomp filter and dropped capabilities
+	Sandbox executers.SandboxOptions
+	// Env are environment variables of the commands, their values can reference {{ssm:name}} and {{ssm-secure:name}}
+	// parameters. The resolved values are only passed to the commands, never written to the script file or logged.
+	Env map[string]string
 }
 
 // Execute runs multiple sets of commands and returns their outputs.
@@ -68,13 +85,13 @@ func (p *Plugin) Execute(context context.T, config contracts.Configuration, canc
 	} else if cancelFlag.Canceled() {
 		output.MarkAsCancelled()
 	} else {
-		p.runCommandsRawInput(log, config.PluginID, config.Properties, config.OrchestrationDirectory, config.DefaultWorkingDirectory, cancelFlag, output)
+		p.runCommandsRawInput(log, config.PluginID, config.Properties, config.OrchestrationDirectory, config.DefaultWorkingDirectory, config.RunAsUser, cancelFlag, output)
 	}
 }
 
 // runCommandsRawInput executes one set of commands and returns their output.
 // The input is in the default json unmarshal format (e.g. map[string]interface{}).
-func (p *Plugin) runCommandsRawInput(log log.T, pluginID string, rawPluginInput interface{}, orchestrationDirectory string, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+func (p *Plugin) runCommandsRawInput(log log.T, pluginID string, rawPluginInput interface{}, orchestrationDirectory string, defaultWorkingDirectory string, defaultRunAsUser string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
 	var pluginInput RunScriptPluginInput
 	err := jsonutil.Remarshal(rawPluginInput, &pluginInput)
 	if err != nil {
@@ -82,6 +99,9 @@ func (p *Plugin) runCommandsRawInput(log log.T, pluginID string, rawPluginInput
 		output.MarkAsFailed(errorString)
 		return
 	}
+	if pluginInput.RunAsUser == "" {
+		pluginInput.RunAsUser = defaultRunAsUser
+	}
 	p.runCommands(log, pluginID, pluginInput, orchestrationDirectory, defaultWorkingDirectory, cancelFlag, output)
 }
 
@@ -101,6 +121,35 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 		}
 	}
 
+	if pluginInput.RunAsUser != "" && !isRunAsUserAllowed(pluginInput.RunAsUser) {
+		output.MarkAsFailed(fmt.Errorf("running commands as %v is not allowed, the user should be listed in Ssm.RunAsAllowedUsers of the agent configuration", pluginInput.RunAsUser))
+		return
+	}
+
+	if pluginInput.Sandbox.Enabled {
+		if err = checkSandbox(pluginInput.Sandbox, orchestrationDirectory, workingDir); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+	}
+
+	// the commands already exited before the document worker was restarted, they must not run twice
+	if exitCode, completed := completedExitCode(output.GetCheckpoint()); completed {
+		log.Infof("commands %v already exited with code %v before the document worker was restarted, not running them again", pluginInput.ID, exitCode)
+		output.SetExitCode(exitCode)
+		output.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+		return
+	}
+
+	env, secrets, err := resolveEnvironment(log, pluginInput.Env)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to resolve the environment variables. %v", err))
+		return
+	}
+	if len(secrets) > 0 {
+		output.RegisterSecrets(secrets...)
+	}
+
 	// TODO:MF: This subdirectory is only needed because we could be running multiple sets of properties for the same plugin - otherwise the orchestration directory would already be unique
 	orchestrationDir := fileutil.BuildPath(orchestrationDirectory, pluginInput.ID)
 	log.Debugf("Running commands %v in workingDirectory %v; orchestrationDir %v ", pluginInput.RunCommand, workingDir, orchestrationDir)
@@ -121,6 +170,17 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 		return
 	}
 
+	// the user only gets access to a copy of the script, the orchestration directory stays the agent's
+	if pluginInput.RunAsUser != "" {
+		userScriptPath, cleanup, err := executers.PrepareScriptForRunAsUser(scriptPath, pluginInput.RunAsUser)
+		if err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to give %v access to the script. %v", pluginInput.RunAsUser, err))
+			return
+		}
+		defer cleanup()
+		scriptPath = userScriptPath
+	}
+
 	// Set execution time
 	executionTimeout := pluginutil.ValidateExecutionTimeout(log, pluginInput.TimeoutSeconds)
 
@@ -129,11 +189,25 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 	commandArguments := append(p.ShellArguments, scriptPath)
 
 	// Execute Command
-	exitCode, err := p.CommandExecuter.NewExecute(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments)
+	options := executers.ExecuteOptions{
+		RunAsUser: pluginInput.RunAsUser,
+		Limits:    resourceLimits(pluginInput.ResourceLimits),
+		Sandbox:   pluginInput.Sandbox,
+		Env:       env,
+	}
+	if options.Sandbox.Enabled {
+		// the script and its output files are in the orchestration directory
+		options.Sandbox.WritablePaths = append(options.Sandbox.WritablePaths, orchestrationDir)
+	}
+	exitCode, err := p.CommandExecuter.ExecuteWithOptions(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments, options)
 
 	// Set output status
+	status := pluginutil.GetStatus(exitCode, cancelFlag)
 	output.SetExitCode(exitCode)
-	output.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+	output.SetStatus(status)
+	if status == contracts.ResultStatusSuccess || status == contracts.ResultStatusFailed {
+		output.SetCheckpoint(fmt.Sprintf("%v%v", completedCheckpointPrefix, exitCode))
+	}
 
 	if err != nil {
 		status := output.GetStatus()
@@ -144,3 +218,153 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 		}
 	}
 }
+
+// completedExitCode returns the exit code recorded in the checkpoint of commands that exited
+func completedExitCode(checkpoint string) (exitCode int, completed bool) {
+	if !strings.HasPrefix(checkpoint, completedCheckpointPrefix) {
+		return 0, false
+	}
+	exitCode, err := strconv.Atoi(strings.TrimPrefix(checkpoint, completedCheckpointPrefix))
+	return exitCode, err == nil
+}
+
+// runAsAllowedUsers returns the allow-list of the agent configuration
+var runAsAllowedUsers = func() []string {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		return nil
+	}
+	return config.Ssm.RunAsAllowedUsers
+}
+
+// defaultResourceLimits returns the limits and the sandbox settings of the agent configuration
+var defaultResourceLimits = func() appconfig.ExecutionCfg {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		return appconfig.ExecutionCfg{}
+	}
+	return config.Execution
+}
+
+// resourceLimits returns the limits of the step, with the ones of the agent configuration for those it doesn't set
+func resourceLimits(step executers.ResourceLimits) executers.ResourceLimits {
+	defaults := defaultResourceLimits()
+	limits := executers.ResourceLimits{
+		CPUQuotaPercent: defaults.CPUQuotaPercent,
+		MemoryMaxMB:     defaults.MemoryMaxMB,
+		PidsMax:         defaults.PidsMax,
+		IOWeight:        defaults.IOWeight,
+	}
+	if step.CPUQuotaPercent > 0 {
+		limits.CPUQuotaPercent = step.CPUQuotaPercent
+	}
+	if step.MemoryMaxMB > 0 {
+		limits.MemoryMaxMB = step.MemoryMaxMB
+	}
+	if step.PidsMax > 0 {
+		limits.PidsMax = step.PidsMax
+	}
+	if step.IOWeight > 0 && step.IOWeight <= appconfig.IOWeightMax {
+		limits.IOWeight = step.IOWeight
+	}
+	return limits
+}
+
+// checkSandbox checks the sandbox of the step doesn't loosen the one the agent configuration allows.
+// The step may always write to its orchestration and working directories.
+func checkSandbox(sandbox executers.SandboxOptions, orchestrationDirectory string, workingDir string) error {
+	config := defaultResourceLimits()
+	if sandbox.SeccompProfile == executers.SeccompProfileUnconfined && !config.SandboxAllowUnconfinedSeccomp {
+		return fmt.Errorf("running commands without the seccomp filter is not allowed, it should be enabled with Execution.SandboxAllowUnconfinedSeccomp of the agent configuration")
+	}
+	allowed := make(map[string]struct{})
+	for _, capability := range config.SandboxAllowedCapabilities {
+		allowed[strings.ToUpper(strings.TrimSpace(capability))] = struct{}{}
+	}
+	for _, capability := range sandbox.Capabilities {
+		if _, ok := allowed[strings.ToUpper(strings.TrimSpace(capability))]; !ok {
+			return fmt.Errorf("keeping capability %v is not allowed, it should be listed in Execution.SandboxAllowedCapabilities of the agent configuration", capability)
+		}
+	}
+	allowedDirs := append([]string{orchestrationDirectory, workingDir}, config.SandboxAllowedWritablePaths...)
+	for _, path := range sandbox.WritablePaths {
+		if !filepath.IsAbs(path) || !isBelowAny(resolvePath(path), allowedDirs) {
+			return fmt.Errorf("writing to %v is not allowed, it should be below a path listed in Execution.SandboxAllowedWritablePaths of the agent configuration", path)
+		}
+	}
+	return nil
+}
+
+// isBelowAny checks the path is one of the directories or below one of them
+func isBelowAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if dir == "" || !filepath.IsAbs(dir) {
+			continue
+		}
+		relative, err := filepath.Rel(resolvePath(dir), path)
+		if err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath cleans the path and follows its symbolic links, a path which doesn't exist yet is only cleaned
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+// isRunAsUserAllowed checks the user is in the allow-list of the agent configuration
+func isRunAsUserAllowed(runAsUser string) bool {
+	for _, allowed := range runAsAllowedUsers() {
+		if allowed == runAsUser {
+			return true
+		}
+	}
+	return false
+}
+
+// extractParameters gets the values of the parameters referenced in the text from Parameter Store
+var extractParameters = func(log log.T, text string) (map[string]ssmparameterresolver.SsmParameterInfo, error) {
+	service := ssmparameterresolver.NewService()
+	return ssmparameterresolver.ExtractParametersFromText(&service, log, text, ssmparameterresolver.ResolveOptions{})
+}
+
+// resolveEnvironment replaces the parameter references in the values of the environment variables,
+// and returns the values of the secure parameters so they can be redacted from the output of the commands
+func resolveEnvironment(log log.T, env map[string]string) (resolved map[string]string, secrets []string, err error) {
+	if len(env) == 0 {
+		return nil, nil, nil
+	}
+	values := make([]string, 0, len(env))
+	for name, value := range env {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return nil, nil, fmt.Errorf("invalid environment variable name %q", name)
+		}
+		values = append(values, value)
+	}
+
+	// all the references are resolved at once to limit the calls to Parameter Store
+	parameters, err := extractParameters(log, strings.Join(values, "\n"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolved = make(map[string]string, len(env))
+	for name, value := range env {
+		resolved[name] = value
+	}
+	for reference, parameter := range parameters {
+		placeholder := regexp.MustCompile("{{\\s*" + regexp.QuoteMeta(reference) + "\\s*}}")
+		for name, value := range resolved {
+			resolved[name] = placeholder.ReplaceAllLiteralString(value, parameter.Value)
+		}
+		if strings.HasPrefix(reference, secureParameterPrefix) && parameter.Value != "" {
+			secrets = append(secrets, parameter.Value)
+		}
+	}
+	return resolved, secrets, nil
+}
@@ -15,8 +15,12 @@ package runscript
 
 import (
 	"fmt"
+	"os/user"
+	"path/filepath"
+	"runtime"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/executers"
@@ -25,6 +29,7 @@ import (
 	multiwritermock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/ssmparameterresolver"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -61,6 +66,12 @@ var MultiInputTestCases = generateTestCaseMultipleInputsOk([]string{"0", "1"})
 
 var logger = log.NewMockLog()
 
+var defaultRunAsAllowedUsers = runAsAllowedUsers
+
+var defaultExecutionCfg = defaultResourceLimits
+
+var defaultExtractParameters = extractParameters
+
 func generateTestCaseOk(id string) TestCase {
 	input := RunScriptPluginInput{
 		RunCommand:       []string{"echo " + id},
@@ -140,7 +151,7 @@ func testRunScripts(t *testing.T, testCase TestCase, rawInput bool) {
 			err := jsonutil.Remarshal(testCase.Input, &rawPluginInput)
 			assert.Nil(t, err)
 
-			p.runCommandsRawInput(logger, pluginID, rawPluginInput, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+			p.runCommandsRawInput(logger, pluginID, rawPluginInput, orchestrationDirectory, defaultWorkingDirectory, "", mockCancelFlag, mockIOHandler)
 		} else {
 			p.runCommands(logger, pluginID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
 		}
@@ -149,6 +160,193 @@ func testRunScripts(t *testing.T, testCase TestCase, rawInput bool) {
 	testExecution(t, runScriptTester)
 }
 
+// TestRunScriptsRunAsUserNotAllowed tests the commands don't run as a user missing from the allow-list.
+func TestRunScriptsRunAsUserNotAllowed(t *testing.T) {
+	runAsAllowedUsers = func() []string { return []string{"ec2-user"} }
+	defer func() { runAsAllowedUsers = defaultRunAsAllowedUsers }()
+
+	testCase := generateTestCaseOk("0")
+	testCase.Input.RunAsUser = "root"
+	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+		p.runCommands(logger, pluginID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+
+		mockExecuter.AssertNotCalled(t, "ExecuteWithOptions", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+
+	testExecution(t, runScriptTester)
+}
+
+// TestRunScriptsRunAsDocumentUser tests the commands run as the user of the document unless the step sets its own.
+func TestRunScriptsRunAsDocumentUser(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("running commands as another user is not supported on Windows")
+	}
+	current, err := user.Current()
+	assert.NoError(t, err)
+	runAsAllowedUsers = func() []string { return []string{current.Username} }
+	defer func() { runAsAllowedUsers = defaultRunAsAllowedUsers }()
+
+	testCase := generateTestCaseOk("0")
+	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		mockExecuter.On("ExecuteWithOptions", mock.Anything, testCase.Input.WorkingDirectory, testCase.Output.StdoutWriter, testCase.Output.StderrWriter, mockCancelFlag, mock.Anything, mock.Anything, mock.Anything,
+			executers.ExecuteOptions{RunAsUser: current.Username}).Return(0, nil)
+		setIOHandlerExpectations(mockIOHandler, testCase)
+
+		var rawPluginInput interface{}
+		assert.NoError(t, jsonutil.Remarshal(testCase.Input, &rawPluginInput))
+		p.runCommandsRawInput(logger, pluginID, rawPluginInput, orchestrationDirectory, defaultWorkingDirectory, current.Username, mockCancelFlag, mockIOHandler)
+	}
+
+	testExecution(t, runScriptTester)
+}
+
+// TestResourceLimits tests the limits of a step override the ones of the agent configuration.
+func TestResourceLimits(t *testing.T) {
+	defaultResourceLimits = func() appconfig.ExecutionCfg {
+		return appconfig.ExecutionCfg{CPUQuotaPercent: 100, MemoryMaxMB: 1024, IOWeight: 100}
+	}
+	defer func() { defaultResourceLimits = defaultExecutionCfg }()
+
+	limits := resourceLimits(executers.ResourceLimits{MemoryMaxMB: 256, PidsMax: 64, IOWeight: 20000})
+
+	assert.Equal(t, executers.ResourceLimits{CPUQuotaPercent: 100, MemoryMaxMB: 256, PidsMax: 64, IOWeight: 100}, limits)
+}
+
+// TestCheckSandbox tests a step can't loosen the sandbox more than the agent configuration allows.
+func TestCheckSandbox(t *testing.T) {
+	defaultResourceLimits = func() appconfig.ExecutionCfg {
+		return appconfig.ExecutionCfg{SandboxAllowedCapabilities: []string{"CAP_NET_BIND_SERVICE"}}
+	}
+	defer func() { defaultResourceLimits = defaultExecutionCfg }()
+
+	assert.NoError(t, checkSandbox(executers.SandboxOptions{Enabled: true, Capabilities: []string{"cap_net_bind_service"}}, orchestrationDirectory, defaultWorkingDirectory))
+	assert.Error(t, checkSandbox(executers.SandboxOptions{Enabled: true, Capabilities: []string{"CAP_SYS_ADMIN"}}, orchestrationDirectory, defaultWorkingDirectory))
+	assert.Error(t, checkSandbox(executers.SandboxOptions{Enabled: true, SeccompProfile: executers.SeccompProfileUnconfined}, orchestrationDirectory, defaultWorkingDirectory))
+
+	defaultResourceLimits = func() appconfig.ExecutionCfg {
+		return appconfig.ExecutionCfg{SandboxAllowUnconfinedSeccomp: true}
+	}
+	assert.NoError(t, checkSandbox(executers.SandboxOptions{Enabled: true, SeccompProfile: executers.SeccompProfileUnconfined}, orchestrationDirectory, defaultWorkingDirectory))
+}
+
+// TestCheckSandboxWritablePaths tests a step only keeps the paths the agent configuration allows writable.
+func TestCheckSandboxWritablePaths(t *testing.T) {
+	defaultResourceLimits = func() appconfig.ExecutionCfg { return appconfig.ExecutionCfg{} }
+	defer func() { defaultResourceLimits = defaultExecutionCfg }()
+
+	orchestration := filepath.Join(string(filepath.Separator), "var", "lib", "amazon", "ssm", "orchestration")
+	working := filepath.Join(string(filepath.Separator), "home", "ssm-user")
+	sandbox := func(paths ...string) executers.SandboxOptions {
+		return executers.SandboxOptions{Enabled: true, WritablePaths: paths}
+	}
+
+	assert.NoError(t, checkSandbox(sandbox(orchestration, filepath.Join(working, "build")), orchestration, working))
+	assert.Error(t, checkSandbox(sandbox(filepath.Join(string(filepath.Separator), "etc")), orchestration, working))
+	assert.Error(t, checkSandbox(sandbox(filepath.Join(orchestration, "..", "credentials")), orchestration, working))
+	assert.Error(t, checkSandbox(sandbox("build"), orchestration, working))
+
+	defaultResourceLimits = func() appconfig.ExecutionCfg {
+		return appconfig.ExecutionCfg{SandboxAllowedWritablePaths: []string{filepath.Join(string(filepath.Separator), "srv", "data")}}
+	}
+	assert.NoError(t, checkSandbox(sandbox(filepath.Join(string(filepath.Separator), "srv", "data", "cache")), orchestration, working))
+	assert.Error(t, checkSandbox(sandbox(filepath.Join(string(filepath.Separator), "srv", "database")), orchestration, working))
+}
+
+// TestRunScriptsSandboxNotAllowed tests the commands don't run in a sandbox looser than the agent configuration allows.
+func TestRunScriptsSandboxNotAllowed(t *testing.T) {
+	defaultResourceLimits = func() appconfig.ExecutionCfg { return appconfig.ExecutionCfg{} }
+	defer func() { defaultResourceLimits = defaultExecutionCfg }()
+
+	testCase := generateTestCaseOk("0")
+	testCase.Input.Sandbox = executers.SandboxOptions{Enabled: true, SeccompProfile: executers.SeccompProfileUnconfined}
+	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+		p.runCommands(logger, pluginID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+
+		mockExecuter.AssertNotCalled(t, "ExecuteWithOptions", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+
+	testExecution(t, runScriptTester)
+}
+
+// TestRunScriptsWithEnv tests the resolved environment variables are passed to the commands and the secrets registered for redaction.
+func TestRunScriptsWithEnv(t *testing.T) {
+	extractParameters = func(log log.T, text string) (map[string]ssmparameterresolver.SsmParameterInfo, error) {
+		return map[string]ssmparameterresolver.SsmParameterInfo{
+			"ssm-secure:db-password": {Name: "db-password", Type: "SecureString", Value: "s3cret"},
+		}, nil
+	}
+	defer func() { extractParameters = defaultExtractParameters }()
+
+	testCase := generateTestCaseOk("0")
+	testCase.Input.Env = map[string]string{"DB_PASSWORD": "{{ssm-secure:db-password}}"}
+	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		mockExecuter.On("ExecuteWithOptions", mock.Anything, testCase.Input.WorkingDirectory, testCase.Output.StdoutWriter, testCase.Output.StderrWriter, mockCancelFlag, mock.Anything, mock.Anything, mock.Anything,
+			executers.ExecuteOptions{Env: map[string]string{"DB_PASSWORD": "s3cret"}}).Return(0, nil)
+		mockIOHandler.On("RegisterSecrets", []string{"s3cret"}).Return()
+		setIOHandlerExpectations(mockIOHandler, testCase)
+
+		p.runCommands(logger, pluginID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+	}
+
+	testExecution(t, runScriptTester)
+}
+
+// TestRunScriptsAlreadyCompleted tests the commands which exited before the document worker was restarted don't run again.
+func TestRunScriptsAlreadyCompleted(t *testing.T) {
+	testCase := generateTestCaseOk("0")
+	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		mockIOHandler.On("GetCheckpoint").Return("completed:1")
+		mockIOHandler.On("SetExitCode", 1).Return()
+		mockIOHandler.On("SetStatus", contracts.ResultStatusFailed).Return()
+
+		p.runCommands(logger, pluginID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+
+		mockExecuter.AssertNotCalled(t, "ExecuteWithOptions", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+
+	testExecution(t, runScriptTester)
+}
+
+// TestCompletedExitCode tests the exit code is only read from the checkpoint of commands that exited.
+func TestCompletedExitCode(t *testing.T) {
+	exitCode, completed := completedExitCode("completed:3")
+	assert.True(t, completed)
+	assert.Equal(t, 3, exitCode)
+
+	_, completed = completedExitCode("")
+	assert.False(t, completed)
+	_, completed = completedExitCode("completed:")
+	assert.False(t, completed)
+}
+
+// TestResolveEnvironment tests the parameter references in the values are replaced and the secure values returned.
+func TestResolveEnvironment(t *testing.T) {
+	extractParameters = func(log log.T, text string) (map[string]ssmparameterresolver.SsmParameterInfo, error) {
+		return map[string]ssmparameterresolver.SsmParameterInfo{
+			"ssm:db-host":            {Name: "db-host", Type: "String", Value: "db.example.com"},
+			"ssm-secure:db-password": {Name: "db-password", Type: "SecureString", Value: "$1s3cret"},
+		}, nil
+	}
+	defer func() { extractParameters = defaultExtractParameters }()
+
+	env, secrets, err := resolveEnvironment(logger, map[string]string{
+		"DB_URL":      "postgres://{{ ssm:db-host }}:5432",
+		"DB_PASSWORD": "{{ssm-secure:db-password}}",
+		"DB_NAME":     "orders",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"DB_URL": "postgres://db.example.com:5432", "DB_PASSWORD": "$1s3cret", "DB_NAME": "orders"}, env)
+	assert.Equal(t, []string{"$1s3cret"}, secrets)
+
+	_, _, err = resolveEnvironment(logger, map[string]string{"DB=NAME": "orders"})
+	assert.Error(t, err)
+}
+
 // TestBucketsInDifferentRegions tests runScripts when S3Buckets are present in IAD and PDX region.
 func TestBucketsInDifferentRegions(t *testing.T) {
 	for _, testCase := range TestCases {
@@ -323,15 +521,17 @@ func testExecution(t *testing.T, commandtester CommandTester) {
 }
 
 func setExecuterExpectations(mockExecuter *executers.MockCommandExecuter, t TestCase, cancelFlag task.CancelFlag, p *Plugin) {
-	mockExecuter.On("NewExecute", mock.Anything, t.Input.WorkingDirectory, t.Output.StdoutWriter, t.Output.StderrWriter, cancelFlag, mock.Anything, mock.Anything, mock.Anything).Return(
+	mockExecuter.On("ExecuteWithOptions", mock.Anything, t.Input.WorkingDirectory, t.Output.StdoutWriter, t.Output.StderrWriter, cancelFlag, mock.Anything, mock.Anything, mock.Anything, executers.ExecuteOptions{}).Return(
 		t.Output.ExitCode, t.ExecuterError)
 }
 
 func setIOHandlerExpectations(mockIOHandler *iohandlermocks.MockIOHandler, t TestCase) {
+	mockIOHandler.On("GetCheckpoint").Return("")
 	mockIOHandler.On("GetStdoutWriter").Return(t.Output.StdoutWriter)
 	mockIOHandler.On("GetStderrWriter").Return(t.Output.StderrWriter)
 	mockIOHandler.On("SetExitCode", t.Output.ExitCode).Return()
 	mockIOHandler.On("SetStatus", t.Output.Status).Return()
+	mockIOHandler.On("SetCheckpoint", fmt.Sprintf("completed:%v", t.Output.ExitCode)).Return()
 	if t.ExecuterError != nil {
 		mockIOHandler.On("GetStatus").Return(t.Output.Status)
 		mockIOHandler.On("MarkAsFailed", fmt.Errorf("failed to run commands: %v", t.ExecuterError)).Return()
@@ -0,0 +1,98 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updatessmagent implements the UpdateSsmAgent plugin.
+package updatessmagent
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"golang.org/x/crypto/ed25519"
+)
+
+const (
+	// MirrorManifestFileName is the name of the manifest in the root of an agent update mirror
+	MirrorManifestFileName = "ssm-agent-manifest.json"
+
+	// ManifestSignatureSuffix is appended to the manifest location to get its detached signature.
+	// The signature file holds the base64 encoded ed25519 signature of the manifest file content.
+	ManifestSignatureSuffix = ".sig"
+
+	fileURLPrefix = "file://"
+)
+
+// MirrorManifestURL returns the location of the manifest served by a mirror.
+// The mirror is either an HTTP(S) URL or a local directory, optionally written as a file:// URL.
+func MirrorManifestURL(mirrorURL string) string {
+	mirrorURL = strings.TrimPrefix(mirrorURL, fileURLPrefix)
+	if isRemoteLocation(mirrorURL) {
+		return strings.TrimSuffix(mirrorURL, "/") + "/" + MirrorManifestFileName
+	}
+	return filepath.Join(mirrorURL, MirrorManifestFileName)
+}
+
+// isRemoteLocation returns true if the location is a URL rather than a local path
+func isRemoteLocation(location string) bool {
+	return strings.Contains(location, "://")
+}
+
+// resolveURIFormat resolves a package uri format relative to the location of the manifest.
+// This allows a mirror to be copied or moved without rewriting its manifest.
+func resolveURIFormat(manifestLocation string, uriFormat string) string {
+	if isRemoteLocation(uriFormat) || filepath.IsAbs(uriFormat) || strings.HasPrefix(uriFormat, "/") {
+		return uriFormat
+	}
+	if isRemoteLocation(manifestLocation) {
+		return manifestLocation[:strings.LastIndex(manifestLocation, "/")+1] + strings.TrimPrefix(uriFormat, "./")
+	}
+	return filepath.Join(filepath.Dir(manifestLocation), uriFormat)
+}
+
+// verifyManifestSignature downloads the detached signature of the manifest and verifies it with the pinned key
+func verifyManifestSignature(log log.T, manifestLocation string, manifestPath string, downloadFolder string, encodedKey string) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("manifest public key is not a base64 encoded %v byte ed25519 public key", ed25519.PublicKeySize)
+	}
+
+	downloadInput := artifact.DownloadInput{
+		SourceURL:            manifestLocation + ManifestSignatureSuffix,
+		DestinationDirectory: downloadFolder,
+	}
+	downloadOutput, err := fileDownload(log, downloadInput)
+	if err != nil || downloadOutput.LocalFilePath == "" {
+		return fmt.Errorf("failed to download manifest signature %v, %v", downloadInput.SourceURL, err)
+	}
+
+	var manifest, encodedSignature []byte
+	if manifest, err = ioutil.ReadFile(manifestPath); err != nil {
+		return err
+	}
+	if encodedSignature, err = ioutil.ReadFile(downloadOutput.LocalFilePath); err != nil {
+		return err
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedSignature)))
+	if err != nil {
+		return fmt.Errorf("manifest signature is not base64 encoded, %v", err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), manifest, signature) {
+		return fmt.Errorf("manifest signature verification failed for %v", manifestLocation)
+	}
+	return nil
+}
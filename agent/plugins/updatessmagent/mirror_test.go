@@ -0,0 +1,131 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updatessmagent implements the UpdateSsmAgent plugin.
+package updatessmagent
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
+)
+
+// createLocalMirror copies the sample manifest into a new local mirror directory and signs it with the given key
+func createLocalMirror(t *testing.T, signingKey ed25519.PrivateKey) string {
+	mirror, err := ioutil.TempDir("", "mirror")
+	assert.NoError(t, err)
+	manifest, err := ioutil.ReadFile("testdata/sampleManifest.json")
+	assert.NoError(t, err)
+	manifestPath := filepath.Join(mirror, MirrorManifestFileName)
+	assert.NoError(t, ioutil.WriteFile(manifestPath, manifest, 0600))
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(signingKey, manifest))
+	assert.NoError(t, ioutil.WriteFile(manifestPath+ManifestSignatureSuffix, []byte(signature), 0600))
+	return mirror
+}
+
+// useArtifactDownload downloads with the real artifact download and returns a function restoring the previous one
+func useArtifactDownload() (restore func()) {
+	download := fileDownload
+	fileDownload = artifact.Download
+	return func() { fileDownload = download }
+}
+
+func TestMirrorManifestURL(t *testing.T) {
+	assert.Equal(t, "https://mirror.example.com/ssm/ssm-agent-manifest.json", MirrorManifestURL("https://mirror.example.com/ssm/"))
+	assert.Equal(t, filepath.Join("/srv/mirror", MirrorManifestFileName), MirrorManifestURL("file:///srv/mirror"))
+}
+
+func TestResolveURIFormat(t *testing.T) {
+	absolute := "https://s3.{Region}.amazonaws.com/amazon-ssm-{Region}/{PackageName}/{PackageVersion}/{FileName}"
+	assert.Equal(t, absolute, resolveURIFormat("https://mirror.example.com/ssm/ssm-agent-manifest.json", absolute))
+	assert.Equal(t,
+		"https://mirror.example.com/ssm/{PackageName}/{PackageVersion}/{FileName}",
+		resolveURIFormat("https://mirror.example.com/ssm/ssm-agent-manifest.json", "{PackageName}/{PackageVersion}/{FileName}"))
+	assert.Equal(t,
+		filepath.Join("/srv/mirror", "{PackageName}/{PackageVersion}/{FileName}"),
+		resolveURIFormat("/srv/mirror/ssm-agent-manifest.json", "{PackageName}/{PackageVersion}/{FileName}"))
+}
+
+func TestDownloadManifestFromSignedLocalMirror(t *testing.T) {
+	restore := useArtifactDownload()
+	defer restore()
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	mirror := createLocalMirror(t, privateKey)
+	defer os.RemoveAll(mirror)
+	plugin := createStubPluginInput()
+	plugin.Source = MirrorManifestURL(mirror)
+	plugin.ManifestKey = base64.StdEncoding.EncodeToString(publicKey)
+	out := iohandler.DefaultIOHandler{}
+
+	manager := updateManager{}
+	manifest, err := manager.downloadManifest(logger, &fakeUtility{}, plugin, createStubInstanceContext(), &out)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, manifest)
+	assert.Contains(t, out.GetStdout(), "Verified signature")
+}
+
+func TestDownloadManifestFromLocalMirrorWithWrongKey(t *testing.T) {
+	restore := useArtifactDownload()
+	defer restore()
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	otherKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	mirror := createLocalMirror(t, privateKey)
+	defer os.RemoveAll(mirror)
+	plugin := createStubPluginInput()
+	plugin.Source = MirrorManifestURL(mirror)
+	plugin.ManifestKey = base64.StdEncoding.EncodeToString(otherKey)
+
+	manager := updateManager{}
+	manifest, err := manager.downloadManifest(logger, &fakeUtility{}, plugin, createStubInstanceContext(), &iohandler.DefaultIOHandler{})
+
+	assert.Nil(t, manifest)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "manifest signature verification failed")
+}
+
+func TestDownloadManifestFromLocalMirrorWithInvalidSignature(t *testing.T) {
+	restore := useArtifactDownload()
+	defer restore()
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	mirror := createLocalMirror(t, privateKey)
+	defer os.RemoveAll(mirror)
+	plugin := createStubPluginInput()
+	plugin.Source = MirrorManifestURL(mirror)
+	plugin.ManifestKey = base64.StdEncoding.EncodeToString(publicKey)
+	assert.NoError(t, ioutil.WriteFile(plugin.Source+ManifestSignatureSuffix, []byte("not a signature"), 0600))
+
+	manager := updateManager{}
+	_, err := manager.downloadManifest(logger, &fakeUtility{}, plugin, createStubInstanceContext(), &iohandler.DefaultIOHandler{})
+
+	assert.Error(t, err)
+}
+
+func TestUpdateAgent_MirrorWithoutManifestKey(t *testing.T) {
+	plugin := &Plugin{MirrorURL: "/srv/mirror", ManifestLocation: MirrorManifestURL("/srv/mirror")}
+	out := iohandler.DefaultIOHandler{}
+
+	runUpdateAgent(plugin, contracts.Configuration{}, logger, &fakeUpdateManager{}, &fakeUtility{}, createStubPluginInput(), nil, &out, time.Now())
+
+	assert.Contains(t, out.GetStderr(), "configured without MirrorManifestPublicKey")
+}
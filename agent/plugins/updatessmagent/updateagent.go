@@ -40,6 +40,10 @@ import (
 type Plugin struct {
 	// Manifest location
 	ManifestLocation string
+	// MirrorURL is the mirror the manifest and packages are downloaded from, empty for the regional S3 bucket
+	MirrorURL string
+	// ManifestPublicKey is the pinned key the manifest signature is verified with, empty if the manifest is not signed
+	ManifestPublicKey string
 }
 
 // UpdatePluginInput represents one set of commands executed by the UpdateAgent plugin.
@@ -50,11 +54,14 @@ type UpdatePluginInput struct {
 	TargetVersion  string `json:"targetVersion"`
 	Source         string `json:"source"`
 	UpdaterName    string `json:"-"`
+	ManifestKey    string `json:"-"`
 }
 
 // UpdatePluginConfig is used for initializing update agent plugin with default values
 type UpdatePluginConfig struct {
-	ManifestLocation string
+	ManifestLocation  string
+	MirrorURL         string
+	ManifestPublicKey string
 }
 
 type updateManager struct{}
@@ -101,6 +108,8 @@ var updateAgent = runUpdateAgent
 func NewPlugin(updatePluginConfig UpdatePluginConfig) (*Plugin, error) {
 	var plugin Plugin
 	plugin.ManifestLocation = updatePluginConfig.ManifestLocation
+	plugin.MirrorURL = updatePluginConfig.MirrorURL
+	plugin.ManifestPublicKey = updatePluginConfig.ManifestPublicKey
 	return &plugin, nil
 }
 
@@ -131,12 +140,20 @@ func runUpdateAgent(
 		return
 	}
 
+	//A mirror is only trusted through its signed manifest
+	if len(p.MirrorURL) != 0 && len(p.ManifestPublicKey) == 0 {
+		output.MarkAsFailed(fmt.Errorf("agent update mirror %v is configured without MirrorManifestPublicKey", p.MirrorURL))
+		return
+	}
+
 	//Use default manifest location is the override is not present
 	if len(pluginInput.Source) == 0 {
 		pluginInput.Source = p.ManifestLocation
 	}
 	//Calculate manifest location base on current instance's region
 	pluginInput.Source = strings.Replace(pluginInput.Source, updateutil.RegionHolder, context.Region, -1)
+	//A pinned manifest key applies to any source, including an override of the mirror
+	pluginInput.ManifestKey = p.ManifestPublicKey
 	//Calculate updater package name base on agent name
 	pluginInput.UpdaterName = pluginInput.AgentName + updateutil.UpdaterPackageNamePrefix
 	//Generate update output
@@ -275,7 +292,7 @@ func (m *updateManager) generateUpdateCmd(log log.T,
 	return
 }
 
-//downloadManifest downloads manifest file from s3 bucket
+//downloadManifest downloads manifest file from s3 bucket or the configured mirror
 func (m *updateManager) downloadManifest(log log.T,
 	util updateutil.T,
 	pluginInput *UpdatePluginInput,
@@ -300,7 +317,19 @@ func (m *updateManager) downloadManifest(log log.T,
 		return nil, downloadErr
 	}
 	out.AppendInfof("Successfully downloaded %v\n", downloadInput.SourceURL)
-	return ParseManifest(log, downloadOutput.LocalFilePath, context, pluginInput.AgentName)
+
+	if pluginInput.ManifestKey != "" {
+		if err = verifyManifestSignature(log, pluginInput.Source, downloadOutput.LocalFilePath, updateDownload, pluginInput.ManifestKey); err != nil {
+			return nil, err
+		}
+		out.AppendInfof("Verified signature of %v\n", downloadInput.SourceURL)
+	}
+
+	if manifest, err = ParseManifest(log, downloadOutput.LocalFilePath, context, pluginInput.AgentName); err != nil {
+		return nil, err
+	}
+	manifest.URIFormat = resolveURIFormat(pluginInput.Source, manifest.URIFormat)
+	return manifest, nil
 }
 
 //downloadUpdater downloads updater from the s3 bucket
@@ -443,6 +472,15 @@ func Name() string {
 // GetUpdatePluginConfig returns the default values for the update plugin
 func GetUpdatePluginConfig(context context.T) UpdatePluginConfig {
 	log := context.Log()
+	if agentUpdate := context.AppConfig().AgentUpdate; agentUpdate.MirrorURL != "" {
+		log.Infof("Using agent update mirror %v", agentUpdate.MirrorURL)
+		return UpdatePluginConfig{
+			ManifestLocation:  MirrorManifestURL(agentUpdate.MirrorURL),
+			MirrorURL:         agentUpdate.MirrorURL,
+			ManifestPublicKey: agentUpdate.MirrorManifestPublicKey,
+		}
+	}
+
 	region, err := platform.Region()
 	if err != nil {
 		log.Errorf("Error retrieving agent region in update plugin config. error: %v\n", err)
@@ -0,0 +1,210 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pluginsdk
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+// Handler runs a step and returns its result
+type Handler func(step *Step) contracts.PluginResult
+
+// Step is a step of a document run by a plugin
+type Step struct {
+	// Configuration is the configuration of the step, its Properties hold the inputs of the step
+	Configuration contracts.Configuration
+	// Stdout is the standard output of the step
+	Stdout io.Writer
+	// Stderr is the standard error of the step
+	Stderr io.Writer
+	// Checkpoint is the last checkpoint of the step, it's the one of its previous run until the plugin sets a new one
+	Checkpoint string
+
+	cancelled chan struct{}
+	encoder   *messageEncoder
+}
+
+// Properties decodes the inputs of the step into properties
+func (s *Step) Properties(properties interface{}) error {
+	return jsonutil.Remarshal(s.Configuration.Properties, properties)
+}
+
+// Cancelled returns a channel closed when the agent cancels the step, or stops talking to the plugin
+func (s *Step) Cancelled() <-chan struct{} {
+	return s.cancelled
+}
+
+// IsCancelled returns true when the agent cancelled the step
+func (s *Step) IsCancelled() bool {
+	select {
+	case <-s.cancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetCheckpoint records the progress of the step, the step is handed the checkpoint when it runs again
+// after the document worker was restarted
+func (s *Step) SetCheckpoint(checkpoint string) error {
+	if err := s.encoder.send(Message{Type: MessageTypeCheckpoint, Checkpoint: checkpoint}); err != nil {
+		return err
+	}
+	s.Checkpoint = checkpoint
+	return nil
+}
+
+// Success returns the result of a successful step
+func Success() contracts.PluginResult {
+	return contracts.PluginResult{Status: contracts.ResultStatusSuccess}
+}
+
+// Failure returns the result of a failed step
+func Failure(code int, err error) contracts.PluginResult {
+	result := contracts.PluginResult{Status: contracts.ResultStatusFailed, Code: code}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Cancelled returns the result of a cancelled step
+func Cancelled() contracts.PluginResult {
+	return contracts.PluginResult{Status: contracts.ResultStatusCancelled, Code: 1}
+}
+
+// Run runs the step the agent sends on the standard input with handler, and exits
+func Run(handler Handler) {
+	if err := Serve(os.Stdin, os.Stdout, handler); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// Serve reads the step to run from in, runs it with handler and writes the output and the result of the step to out
+func Serve(in io.Reader, out io.Writer, handler Handler) (err error) {
+	encoder := &messageEncoder{encoder: json.NewEncoder(out)}
+	if err = encoder.send(Message{Type: MessageTypeHello, ProtocolVersion: ProtocolVersion}); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), MaxMessageSize)
+	var execute Message
+	if execute, err = readMessage(scanner); err != nil {
+		return err
+	}
+	if execute.Type != MessageTypeExecute || execute.Configuration == nil {
+		return fmt.Errorf("expected an %v message, received %v", MessageTypeExecute, execute.Type)
+	}
+	if !IsCompatibleVersion(execute.ProtocolVersion) {
+		result := Failure(1, fmt.Errorf("protocol version %v is not supported by the plugin, which supports version %v", execute.ProtocolVersion, ProtocolVersion))
+		return encoder.send(Message{Type: MessageTypeResult, Result: &result})
+	}
+
+	step := &Step{
+		Configuration: *execute.Configuration,
+		Stdout:        &streamWriter{encoder: encoder, stream: StreamStdout},
+		Stderr:        &streamWriter{encoder: encoder, stream: StreamStderr},
+		Checkpoint:    execute.Checkpoint,
+		cancelled:     make(chan struct{}),
+		encoder:       encoder,
+	}
+	go func() {
+		// the agent closes the standard input of the plugin if it goes away, which cancels the step too
+		defer close(step.cancelled)
+		for {
+			message, err := readMessage(scanner)
+			if err != nil || message.Type == MessageTypeCancel {
+				return
+			}
+		}
+	}()
+
+	result := runHandler(step, handler)
+	return encoder.send(Message{Type: MessageTypeResult, Result: &result})
+}
+
+// runHandler runs handler, turning a panic into a failed result
+func runHandler(step *Step, handler Handler) (result contracts.PluginResult) {
+	defer func() {
+		if msg := recover(); msg != nil {
+			result = Failure(1, fmt.Errorf("plugin crashed with message %v", msg))
+		}
+	}()
+	return handler(step)
+}
+
+// readMessage reads the next message
+func readMessage(scanner *bufio.Scanner) (message Message, err error) {
+	if !scanner.Scan() {
+		if err = scanner.Err(); err == nil {
+			err = io.EOF
+		}
+		return
+	}
+	err = json.Unmarshal(scanner.Bytes(), &message)
+	return
+}
+
+// messageEncoder writes messages from several goroutines
+type messageEncoder struct {
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+// send writes message on a line
+func (e *messageEncoder) send(message Message) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.encoder.Encode(message)
+}
+
+// streamWriter writes output messages of a stream
+type streamWriter struct {
+	encoder *messageEncoder
+	stream  string
+}
+
+// Write sends p in output messages
+func (w *streamWriter) Write(p []byte) (n int, err error) {
+	for n < len(p) {
+		size := len(p) - n
+		if size > maxOutputChunkSize {
+			// cut the chunk between two characters, so that it remains valid UTF-8
+			size = maxOutputChunkSize
+			for cut := size; cut > size-utf8.UTFMax; cut-- {
+				if utf8.RuneStart(p[n+cut]) {
+					size = cut
+					break
+				}
+			}
+		}
+		if err = w.encoder.send(Message{Type: MessageTypeOutput, Stream: w.stream, Data: string(p[n : n+size])}); err != nil {
+			return n, err
+		}
+		n += size
+	}
+	return n, nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pluginsdk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
+)
+
+type deployInput struct {
+	Service string
+}
+
+func TestServe(t *testing.T) {
+	in := executeMessage(t, ProtocolVersion, map[string]interface{}{"Service": "billing"})
+	var out bytes.Buffer
+
+	err := Serve(in, &out, func(step *Step) contracts.PluginResult {
+		var input deployInput
+		if err := step.Properties(&input); err != nil {
+			return Failure(1, err)
+		}
+		fmt.Fprintf(step.Stdout, "deploying %v\n", input.Service)
+		fmt.Fprint(step.Stderr, "slow rollout")
+		return Success()
+	})
+
+	assert.NoError(t, err)
+	messages := readMessages(t, &out)
+	assert.Equal(t, []Message{
+		{Type: MessageTypeHello, ProtocolVersion: ProtocolVersion},
+		{Type: MessageTypeOutput, Stream: StreamStdout, Data: "deploying billing\n"},
+		{Type: MessageTypeOutput, Stream: StreamStderr, Data: "slow rollout"},
+		{Type: MessageTypeResult, Result: &contracts.PluginResult{Status: contracts.ResultStatusSuccess}},
+	}, messages)
+}
+
+func TestServeSplitsLargeOutput(t *testing.T) {
+	in := executeMessage(t, ProtocolVersion, nil)
+	var out bytes.Buffer
+	output := strings.Repeat("℃", maxOutputChunkSize)
+
+	err := Serve(in, &out, func(step *Step) contracts.PluginResult {
+		io.WriteString(step.Stdout, output)
+		return Success()
+	})
+
+	assert.NoError(t, err)
+	received := ""
+	for _, message := range readMessages(t, &out) {
+		if message.Type == MessageTypeOutput {
+			assert.True(t, len(message.Data) <= maxOutputChunkSize)
+			received += message.Data
+		}
+	}
+	assert.Equal(t, output, received)
+}
+
+func TestServeRejectsIncompatibleVersion(t *testing.T) {
+	in := executeMessage(t, "2.0", nil)
+	var out bytes.Buffer
+
+	err := Serve(in, &out, func(step *Step) contracts.PluginResult {
+		t.Fatal("the step must not run")
+		return Success()
+	})
+
+	assert.NoError(t, err)
+	messages := readMessages(t, &out)
+	assert.Equal(t, contracts.ResultStatusFailed, messages[len(messages)-1].Result.Status)
+	assert.Contains(t, messages[len(messages)-1].Result.Error, "protocol version 2.0")
+}
+
+func TestServeRecoversFromPanic(t *testing.T) {
+	in := executeMessage(t, ProtocolVersion, nil)
+	var out bytes.Buffer
+
+	err := Serve(in, &out, func(step *Step) contracts.PluginResult {
+		panic("nil service")
+	})
+
+	assert.NoError(t, err)
+	messages := readMessages(t, &out)
+	assert.Equal(t, Failure(1, errors.New("plugin crashed with message nil service")), *messages[len(messages)-1].Result)
+}
+
+func TestServeCancel(t *testing.T) {
+	in, agent := io.Pipe()
+	var out bytes.Buffer
+	go func() {
+		encoder := json.NewEncoder(agent)
+		encoder.Encode(Message{Type: MessageTypeExecute, ProtocolVersion: ProtocolVersion, Configuration: &contracts.Configuration{}})
+		encoder.Encode(Message{Type: MessageTypeCancel})
+	}()
+
+	err := Serve(in, &out, func(step *Step) contracts.PluginResult {
+		<-step.Cancelled()
+		assert.True(t, step.IsCancelled())
+		return Cancelled()
+	})
+
+	assert.NoError(t, err)
+	messages := readMessages(t, &out)
+	assert.Equal(t, contracts.ResultStatusCancelled, messages[len(messages)-1].Result.Status)
+}
+
+func TestServeCheckpoint(t *testing.T) {
+	var in bytes.Buffer
+	assert.NoError(t, json.NewEncoder(&in).Encode(Message{
+		Type:            MessageTypeExecute,
+		ProtocolVersion: ProtocolVersion,
+		Configuration:   &contracts.Configuration{PluginID: "deploy"},
+		Checkpoint:      "host-2",
+	}))
+	var out bytes.Buffer
+
+	err := Serve(&in, &out, func(step *Step) contracts.PluginResult {
+		assert.Equal(t, "host-2", step.Checkpoint)
+		assert.NoError(t, step.SetCheckpoint("host-3"))
+		assert.Equal(t, "host-3", step.Checkpoint)
+		return Success()
+	})
+
+	assert.NoError(t, err)
+	messages := readMessages(t, &out)
+	assert.Equal(t, Message{Type: MessageTypeCheckpoint, Checkpoint: "host-3"}, messages[1])
+}
+
+func TestIsCompatibleVersion(t *testing.T) {
+	assert.True(t, IsCompatibleVersion("1.0"))
+	assert.True(t, IsCompatibleVersion("1.3"))
+	assert.False(t, IsCompatibleVersion("2.0"))
+	assert.False(t, IsCompatibleVersion(""))
+}
+
+func TestSignature(t *testing.T) {
+	directory, err := ioutil.TempDir("", "pluginsdk")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+	path := filepath.Join(directory, "deployService")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"), 0700))
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	otherKey, _, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	signature, err := SignFile(path, privateKey)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(path+SignatureExtension, []byte(signature+"\n"), 0600))
+
+	assert.NoError(t, VerifyFile(path, []ed25519.PublicKey{otherKey, publicKey}))
+	assert.Error(t, VerifyFile(path, []ed25519.PublicKey{otherKey}))
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\nrm -rf /\n"), 0700))
+	assert.Error(t, VerifyFile(path, []ed25519.PublicKey{publicKey}))
+}
+
+func executeMessage(t *testing.T, version string, properties interface{}) io.Reader {
+	var in bytes.Buffer
+	err := json.NewEncoder(&in).Encode(Message{
+		Type:            MessageTypeExecute,
+		ProtocolVersion: version,
+		Configuration:   &contracts.Configuration{PluginID: "deploy", Properties: properties},
+	})
+	assert.NoError(t, err)
+	return &in
+}
+
+func readMessages(t *testing.T, out io.Reader) (messages []Message) {
+	scanner := bufio.NewScanner(out)
+	scanner.Buffer(nil, MaxMessageSize)
+	for scanner.Scan() {
+		var message Message
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &message))
+		messages = append(messages, message)
+	}
+	return messages
+}
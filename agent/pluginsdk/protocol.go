@@ -0,0 +1,104 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package pluginsdk helps writing out-of-tree plugins, executables running the steps of documents
+// whose action has a custom prefix such as acme:deployService.
+//
+// The agent starts the executable of the plugin for each step and talks to it over a versioned protocol
+// of JSON messages, one per line:
+//
+//	plugin -> agent  {"type":"hello","protocolVersion":"1.1"}
+//	agent -> plugin  {"type":"execute","protocolVersion":"1.1","configuration":{...},"checkpoint":"..."}
+//	plugin -> agent  {"type":"output","stream":"stdout","data":"..."}   (any number of times)
+//	plugin -> agent  {"type":"checkpoint","checkpoint":"..."}           (any number of times, since 1.1)
+//	agent -> plugin  {"type":"cancel"}                                   (when the command is cancelled)
+//	plugin -> agent  {"type":"result","result":{...}}
+//
+// The checkpoint of the execute message is the last one the plugin recorded for the step before the document
+// worker running it was restarted, the plugin resumes the step from it.
+//
+// The standard input and output of the plugin carry the protocol, so plugins must write their output
+// through the writers of the Step. The standard error of the plugin is added to the standard error of the step.
+//
+// A plugin built with this package only needs a handler:
+//
+//	func main() {
+//		pluginsdk.Run(func(step *pluginsdk.Step) contracts.PluginResult {
+//			var input struct{ Service string }
+//			if err := step.Properties(&input); err != nil {
+//				return pluginsdk.Failure(1, err)
+//			}
+//			fmt.Fprintf(step.Stdout, "deploying %v\n", input.Service)
+//			return pluginsdk.Success()
+//		})
+//	}
+//
+// Its executable is installed as <ExternalPluginDirectory>/acme/deployService, owned by root and not writable
+// by anybody else, and optionally signed with SignFile when the agent is configured with ExternalPluginPublicKeys.
+package pluginsdk
+
+import (
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+const (
+	// ProtocolVersion is the version of the protocol, the agent and the plugins only talk if its major version matches
+	ProtocolVersion = "1.1"
+
+	// MessageTypeHello is the first message of the plugin, announcing its protocol version
+	MessageTypeHello = "hello"
+	// MessageTypeExecute is the first message of the agent, holding the configuration of the step
+	MessageTypeExecute = "execute"
+	// MessageTypeCancel asks the plugin to stop the step
+	MessageTypeCancel = "cancel"
+	// MessageTypeOutput holds a chunk of the output of the step
+	MessageTypeOutput = "output"
+	// MessageTypeCheckpoint records the progress of the step, the step resumes from it if it runs again
+	MessageTypeCheckpoint = "checkpoint"
+	// MessageTypeResult is the last message of the plugin, holding the result of the step
+	MessageTypeResult = "result"
+
+	// StreamStdout is the stream of output messages written to the standard output of the step
+	StreamStdout = "stdout"
+	// StreamStderr is the stream of output messages written to the standard error of the step
+	StreamStderr = "stderr"
+
+	// MaxMessageSize is the maximum size of a message line
+	MaxMessageSize = 1024 * 1024
+
+	// maxOutputChunkSize is the maximum size of the data of an output message
+	maxOutputChunkSize = 64 * 1024
+)
+
+// Message is a message of the protocol
+type Message struct {
+	Type            string                   `json:"type"`
+	ProtocolVersion string                   `json:"protocolVersion,omitempty"`
+	Configuration   *contracts.Configuration `json:"configuration,omitempty"`
+	Stream          string                   `json:"stream,omitempty"`
+	Data            string                   `json:"data,omitempty"`
+	Result          *contracts.PluginResult  `json:"result,omitempty"`
+	Checkpoint      string                   `json:"checkpoint,omitempty"`
+}
+
+// IsCompatibleVersion returns true when version has the same major version as ProtocolVersion
+func IsCompatibleVersion(version string) bool {
+	return majorVersion(version) == majorVersion(ProtocolVersion)
+}
+
+// majorVersion returns the part of version before the first dot
+func majorVersion(version string) string {
+	return strings.SplitN(version, ".", 2)[0]
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pluginsdk
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+// SignatureExtension is the extension of the file holding the signature of the executable of a plugin
+const SignatureExtension = ".sig"
+
+// Digest returns the sha256 digest of the file at path
+func Digest(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// SignFile returns the base64 encoded signature of the digest of the file at path,
+// to be written to the file at path + SignatureExtension
+func SignFile(path string, privateKey ed25519.PrivateKey) (string, error) {
+	digest, err := Digest(path)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, digest)), nil
+}
+
+// VerifyFile checks that the file at path + SignatureExtension holds a signature of the file at path by one of publicKeys
+func VerifyFile(path string, publicKeys []ed25519.PublicKey) error {
+	encoded, err := ioutil.ReadFile(path + SignatureExtension)
+	if err != nil {
+		return fmt.Errorf("failed to read the signature of %v: %v", path, err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return fmt.Errorf("invalid signature of %v: %v", path, err)
+	}
+	digest, err := Digest(path)
+	if err != nil {
+		return err
+	}
+	for _, publicKey := range publicKeys {
+		if ed25519.Verify(publicKey, digest, signature) {
+			return nil
+		}
+	}
+	return errors.New("the signature of " + path + " does not match any trusted key")
+}
+
+// ParsePublicKey decodes a base64 encoded ed25519 public key
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("an ed25519 public key has %v bytes, not %v", ed25519.PublicKeySize, len(key))
+	}
+	return ed25519.PublicKey(key), nil
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
 	mdsService "github.com/aws/amazon-ssm-agent/agent/runcommand/mds"
@@ -89,6 +90,50 @@ func (s *RunCommandService) ModuleExecute(context context.T) (err error) {
 	return
 }
 
+// ModuleHealth returns the health of the message polling and of the association processor
+func (s *RunCommandService) ModuleHealth() contracts.ModuleHealth {
+	health := s.health.Health()
+	if s.assocProcessor != nil {
+		health.Components = append(health.Components, s.assocProcessor.ModuleHealth())
+	}
+	return health
+}
+
+// ModuleDrain stops polling for messages, the documents in progress run to completion and their replies are still sent
+func (s *RunCommandService) ModuleDrain() {
+	s.drainLock.Lock()
+	defer s.drainLock.Unlock()
+	if s.draining {
+		return
+	}
+	s.draining = true
+
+	s.context.Log().Info("Draining, no longer polling for messages")
+	if s.messagePollJob != nil {
+		s.messagePollJob.Quit <- true
+	}
+	s.processor.Drain()
+
+	if s.assocProcessor != nil {
+		s.assocProcessor.ModuleDrain()
+	}
+}
+
+// ModuleInFlight returns the number of documents in progress, associations included
+func (s *RunCommandService) ModuleInFlight() int {
+	inFlight := s.processor.InFlight()
+	if s.assocProcessor != nil {
+		inFlight += s.assocProcessor.ModuleInFlight()
+	}
+	return inFlight
+}
+
+func (s *RunCommandService) isDraining() bool {
+	s.drainLock.RLock()
+	defer s.drainLock.RUnlock()
+	return s.draining
+}
+
 func (s *RunCommandService) ModuleRequestStop(stopType contracts.StopType) (err error) {
 	//first stop sending failed replies to the service and the message poller
 	s.stop()
@@ -122,7 +167,11 @@ func (s *RunCommandService) listenReply(resultChan chan contracts.DocumentResult
 				s.context.AppConfig().Ssm.RunCommandLogsRetentionDurationHours,
 				s.context.AppConfig().Ssm.AssociationLogsRetentionDurationHours)
 		}
+		span := s.context.Tracer().Start("sendReply", res.TraceParent)
+		span.SetAttribute("MessageId", res.MessageID)
+		span.SetAttribute("PluginId", res.LastPlugin)
 		s.sendResponse(res.MessageID, res)
+		span.End()
 	}
 }
 
@@ -153,18 +202,30 @@ func (s *RunCommandService) processMessage(msg *ssmmds.Message) {
 	)
 
 	// create separate logger that includes messageID with every log message
-	context := s.context.With("[messageID=" + *msg.MessageId + "]")
+	context := s.context.With("[messageID=" + *msg.MessageId + "]").WithFields(log.Fields{log.FieldMessageID: *msg.MessageId})
 	log := context.Log()
 	log.Debug("Processing message")
 
+	// the trace of a document starts when its message is received
+	span := context.Tracer().Start("processMessage", "")
+	span.SetAttribute("MessageId", *msg.MessageId)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
 	if err = validate(msg); err != nil {
 		log.Error("message not valid, ignoring: ", err)
 		return
 	}
+	span.SetAttribute("Topic", *msg.Topic)
 
+	parseSpan := context.Tracer().Start("parseMessage", span.TraceParent())
 	if strings.HasPrefix(*msg.Topic, string(SendCommandTopicPrefix)) {
 		docState, err = loadDocStateFromSendCommand(context, msg, s.orchestrationRootDir)
 		if err != nil {
+			parseSpan.RecordError(err)
+			parseSpan.End()
 			log.Error(err)
 			s.sendDocLevelResponse(*msg.MessageId, contracts.ResultStatusFailed, err.Error())
 			return
@@ -174,6 +235,8 @@ func (s *RunCommandService) processMessage(msg *ssmmds.Message) {
 	} else {
 		err = fmt.Errorf("unexpected topic name %v", *msg.Topic)
 	}
+	parseSpan.RecordError(err)
+	parseSpan.End()
 
 	if err != nil {
 		log.Error("format of received message is invalid ", err)
@@ -192,9 +255,14 @@ func (s *RunCommandService) processMessage(msg *ssmmds.Message) {
 	log.Debugf("Processing to send a reply to update the document status to InProgress")
 
 	//TODO This function should be called in service when it submits the document to the engine
+	replySpan := context.Tracer().Start("sendReply", span.TraceParent())
 	s.sendDocLevelResponse(*msg.MessageId, contracts.ResultStatusInProgress, "")
+	replySpan.End()
 
 	log.Debugf("SendReply done. Received message - messageId - %v", *msg.MessageId)
+	if traceParent := span.TraceParent(); traceParent != "" {
+		docState.DocumentInformation.TraceParent = traceParent
+	}
 	switch docState.DocumentType {
 	case contracts.SendCommand, contracts.SendCommandOffline:
 		s.processor.Submit(*docState)
@@ -214,6 +282,10 @@ func (s *RunCommandService) sendFailedReplies() {
 	log.Debug("Checking if there are document replies that failed to reach the service, and retry sending them")
 	replies := s.service.LoadFailedReplies(log)
 
+	// replies still waiting to reach the service are the backlog of the module
+	backlog := len(replies)
+	defer func() { s.health.SetBacklog(backlog) }()
+
 	if len(replies) != 0 {
 		log.Infof("Found document replies that need to be sent to the service")
 		for _, reply := range replies {
@@ -221,6 +293,7 @@ func (s *RunCommandService) sendFailedReplies() {
 			if isValidReplyRequest(reply) == false {
 				log.Debug("Reply is old, document execution must have timed out. Deleting the reply")
 				s.service.DeleteFailedReply(log, reply)
+				backlog--
 				continue
 			}
 			sendReplyRequest, err := s.service.GetFailedReply(log, reply)
@@ -232,10 +305,12 @@ func (s *RunCommandService) sendFailedReplies() {
 			log.Info("Sending reply ", reply)
 			if err = s.service.SendReplyWithInput(log, sendReplyRequest); err != nil {
 				sdkutil.HandleAwsError(log, err, s.processorStopPolicy)
+				s.health.RecordError(err)
 				break
 			} else {
 				log.Infof("Sending reply %v succeeded, deleting the reply file from disk", reply)
 				s.service.DeleteFailedReply(log, reply)
+				backlog--
 			}
 		}
 	} else {
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/carlescere/scheduler"
 )
@@ -30,6 +31,8 @@ var lock sync.RWMutex
 
 var processMessage = (*RunCommandService).processMessage
 
+const stopPolicyErrorsHelp = "Errors counted by the stop policy since its last reset."
+
 func updateLastPollTime(processorType string, currentTime time.Time) {
 	lock.Lock()
 	defer lock.Unlock()
@@ -72,6 +75,9 @@ func (s *RunCommandService) messagePollLoop() {
 	if s.name == mdsName {
 		log.Debugf("%v's stoppolicy after polling is %v", s.name, s.processorStopPolicy)
 	}
+	if s.isDraining() {
+		return
+	}
 
 	// Slow down a bit in case GetMessages returns
 	// without blocking, which may cause us to
@@ -96,6 +102,7 @@ func (s *RunCommandService) checkStopPolicy(log log.T) error {
 		if s.processorStopPolicy.IsHealthy() == false {
 			err := fmt.Errorf("%v stopped temporarily due to internal failure. We will retry automatically after %v minutes", s.name, pollMessageFrequencyMinutes)
 			log.Errorf("%v", err)
+			s.health.RecordError(err)
 			s.reset()
 			return err
 		}
@@ -115,6 +122,7 @@ func (s *RunCommandService) reset() {
 	log.Debugf("Resetting processor:%v", s.name)
 	// reset stop policy and let the scheduler start the polling after pollMessageFrequencyMinutes timeout
 	s.processorStopPolicy.ResetErrorCount()
+	s.context.Metrics().Gauge(metrics.StopPolicyErrors, stopPolicyErrorsHelp, metrics.Labels{"service": s.name}).Set(0)
 
 	// creating a new mds service object for the retry
 	// this is extra insurance to avoid service object getting corrupted - adding resiliency
@@ -130,7 +138,8 @@ func (s *RunCommandService) stop() {
 	log.Debugf("Stopping processor:%v", s.name)
 	s.service.Stop()
 
-	if s.messagePollJob != nil {
+	// the poller is already stopped when draining
+	if s.messagePollJob != nil && !s.isDraining() {
 		s.messagePollJob.Quit <- true
 	}
 	if s.sendReplyJob != nil {
@@ -144,16 +153,32 @@ func (s *RunCommandService) pollOnce() {
 	if s.name == mdsName {
 		log.Debugf("Polling for messages")
 	}
+	registry := s.context.Metrics()
+	labels := metrics.Labels{"service": s.name}
+	pollStart := time.Now()
 	messages, err := s.service.GetMessages(log, s.config.InstanceID)
+	registry.Histogram(metrics.MessagePollDuration, "Latency of polling the message service.", metrics.DefaultDurationBuckets, labels).ObserveSince(pollStart)
 	if err != nil {
 		sdkutil.HandleAwsError(log, err, s.processorStopPolicy)
+		s.health.RecordError(err)
+		if s.processorStopPolicy != nil {
+			registry.Gauge(metrics.StopPolicyErrors, stopPolicyErrorsHelp, labels).Set(float64(s.processorStopPolicy.ErrorCount()))
+		}
 		return
 	}
+	s.health.RecordSuccess()
 	if len(messages.Messages) > 0 {
 		log.Debugf("Got %v messages", len(messages.Messages))
 	}
-
-	for _, msg := range messages.Messages {
+	registry.Counter(metrics.MessagesReceived, "Messages received from the message service.", labels).Add(float64(len(messages.Messages)))
+
+	for i, msg := range messages.Messages {
+		// messages received while draining are not acknowledged, so the service delivers them again later.
+		// Draining may start while the messages are processed, so it is checked before each acknowledgement.
+		if s.isDraining() {
+			log.Infof("Draining, leaving %v messages to be delivered again", len(messages.Messages)-i)
+			return
+		}
 		processMessage(s, msg)
 	}
 	if s.name == mdsName {
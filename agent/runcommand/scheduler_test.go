@@ -57,6 +57,7 @@ func prepareTestPollOnce() (svc RunCommandService, testCase TestCasePollOnce) {
 		context: contextMock,
 		config:  agentConfig,
 		service: mdsMock,
+		health:  contracts.NewHealthTracker(mdsName),
 	}
 
 	testCase = TestCasePollOnce{
@@ -95,6 +96,49 @@ func TestPollOnce(t *testing.T) {
 	assert.Equal(t, countMessageProcessed, 1)
 }
 
+// TestPollOnceWhileDraining tests the messages received while draining are left to be delivered again
+func TestPollOnceWhileDraining(t *testing.T) {
+	proc, tc := prepareTestPollOnce()
+	proc.draining = true
+
+	getMessageOutput := ssmmds.GetMessagesOutput{
+		Destination:       &testDestination,
+		Messages:          make([]*ssmmds.Message, 1),
+		MessagesRequestId: &testMessageId,
+	}
+	tc.MdsMock.On("GetMessages", mock.AnythingOfType("*log.Mock"), mock.AnythingOfType("string")).Return(&getMessageOutput, nil)
+	countMessageProcessed := 0
+	processMessage = func(svc *RunCommandService, msg *ssmmds.Message) {
+		countMessageProcessed++
+	}
+
+	proc.pollOnce()
+
+	tc.MdsMock.AssertExpectations(t)
+	assert.Equal(t, 0, countMessageProcessed)
+}
+
+// TestPollOnceDrainingStartsDuringProcessing tests the messages left when draining starts are not acknowledged
+func TestPollOnceDrainingStartsDuringProcessing(t *testing.T) {
+	proc, tc := prepareTestPollOnce()
+
+	getMessageOutput := ssmmds.GetMessagesOutput{
+		Destination:       &testDestination,
+		Messages:          make([]*ssmmds.Message, 3),
+		MessagesRequestId: &testMessageId,
+	}
+	tc.MdsMock.On("GetMessages", mock.AnythingOfType("*log.Mock"), mock.AnythingOfType("string")).Return(&getMessageOutput, nil)
+	countMessageProcessed := 0
+	processMessage = func(svc *RunCommandService, msg *ssmmds.Message) {
+		countMessageProcessed++
+		svc.draining = true
+	}
+
+	proc.pollOnce()
+
+	assert.Equal(t, 1, countMessageProcessed)
+}
+
 // TestPollOnceWithZeroMessage tests the pollOnce function with zero message
 func TestPollOnceWithZeroMessage(t *testing.T) {
 	// prepare test case fields
@@ -174,4 +218,6 @@ func TestPollOnceWithGetMessagesReturnError(t *testing.T) {
 	// check expectations
 	tc.MdsMock.AssertExpectations(t)
 	assert.False(t, isMessageProcessed)
+	assert.True(t, proc.ModuleHealth().IsFailing())
+	assert.Equal(t, "Test", proc.ModuleHealth().LastError)
 }
@@ -17,9 +17,11 @@ package runcommand
 import (
 	"encoding/json"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig/configwatcher"
 	associationProcessor "github.com/aws/amazon-ssm-agent/agent/association/processor"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
@@ -97,6 +99,9 @@ type RunCommandService struct {
 	processorStopPolicy *sdkutil.StopPolicy
 	pollAssociations    bool
 	processor           processor.Processor
+	health              *contracts.HealthTracker
+	drainLock           sync.RWMutex
+	draining            bool
 }
 
 // NewOfflineProcessor initialize a new offline command document processor
@@ -119,7 +124,13 @@ func NewMDSService(context context.T) *RunCommandService {
 	mdsService := newMdsService(context.AppConfig())
 	config := context.AppConfig()
 
-	return NewService(messageContext, mdsName, mdsService, config.Mds.CommandWorkersLimit, CancelWorkersLimit, true, []contracts.DocumentType{contracts.SendCommand, contracts.CancelCommand})
+	service := NewService(messageContext, mdsName, mdsService, config.Mds.CommandWorkersLimit, CancelWorkersLimit, true, []contracts.DocumentType{contracts.SendCommand, contracts.CancelCommand})
+	if service != nil {
+		context.ConfigWatcher().Subscribe(mdsName, []string{"Mds.CommandWorkersLimit"}, func(change configwatcher.Change) {
+			service.processor.SetWorkersLimit(change.Current.Mds.CommandWorkersLimit)
+		})
+	}
+	return service
 }
 
 // NewProcessor performs common initialization for Mds and Offline processors
@@ -182,6 +193,7 @@ func NewService(ctx context.T, serviceName string, service mdsService.Service, c
 		assocProcessor:       assocProc,
 		pollAssociations:     pollAssoc,
 		processor:            processor,
+		health:               contracts.NewHealthTracker(serviceName),
 	}
 }
 
@@ -35,6 +35,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/times"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ssmmds"
+	"github.com/carlescere/scheduler"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -62,6 +63,27 @@ type TestCaseProcessMessage struct {
 	IsDocLevelResponseSent *bool
 }
 
+// TestModuleDrain tests draining stops the message poller and the processor
+func TestModuleDrain(t *testing.T) {
+	processorMock := new(processormock.MockedProcessor)
+	processorMock.On("Drain").Return()
+	processorMock.On("InFlight").Return(2)
+	pollJob := &scheduler.Job{Quit: make(chan bool, 1)}
+	svc := RunCommandService{
+		context:        context.NewMockDefault(),
+		processor:      processorMock,
+		messagePollJob: pollJob,
+	}
+
+	svc.ModuleDrain()
+	svc.ModuleDrain()
+
+	assert.True(t, svc.isDraining())
+	assert.True(t, <-pollJob.Quit)
+	assert.Equal(t, 2, svc.ModuleInFlight())
+	processorMock.AssertNumberOfCalls(t, "Drain", 1)
+}
+
 // TestProcessMessageWithSendCommandTopicPrefix tests processMessage with SendCommand topic prefix
 func TestProcessMessageWithSendCommandTopicPrefix(t *testing.T) {
 	// SendCommand topic prefix
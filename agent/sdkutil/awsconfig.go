@@ -18,14 +18,10 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
-	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
-	"github.com/aws/amazon-ssm-agent/agent/managedInstances/rolecreds"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/defaults"
 )
 
 // AwsConfig returns the default aws.Config object while the appropriate
@@ -44,39 +40,14 @@ func AwsConfig() (awsConfig *aws.Config) {
 		awsConfig.Region = &region
 	}
 
-	//load Task IAM credentials if applicable
+	// the credentials come from the providers of the profile, by default the managed instance
+	// credentials if the instance is registered, else the ec2/ecs credentials
 	config, _ := appconfig.Config(false)
-	if config.Agent.ContainerMode {
-		cfg := defaults.Config()
-		handlers := defaults.Handlers()
-		awsConfig.Credentials = defaults.CredChain(cfg, handlers)
-	}
-
-	// load managed credentials if applicable
-	if isManaged, err := registration.HasManagedInstancesCredentials(); isManaged && err == nil {
-		awsConfig.Credentials =
-			rolecreds.ManagedInstanceCredentialsInstance()
-		return
-	}
-
-	// default credentials will be ec2/ecs credentials
-	awsConfig.Credentials = defaultRemoteCredentials()
+	awsConfig.Credentials = agentCredentials(config.Profile, region)
 
 	return
 }
 
-// This will return the same remote credential provider as the SDK
-// We are creating this explicitly and passing it to the SDK
-// because we do not care for the shared credentials / ENV credentials in the
-// default SDK credential chain.
-func defaultRemoteCredentials() *credentials.Credentials {
-	cfg := defaults.Config()
-	handlers := defaults.Handlers()
-	remotecreds := defaults.RemoteCredProvider(*cfg, handlers)
-
-	return credentials.NewCredentials(remotecreds)
-}
-
 var newRetryer = func() aws.RequestRetryer {
 	r := retryer.SsmRetryer{}
 	r.NumMaxRetries = 3
@@ -0,0 +1,247 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sdkutil
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
+	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
+	"github.com/aws/amazon-ssm-agent/agent/managedInstances/rolecreds"
+	"github.com/aws/amazon-ssm-agent/agent/managedInstances/x509creds"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/processcreds"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/defaults"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// Credential providers which can be listed in the Profile.Providers setting
+const (
+	// CredentialProviderManagedInstance uses the credentials of the registration of the managed instance
+	CredentialProviderManagedInstance = "ManagedInstance"
+	// CredentialProviderX509 exchanges a client certificate for role credentials with IAM Roles Anywhere
+	CredentialProviderX509 = "X509"
+	// CredentialProviderProcess runs a helper command which prints the credentials
+	CredentialProviderProcess = "CredentialProcess"
+	// CredentialProviderWebIdentity exchanges an OIDC token file for role credentials with STS
+	CredentialProviderWebIdentity = "WebIdentity"
+	// CredentialProviderInstanceProfile uses the EC2 instance profile, or the ECS task role in a container
+	CredentialProviderInstanceProfile = "InstanceProfile"
+
+	webIdentitySessionName = "amazon-ssm-agent"
+)
+
+var credentialProviderNames = []string{
+	CredentialProviderManagedInstance,
+	CredentialProviderX509,
+	CredentialProviderProcess,
+	CredentialProviderWebIdentity,
+	CredentialProviderInstanceProfile,
+}
+
+// namedProvider is a provider of the chain with the name it is configured with
+type namedProvider struct {
+	name     string
+	provider credentials.Provider
+}
+
+// credentialChain tries its providers in order and uses the first one which returns credentials.
+// Unlike the chain of the SDK it logs the provider the credentials come from whenever it changes.
+type credentialChain struct {
+	log       log.T
+	providers []namedProvider
+	current   *namedProvider
+}
+
+// chainKey identifies the settings a chain was built from
+type chainKey struct {
+	profile   appconfig.CredentialProfile
+	providers []string
+	region    string
+}
+
+var (
+	chainLock        sync.Mutex
+	cachedChainKey   *chainKey
+	cachedChainCreds *credentials.Credentials
+)
+
+// agentCredentials returns the credentials of the providers of the profile, the chain is shared by the
+// service clients until the profile changes so that each refresh of the credentials is made once
+func agentCredentials(profile appconfig.CredentialProfile, region string) *credentials.Credentials {
+	key := chainKey{profile: profile, providers: resolveCredentialProviders(profile), region: region}
+
+	chainLock.Lock()
+	defer chainLock.Unlock()
+	if cachedChainKey != nil && reflect.DeepEqual(*cachedChainKey, key) {
+		return cachedChainCreds
+	}
+	logger := ssmlog.SSMLogger(true)
+	cachedChainKey = &key
+	cachedChainCreds = credentials.NewCredentials(newCredentialChain(logger, key.providers, profile, region))
+	return cachedChainCreds
+}
+
+// resolveCredentialProviders returns the configured providers, or when none is configured the managed instance
+// credentials if the instance is registered, else the instance profile
+func resolveCredentialProviders(profile appconfig.CredentialProfile) []string {
+	if len(profile.Providers) > 0 {
+		return profile.Providers
+	}
+	if isManaged, err := registration.HasManagedInstancesCredentials(); isManaged && err == nil {
+		return []string{CredentialProviderManagedInstance}
+	}
+	return []string{CredentialProviderInstanceProfile}
+}
+
+// newCredentialChain creates the providers with the given names, the ones which are unknown or missing
+// settings are left out of the chain
+func newCredentialChain(log log.T, names []string, profile appconfig.CredentialProfile, region string) *credentialChain {
+	chain := &credentialChain{log: log}
+	for _, name := range names {
+		canonicalName, known := canonicalProviderName(name)
+		if !known {
+			log.Warnf("Ignoring unknown credential provider %v, the supported providers are %v", name, strings.Join(credentialProviderNames, ", "))
+			continue
+		}
+		provider, err := newCredentialProvider(canonicalName, profile, region)
+		if err != nil {
+			log.Warnf("Ignoring the %v credential provider, %v", canonicalName, err)
+			continue
+		}
+		chain.providers = append(chain.providers, namedProvider{name: canonicalName, provider: provider})
+	}
+	log.Infof("Credential providers in order of precedence: %v", chain.names())
+	return chain
+}
+
+func canonicalProviderName(name string) (string, bool) {
+	for _, known := range credentialProviderNames {
+		if strings.EqualFold(strings.TrimSpace(name), known) {
+			return known, true
+		}
+	}
+	return name, false
+}
+
+func newCredentialProvider(name string, profile appconfig.CredentialProfile, region string) (credentials.Provider, error) {
+	switch name {
+	case CredentialProviderManagedInstance:
+		return &managedInstanceProvider{}, nil
+	case CredentialProviderX509:
+		return x509creds.NewProvider(x509creds.Config{
+			CertificateFile: profile.X509CertificateFile,
+			PrivateKeyFile:  profile.X509PrivateKeyFile,
+			TrustAnchorArn:  profile.X509TrustAnchorArn,
+			ProfileArn:      profile.X509ProfileArn,
+			RoleArn:         profile.X509RoleArn,
+			Endpoint:        profile.X509Endpoint,
+			DurationSeconds: profile.SessionDurationSeconds,
+		})
+	case CredentialProviderProcess:
+		if strings.TrimSpace(profile.CredentialProcess) == "" {
+			return nil, errors.New("CredentialProcess is not set")
+		}
+		return &credentialsProvider{processcreds.NewCredentials(profile.CredentialProcess)}, nil
+	case CredentialProviderWebIdentity:
+		if profile.WebIdentityTokenFile == "" || profile.WebIdentityRoleArn == "" {
+			return nil, errors.New("WebIdentityTokenFile and WebIdentityRoleArn are required")
+		}
+		client := sts.New(session.New(), &aws.Config{Region: aws.String(region)})
+		return stscreds.NewWebIdentityRoleProvider(client, profile.WebIdentityRoleArn, webIdentitySessionName, profile.WebIdentityTokenFile), nil
+	case CredentialProviderInstanceProfile:
+		return defaults.RemoteCredProvider(*defaults.Config(), defaults.Handlers()), nil
+	}
+	return nil, fmt.Errorf("unknown provider %v", name)
+}
+
+// Retrieve returns the credentials of the first provider which returns some
+func (c *credentialChain) Retrieve() (credentials.Value, error) {
+	var errs []error
+	for i := range c.providers {
+		provider := &c.providers[i]
+		value, err := provider.provider.Retrieve()
+		if err == nil {
+			if c.current != provider {
+				c.log.Infof("Using the credentials of the %v provider", provider.name)
+			}
+			c.current = provider
+			return value, nil
+		}
+		c.log.Warnf("The %v credential provider failed to provide credentials, %v", provider.name, err)
+		errs = append(errs, err)
+	}
+	c.current = nil
+	// same error code as the SDK chain, the agent looks for it to tell missing credentials apart
+	return credentials.Value{}, awserr.NewBatchError("NoCredentialProviders",
+		fmt.Sprintf("no valid providers in chain %v", c.names()), errs)
+}
+
+// IsExpired returns if the credentials of the provider in use are expired
+func (c *credentialChain) IsExpired() bool {
+	if c.current == nil {
+		return true
+	}
+	return c.current.provider.IsExpired()
+}
+
+func (c *credentialChain) names() []string {
+	names := make([]string, 0, len(c.providers))
+	for _, provider := range c.providers {
+		names = append(names, provider.name)
+	}
+	return names
+}
+
+// credentialsProvider adapts credentials of the SDK to a provider of the chain
+type credentialsProvider struct {
+	creds *credentials.Credentials
+}
+
+func (p *credentialsProvider) Retrieve() (credentials.Value, error) {
+	return p.creds.Get()
+}
+
+func (p *credentialsProvider) IsExpired() bool {
+	return p.creds.IsExpired()
+}
+
+// managedInstanceProvider uses the credentials of the registration, the instance may be registered after
+// the chain is created so the registration is checked on every refresh
+type managedInstanceProvider struct {
+	creds *credentials.Credentials
+}
+
+func (p *managedInstanceProvider) Retrieve() (credentials.Value, error) {
+	if isManaged, err := registration.HasManagedInstancesCredentials(); !isManaged || err != nil {
+		return credentials.Value{}, fmt.Errorf("the instance is not registered as a managed instance")
+	}
+	p.creds = rolecreds.ManagedInstanceCredentialsInstance()
+	return p.creds.Get()
+}
+
+func (p *managedInstanceProvider) IsExpired() bool {
+	return p.creds == nil || p.creds.IsExpired()
+}
@@ -0,0 +1,126 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sdkutil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProvider returns the given credentials or error
+type fakeProvider struct {
+	value     credentials.Value
+	err       error
+	expired   bool
+	retrieved int
+}
+
+func (p *fakeProvider) Retrieve() (credentials.Value, error) {
+	p.retrieved++
+	return p.value, p.err
+}
+
+func (p *fakeProvider) IsExpired() bool {
+	return p.expired
+}
+
+func TestCredentialChainPrecedence(t *testing.T) {
+	first := &fakeProvider{err: errors.New("no certificate")}
+	second := &fakeProvider{value: credentials.Value{AccessKeyID: "second"}}
+	third := &fakeProvider{value: credentials.Value{AccessKeyID: "third"}}
+	chain := &credentialChain{log: log.NewMockLog(), providers: []namedProvider{
+		{CredentialProviderX509, first},
+		{CredentialProviderProcess, second},
+		{CredentialProviderInstanceProfile, third},
+	}}
+	assert.True(t, chain.IsExpired())
+
+	value, err := chain.Retrieve()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "second", value.AccessKeyID)
+	assert.Equal(t, 1, first.retrieved)
+	assert.Equal(t, 0, third.retrieved)
+	assert.Equal(t, CredentialProviderProcess, chain.current.name)
+
+	assert.False(t, chain.IsExpired())
+	second.expired = true
+	assert.True(t, chain.IsExpired())
+}
+
+func TestCredentialChainLogsProviderChange(t *testing.T) {
+	logger := log.NewMockLog()
+	first := &fakeProvider{value: credentials.Value{AccessKeyID: "first"}}
+	second := &fakeProvider{value: credentials.Value{AccessKeyID: "second"}}
+	chain := &credentialChain{log: logger, providers: []namedProvider{
+		{CredentialProviderWebIdentity, first},
+		{CredentialProviderInstanceProfile, second},
+	}}
+
+	chain.Retrieve()
+	chain.Retrieve()
+	logger.AssertNumberOfCalls(t, "Infof", 1)
+
+	first.err = errors.New("token expired")
+	value, _ := chain.Retrieve()
+	assert.Equal(t, "second", value.AccessKeyID)
+	logger.AssertNumberOfCalls(t, "Infof", 2)
+}
+
+func TestCredentialChainNoProviders(t *testing.T) {
+	chain := &credentialChain{log: log.NewMockLog(), providers: []namedProvider{
+		{CredentialProviderX509, &fakeProvider{err: errors.New("access denied")}},
+	}}
+
+	_, err := chain.Retrieve()
+
+	assert.Error(t, err)
+	assert.Equal(t, "NoCredentialProviders", err.(awserr.Error).Code())
+	assert.True(t, chain.IsExpired())
+}
+
+func TestNewCredentialChain(t *testing.T) {
+	profile := appconfig.DefaultConfig().Profile
+	profile.CredentialProcess = "/usr/local/bin/credential-helper"
+
+	chain := newCredentialChain(log.NewMockLog(), []string{"credentialprocess", "Kerberos", "WebIdentity", "X509", "InstanceProfile"}, profile, "us-east-1")
+
+	// the unknown provider and the ones missing settings are left out
+	assert.Equal(t, []string{CredentialProviderProcess, CredentialProviderInstanceProfile}, chain.names())
+}
+
+func TestResolveCredentialProviders(t *testing.T) {
+	profile := appconfig.DefaultConfig().Profile
+	profile.Providers = []string{CredentialProviderX509, CredentialProviderManagedInstance}
+
+	assert.Equal(t, profile.Providers, resolveCredentialProviders(profile))
+}
+
+func TestAgentCredentialsSharedUntilProfileChanges(t *testing.T) {
+	profile := appconfig.DefaultConfig().Profile
+	profile.Providers = []string{CredentialProviderInstanceProfile}
+
+	creds := agentCredentials(profile, "us-east-1")
+	assert.True(t, creds == agentCredentials(profile, "us-east-1"))
+
+	profile.Providers = []string{CredentialProviderProcess, CredentialProviderInstanceProfile}
+	profile.CredentialProcess = "/usr/local/bin/credential-helper"
+	assert.False(t, creds == agentCredentials(profile, "us-east-1"))
+}
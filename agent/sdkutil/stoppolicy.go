@@ -68,6 +68,13 @@ func (s *StopPolicy) AddErrorCount(x int) {
 	s.errorCount += x
 }
 
+// ErrorCount returns the number of errors counted since the last reset
+func (s *StopPolicy) ErrorCount() int {
+	s.SyncObject.Lock()
+	defer s.SyncObject.Unlock()
+	return s.errorCount
+}
+
 // ResetErrorCount resets the error count, typically on successful operation
 func (s *StopPolicy) ResetErrorCount() {
 	s.SyncObject.Lock()
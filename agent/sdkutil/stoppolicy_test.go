@@ -93,4 +93,8 @@ func TestStopPolicyUsage(t *testing.T) {
 
 	s.AddErrorCount(-1)
 	assert.Equal(t, true, s.IsHealthy())
+	assert.Equal(t, 9, s.ErrorCount())
+
+	s.ResetErrorCount()
+	assert.Equal(t, 0, s.ErrorCount())
 }
@@ -33,6 +33,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/session/retry"
 	"github.com/aws/amazon-ssm-agent/agent/session/service"
 	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/gorilla/websocket"
@@ -57,6 +58,14 @@ type ControlChannel struct {
 	channelType string
 }
 
+// channelHealth records the outcome of opening the control channel and of its connection
+var channelHealth = contracts.NewHealthTracker("ControlChannel")
+
+// Health returns the health of the control channel
+func Health() contracts.ModuleHealth {
+	return channelHealth.Health()
+}
+
 // Initialize populates controlchannel object and opens controlchannel to communicate with mgs.
 func (controlChannel *ControlChannel) Initialize(context context.T,
 	mgsService service.Service,
@@ -101,6 +110,7 @@ func (controlChannel *ControlChannel) SetWebSocket(context context.T,
 		controlChannelIncomingMessageHandler(context, processor, input, orchestrationRootDir, instanceId)
 	}
 	onErrorHandler := func(err error) {
+		channelHealth.RecordError(err)
 		callable := func() (channel interface{}, err error) {
 			uuid.SwitchFormat(uuid.CleanHyphen)
 			requestId := uuid.NewV4().String()
@@ -177,7 +187,9 @@ func (controlChannel *ControlChannel) Close(log log.T) error {
 // Open opens a websocket connection and sends the token for service to acknowledge the connection.
 func (controlChannel *ControlChannel) Open(log log.T) error {
 	if err := controlChannel.wsChannel.Open(log); err != nil {
-		return fmt.Errorf("failed to connect controlchannel with error: %s", err)
+		err = fmt.Errorf("failed to connect controlchannel with error: %s", err)
+		channelHealth.RecordError(err)
+		return err
 	}
 
 	uuid.SwitchFormat(uuid.CleanHyphen)
@@ -198,7 +210,13 @@ func (controlChannel *ControlChannel) Open(log log.T) error {
 		return fmt.Errorf("error serializing openControlChannelInput: %s", err)
 	}
 
-	return controlChannel.SendMessage(log, jsonValue, websocket.TextMessage)
+	if err = controlChannel.SendMessage(log, jsonValue, websocket.TextMessage); err != nil {
+		channelHealth.RecordError(err)
+		return err
+	}
+	channelHealth.RecordSuccess()
+	updateutil.RecordHealthMarker(log, updateutil.HealthMarkerControlChannel, version.Version)
+	return nil
 }
 
 // controlChannelIncomingMessageHandler handles the incoming messages coming to the agent.
@@ -270,6 +288,7 @@ func sendTerminateSessionMessageToProcessor(
 		log.Errorf("Cannot parse AgentTask message to ChannelClosed message: %s, err: %v.", agentMessage.MessageId, err)
 		return err
 	}
+	log = context.WithFields(sessionFields(channelClosed.SessionId)).Log()
 	log.Debugf("ChannelClosed message %s, sessionId %s", channelClosed.MessageId, channelClosed.SessionId)
 
 	documentInfo := contracts.DocumentInfo{
@@ -300,6 +319,11 @@ func sendTerminateSessionMessageToProcessor(
 	return nil
 }
 
+// sessionFields returns the log fields identifying a session
+func sessionFields(sessionId string) log.Fields {
+	return log.Fields{log.FieldSessionID: sessionId}
+}
+
 // getControlChannelToken calls CreateControlChannel to get the token for this instance
 func getControlChannelToken(log log.T,
 	mgsService service.Service,
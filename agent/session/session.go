@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig/configwatcher"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
@@ -48,6 +49,7 @@ type Session struct {
 	service        service.Service
 	controlChannel controlchannel.IControlChannel
 	processor      processor.Processor
+	health         *contracts.HealthTracker
 }
 
 // NewSession gets session core module that manages the web-socket connection between Agent and message gateway service.
@@ -116,6 +118,10 @@ func NewSession(context context.T) *Session {
 
 	controlChannel := &controlchannel.ControlChannel{}
 
+	sessionContext.ConfigWatcher().Subscribe(mgsConfig.SessionServiceName, []string{"Mgs.SessionWorkersLimit"}, func(change configwatcher.Change) {
+		processor.SetWorkersLimit(change.Current.Mgs.SessionWorkersLimit)
+	})
+
 	return &Session{
 		context:        sessionContext,
 		agentConfig:    agentConfig,
@@ -124,6 +130,7 @@ func NewSession(context context.T) *Session {
 		service:        mgsService,
 		processor:      processor,
 		controlChannel: controlChannel,
+		health:         contracts.NewHealthTracker(mgsConfig.SessionServiceName),
 	}
 }
 
@@ -191,8 +198,10 @@ func (s *Session) ModuleExecute(context context.T) (err error) {
 	s.controlChannel, err = setupControlChannel(s.context, s.service, s.processor, instanceId)
 	if err != nil {
 		log.Errorf("Failed to setup control channel, err: %v", err)
+		s.health.RecordError(err)
 		return
 	}
+	s.health.RecordSuccess()
 
 	log.Info("Starting receiving message from control channel")
 
@@ -204,6 +213,24 @@ func (s *Session) ModuleExecute(context context.T) (err error) {
 	return nil
 }
 
+// ModuleHealth returns the health of the session module and of its control channel
+func (s *Session) ModuleHealth() contracts.ModuleHealth {
+	health := s.health.Health()
+	health.Components = append(health.Components, controlchannel.Health())
+	return health
+}
+
+// ModuleDrain stops starting new sessions, the control channel stays open for the sessions in progress
+func (s *Session) ModuleDrain() {
+	s.context.Log().Info("Draining, no longer starting new sessions")
+	s.processor.Drain()
+}
+
+// ModuleInFlight returns the number of sessions in progress
+func (s *Session) ModuleInFlight() int {
+	return s.processor.InFlight()
+}
+
 // ModuleRequestStop handles the termination of the session module
 func (s *Session) ModuleRequestStop(stopType contracts.StopType) (err error) {
 	log := s.context.Log()
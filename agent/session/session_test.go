@@ -16,6 +16,7 @@ package session
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
@@ -75,7 +76,8 @@ func (suite *SessionTestSuite) SetupTest() {
 		agentConfig:    agentConfig,
 		service:        mockService,
 		processor:      mockProcessor,
-		controlChannel: mockControlChannel}
+		controlChannel: mockControlChannel,
+		health:         contracts.NewHealthTracker(mgsConfig.SessionServiceName)}
 }
 
 // Testing the module name
@@ -124,6 +126,24 @@ func (suite *SessionTestSuite) TestModuleExecute() {
 	close(resChan)
 }
 
+// Testing the module health records a failure to setup the control channel
+func (suite *SessionTestSuite) TestModuleHealthControlChannelSetupFailed() {
+	resChan := make(chan contracts.DocumentResult)
+	suite.mockProcessor.On("Start").Return(resChan, nil)
+
+	setupControlChannel = func(context context.T, service service.Service, processor processor.Processor, instanceId string) (controlchannel.IControlChannel, error) {
+		return nil, fmt.Errorf("unable to reach MGS")
+	}
+
+	suite.session.ModuleExecute(suite.mockContext)
+
+	health := suite.session.(contracts.IModuleHealth).ModuleHealth()
+	assert.True(suite.T(), health.IsFailing())
+	assert.Equal(suite.T(), "unable to reach MGS", health.LastError)
+	assert.Len(suite.T(), health.Components, 1)
+	close(resChan)
+}
+
 // Testing the module request stop
 func (suite *SessionTestSuite) TestModuleRequestStop() {
 	suite.mockControlChannel.On("Close", mock.Anything).Return(nil)
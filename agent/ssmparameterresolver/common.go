@@ -38,8 +38,9 @@ type SsmParameterInfo struct {
 }
 
 // ResolveOptions structure represents a set of options for the parameter resolution.
-// At this time it has only one flag IgnoreSecureParameters
 // if IgnoreSecureParameters == true the parameters prefixed with ssm-secure: will not be resolved.
+// RegisterSecrets, when set, gets the values of the secure parameters resolved so they can be redacted from the output.
 type ResolveOptions struct {
 	IgnoreSecureParameters bool
+	RegisterSecrets        func(secrets ...string)
 }
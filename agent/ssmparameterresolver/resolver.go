@@ -45,6 +45,7 @@ func ExtractParametersFromText(
 		return nil, prefixValidationError
 	}
 
+	registerSecureValues(parametersWithValues, options)
 	return parametersWithValues, nil
 }
 
@@ -78,6 +79,7 @@ func ResolveParameterReferenceList(
 		return nil, prefixValidationError
 	}
 
+	registerSecureValues(parametersWithValues, options)
 	return parametersWithValues, nil
 }
 
@@ -102,6 +104,22 @@ func ResolveParametersInText(
 	return input, nil
 }
 
+// registerSecureValues passes the values of the secure parameters to the RegisterSecrets option
+func registerSecureValues(parameters map[string]SsmParameterInfo, options ResolveOptions) {
+	if options.RegisterSecrets == nil {
+		return
+	}
+	var secrets []string
+	for _, parameter := range parameters {
+		if parameter.Type == secureStringType && parameter.Value != "" {
+			secrets = append(secrets, parameter.Value)
+		}
+	}
+	if len(secrets) > 0 {
+		options.RegisterSecrets(secrets...)
+	}
+}
+
 func validateParameterReferencePrefix(resolvedParametersMap *map[string]SsmParameterInfo) error {
 	for key, value := range *resolvedParametersMap {
 		if strings.HasPrefix(key, ssmSecurePrefix) && value.Type != secureStringType {
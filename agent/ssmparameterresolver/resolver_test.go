@@ -41,6 +41,22 @@ func TestExtractParametersFromText(t *testing.T) {
 	assert.True(t, reflect.DeepEqual(resolvedParameters, expectedResult))
 }
 
+func TestExtractParametersFromTextRegistersSecrets(t *testing.T) {
+	serviceObject := newServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/a/b/c/param1": {Name: "/a/b/c/param1", Type: stringType, Value: "value_/a/b/c/param1"},
+		"ssm-secure:param2": {Name: "param2", Type: secureStringType, Value: "value_param2"},
+	})
+	var registered []string
+
+	text := "Some text {{ ssm:/a/b/c/param1}}, some more text {{ssm-secure:param2}}."
+	_, err := ExtractParametersFromText(&serviceObject, log.DefaultLogger(), text, ResolveOptions{
+		RegisterSecrets: func(secrets ...string) { registered = append(registered, secrets...) },
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"value_param2"}, registered)
+}
+
 func TestExtractParametersFromTextIgnoreSecureParams(t *testing.T) {
 	serviceObject := newServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
 		"ssm:/a/b/c/param1":        {Name: "/a/b/c/param1", Type: stringType, Value: "value_/a/b/c/param1"},
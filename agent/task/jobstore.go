@@ -54,6 +54,13 @@ func (t *JobStore) GetJob(jobID string) (token *JobToken, found bool) {
 	return s, ok
 }
 
+// Count returns the number of jobs of this task.
+func (t *JobStore) Count() int {
+	t.m.RLock()
+	defer t.m.RUnlock()
+	return len(t.jobs)
+}
+
 // DeleteJob deletes the job with the given jobID.
 func (t *JobStore) DeleteJob(jobID string) {
 	t.m.Lock()
@@ -45,6 +45,14 @@ type Pool interface {
 
 	// HasJob returns if jobStore has specified job
 	HasJob(jobID string) bool
+
+	// Resize changes the number of jobs executed in parallel.
+	// Running jobs are not interrupted when the pool shrinks, the pool waits for them to complete
+	// before starting new jobs.
+	Resize(maxParallel int)
+
+	// JobCount returns the number of jobs submitted which haven't completed or been canceled yet
+	JobCount() int
 }
 
 // pool implements a task pool where all jobs are managed by a root task
@@ -58,6 +66,11 @@ type pool struct {
 	mut            sync.Mutex
 	jobStore       *JobStore
 	cancelDuration time.Duration
+	jobProcessor   func(JobToken)
+	// maxParallel is the number of jobs the workers can run at the same time, active the number of jobs running
+	maxParallel int
+	active      int
+	slotFreed   *sync.Cond
 }
 
 // JobToken embeds a job and its associated info
@@ -79,22 +92,68 @@ func NewPool(log log.T, maxParallel int, cancelWaitDuration time.Duration, clock
 		doneWorker:     make(chan struct{}),
 		clock:          clock,
 		cancelDuration: cancelWaitDuration,
+		maxParallel:    maxParallel,
 	}
+	p.slotFreed = sync.NewCond(&p.mut)
 
 	p.jobStore = NewJobStore()
 
 	// defines the job processing function.
-	processor := func(j JobToken) {
+	p.jobProcessor = func(j JobToken) {
 		defer p.jobStore.DeleteJob(j.id)
+		p.acquireSlot()
+		defer p.releaseSlot()
+		// the job may have been canceled while waiting for the pool to free a slot
+		if j.cancelFlag.Canceled() {
+			return
+		}
 		process(j.log, j.job, j.cancelFlag, cancelWaitDuration, p.clock)
 	}
 
 	// start the workers
-	p.start(processor)
+	p.start()
 
 	return p
 }
 
+// Resize changes the number of jobs executed in parallel, starting workers when the pool grows
+func (p *pool) Resize(maxParallel int) {
+	if maxParallel < 1 {
+		return
+	}
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if p.isShutdown {
+		return
+	}
+	for i := p.nWorkers; i < maxParallel; i++ {
+		p.startWorker(fmt.Sprintf("worker-%d", i))
+	}
+	if p.nWorkers < maxParallel {
+		p.nWorkers = maxParallel
+	}
+	p.maxParallel = maxParallel
+	p.slotFreed.Broadcast()
+}
+
+// acquireSlot waits until the pool runs less jobs than allowed, then reserves a slot for a job
+func (p *pool) acquireSlot() {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	for p.active >= p.maxParallel {
+		p.slotFreed.Wait()
+	}
+	p.active++
+}
+
+// releaseSlot frees the slot of a job which completed
+func (p *pool) releaseSlot() {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	p.active--
+	p.slotFreed.Signal()
+}
+
 // Shutdown cancels all the jobs in this pool and shuts down the workers.
 func (p *pool) Shutdown() {
 	// ShutDown and delete all jobs
@@ -119,7 +178,9 @@ func (p *pool) ShutdownAndWait(timeout time.Duration) (finished bool) {
 
 	timeoutTimer := p.clock.After(timeout)
 	exitTimer := p.clock.After(timeout + p.cancelDuration)
+	p.mut.Lock()
 	workersRunning := p.nWorkers
+	p.mut.Unlock()
 	for workersRunning > 0 {
 		select {
 		case <-p.doneWorker:
@@ -143,16 +204,20 @@ func (p *pool) ShutdownAndWait(timeout time.Duration) (finished bool) {
 }
 
 // start starts the workers of this pool
-func (p *pool) start(jobProcessor func(JobToken)) {
+func (p *pool) start() {
 	for i := 0; i < p.nWorkers; i++ {
-		workerName := fmt.Sprintf("worker-%d", i)
-		go func() {
-			defer p.workerDone()
-			worker(workerName, p.jobQueue, jobProcessor)
-		}()
+		p.startWorker(fmt.Sprintf("worker-%d", i))
 	}
 }
 
+// startWorker starts a worker processing the jobs of this pool
+func (p *pool) startWorker(workerName string) {
+	go func() {
+		defer p.workerDone()
+		worker(workerName, p.jobQueue, p.jobProcessor)
+	}()
+}
+
 // workerDone signals that a worker has terminated.
 func (p *pool) workerDone() {
 	p.doneWorker <- struct{}{}
@@ -189,6 +254,11 @@ func (p *pool) HasJob(jobID string) bool {
 	return found
 }
 
+// JobCount returns the number of jobs queued or running
+func (p *pool) JobCount() int {
+	return p.jobStore.Count()
+}
+
 // Cancel cancels the job with the given id.
 func (p *pool) Cancel(jobID string) (canceled bool) {
 	jobToken, found := p.jobStore.GetJob(jobID)
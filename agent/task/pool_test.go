@@ -119,3 +119,65 @@ func exercisePool(t *testing.T, pool Pool, jobID string, shouldCancel bool) {
 	// see that job completes
 	assert.True(t, <-jobState)
 }
+
+func TestPoolResize(t *testing.T) {
+	pool := NewPool(logger, 1, 100*time.Millisecond, times.DefaultClock)
+	defer pool.Shutdown()
+
+	started := make(chan string, 2)
+	release := make(chan bool)
+	blockingJob := func(jobID string) Job {
+		return func(CancelFlag) {
+			started <- jobID
+			<-release
+		}
+	}
+
+	assert.Nil(t, pool.Submit(logger, "job1", blockingJob("job1")))
+	assert.Equal(t, "job1", <-started)
+	go pool.Submit(logger, "job2", blockingJob("job2"))
+
+	// the second job starts once the pool grows
+	pool.Resize(2)
+	assert.Equal(t, "job2", <-started)
+	release <- true
+	release <- true
+
+	// the pool waits for a running job to complete before starting the next one once it shrinks
+	pool.Resize(1)
+	assert.Nil(t, pool.Submit(logger, "job3", blockingJob("job3")))
+	assert.Equal(t, "job3", <-started)
+	go pool.Submit(logger, "job4", blockingJob("job4"))
+	select {
+	case jobID := <-started:
+		assert.Fail(t, "job started beyond the size of the pool", jobID)
+	case <-time.After(100 * time.Millisecond):
+	}
+	release <- true
+	assert.Equal(t, "job4", <-started)
+	release <- true
+}
+
+func TestPoolJobCount(t *testing.T) {
+	pool := NewPool(logger, 1, 100*time.Millisecond, times.DefaultClock)
+	defer pool.Shutdown()
+
+	release := make(chan bool)
+	done := make(chan bool)
+	job := func(CancelFlag) {
+		<-release
+		done <- true
+	}
+
+	assert.Equal(t, 0, pool.JobCount())
+	assert.Nil(t, pool.Submit(logger, "job1", job))
+	assert.Equal(t, 1, pool.JobCount())
+
+	release <- true
+	<-done
+	// the job leaves the store once its processing function returns
+	for i := 0; i < 100 && pool.JobCount() != 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 0, pool.JobCount())
+}
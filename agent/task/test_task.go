@@ -55,6 +55,16 @@ func (mockPool *MockedPool) HasJob(jobID string) bool {
 	return args.Bool(0)
 }
 
+// Resize mocks the method with the same name.
+func (mockPool *MockedPool) Resize(maxParallel int) {
+	mockPool.Called(maxParallel)
+}
+
+// JobCount mocks the method with the same name.
+func (mockPool *MockedPool) JobCount() int {
+	return mockPool.Called().Int(0)
+}
+
 // MockCancelFlag mocks a cancel flag.
 type MockCancelFlag struct {
 	mock.Mock
@@ -0,0 +1,216 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+const (
+	serviceName = "amazon-ssm-agent"
+	scopeName   = "github.com/aws/amazon-ssm-agent/agent/tracing"
+
+	// otlpTracesPath is the path of the OTLP/HTTP traces service
+	otlpTracesPath = "/v1/traces"
+	otlpTimeout    = 10 * time.Second
+
+	// spanKindInternal and statusCodeError are the values of the OTLP enums used by the agent
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+// exporter sends a batch of ended spans to their destination
+type exporter interface {
+	export(spans []*Span) error
+}
+
+// otlpExporter posts the spans to an OTLP/HTTP collector in the JSON encoding
+type otlpExporter struct {
+	url    string
+	client *http.Client
+}
+
+func newOTLPExporter(endpoint string) *otlpExporter {
+	return &otlpExporter{
+		url:    strings.TrimSuffix(endpoint, "/") + otlpTracesPath,
+		client: &http.Client{Timeout: otlpTimeout},
+	}
+}
+
+func (e *otlpExporter) export(spans []*Span) error {
+	content, err := json.Marshal(newExportRequest(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector %v returned %v", e.url, resp.Status)
+	}
+	return nil
+}
+
+// fileExporter appends each batch of spans to a file as one OTLP JSON export request per line
+type fileExporter struct {
+	path string
+	mu   sync.Mutex
+}
+
+func newFileExporter(path string) *fileExporter {
+	if path == "" {
+		path = filepath.Join(log.DefaultLogDir, appconfig.DefaultTracingFileName)
+	}
+	return &fileExporter{path: path}
+}
+
+func (e *fileExporter) export(spans []*Span) error {
+	content, err := json.Marshal(newExportRequest(spans))
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	file, err := os.OpenFile(e.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(content, '\n'))
+	return err
+}
+
+// The types below are the subset of the OTLP JSON encoding of an ExportTraceServiceRequest used by the agent
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanData `json:"spans"`
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type spanData struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            *status    `json:"status,omitempty"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func newExportRequest(spans []*Span) exportRequest {
+	data := make([]spanData, 0, len(spans))
+	for _, span := range spans {
+		data = append(data, span.data())
+	}
+	return exportRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: resource{Attributes: []keyValue{
+				stringAttribute("service.name", serviceName),
+				stringAttribute("service.version", version.Version),
+				stringAttribute("process.executable.name", filepath.Base(os.Args[0])),
+			}},
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: scopeName, Version: version.Version},
+				Spans: data,
+			}},
+		}},
+	}
+}
+
+// data returns the OTLP encoding of an ended span
+func (s *Span) data() spanData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := spanData{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+	if s.parentID != [8]byte{} {
+		data.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	keys := make([]string, 0, len(s.attributes))
+	for key := range s.attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		data.Attributes = append(data.Attributes, stringAttribute(key, s.attributes[key]))
+	}
+	if s.err != "" {
+		data.Status = &status{Code: statusCodeError, Message: s.err}
+	}
+	return data
+}
+
+func stringAttribute(key string, value string) keyValue {
+	return keyValue{Key: key, Value: anyValue{StringValue: value}}
+}
@@ -0,0 +1,245 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package tracing records the spans of the execution of documents and exports them
+// in the OpenTelemetry protocol format, to a collector or to a file.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// traceParentVersion is the version of the W3C trace context format
+	traceParentVersion = "00"
+	// traceFlagSampled marks a sampled trace, every trace recorded by the agent is sampled
+	traceFlagSampled = "01"
+
+	// batchSize is the number of ended spans triggering an export
+	batchSize = 512
+	// batchInterval is the longest time an ended span waits to be exported
+	batchInterval = 5 * time.Second
+)
+
+// Tracer records spans and exports them in batches.
+// A nil Tracer records nothing, so tracing costs nothing when it is disabled.
+type Tracer struct {
+	log      log.T
+	exporter exporter
+
+	mu      sync.Mutex
+	pending []*Span
+
+	flush    chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopped  sync.WaitGroup
+}
+
+// Span is a unit of work, such as the processing of a message or the execution of a plugin
+type Span struct {
+	tracer   *Tracer
+	name     string
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+
+	mu         sync.Mutex
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+	err        string
+}
+
+// NewTracer creates the tracer for the configuration, or returns nil when tracing is disabled
+func NewTracer(log log.T, config appconfig.TracingCfg) *Tracer {
+	var exp exporter
+	switch strings.ToLower(config.Exporter) {
+	case "":
+		return nil
+	case appconfig.TracingExporterOTLP:
+		exp = newOTLPExporter(config.Endpoint)
+	case appconfig.TracingExporterFile:
+		exp = newFileExporter(config.FilePath)
+	default:
+		log.Warnf("Tracing exporter %v is not supported, tracing is disabled", config.Exporter)
+		return nil
+	}
+	return newTracer(log, exp)
+}
+
+func newTracer(log log.T, exp exporter) *Tracer {
+	t := &Tracer{
+		log:      log,
+		exporter: exp,
+		flush:    make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+	t.stopped.Add(1)
+	go t.run()
+	return t
+}
+
+// Start starts a span, the child of the span identified by traceParent when it is a valid W3C traceparent
+func (t *Tracer) Start(name string, traceParent string) *Span {
+	if t == nil {
+		return nil
+	}
+	span := &Span{
+		tracer:     t,
+		name:       name,
+		start:      time.Now(),
+		attributes: make(map[string]string),
+	}
+	if traceID, parentID, ok := ParseTraceParent(traceParent); ok {
+		span.traceID = traceID
+		span.parentID = parentID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+	rand.Read(span.spanID[:])
+	return span
+}
+
+// Shutdown exports the spans not exported yet and stops the tracer
+func (t *Tracer) Shutdown() {
+	if t == nil {
+		return
+	}
+	t.stopOnce.Do(func() {
+		close(t.stop)
+	})
+	t.stopped.Wait()
+}
+
+// run exports the ended spans when the batch is full, periodically, and when the tracer stops
+func (t *Tracer) run() {
+	defer t.stopped.Done()
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.flush:
+			t.export()
+		case <-ticker.C:
+			t.export()
+		case <-t.stop:
+			t.export()
+			return
+		}
+	}
+}
+
+func (t *Tracer) export() {
+	t.mu.Lock()
+	spans := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+
+	if len(spans) == 0 {
+		return
+	}
+	if err := t.exporter.export(spans); err != nil {
+		t.log.Debugf("failed to export %v spans, %v", len(spans), err)
+	}
+}
+
+func (t *Tracer) enqueue(span *Span) {
+	t.mu.Lock()
+	t.pending = append(t.pending, span)
+	full := len(t.pending) >= batchSize
+	t.mu.Unlock()
+
+	if full {
+		select {
+		case t.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// SetAttribute attaches a key/value pair to the span, empty values are ignored
+func (s *Span) SetAttribute(key string, value string) {
+	if s == nil || value == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes[key] = value
+}
+
+// RecordError marks the span as failed
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err.Error()
+}
+
+// End ends the span, only the first call has an effect
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	s.tracer.enqueue(s)
+}
+
+// TraceParent returns the W3C traceparent identifying the span, to propagate to the work it starts
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v-%v-%v-%v", traceParentVersion, hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]), traceFlagSampled)
+}
+
+// ParseTraceParent returns the trace id and the span id of a W3C traceparent
+func ParseTraceParent(traceParent string) (traceID [16]byte, spanID [8]byte, ok bool) {
+	parts := strings.Split(traceParent, "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[3]) != 2 {
+		return traceID, spanID, false
+	}
+	if !decodeID(parts[1], traceID[:]) || !decodeID(parts[2], spanID[:]) {
+		return [16]byte{}, [8]byte{}, false
+	}
+	// all zero ids are invalid
+	ok = traceID != [16]byte{} && spanID != [8]byte{}
+	return traceID, spanID, ok
+}
+
+// decodeID decodes a hexadecimal trace or span id of the length of id into id
+func decodeID(encoded string, id []byte) bool {
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil || len(decoded) != len(id) {
+		return false
+	}
+	copy(id, decoded)
+	return true
+}
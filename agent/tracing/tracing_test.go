@@ -0,0 +1,159 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	traceID, spanID, ok := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(traceID[:]))
+	assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(spanID[:]))
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-xyz92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		_, _, ok = ParseTraceParent(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
+
+func TestChildSpanJoinsTheTrace(t *testing.T) {
+	tracer := newTracer(log.NewMockLog(), &recordingExporter{})
+	defer tracer.Shutdown()
+
+	parent := tracer.Start("parent", "")
+	child := tracer.Start("child", parent.TraceParent())
+
+	assert.Equal(t, parent.traceID, child.traceID)
+	assert.Equal(t, parent.spanID, child.parentID)
+	assert.NotEqual(t, parent.spanID, child.spanID)
+	assert.Equal(t, [8]byte{}, parent.parentID)
+}
+
+func TestShutdownExportsEndedSpans(t *testing.T) {
+	exp := &recordingExporter{}
+	tracer := newTracer(log.NewMockLog(), exp)
+
+	span := tracer.Start("runPlugin", "")
+	span.SetAttribute("plugin", "aws:runShellScript")
+	span.SetAttribute("empty", "")
+	span.RecordError(errors.New("exit status 1"))
+	span.End()
+	span.End()
+	tracer.Start("notEnded", "")
+	tracer.Shutdown()
+
+	assert.Len(t, exp.spans, 1)
+	data := exp.spans[0].data()
+	assert.Equal(t, "runPlugin", data.Name)
+	assert.Equal(t, []keyValue{stringAttribute("plugin", "aws:runShellScript")}, data.Attributes)
+	assert.Equal(t, &status{Code: statusCodeError, Message: "exit status 1"}, data.Status)
+}
+
+func TestNilTracer(t *testing.T) {
+	tracer := NewTracer(log.NewMockLog(), appconfig.TracingCfg{})
+	assert.Nil(t, tracer)
+
+	span := tracer.Start("processMessage", "")
+	span.SetAttribute("key", "value")
+	span.RecordError(errors.New("failed"))
+	span.End()
+	assert.Empty(t, span.TraceParent())
+	tracer.Shutdown()
+}
+
+func TestFileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "traces")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "traces.json")
+	tracer := NewTracer(log.NewMockLog(), appconfig.TracingCfg{Exporter: appconfig.TracingExporterFile, FilePath: path})
+
+	tracer.Start("first", "").End()
+	tracer.export()
+	tracer.Start("second", "").End()
+	tracer.Shutdown()
+
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 2)
+
+	var request exportRequest
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &request))
+	assert.Equal(t, "second", request.ResourceSpans[0].ScopeSpans[0].Spans[0].Name)
+	assert.Equal(t, stringAttribute("service.name", serviceName), request.ResourceSpans[0].Resource.Attributes[0])
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var request exportRequest
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&request)
+	}))
+	defer server.Close()
+
+	tracer := NewTracer(log.NewMockLog(), appconfig.TracingCfg{Exporter: "OTLP", Endpoint: server.URL + "/"})
+	span := tracer.Start("processMessage", "")
+	span.End()
+	tracer.Shutdown()
+
+	assert.Equal(t, otlpTracesPath, path)
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	assert.Len(t, spans, 1)
+	assert.Equal(t, span.TraceParent(), "00-"+spans[0].TraceID+"-"+spans[0].SpanID+"-01")
+	assert.Equal(t, spanKindInternal, spans[0].Kind)
+}
+
+func TestOTLPExporterReportsCollectorErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := newOTLPExporter(server.URL).export([]*Span{})
+	assert.Error(t, err)
+}
+
+type recordingExporter struct {
+	spans []*Span
+}
+
+func (e *recordingExporter) export(spans []*Span) error {
+	e.spans = append(e.spans, spans...)
+	return nil
+}
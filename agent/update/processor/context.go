@@ -122,7 +122,8 @@ func (context *UpdateContext) IsUpdateInProgress(log log.T) bool {
 	} else {
 		duration := time.Since(context.Current.StartDateTime)
 		log.Infof("Attemping to retry update after %v seconds", duration.Seconds())
-		if duration.Seconds() > maxAllowedUpdateDuration {
+		// the new version may still be evaluated by its health gates
+		if duration.Seconds() > float64(maxAllowedUpdateDuration+loadAgentUpdateConfig().HealthGateWindowSeconds) {
 			return false
 		}
 	}
@@ -0,0 +1,209 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor contains the methods for update ssm agent.
+// It also provides methods for sendReply and updateInstanceInfo
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+)
+
+const (
+	// healthGateServiceRunning passes while the agent service is running
+	healthGateServiceRunning = "ServiceRunning"
+	// healthGateControlChannel passes once the new agent opened its MGS control channel
+	healthGateControlChannel = "ControlChannel"
+	// healthGateHealthPing passes once the new agent reported its health to SSM
+	healthGateHealthPing = "HealthPing"
+	// healthGateNoCrashLoop passes once the new agent stayed up without restarting too often
+	healthGateNoCrashLoop = "NoCrashLoop"
+	// healthGateScript passes once the configured check script exits 0
+	healthGateScript = "Script"
+
+	// maxCrashLoopStablePeriod is the time the new agent must stay up to pass the NoCrashLoop gate
+	maxCrashLoopStablePeriod = 60 * time.Second
+)
+
+var (
+	loadHealthMarker       = updateutil.LoadHealthMarker
+	clearHealthMarkers     = updateutil.ClearHealthMarkers
+	loadUpdatePluginResult = updateutil.LoadUpdatePluginResult
+	healthGatePollInterval = 10 * time.Second
+)
+
+// gateStatus is the outcome of a single evaluation of a health gate
+type gateStatus int
+
+const (
+	gatePending gateStatus = iota
+	gatePassed
+	// gateFailed fails the update right away, waiting for the end of the window would not change the outcome
+	gateFailed
+)
+
+// healthGateCheck evaluates one health gate of the target version
+type healthGateCheck func(mgr *updateManager, log log.T, context *UpdateContext, instanceContext *updateutil.InstanceContext, config appconfig.AgentUpdateCfg) (status gateStatus, message string)
+
+var healthGateChecks = map[string]healthGateCheck{
+	healthGateServiceRunning: checkServiceRunning,
+	healthGateControlChannel: checkHealthMarker(updateutil.HealthMarkerControlChannel, "control channel opened"),
+	healthGateHealthPing:     checkHealthMarker(updateutil.HealthMarkerHealthPing, "health ping succeeded"),
+	healthGateNoCrashLoop:    checkNoCrashLoop,
+	healthGateScript:         checkScript,
+}
+
+// loadAgentUpdateConfig loads the health gate configuration of the agent
+var loadAgentUpdateConfig = func() appconfig.AgentUpdateCfg {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		config = appconfig.DefaultConfig()
+	}
+	return config.AgentUpdate
+}
+
+// evaluateHealthGates waits until the target version passes all configured health gates or the gate window expires
+func evaluateHealthGates(mgr *updateManager, log log.T, context *UpdateContext, instanceContext *updateutil.InstanceContext) (results []updateutil.HealthGateResult, passed bool) {
+	config := loadAgentUpdateConfig()
+	if len(config.HealthGates) == 0 {
+		return nil, true
+	}
+
+	deadline := time.Now().Add(time.Duration(config.HealthGateWindowSeconds) * time.Second)
+	results = make([]updateutil.HealthGateResult, len(config.HealthGates))
+	for i, name := range config.HealthGates {
+		results[i] = updateutil.HealthGateResult{Name: name, Message: "not evaluated"}
+	}
+
+	log.Infof("Evaluating update health gates %v for %v seconds", config.HealthGates, config.HealthGateWindowSeconds)
+	for {
+		passed = true
+		failed := false
+		for i := range results {
+			if results[i].Passed {
+				continue
+			}
+			status, message := gatePending, "unknown health gate"
+			if check, ok := healthGateChecks[results[i].Name]; ok {
+				status, message = check(mgr, log, context, instanceContext, config)
+			} else {
+				status = gateFailed
+			}
+			results[i].Passed = status == gatePassed
+			results[i].Message = message
+			results[i].CheckedAt = time.Now().UTC()
+
+			passed = passed && results[i].Passed
+			failed = failed || status == gateFailed
+		}
+
+		if passed || failed || !time.Now().Before(deadline) {
+			return results, passed
+		}
+		time.Sleep(healthGatePollInterval)
+	}
+}
+
+// checkServiceRunning verifies the agent service is still running
+func checkServiceRunning(mgr *updateManager, log log.T, context *UpdateContext, instanceContext *updateutil.InstanceContext, config appconfig.AgentUpdateCfg) (gateStatus, string) {
+	if isRunning, err := mgr.util.IsServiceRunning(log, instanceContext); err != nil {
+		return gatePending, fmt.Sprintf("failed to check the agent service, %v", err)
+	} else if !isRunning {
+		return gatePending, "agent service is not running"
+	}
+	return gatePassed, "agent service is running"
+}
+
+// checkHealthMarker returns a gate that passes once the target version recorded the health marker after the update started
+func checkHealthMarker(marker string, description string) healthGateCheck {
+	return func(mgr *updateManager, log log.T, context *UpdateContext, instanceContext *updateutil.InstanceContext, config appconfig.AgentUpdateCfg) (gateStatus, string) {
+		healthMarker, err := loadHealthMarker(marker)
+		if err != nil {
+			return gatePending, fmt.Sprintf("failed to load health marker %v, %v", marker, err)
+		}
+		if healthMarker == nil || healthMarker.Version != context.Current.TargetVersion || healthMarker.Time.Before(context.Current.StartDateTime) {
+			return gatePending, fmt.Sprintf("%v %v not yet", context.Current.TargetVersion, description)
+		}
+		return gatePassed, fmt.Sprintf("%v %v at %v", healthMarker.Version, description, healthMarker.Time.Format(time.RFC3339))
+	}
+}
+
+// checkNoCrashLoop verifies the target version stays up and did not restart more often than allowed
+func checkNoCrashLoop(mgr *updateManager, log log.T, context *UpdateContext, instanceContext *updateutil.InstanceContext, config appconfig.AgentUpdateCfg) (gateStatus, string) {
+	healthMarker, err := loadHealthMarker(updateutil.HealthMarkerAgentStart)
+	if err != nil {
+		return gatePending, fmt.Sprintf("failed to load agent start marker, %v", err)
+	}
+	if healthMarker == nil || healthMarker.Version != context.Current.TargetVersion || healthMarker.Time.Before(context.Current.StartDateTime) {
+		return gatePending, fmt.Sprintf("%v has not started yet", context.Current.TargetVersion)
+	}
+	if healthMarker.Count > config.CrashLoopRestartLimit {
+		return gateFailed, fmt.Sprintf("%v started %v times, more than the limit of %v", healthMarker.Version, healthMarker.Count, config.CrashLoopRestartLimit)
+	}
+
+	// a short window must still leave time to observe restarts
+	stablePeriod := maxCrashLoopStablePeriod
+	if window := time.Duration(config.HealthGateWindowSeconds) * time.Second / 2; window < stablePeriod {
+		stablePeriod = window
+	}
+	if uptime := time.Since(healthMarker.Time); uptime < stablePeriod {
+		return gatePending, fmt.Sprintf("%v up for %v, waiting for %v", healthMarker.Version, uptime.Round(time.Second), stablePeriod)
+	}
+	return gatePassed, fmt.Sprintf("%v started %v times and stayed up", healthMarker.Version, healthMarker.Count)
+}
+
+// checkScript runs the configured check script
+func checkScript(mgr *updateManager, log log.T, context *UpdateContext, instanceContext *updateutil.InstanceContext, config appconfig.AgentUpdateCfg) (gateStatus, string) {
+	if config.HealthGateScript == "" {
+		return gateFailed, "no HealthGateScript is configured"
+	}
+	if err := mgr.util.ExeCommand(
+		log,
+		config.HealthGateScript,
+		context.Current.UpdateRoot,
+		context.Current.UpdateRoot,
+		context.Current.StdoutFileName,
+		context.Current.StderrFileName,
+		false); err != nil {
+		return gatePending, fmt.Sprintf("%v failed, %v", config.HealthGateScript, err)
+	}
+	return gatePassed, fmt.Sprintf("%v exited 0", config.HealthGateScript)
+}
+
+// reportHealthGates adds the gate results to the update output and to the update plugin result
+func reportHealthGates(mgr *updateManager, log log.T, context *UpdateContext, results []updateutil.HealthGateResult) {
+	if len(results) == 0 {
+		return
+	}
+	for _, result := range results {
+		if result.Passed {
+			context.Current.AppendInfo(log, "Health gate %v passed: %v", result.Name, result.Message)
+		} else {
+			context.Current.AppendError(log, "Health gate %v failed: %v", result.Name, result.Message)
+		}
+	}
+
+	pluginResult, err := loadUpdatePluginResult(log, context.Current.UpdateRoot)
+	if err != nil || pluginResult == nil {
+		pluginResult = &updateutil.UpdatePluginResult{StartDateTime: context.Current.StartDateTime}
+	}
+	pluginResult.HealthGates = results
+	if err = mgr.util.SaveUpdatePluginResult(log, context.Current.UpdateRoot, pluginResult); err != nil {
+		log.Errorf("failed to save health gate results, %v", err)
+	}
+}
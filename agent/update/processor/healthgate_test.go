@@ -0,0 +1,164 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor contains the methods for update ssm agent.
+// It also provides methods for sendReply and updateInstanceInfo
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubHealthGates replaces the configuration and health markers used by the health gates
+// and returns a function restoring the original ones
+func stubHealthGates(config appconfig.AgentUpdateCfg, markers map[string]*updateutil.HealthMarker) (restore func()) {
+	loadConfig, loadMarker, interval := loadAgentUpdateConfig, loadHealthMarker, healthGatePollInterval
+	loadAgentUpdateConfig = func() appconfig.AgentUpdateCfg { return config }
+	loadHealthMarker = func(marker string) (*updateutil.HealthMarker, error) { return markers[marker], nil }
+	healthGatePollInterval = 10 * time.Millisecond
+	return func() {
+		loadAgentUpdateConfig, loadHealthMarker, healthGatePollInterval = loadConfig, loadMarker, interval
+	}
+}
+
+func createHealthGateContext() *UpdateContext {
+	context := createUpdateContext(Installed)
+	context.Current.StartDateTime = time.Now().Add(-5 * time.Minute)
+	return context
+}
+
+func TestEvaluateHealthGatesPassed(t *testing.T) {
+	started := time.Now().Add(-2 * time.Minute)
+	defer stubHealthGates(appconfig.AgentUpdateCfg{
+		HealthGates:             []string{healthGateServiceRunning, healthGateControlChannel, healthGateHealthPing, healthGateNoCrashLoop},
+		HealthGateWindowSeconds: 30,
+		CrashLoopRestartLimit:   3,
+	}, map[string]*updateutil.HealthMarker{
+		updateutil.HealthMarkerAgentStart:     {Version: "6.0.0.0", Time: started, Count: 1},
+		updateutil.HealthMarkerControlChannel: {Version: "6.0.0.0", Time: started},
+		updateutil.HealthMarkerHealthPing:     {Version: "6.0.0.0", Time: started},
+	})()
+	updater := createUpdaterStubs(&stubControl{serviceIsRunning: true})
+	context := createHealthGateContext()
+
+	results, passed := evaluateHealthGates(updater.mgr, logger, context, &updateutil.InstanceContext{})
+
+	assert.True(t, passed)
+	assert.Len(t, results, 4)
+	for _, result := range results {
+		assert.True(t, result.Passed, result.Name)
+	}
+}
+
+func TestEvaluateHealthGatesMarkerOfSourceVersion(t *testing.T) {
+	defer stubHealthGates(appconfig.AgentUpdateCfg{
+		HealthGates:             []string{healthGateControlChannel},
+		HealthGateWindowSeconds: 0,
+	}, map[string]*updateutil.HealthMarker{
+		updateutil.HealthMarkerControlChannel: {Version: "1.0.0.0", Time: time.Now()},
+	})()
+	updater := createUpdaterStubs(&stubControl{serviceIsRunning: true})
+
+	results, passed := evaluateHealthGates(updater.mgr, logger, createHealthGateContext(), &updateutil.InstanceContext{})
+
+	assert.False(t, passed)
+	assert.False(t, results[0].Passed)
+	assert.Contains(t, results[0].Message, "control channel opened not yet")
+}
+
+func TestEvaluateHealthGatesCrashLoopFailsEarly(t *testing.T) {
+	defer stubHealthGates(appconfig.AgentUpdateCfg{
+		HealthGates:             []string{healthGateNoCrashLoop},
+		HealthGateWindowSeconds: 3600,
+		CrashLoopRestartLimit:   3,
+	}, map[string]*updateutil.HealthMarker{
+		updateutil.HealthMarkerAgentStart: {Version: "6.0.0.0", Time: time.Now(), Count: 4},
+	})()
+	updater := createUpdaterStubs(&stubControl{serviceIsRunning: true})
+
+	results, passed := evaluateHealthGates(updater.mgr, logger, createHealthGateContext(), &updateutil.InstanceContext{})
+
+	assert.False(t, passed)
+	assert.Contains(t, results[0].Message, "started 4 times")
+}
+
+func TestEvaluateHealthGatesScript(t *testing.T) {
+	defer stubHealthGates(appconfig.AgentUpdateCfg{
+		HealthGates:             []string{healthGateScript},
+		HealthGateWindowSeconds: 0,
+		HealthGateScript:        "/opt/check.sh",
+	}, nil)()
+
+	_, passed := evaluateHealthGates(createUpdaterStubs(&stubControl{}).mgr, logger, createHealthGateContext(), &updateutil.InstanceContext{})
+	assert.True(t, passed)
+
+	_, passed = evaluateHealthGates(createUpdaterStubs(&stubControl{failExeCommand: true}).mgr, logger, createHealthGateContext(), &updateutil.InstanceContext{})
+	assert.False(t, passed)
+}
+
+func TestEvaluateHealthGatesUnknownGate(t *testing.T) {
+	defer stubHealthGates(appconfig.AgentUpdateCfg{
+		HealthGates:             []string{"Typo"},
+		HealthGateWindowSeconds: 3600,
+	}, nil)()
+
+	results, passed := evaluateHealthGates(createUpdaterStubs(&stubControl{}).mgr, logger, createHealthGateContext(), &updateutil.InstanceContext{})
+
+	assert.False(t, passed)
+	assert.Equal(t, "unknown health gate", results[0].Message)
+}
+
+func TestVerifyInstallationHealthGatesFailed(t *testing.T) {
+	// setup
+	control := &stubControl{serviceIsRunning: true}
+	updater := createUpdaterStubs(control)
+	context := createUpdateContext(Installed)
+	isRollbackCalled := false
+	var savedResult *updateutil.UpdatePluginResult
+
+	updater.mgr.gates = func(mgr *updateManager, log log.T, context *UpdateContext, instanceContext *updateutil.InstanceContext) ([]updateutil.HealthGateResult, bool) {
+		return []updateutil.HealthGateResult{{Name: healthGateHealthPing, Passed: false, Message: "not yet"}}, false
+	}
+	updater.mgr.util = &pluginResultUtilityStub{utilityStub: utilityStub{controller: control}, saved: &savedResult}
+	updater.mgr.rollback = func(mgr *updateManager, log log.T, context *UpdateContext) (err error) {
+		isRollbackCalled = true
+		return nil
+	}
+
+	// action
+	err := verifyInstallation(updater.mgr, logger, context, false)
+
+	// assert
+	assert.NoError(t, err)
+	assert.True(t, isRollbackCalled)
+	assert.Equal(t, context.Current.State, Rollback)
+	assert.Contains(t, context.Current.StandardOut, "Health gate HealthPing failed: not yet")
+	assert.NotNil(t, savedResult)
+	assert.Equal(t, healthGateHealthPing, savedResult.HealthGates[0].Name)
+}
+
+type pluginResultUtilityStub struct {
+	utilityStub
+	saved **updateutil.UpdatePluginResult
+}
+
+func (u *pluginResultUtilityStub) SaveUpdatePluginResult(log log.T, updaterRoot string, updateResult *updateutil.UpdatePluginResult) (err error) {
+	*u.saved = updateResult
+	return nil
+}
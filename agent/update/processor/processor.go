@@ -49,6 +49,7 @@ func NewUpdater() *Updater {
 			uninstall: uninstallAgent,
 			install:   installAgent,
 			download:  downloadAndUnzipArtifact,
+			gates:     evaluateHealthGates,
 		},
 	}
 
@@ -97,6 +98,11 @@ func (u *Updater) InitializeUpdate(log log.T, detail *UpdateDetail) (context *Up
 		return context, fmt.Errorf("another update is in progress, please retry later")
 	}
 
+	// restarts and milestones of an earlier update must not count towards the health gates of this one
+	if err = clearHealthMarkers(); err != nil {
+		log.Warnf("failed to clear health markers, %v", err)
+	}
+
 	context.Current = detail
 	if err = u.mgr.inProgress(context, log, Initialized); err != nil {
 		return
@@ -310,6 +316,25 @@ func verifyInstallation(mgr *updateManager, log log.T, context *UpdateContext, i
 
 	log.Infof("%v is running", context.Current.PackageName)
 	if !isRollback {
+		// the update is only committed once the new version passed its health gates
+		results, passed := mgr.gates(mgr, log, context, instanceContext)
+		reportHealthGates(mgr, log, context, results)
+		if !passed {
+			context.Current.AppendError(log,
+				"failed to update %v to %v, %v",
+				context.Current.PackageName,
+				context.Current.TargetVersion,
+				"health gates did not pass")
+			context.Current.AppendInfo(
+				log,
+				"Initiating rollback %v to %v",
+				context.Current.PackageName,
+				context.Current.SourceVersion)
+			if err = mgr.inProgress(context, log, Rollback); err != nil {
+				return err
+			}
+			return mgr.rollback(mgr, log, context)
+		}
 		return mgr.succeeded(context, log)
 	}
 
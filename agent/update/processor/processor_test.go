@@ -106,6 +106,12 @@ func TestInitializeUpdate(t *testing.T) {
 	// setup
 	updater := createDefaultUpdaterStub()
 	context := createUpdateContext("")
+	markersCleared := false
+	clearHealthMarkers = func() error {
+		markersCleared = true
+		return nil
+	}
+	defer func() { clearHealthMarkers = updateutil.ClearHealthMarkers }()
 
 	// action
 	context, err := updater.InitializeUpdate(logger, context.Current)
@@ -113,6 +119,7 @@ func TestInitializeUpdate(t *testing.T) {
 	// assert
 	assert.NotEmpty(t, context.Current.StandardOut)
 	assert.NotEmpty(t, context.Current.StartDateTime)
+	assert.True(t, markersCleared)
 	assert.NoError(t, err)
 }
 
@@ -0,0 +1,104 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updateutil contains updater specific utilities.
+package updateutil
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// HealthMarkerAgentStart is recorded every time the agent starts
+	HealthMarkerAgentStart = "agentstart"
+	// HealthMarkerHealthPing is recorded when the agent successfully reported its health to SSM
+	HealthMarkerHealthPing = "healthping"
+	// HealthMarkerControlChannel is recorded when the agent opened its MGS control channel
+	HealthMarkerControlChannel = "controlchannel"
+
+	healthMarkerFolder = "health"
+)
+
+// HealthMarker records the last time the running agent reached a milestone.
+// The updater reads the markers to decide whether a newly installed agent version is healthy.
+type HealthMarker struct {
+	Version string    `json:"Version"`
+	PID     int       `json:"PID"`
+	Time    time.Time `json:"Time"`
+	// Count is the number of consecutive times the marker was recorded by the same agent version
+	// since the markers were last cleared
+	Count int `json:"Count"`
+}
+
+// HealthMarkerFilePath returns the path of the file storing a health marker
+func HealthMarkerFilePath(marker string) string {
+	return filepath.Join(appconfig.UpdaterArtifactsRoot, healthMarkerFolder, marker+".json")
+}
+
+// LoadHealthMarker loads a health marker, returning nil if the marker was never recorded
+func LoadHealthMarker(marker string) (*HealthMarker, error) {
+	content, err := ioutil.ReadFile(HealthMarkerFilePath(marker))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var healthMarker HealthMarker
+	if err = json.Unmarshal(content, &healthMarker); err != nil {
+		return nil, err
+	}
+	return &healthMarker, nil
+}
+
+// ClearHealthMarkers removes all recorded health markers.
+// The updater clears them when an update starts, so the health gates only see the milestones reached after it.
+func ClearHealthMarkers() error {
+	return os.RemoveAll(filepath.Join(appconfig.UpdaterArtifactsRoot, healthMarkerFolder))
+}
+
+// RecordHealthMarker records that the running agent version reached a milestone.
+// Failures are only logged, the markers must never prevent the agent from working.
+func RecordHealthMarker(log log.T, marker string, version string) {
+	healthMarker := HealthMarker{
+		Version: version,
+		PID:     os.Getpid(),
+		Time:    time.Now().UTC(),
+		Count:   1,
+	}
+	if previous, err := LoadHealthMarker(marker); err == nil && previous != nil && previous.Version == version {
+		healthMarker.Count = previous.Count + 1
+	}
+
+	content, err := json.Marshal(healthMarker)
+	if err != nil {
+		log.Debugf("failed to serialize health marker %v, %v", marker, err)
+		return
+	}
+	filePath := HealthMarkerFilePath(marker)
+	if err = fileutil.MakeDirs(filepath.Dir(filePath)); err != nil {
+		log.Debugf("failed to create health marker folder, %v", err)
+		return
+	}
+	if err = ioutil.WriteFile(filePath, content, appconfig.ReadWriteAccess); err != nil {
+		log.Debugf("failed to record health marker %v, %v", marker, err)
+	}
+}
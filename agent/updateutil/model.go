@@ -25,8 +25,17 @@ import (
 
 //UpdatePluginResult represents Agent update plugin result
 type UpdatePluginResult struct {
-	StandOut      string    `json:"StandOut"`
-	StartDateTime time.Time `json:"StartDateTime"`
+	StandOut      string             `json:"StandOut"`
+	StartDateTime time.Time          `json:"StartDateTime"`
+	HealthGates   []HealthGateResult `json:"HealthGates,omitempty"`
+}
+
+//HealthGateResult represents the outcome of a post-update health gate of the new agent version
+type HealthGateResult struct {
+	Name      string    `json:"Name"`
+	Passed    bool      `json:"Passed"`
+	Message   string    `json:"Message"`
+	CheckedAt time.Time `json:"CheckedAt"`
 }
 
 //LoadUpdatePluginResult loads UpdatePluginResult from local storage
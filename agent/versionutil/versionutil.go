@@ -1,6 +1,7 @@
 package versionutil
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 
@@ -91,3 +92,82 @@ func normalizeForCompare(version string) string {
 	}
 	return version[0:lenSignificant]
 }
+
+// constraintOperators lists the supported comparison operators, longest first so that prefixes match correctly
+var constraintOperators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// SatisfiesConstraint returns true if version matches every comma separated comparison in the constraint,
+// for example ">=1.2.0, <2.0". A version without an operator requires an exact match and an empty constraint matches any version.
+func SatisfiesConstraint(version string, constraint string) (bool, error) {
+	if strings.TrimSpace(constraint) == "" {
+		return true, nil
+	}
+	for _, term := range strings.Split(constraint, ",") {
+		operator, target, err := parseConstraintTerm(term)
+		if err != nil {
+			return false, err
+		}
+		result := Compare(version, target, false)
+		var matches bool
+		switch operator {
+		case ">=":
+			matches = result >= 0
+		case "<=":
+			matches = result <= 0
+		case ">":
+			matches = result > 0
+		case "<":
+			matches = result < 0
+		case "!=":
+			matches = result != 0
+		default:
+			matches = result == 0
+		}
+		if !matches {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ValidateConstraint returns an error if the constraint cannot be parsed
+func ValidateConstraint(constraint string) error {
+	if strings.TrimSpace(constraint) == "" {
+		return nil
+	}
+	for _, term := range strings.Split(constraint, ",") {
+		if _, _, err := parseConstraintTerm(term); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExactVersion returns the version pinned by a constraint that consists of a single exact comparison
+func ExactVersion(constraint string) (version string, ok bool) {
+	if strings.Contains(constraint, ",") {
+		return "", false
+	}
+	operator, target, err := parseConstraintTerm(constraint)
+	if err != nil || (operator != "=" && operator != "==") {
+		return "", false
+	}
+	return target, true
+}
+
+// parseConstraintTerm splits a single comparison into its operator and version
+func parseConstraintTerm(term string) (operator string, version string, err error) {
+	term = strings.TrimSpace(term)
+	operator = "="
+	for _, candidate := range constraintOperators {
+		if strings.HasPrefix(term, candidate) {
+			operator = candidate
+			term = strings.TrimSpace(strings.TrimPrefix(term, candidate))
+			break
+		}
+	}
+	if term == "" {
+		return "", "", fmt.Errorf("invalid version constraint: missing version after %v", operator)
+	}
+	return operator, term, nil
+}
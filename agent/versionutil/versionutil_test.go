@@ -69,3 +69,50 @@ func TestSort(t *testing.T) {
 	sort.Sort(ByVersion(actual))
 	assert.Equal(t, actual, expected)
 }
+
+func TestSatisfiesConstraint(t *testing.T) {
+	testCases := []struct {
+		version    string
+		constraint string
+		expected   bool
+	}{
+		{"1.2.3", "", true},
+		{"1.2.3", "1.2.3", true},
+		{"1.2.3", "=1.2.3", true},
+		{"1.2.3", "==1.2", false},
+		{"1.2.0", "1.2", true},
+		{"1.2.3", ">=1.2.0, <2.0", true},
+		{"2.0.0", ">=1.2.0, <2.0", false},
+		{"1.1.9", ">=1.2.0, <2.0", false},
+		{"1.10.0", ">1.9", true},
+		{"1.2.3", "<=1.2.3", true},
+		{"1.2.3", "!=1.2.3", false},
+	}
+	for _, testCase := range testCases {
+		matches, err := SatisfiesConstraint(testCase.version, testCase.constraint)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expected, matches, "%v %v", testCase.version, testCase.constraint)
+	}
+
+	_, err := SatisfiesConstraint("1.0", ">=")
+	assert.Error(t, err)
+	assert.Error(t, ValidateConstraint("1.0, <"))
+	assert.NoError(t, ValidateConstraint(">= 1.0, < 2"))
+}
+
+func TestExactVersion(t *testing.T) {
+	version, ok := ExactVersion("1.2.3")
+	assert.True(t, ok)
+	assert.Equal(t, "1.2.3", version)
+
+	version, ok = ExactVersion("== 1.2.3")
+	assert.True(t, ok)
+	assert.Equal(t, "1.2.3", version)
+
+	_, ok = ExactVersion(">=1.2.3")
+	assert.False(t, ok)
+	_, ok = ExactVersion("1.2.3, <2")
+	assert.False(t, ok)
+	_, ok = ExactVersion("")
+	assert.False(t, ok)
+}